@@ -11,7 +11,9 @@ import (
 	"github.com/ollatomiwa/hotelsystem/booking-service/internal/services"
 	"github.com/ollatomiwa/hotelsystem/booking-service/pkg/config"
 	"github.com/ollatomiwa/hotelsystem/booking-service/pkg/database"
+	"github.com/ollatomiwa/hotelsystem/booking-service/pkg/guests"
 	"github.com/ollatomiwa/hotelsystem/booking-service/pkg/notifications"
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
 )
 
 func main() {
@@ -47,9 +49,12 @@ func main() {
 	// Initialize repositories
 	bookingRepo := postgres.NewBookingRepository(db)
 	roomRepo := postgres.NewRoomRepository(db)
+	ratePlanRepo := postgres.NewRatePlanRepository(db)
+	analyticsRepo := postgres.NewAnalyticsRepository(db)
+	feedRepo := postgres.NewICalFeedRepository(db)
 
 	// Initialize notification client
-	notifyClient := notifications.NewClient(cfg.Notifications.BaseURL)
+	notifyClient := notifications.NewClient(cfg.Notifications.BaseURL, cfg.Auth.ServiceKey)
 	
 	// Check notification service health
 	if cfg.Notifications.Enabled {
@@ -67,14 +72,30 @@ func main() {
 	}
 
 	// Initialize services
-	bookingService := services.NewBookingService(bookingRepo, roomRepo, notifyClient,
+	bookingService := services.NewBookingService(bookingRepo, roomRepo, ratePlanRepo, notifyClient,
 		cfg.Notifications.Enabled)
+	bookingService.SetGuestClient(guests.NewClient(cfg.Guests.BaseURL, cfg.Auth.ServiceKey))
+	ratePlanService := services.NewRatePlanService(ratePlanRepo)
+	analyticsService := services.NewAnalyticsService(analyticsRepo)
+
+	// Keep the analytics snapshot table fresh in the background
+	go analyticsService.RunSnapshotJob(context.Background(), cfg.Analytics.RefreshInterval,
+		cfg.Analytics.LookbackDays, cfg.Analytics.LookaheadDays)
+
+	calendarService := services.NewCalendarService(bookingRepo, roomRepo, feedRepo, cfg.ICal.ExportSecret)
+	go calendarService.RunFeedPoller(context.Background(), cfg.ICal.PollInterval)
+
+	// Channel adapters are registered here as OTA integrations are added
+	channelService := services.NewChannelService(bookingRepo, roomRepo, ratePlanRepo, cfg.Channels.HorizonDays)
+	bookingService.SetInventoryListener(channelService)
+	go channelService.RunSyncJob(context.Background(), cfg.Channels.SyncInterval)
 
 	// Create Gin router
 	router := gin.Default()
 
 	// Setup routes
-	handlers.SetupRoutes(router, bookingService)
+	handlers.SetupRoutes(router, bookingService, ratePlanService, analyticsService, calendarService,
+		channelService, auth.NewFromConfig(cfg.Auth), cfg.Policies)
 
 	// Start server - FIXED: Use proper port format
 	address := ":" + cfg.Server.Port
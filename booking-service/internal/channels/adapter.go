@@ -0,0 +1,22 @@
+// Package channels defines how booking-service talks to OTAs and other
+// distribution channels. Each channel is wrapped in an Adapter.
+package channels
+
+import (
+	"context"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+)
+
+// Adapter connects one distribution channel
+type Adapter interface {
+	// Name is the channel code, stored as the booking source
+	Name() string
+	// PublishARI pushes availability, rates and inventory to the channel
+	PublishARI(ctx context.Context, updates []models.ARIUpdate) error
+	// FetchReservations returns reservations created, modified or cancelled since the given time
+	FetchReservations(ctx context.Context, since time.Time) ([]models.ExternalReservation, error)
+	// GetARI returns what the channel currently shows, for reconciliation
+	GetARI(ctx context.Context, from, to time.Time) ([]models.ARIUpdate, error)
+}
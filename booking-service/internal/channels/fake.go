@@ -0,0 +1,98 @@
+package channels
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+)
+
+// FakeAdapter is an in-memory channel for tests and local development.
+// It stores whatever ARI is published and serves reservations added with AddReservation
+type FakeAdapter struct {
+	name         string
+	mu           sync.Mutex
+	ari          map[string]models.ARIUpdate
+	reservations []fakeReservation
+	publishCalls int
+}
+
+type fakeReservation struct {
+	reservation models.ExternalReservation
+	modifiedAt  time.Time
+}
+
+func NewFakeAdapter(name string) *FakeAdapter {
+	return &FakeAdapter{
+		name: name,
+		ari:  map[string]models.ARIUpdate{},
+	}
+}
+
+var _ Adapter = (*FakeAdapter)(nil)
+
+func (f *FakeAdapter) Name() string {
+	return f.name
+}
+
+func (f *FakeAdapter) PublishARI(ctx context.Context, updates []models.ARIUpdate) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.publishCalls++
+	for _, u := range updates {
+		f.ari[ariKey(u.RoomType, u.Date)] = u
+	}
+	return nil
+}
+
+func (f *FakeAdapter) FetchReservations(ctx context.Context, since time.Time) ([]models.ExternalReservation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []models.ExternalReservation
+	for _, r := range f.reservations {
+		if !r.modifiedAt.Before(since) {
+			out = append(out, r.reservation)
+		}
+	}
+	return out, nil
+}
+
+func (f *FakeAdapter) GetARI(ctx context.Context, from, to time.Time) ([]models.ARIUpdate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []models.ARIUpdate
+	for _, u := range f.ari {
+		date, err := time.Parse("2006-01-02", u.Date)
+		if err != nil || date.Before(from) || date.After(to) {
+			continue
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+// AddReservation makes a reservation (or a new version of one) visible to FetchReservations
+func (f *FakeAdapter) AddReservation(r models.ExternalReservation) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reservations = append(f.reservations, fakeReservation{reservation: r, modifiedAt: time.Now()})
+}
+
+// SetARI overwrites what the channel shows, to simulate drift
+func (f *FakeAdapter) SetARI(u models.ARIUpdate) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ari[ariKey(u.RoomType, u.Date)] = u
+}
+
+// PublishCalls reports how many times ARI was published
+func (f *FakeAdapter) PublishCalls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.publishCalls
+}
+
+func ariKey(roomType models.RoomType, date string) string {
+	return string(roomType) + "|" + date
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/repositories"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/services"
+)
+
+type AdminHandler struct {
+	bookingService *services.BookingService
+}
+
+func NewAdminHandler(bookingService *services.BookingService) *AdminHandler {
+	return &AdminHandler{
+		bookingService: bookingService,
+	}
+}
+
+// SearchBookings lists bookings for staff. Pass format=csv to download every match as CSV
+func (h *AdminHandler) SearchBookings(c *gin.Context) {
+	filter, err := parseBookingSearchFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("invalid_filter", err.Error()))
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		bookings, err := h.bookingService.ExportBookings(c.Request.Context(), filter)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, NewErrorResponse("export_failed", err.Error()))
+			return
+		}
+		writeBookingsCSV(c, bookings)
+		return
+	}
+
+	result, err := h.bookingService.SearchBookings(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("search_failed", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// CompleteBooking checks the guest out, which earns their loyalty points
+func (h *AdminHandler) CompleteBooking(c *gin.Context) {
+	booking, err := h.bookingService.CompleteBooking(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, repositories.ErrBookingNotFound) {
+		c.JSON(http.StatusNotFound, NewErrorResponse("not_found", "booking not found"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("complete_failed", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, booking)
+}
+
+// GetGuestPreferences shows the guest's stay preferences from user-service for room assignment
+func (h *AdminHandler) GetGuestPreferences(c *gin.Context) {
+	booking, err := h.bookingService.GetBooking(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, NewErrorResponse("not_found", "booking not found"))
+		return
+	}
+
+	preferences, err := h.bookingService.GetGuestPreferences(c.Request.Context(), booking)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, NewErrorResponse("preferences_unavailable", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, preferences)
+}
+
+func parseBookingSearchFilter(c *gin.Context) (*models.BookingSearchFilter, error) {
+	filter := &models.BookingSearchFilter{
+		Status:     models.BookingStatus(c.Query("status")),
+		RoomId:     c.Query("room_id"),
+		Guest:      c.Query("guest"),
+		SortBy:     models.BookingSortField(c.DefaultQuery("sort", string(models.SortByCreatedAt))),
+		Descending: c.DefaultQuery("order", "desc") == "desc",
+		Cursor:     c.Query("cursor"),
+	}
+
+	switch filter.SortBy {
+	case models.SortByCreatedAt, models.SortByCheckIn, models.SortByTotalAmount:
+	default:
+		return nil, fmt.Errorf("sort must be one of: created_at, check_in, total_amount")
+	}
+
+	switch filter.Status {
+	case "", models.StatusPending, models.StatusConfirmed, models.StatusCancelled, models.StatusCompleted:
+	default:
+		return nil, fmt.Errorf("invalid status: %s", filter.Status)
+	}
+
+	dates := []struct {
+		param  string
+		target **time.Time
+	}{
+		{"stay_from", &filter.StayFrom},
+		{"stay_to", &filter.StayTo},
+		{"created_from", &filter.CreatedFrom},
+		{"created_to", &filter.CreatedTo},
+	}
+	for _, d := range dates {
+		value := c.Query(d.param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s date: %w", d.param, err)
+		}
+		*d.target = &t
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit: %w", err)
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+func writeBookingsCSV(c *gin.Context, bookings []models.Booking) {
+	filename := fmt.Sprintf("bookings-%s.csv", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"id", "user_id", "guest_name", "guest_email", "room_id", "room_type", "rate_plan_id",
+		"check_in", "check_out", "guests", "total_amount", "status", "created_at"})
+	for _, b := range bookings {
+		writeCSVRow(w, []string{
+			b.Id,
+			b.UserId,
+			b.GuestName,
+			b.GuestEmail,
+			b.RoomId,
+			string(b.RoomType),
+			b.RatePlanId,
+			b.CheckIn.Format("2006-01-02"),
+			b.CheckOut.Format("2006-01-02"),
+			strconv.Itoa(b.Guest),
+			strconv.FormatFloat(b.TotalAmount, 'f', 2, 64),
+			string(b.Status),
+			b.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	w.Flush()
+}
+
+// writeCSVRow neutralises cells a spreadsheet would run as a formula, since guest
+// names and emails come from whoever made the booking
+func writeCSVRow(w *csv.Writer, cells []string) {
+	for i, cell := range cells {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			cells[i] = "'" + cell
+		}
+	}
+	w.Write(cells)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteBookingsCSV_EscapesFormulas(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		name, guest, want string
+	}{
+		{"plain name", "Ada Lovelace", "Ada Lovelace"},
+		{"formula", "=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"plus", "+1+1", "'+1+1"},
+		{"minus", "-2+3", "'-2+3"},
+		{"at", "@SUM(A1)", "'@SUM(A1)"},
+		{"tab", "\t=1", "'\t=1"},
+		{"formula characters later on", "Smith-Jones = guest", "Smith-Jones = guest"},
+		{"empty", "", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			writeBookingsCSV(c, []models.Booking{{
+				Id:          "booking-1",
+				GuestName:   tc.guest,
+				GuestEmail:  "guest@example.com",
+				CheckIn:     time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC),
+				CheckOut:    time.Date(2026, 5, 3, 0, 0, 0, 0, time.UTC),
+				TotalAmount: 240,
+				Status:      models.StatusConfirmed,
+			}})
+
+			rows, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+			require.NoError(t, err)
+			require.Len(t, rows, 2)
+			assert.Equal(t, "guest_name", rows[0][2])
+			assert.Equal(t, tc.want, rows[1][2])
+			assert.Equal(t, "240.00", rows[1][10])
+		})
+	}
+}
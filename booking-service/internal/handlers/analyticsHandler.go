@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/services"
+)
+
+type AnalyticsHandler struct {
+	analyticsService *services.AnalyticsService
+}
+
+func NewAnalyticsHandler(analyticsService *services.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		analyticsService: analyticsService,
+	}
+}
+
+// GetKPIs reports occupancy, ADR, RevPAR, pace and cancellation rate.
+// group_by takes a comma separated list of day, room_type and property
+func (h *AnalyticsHandler) GetKPIs(c *gin.Context) {
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("invalid_range", err.Error()))
+		return
+	}
+
+	query := &models.AnalyticsQuery{
+		From:       from,
+		To:         to,
+		PropertyId: c.Query("property_id"),
+		RoomType:   models.RoomType(c.Query("room_type")),
+	}
+	if groupBy := c.DefaultQuery("group_by", string(models.DimensionDay)); groupBy != "" {
+		for _, dim := range strings.Split(groupBy, ",") {
+			switch d := models.AnalyticsDimension(strings.TrimSpace(dim)); d {
+			case models.DimensionDay, models.DimensionRoomType, models.DimensionProperty:
+				query.GroupBy = append(query.GroupBy, d)
+			default:
+				c.JSON(http.StatusBadRequest, NewErrorResponse("invalid_group_by", "group_by must be a list of: day, room_type, property"))
+				return
+			}
+		}
+	}
+
+	report, err := h.analyticsService.GetReport(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("analytics_failed", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// RefreshSnapshots rebuilds the daily snapshot for a date range on demand
+func (h *AnalyticsHandler) RefreshSnapshots(c *gin.Context) {
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("invalid_range", err.Error()))
+		return
+	}
+
+	if err := h.analyticsService.RefreshSnapshots(c.Request.Context(), from, to); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("refresh_failed", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Message: "snapshots refreshed", Timestamp: time.Now()})
+}
+
+func parseDateRange(c *gin.Context) (time.Time, time.Time, error) {
+	from, err := time.Parse("2006-01-02", c.Query("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("from is required as YYYY-MM-DD")
+	}
+	to, err := time.Parse("2006-01-02", c.Query("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("to is required as YYYY-MM-DD")
+	}
+	return from, to, nil
+}
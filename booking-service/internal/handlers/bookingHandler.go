@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
 	"github.com/ollatomiwa/hotelsystem/booking-service/internal/services"
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
 )
 
 type BookingHandler struct {
@@ -27,6 +28,18 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 		return 
 	}
 
+	// guests always book for themselves
+	if claims, ok := auth.ClaimsFrom(c); ok && !actsForGuests(claims, auth.PermBookingsWrite) {
+		req.UserId = claims.UserId
+		if claims.Email != "" {
+			req.UserEmail = claims.Email
+		}
+	}
+	if req.UserId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user id is required"})
+		return 
+	}
+
 	booking, err := h.bookingService.CreateBooking(c.Request.Context(), &req)
 	if err != nil{
 		c.JSON(http.StatusBadRequest, gin.H{"error" :" booking creation failed"+ err.Error()})
@@ -44,8 +57,8 @@ func (h *BookingHandler) GetBooking(c *gin.Context){
 	}
 
 	booking, err := h.bookingService.GetBooking(c.Request.Context(), bookingId)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "booking not found" + err.Error()})
+	if err != nil || !canAccessBooking(c, booking, auth.PermBookingsRead) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "booking not found"})
 		return 
 	}
 
@@ -54,6 +67,9 @@ func (h *BookingHandler) GetBooking(c *gin.Context){
 
 func (h *BookingHandler) GetUserBookings(c *gin.Context) {
 	userId := c.Query("user_id")
+	if claims, ok := auth.ClaimsFrom(c); ok && !actsForGuests(claims, auth.PermBookingsRead) {
+		userId = claims.UserId
+	}
 	if userId == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "user id is required"})
 		return 
@@ -75,7 +91,13 @@ func (h *BookingHandler) CancelBooking(c *gin.Context) {
 		return 
 	}
 
-	err := h.bookingService.CancelBooking(c.Request.Context(), bookingId)
+	booking, err := h.bookingService.GetBooking(c.Request.Context(), bookingId)
+	if err != nil || !canAccessBooking(c, booking, auth.PermBookingsWrite) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "booking not found"})
+		return 
+	}
+
+	err = h.bookingService.CancelBooking(c.Request.Context(), bookingId)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "booking not found"})
 		return 
@@ -118,6 +140,18 @@ func (h *BookingHandler) CheckAvailability(c *gin.Context) {
 }
 
 // Helper functions
+
+// actsForGuests reports whether the caller may work on other people's bookings
+func actsForGuests(claims *auth.Claims, permission string) bool {
+	return claims.IsService() || claims.HasPermission(permission)
+}
+
+// guests only reach their own bookings, staff need the permission for the action
+func canAccessBooking(c *gin.Context, booking *models.Booking, permission string) bool {
+	claims, ok := auth.ClaimsFrom(c)
+	return ok && (actsForGuests(claims, permission) || booking.UserId == claims.UserId)
+}
+
 func isValidRoomType(roomType models.RoomType) bool {
 	switch roomType {
 	case models.RoomTypeSingle, models.RoomTypeDouble, models.RoomTypeDeluxe:
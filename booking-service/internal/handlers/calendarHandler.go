@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/services"
+)
+
+type CalendarHandler struct {
+	calendarService *services.CalendarService
+}
+
+func NewCalendarHandler(calendarService *services.CalendarService) *CalendarHandler {
+	return &CalendarHandler{
+		calendarService: calendarService,
+	}
+}
+
+// ExportRoomCalendar serves a room's booked dates as an ICS feed for OTAs and owners
+func (h *CalendarHandler) ExportRoomCalendar(c *gin.Context) {
+	roomId := c.Param("id")
+	if !h.calendarService.ExportEnabled() {
+		c.JSON(http.StatusNotFound, NewErrorResponse("calendar_export_disabled", "calendar export is not enabled"))
+		return
+	}
+	if !h.calendarService.VerifyExportToken(roomId, c.Query("token")) {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("invalid_token", "invalid calendar token"))
+		return
+	}
+
+	calendar, err := h.calendarService.ExportRoomCalendar(c.Request.Context(), roomId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, NewErrorResponse("calendar_export_failed", err.Error()))
+		return
+	}
+	c.Header("Content-Disposition", "inline; filename=room-"+roomId+".ics")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", calendar)
+}
+
+// GetRoomCalendarURL returns the tokenized export path to hand to a channel
+func (h *CalendarHandler) GetRoomCalendarURL(c *gin.Context) {
+	roomId := c.Param("id")
+	if !h.calendarService.ExportEnabled() {
+		c.JSON(http.StatusNotFound, NewErrorResponse("calendar_export_disabled", "calendar export is not enabled, set ICAL_EXPORT_SECRET"))
+		return
+	}
+	path := "/api/v1/rooms/" + roomId + "/calendar.ics?token=" + h.calendarService.ExportToken(roomId)
+	c.JSON(http.StatusOK, gin.H{"room_id": roomId, "path": path})
+}
+
+func (h *CalendarHandler) CreateFeed(c *gin.Context) {
+	var req models.CreateICalFeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("invalid_request", "Invalid request payload: "+err.Error()))
+		return
+	}
+
+	feed, err := h.calendarService.CreateFeed(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("feed_creation_failed", err.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, feed)
+}
+
+func (h *CalendarHandler) ListFeeds(c *gin.Context) {
+	feeds, err := h.calendarService.ListFeeds(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("feeds_failed", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, feeds)
+}
+
+// SyncFeeds imports every registered feed now instead of waiting for the poller
+func (h *CalendarHandler) SyncFeeds(c *gin.Context) {
+	c.JSON(http.StatusOK, h.calendarService.SyncAllFeeds(c.Request.Context()))
+}
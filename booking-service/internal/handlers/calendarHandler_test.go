@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportRoomCalendar_DisabledWithoutSecret(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewCalendarHandler(services.NewCalendarService(nil, nil, nil, ""))
+	router := gin.New()
+	router.GET("/rooms/:id/calendar.ics", handler.ExportRoomCalendar)
+	router.GET("/rooms/:id/calendar-url", handler.GetRoomCalendarURL)
+
+	for _, path := range []string{"/rooms/room-1/calendar.ics", "/rooms/room-1/calendar.ics?token=", "/rooms/room-1/calendar-url"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+}
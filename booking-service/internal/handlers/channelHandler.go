@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/services"
+)
+
+type ChannelHandler struct {
+	channelService *services.ChannelService
+}
+
+func NewChannelHandler(channelService *services.ChannelService) *ChannelHandler {
+	return &ChannelHandler{
+		channelService: channelService,
+	}
+}
+
+// PublishARI pushes availability, rates and inventory for a date range to every channel
+func (h *ChannelHandler) PublishARI(c *gin.Context) {
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("invalid_range", err.Error()))
+		return
+	}
+
+	if err := h.channelService.PublishARI(c.Request.Context(), from, to); err != nil {
+		c.JSON(http.StatusBadGateway, NewErrorResponse("publish_failed", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Message: "ARI published", Timestamp: time.Now()})
+}
+
+// IngestReservations pulls reservations from every channel now instead of waiting for the job
+func (h *ChannelHandler) IngestReservations(c *gin.Context) {
+	c.JSON(http.StatusOK, h.channelService.IngestReservations(c.Request.Context()))
+}
+
+// Reconcile reports where channels differ from us for a date range and republishes those dates
+func (h *ChannelHandler) Reconcile(c *gin.Context) {
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("invalid_range", err.Error()))
+		return
+	}
+
+	drift, err := h.channelService.Reconcile(c.Request.Context(), from, to)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, NewErrorResponse("reconcile_failed", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"drift": drift, "count": len(drift)})
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/services"
+)
+
+type PrivacyHandler struct {
+	bookingService *services.BookingService
+}
+
+func NewPrivacyHandler(bookingService *services.BookingService) *PrivacyHandler {
+	return &PrivacyHandler{
+		bookingService: bookingService,
+	}
+}
+
+// ExportUserData returns everything this service holds about a user
+func (h *PrivacyHandler) ExportUserData(c *gin.Context) {
+	bookings, err := h.bookingService.GetUserBooking(c.Request.Context(), c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("export_failed", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"bookings": bookings})
+}
+
+// AnonymizeUserData removes a user's personal details, keeping the bookings for accounting.
+// It is safe to call again if a previous erasure failed part way
+func (h *PrivacyHandler) AnonymizeUserData(c *gin.Context) {
+	count, err := h.bookingService.AnonymizeUserBookings(c.Request.Context(), c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("anonymize_failed", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"bookings_anonymized": count})
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/services"
+)
+
+type RatePlanHandler struct {
+	ratePlanService *services.RatePlanService
+}
+
+func NewRatePlanHandler(ratePlanService *services.RatePlanService) *RatePlanHandler {
+	return &RatePlanHandler{
+		ratePlanService: ratePlanService,
+	}
+}
+
+func (h *RatePlanHandler) CreateRatePlan(c *gin.Context) {
+	var req models.CreateRatePlanRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("invalid_request", "Invalid request payload: "+err.Error()))
+		return
+	}
+
+	if !isValidRoomType(req.RoomType) {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("invalid_room_type", "Room type must be one of: single, double, deluxe"))
+		return
+	}
+
+	plan, err := h.ratePlanService.CreateRatePlan(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("rate_plan_creation_failed", err.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, plan)
+}
+
+func (h *RatePlanHandler) ListRatePlans(c *gin.Context) {
+	roomType := models.RoomType(c.Query("room_type"))
+	if roomType != "" && !isValidRoomType(roomType) {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("invalid_room_type", "Room type must be one of: single, double, deluxe"))
+		return
+	}
+
+	plans, err := h.ratePlanService.ListRatePlans(c.Request.Context(), roomType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("rate_plans_failed", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, plans)
+}
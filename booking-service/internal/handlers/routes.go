@@ -3,11 +3,21 @@ package handlers
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/ollatomiwa/hotelsystem/booking-service/internal/services"
+	"github.com/ollatomiwa/hotelsystem/booking-service/pkg/config"
 	"github.com/ollatomiwa/hotelsystem/booking-service/pkg/middleware"
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
 )
 
-func SetupRoutes(router *gin.Engine, bookingService *services.BookingService) {
+func SetupRoutes(router *gin.Engine, bookingService *services.BookingService, ratePlanService *services.RatePlanService,
+	analyticsService *services.AnalyticsService, calendarService *services.CalendarService,
+	channelService *services.ChannelService, authn *auth.Middleware, policies config.PoliciesConfig) {
 	bookingHandler := NewBookingHandler(bookingService)
+	ratePlanHandler := NewRatePlanHandler(ratePlanService)
+	adminHandler := NewAdminHandler(bookingService)
+	analyticsHandler := NewAnalyticsHandler(analyticsService)
+	calendarHandler := NewCalendarHandler(calendarService)
+	channelHandler := NewChannelHandler(channelService)
+	privacyHandler := NewPrivacyHandler(bookingService)
 	healthHandler := NewHealthHandler()
 
 	router.Use(middleware.CORS())
@@ -18,14 +28,65 @@ func SetupRoutes(router *gin.Engine, bookingService *services.BookingService) {
 
 	v1 := router.Group("/api/v1")
 	{
+		// Availability is public, everything else needs a guest token or service key
+		v1.POST("/bookings/availability", bookingHandler.CheckAvailability)
+
+		// Guests with an unverified email can still look at their bookings but not make new ones
+		createBooking := []gin.HandlerFunc{bookingHandler.CreateBooking}
+		if policies.RequireVerifiedEmail {
+			createBooking = append([]gin.HandlerFunc{authn.RequireVerifiedEmail()}, createBooking...)
+		}
+
 		bookings := v1.Group("/bookings")
+		bookings.Use(authn.Authenticate())
 		{
-			bookings.POST("", bookingHandler.CreateBooking)
-			bookings.POST("/availability", bookingHandler.CheckAvailability)
+			bookings.POST("", createBooking...)
 			bookings.GET("", bookingHandler.GetUserBookings)
 			bookings.GET("/:id", bookingHandler.GetBooking)
 			bookings.PUT("/:id/cancel", bookingHandler.CancelBooking)
 		}
+
+		rooms := v1.Group("/rooms")
+		{
+			rooms.GET("/:id/calendar.ics", calendarHandler.ExportRoomCalendar)
+		}
+
+		ratePlans := v1.Group("/rate-plans")
+		{
+			ratePlans.GET("", ratePlanHandler.ListRatePlans)
+			ratePlans.POST("", authn.Authenticate(), authn.RequirePermission(auth.PermRatesManage), ratePlanHandler.CreateRatePlan)
+		}
+
+		// Internal routes for other services, user-service uses these for data export and erasure
+		internal := v1.Group("/internal")
+		internal.Use(authn.Authenticate(), authn.RequireService())
+		{
+			internal.GET("/users/:userId/data", privacyHandler.ExportUserData)
+			internal.DELETE("/users/:userId/data", privacyHandler.AnonymizeUserData)
+		}
+
+		// Staff routes, each needs the permission for its area
+		admin := v1.Group("/admin")
+		admin.Use(authn.Authenticate())
+		{
+			admin.GET("/bookings", authn.RequirePermission(auth.PermBookingsRead), adminHandler.SearchBookings)
+			admin.PUT("/bookings/:id/complete", authn.RequirePermission(auth.PermBookingsWrite), adminHandler.CompleteBooking)
+			admin.GET("/bookings/:id/guest-preferences", authn.RequirePermission(auth.PermBookingsRead), adminHandler.GetGuestPreferences)
+			admin.GET("/analytics/kpis", authn.RequirePermission(auth.PermReportsRead), analyticsHandler.GetKPIs)
+			admin.POST("/analytics/refresh", authn.RequirePermission(auth.PermReportsManage), analyticsHandler.RefreshSnapshots)
+
+			channels := admin.Group("")
+			channels.Use(authn.RequirePermission(auth.PermChannelsManage))
+			{
+				channels.GET("/rooms/:id/calendar-url", calendarHandler.GetRoomCalendarURL)
+				channels.GET("/ical-feeds", calendarHandler.ListFeeds)
+				channels.POST("/ical-feeds", calendarHandler.CreateFeed)
+				channels.POST("/ical-feeds/sync", calendarHandler.SyncFeeds)
+				channels.POST("/channels/publish", channelHandler.PublishARI)
+				channels.POST("/channels/ingest", channelHandler.IngestReservations)
+				channels.POST("/channels/reconcile", channelHandler.Reconcile)
+			}
+		}
 	}
 
 	router.NoRoute(func(c *gin.Context){
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// analytics dimension represents a field KPI rows can be grouped by
+type AnalyticsDimension string
+
+const (
+	DimensionDay      AnalyticsDimension = "day"
+	DimensionRoomType AnalyticsDimension = "room_type"
+	DimensionProperty AnalyticsDimension = "property"
+)
+
+// analytics query represents the filters and grouping for a KPI report
+type AnalyticsQuery struct {
+	From       time.Time
+	To         time.Time
+	PropertyId string
+	RoomType   RoomType
+	GroupBy    []AnalyticsDimension
+}
+
+// kpi row represents hotel KPIs aggregated over one group of daily snapshots.
+// date, property and room type are only set when grouped by them
+type KPIRow struct {
+	Date                string   `json:"date,omitempty"`
+	PropertyId          string   `json:"property_id,omitempty"`
+	RoomType            RoomType `json:"room_type,omitempty"`
+	RoomNightsAvailable int      `json:"room_nights_available"`
+	RoomNightsSold      int      `json:"room_nights_sold"`
+	RoomNightsCancelled int      `json:"room_nights_cancelled"`
+	RoomNightsPickedUp  int      `json:"room_nights_picked_up"`
+	RoomRevenue         float64  `json:"room_revenue"`
+	OccupancyRate       float64  `json:"occupancy_rate"`
+	ADR                 float64  `json:"adr"`
+	RevPAR              float64  `json:"revpar"`
+	CancellationRate    float64  `json:"cancellation_rate"`
+}
+
+// analytics report represents the KPI rows for a query plus the overall totals
+type AnalyticsReport struct {
+	From  string   `json:"from"`
+	To    string   `json:"to"`
+	Rows  []KPIRow `json:"rows"`
+	Total KPIRow   `json:"total"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// booking sort field represents the columns staff can order search results by
+type BookingSortField string
+
+const (
+	SortByCreatedAt   BookingSortField = "created_at"
+	SortByCheckIn     BookingSortField = "check_in"
+	SortByTotalAmount BookingSortField = "total_amount"
+)
+
+// booking search filter represents the staff search criteria for bookings.
+// zero values mean "no filter". the date bounds are whole days and inclusive
+// at both ends
+type BookingSearchFilter struct {
+	StayFrom    *time.Time
+	StayTo      *time.Time
+	Status      BookingStatus
+	RoomId      string
+	Guest       string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	SortBy      BookingSortField
+	Descending  bool
+	Cursor      string
+	Limit       int
+}
+
+// booking search result represents one page of search results
+type BookingSearchResult struct {
+	Bookings   []Booking `json:"bookings"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
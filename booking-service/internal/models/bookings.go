@@ -11,6 +11,7 @@ const (
 	StatusPending BookingStatus = "pending"
 	StatusConfirmed BookingStatus = "confirmed"
 	StatusCancelled BookingStatus = "cancelled"
+	StatusCompleted BookingStatus = "completed"
 )
 
 //room type represents different types of rooms
@@ -27,6 +28,11 @@ type Booking struct {
 	UserId string `json:"user_id"`
 	RoomId string `json:"room_id"`
 	RoomType RoomType `json:"room_type"`
+	RatePlanId string `json:"rate_plan_id,omitempty"`
+	GuestName string `json:"guest_name,omitempty"`
+	GuestEmail string `json:"guest_email,omitempty"`
+	Source string `json:"source"`
+	ExternalRef string `json:"external_ref,omitempty"`
 	CheckIn time.Time `json:"check_in"`
 	CheckOut time.Time `json:"check_out"`
 	Guest int `json:"guests"`
@@ -39,6 +45,7 @@ type Booking struct {
 //room represents a hotel room
 type Room struct {
 	Id string `json:"id"`
+	PropertyId string `json:"property_id"`
 	RoomNumber string `json:"room_number"`
 	RoomType RoomType `json:"room_type"`
 	PricePerNight float64 `json:"price_per_night"`
@@ -48,9 +55,12 @@ type Room struct {
 }
 //createbooking request represents the payload for creating a booking
 type BookingRequest struct {
-	UserId string `json:"user_id" binding:"required"`
+	// set from the caller's token for guests, required from services
+	UserId string `json:"user_id"`
 	UserEmail string `json:"user_email" binding:"required,email"`
+	GuestName string `json:"guest_name,omitempty"`
 	RoomId string `json:"room_id" binding:"required"`
+	RatePlanId string `json:"rate_plan_id,omitempty"`
 	CheckIn string `json:"check_in" binding:"required"`
 	CheckOut string `json:"check_out" binding:"required"`
 	Guests int `json:"guests" binding:"required,min=1,max=5"`
@@ -66,9 +76,12 @@ type AvailabilityRequest struct {
 //availability response represents available rooms for a given dates
 type AvailabilityResponse struct {
 	AvailableRooms []RoomAvailability `json:"available_rooms"`
+	// total available counts rooms, total offers counts entries in available rooms,
+	// which lists each room once per rate plan
 	TotalAvailable int `json:"total_available"`
+	TotalOffers    int `json:"total_offers"`
 }
-//room availability represents an available room with pricing
+//room availability represents an available room offer with pricing for one rate plan
 type RoomAvailability struct {
 	RoomId string `json:"room_id"`
 	RoomNumber string `json:"room_number"`
@@ -76,4 +89,9 @@ type RoomAvailability struct {
 	PricePerNight float64 `json:"price_per_night"`
 	TotalPrice float64 `json:"total_price"`
 	MaxGuests int `json:"max_guests"`
+	RatePlanId string `json:"rate_plan_id,omitempty"`
+	RatePlanName string `json:"rate_plan_name,omitempty"`
+	Inclusions []string `json:"inclusions,omitempty"`
+	CancellationPolicy CancellationPolicy `json:"cancellation_policy,omitempty"`
+	PaymentTerms PaymentTerms `json:"payment_terms,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// booking sources. external channels use their own source code
+const (
+	SourceDirect = "direct"
+	SourceICal   = "ical"
+)
+
+// ical feed represents an external calendar polled for blocking bookings on a room
+type ICalFeed struct {
+	Id           string     `json:"id"`
+	RoomId       string     `json:"room_id"`
+	Name         string     `json:"name"`
+	URL          string     `json:"url"`
+	LastSyncedAt *time.Time `json:"last_synced_at,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// create ical feed request represents the payload for registering a feed
+type CreateICalFeedRequest struct {
+	RoomId string `json:"room_id" binding:"required"`
+	Name   string `json:"name" binding:"required"`
+	URL    string `json:"url" binding:"required,url"`
+}
+
+// feed sync result represents what one import run changed
+type FeedSyncResult struct {
+	FeedId    string `json:"feed_id"`
+	Created   int    `json:"created"`
+	Updated   int    `json:"updated"`
+	Unchanged int    `json:"unchanged"`
+	Cancelled int    `json:"cancelled"`
+	// conflicts counts events skipped because the room is already booked then
+	Conflicts int    `json:"conflicts"`
+	Error     string `json:"error,omitempty"`
+}
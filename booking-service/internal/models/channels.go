@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// ari update represents availability, rates and inventory for one room type on one night
+type ARIUpdate struct {
+	RoomType  RoomType     `json:"room_type"`
+	Date      string       `json:"date"`
+	Inventory int          `json:"inventory"`
+	Available int          `json:"available"`
+	Rates     []RateUpdate `json:"rates"`
+}
+
+// rate update represents the nightly price of one rate plan
+type RateUpdate struct {
+	RatePlanId string  `json:"rate_plan_id"`
+	Price      float64 `json:"price"`
+}
+
+// external reservation represents a reservation received from a channel
+type ExternalReservation struct {
+	ExternalRef string        `json:"external_ref"`
+	RoomType    RoomType      `json:"room_type"`
+	GuestName   string        `json:"guest_name"`
+	GuestEmail  string        `json:"guest_email"`
+	CheckIn     time.Time     `json:"check_in"`
+	CheckOut    time.Time     `json:"check_out"`
+	Guests      int           `json:"guests"`
+	TotalAmount float64       `json:"total_amount"`
+	Status      BookingStatus `json:"status"`
+}
+
+// inventory count represents how many rooms of a type are booked on a night
+type InventoryCount struct {
+	RoomType RoomType
+	Date     string
+	Booked   int
+}
+
+// ingest result represents what one reservation pull from a channel changed
+type IngestResult struct {
+	Channel   string   `json:"channel"`
+	Created   int      `json:"created"`
+	Updated   int      `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	Failed    []string `json:"failed,omitempty"`
+}
+
+// drift item represents a value a channel shows that differs from ours
+type DriftItem struct {
+	Channel  string   `json:"channel"`
+	RoomType RoomType `json:"room_type"`
+	Date     string   `json:"date"`
+	Field    string   `json:"field"`
+	Local    float64  `json:"local"`
+	Remote   float64  `json:"remote"`
+}
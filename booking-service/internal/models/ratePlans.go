@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// cancellation policy represents how a booking on a rate plan can be cancelled
+type CancellationPolicy string
+
+const (
+	CancellationFlexible      CancellationPolicy = "flexible"
+	CancellationNonRefundable CancellationPolicy = "non_refundable"
+)
+
+// payment terms represents when the guest pays for a booking
+type PaymentTerms string
+
+const (
+	PaymentPayNow     PaymentTerms = "pay_now"
+	PaymentPayAtHotel PaymentTerms = "pay_at_hotel"
+)
+
+// rate plan represents a sellable price and policy package for a room type
+type RatePlan struct {
+	Id                 string             `json:"id"`
+	Code               string             `json:"code"`
+	Name               string             `json:"name"`
+	RoomType           RoomType           `json:"room_type"`
+	PriceModifierPct   float64            `json:"price_modifier_pct"`
+	PriceModifierFlat  float64            `json:"price_modifier_flat"`
+	Inclusions         []string           `json:"inclusions"`
+	CancellationPolicy CancellationPolicy `json:"cancellation_policy"`
+	CancellationHours  int                `json:"cancellation_hours"`
+	PaymentTerms       PaymentTerms       `json:"payment_terms"`
+	Active             bool               `json:"active"`
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
+}
+
+// nightly rate applies the plan's modifiers to a room's base price
+func (p *RatePlan) NightlyRate(basePrice float64) float64 {
+	rate := basePrice*(1+p.PriceModifierPct/100) + p.PriceModifierFlat
+	if rate < 0 {
+		return 0
+	}
+	return rate
+}
+
+// create rate plan request represents the payload for creating a rate plan.
+// cancellation hours defaults to the standard 24 when omitted, 0 allows cancelling up to check in
+type CreateRatePlanRequest struct {
+	Code               string             `json:"code" binding:"required"`
+	Name               string             `json:"name" binding:"required"`
+	RoomType           RoomType           `json:"room_type" binding:"required"`
+	PriceModifierPct   float64            `json:"price_modifier_pct" binding:"min=-100"`
+	PriceModifierFlat  float64            `json:"price_modifier_flat"`
+	Inclusions         []string           `json:"inclusions"`
+	CancellationPolicy CancellationPolicy `json:"cancellation_policy" binding:"required"`
+	CancellationHours  *int               `json:"cancellation_hours" binding:"omitempty,min=0"`
+	PaymentTerms       PaymentTerms       `json:"payment_terms" binding:"required"`
+}
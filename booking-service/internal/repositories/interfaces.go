@@ -2,17 +2,28 @@ package repositories
 
 import (
 	"context"
-	
+	"errors"
+	"time"
 
 	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
 )
 
+// ErrBookingNotFound is returned by lookups that find no booking
+var ErrBookingNotFound = errors.New("booking not found")
+
 type BookingRepository interface {
 	CreateBooking(ctx context.Context, booking *models.Booking) error
 	GetAvailableRooms(ctx context.Context, req *models.AvailabilityRequest) ([]models.RoomAvailability, error)
 	GetBookingById(ctx context.Context, id string) (*models.Booking, error)
 	GetUserBookings(ctx context.Context, userId string) ([]models.Booking, error)
 	UpdateBookingStatus(ctx context.Context, id string, status models.BookingStatus) error
+	SearchBookings(ctx context.Context, filter *models.BookingSearchFilter) (*models.BookingSearchResult, error)
+	GetRoomBookings(ctx context.Context, roomId string, from time.Time) ([]models.Booking, error)
+	UpsertExternalBooking(ctx context.Context, booking *models.Booking) (created bool, updated bool, err error)
+	CancelMissingExternalBookings(ctx context.Context, source string, keepRefs []string) (int, error)
+	GetBookingByExternalRef(ctx context.Context, source, externalRef string) (*models.Booking, error)
+	GetBookedRoomCounts(ctx context.Context, from, to time.Time) ([]models.InventoryCount, error)
+	AnonymizeUserBookings(ctx context.Context, userId string) (int, error)
 }
 
 type RoomRepository interface {
@@ -20,3 +31,21 @@ type RoomRepository interface {
 	CreateRoom(ctx context.Context, room *models.Room) error
 	GetAllRooms(ctx context.Context) ([]models.Room, error)
 }
+
+type RatePlanRepository interface {
+	CreateRatePlan(ctx context.Context, plan *models.RatePlan) error
+	GetRatePlanById(ctx context.Context, id string) (*models.RatePlan, error)
+	GetRatePlansByRoomType(ctx context.Context, roomType models.RoomType) ([]models.RatePlan, error)
+	GetAllRatePlans(ctx context.Context) ([]models.RatePlan, error)
+}
+
+type AnalyticsRepository interface {
+	RefreshDailySnapshots(ctx context.Context, from, to time.Time) error
+	GetDailyStats(ctx context.Context, query *models.AnalyticsQuery) ([]models.KPIRow, error)
+}
+
+type ICalFeedRepository interface {
+	CreateFeed(ctx context.Context, feed *models.ICalFeed) error
+	GetAllFeeds(ctx context.Context) ([]models.ICalFeed, error)
+	UpdateFeedSyncStatus(ctx context.Context, id string, syncedAt time.Time, syncErr string) error
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/repositories"
+)
+
+type AnalyticsRepository struct {
+	db *sql.DB
+}
+
+func NewAnalyticsRepository(db *sql.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+var _ repositories.AnalyticsRepository = (*AnalyticsRepository)(nil)
+
+// rebuilds the daily_stats snapshot rows for every day in [from, to]
+func (r *AnalyticsRepository) RefreshDailySnapshots(ctx context.Context, from, to time.Time) error {
+	query := `
+		INSERT INTO daily_stats (stat_date, property_id, room_type, rooms_available, rooms_sold, rooms_cancelled, room_nights_picked_up, room_revenue, refreshed_at)
+		SELECT d.day::date, rt.property_id, rt.room_type, rt.rooms,
+			COALESCE(s.sold, 0), COALESCE(s.cancelled, 0), COALESCE(p.picked_up, 0), COALESCE(s.revenue, 0), NOW()
+		FROM generate_series($1::date, $2::date, INTERVAL '1 day') AS d(day)
+		CROSS JOIN (
+			SELECT property_id, room_type, COUNT(*) FILTER (WHERE available) AS rooms
+			FROM rooms GROUP BY property_id, room_type
+		) rt
+		LEFT JOIN LATERAL (
+			SELECT
+				COUNT(*) FILTER (WHERE b.status IN ('confirmed', 'completed')) AS sold,
+				COUNT(*) FILTER (WHERE b.status = 'cancelled') AS cancelled,
+				SUM(b.total_amount / GREATEST(1, b.check_out::date - b.check_in::date))
+					FILTER (WHERE b.status IN ('confirmed', 'completed')) AS revenue
+			FROM bookings b JOIN rooms r ON r.id = b.room_id
+			WHERE r.property_id = rt.property_id AND b.room_type = rt.room_type
+			AND b.check_in::date <= d.day::date AND b.check_out::date > d.day::date
+		) s ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT SUM(GREATEST(1, b.check_out::date - b.check_in::date)) AS picked_up
+			FROM bookings b JOIN rooms r ON r.id = b.room_id
+			WHERE r.property_id = rt.property_id AND b.room_type = rt.room_type
+			AND b.created_at::date = d.day::date AND b.status <> 'cancelled'
+		) p ON TRUE
+		ON CONFLICT (stat_date, property_id, room_type) DO UPDATE SET
+			rooms_available = EXCLUDED.rooms_available,
+			rooms_sold = EXCLUDED.rooms_sold,
+			rooms_cancelled = EXCLUDED.rooms_cancelled,
+			room_nights_picked_up = EXCLUDED.room_nights_picked_up,
+			room_revenue = EXCLUDED.room_revenue,
+			refreshed_at = EXCLUDED.refreshed_at
+	`
+	if _, err := r.db.ExecContext(ctx, query, from, to); err != nil {
+		return fmt.Errorf("failed to refresh daily stats: %w", err)
+	}
+	return nil
+}
+
+var dimensionColumns = map[models.AnalyticsDimension]string{
+	models.DimensionDay:      "stat_date",
+	models.DimensionProperty: "property_id",
+	models.DimensionRoomType: "room_type",
+}
+
+// sums the daily_stats snapshot over the query range, grouped by the requested dimensions
+func (r *AnalyticsRepository) GetDailyStats(ctx context.Context, q *models.AnalyticsQuery) ([]models.KPIRow, error) {
+	args := []interface{}{q.From, q.To}
+	conditions := []string{"stat_date BETWEEN $1::date AND $2::date"}
+	if q.PropertyId != "" {
+		args = append(args, q.PropertyId)
+		conditions = append(conditions, "property_id = $"+strconv.Itoa(len(args)))
+	}
+	if q.RoomType != "" {
+		args = append(args, q.RoomType)
+		conditions = append(conditions, "room_type = $"+strconv.Itoa(len(args)))
+	}
+
+	var groupColumns []string
+	for _, dim := range q.GroupBy {
+		column, ok := dimensionColumns[dim]
+		if !ok {
+			return nil, fmt.Errorf("unknown dimension: %s", dim)
+		}
+		groupColumns = append(groupColumns, column)
+	}
+
+	selectColumns := append(append([]string{}, groupColumns...),
+		"SUM(rooms_available)", "SUM(rooms_sold)", "SUM(rooms_cancelled)", "SUM(room_nights_picked_up)", "SUM(room_revenue)")
+	query := `SELECT ` + strings.Join(selectColumns, ", ") + ` FROM daily_stats WHERE ` + strings.Join(conditions, " AND ")
+	if len(groupColumns) > 0 {
+		query += ` GROUP BY ` + strings.Join(groupColumns, ", ") + ` ORDER BY ` + strings.Join(groupColumns, ", ")
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []models.KPIRow
+	for rows.Next() {
+		var row models.KPIRow
+		var day time.Time
+		var revenue sql.NullFloat64
+		var available, sold, cancelled, pickedUp sql.NullInt64
+
+		dest := make([]interface{}, 0, len(selectColumns))
+		for _, dim := range q.GroupBy {
+			switch dim {
+			case models.DimensionDay:
+				dest = append(dest, &day)
+			case models.DimensionProperty:
+				dest = append(dest, &row.PropertyId)
+			case models.DimensionRoomType:
+				dest = append(dest, &row.RoomType)
+			}
+		}
+		dest = append(dest, &available, &sold, &cancelled, &pickedUp, &revenue)
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan daily stats: %w", err)
+		}
+		if !day.IsZero() {
+			row.Date = day.Format("2006-01-02")
+		}
+		row.RoomNightsAvailable = int(available.Int64)
+		row.RoomNightsSold = int(sold.Int64)
+		row.RoomNightsCancelled = int(cancelled.Int64)
+		row.RoomNightsPickedUp = int(pickedUp.Int64)
+		row.RoomRevenue = revenue.Float64
+		stats = append(stats, row)
+	}
+	return stats, rows.Err()
+}
@@ -21,6 +21,8 @@ func NewBookingRepository(db *sql.DB) *BookingRepository {
 
 var _ repositories.BookingRepository = (*BookingRepository)(nil)
 
+const bookingColumns = `id, user_id, room_id, room_type, COALESCE(rate_plan_id, ''), guest_name, guest_email, source, COALESCE(external_ref, ''), check_in, check_out, guests, total_amount, status, created_at, updated_at`
+
 //ceate creates a new booking with transaction
 func (r *BookingRepository) CreateBooking(ctx context.Context, booking *models.Booking) error {
 	//start transaction
@@ -38,7 +40,7 @@ func (r *BookingRepository) CreateBooking(ctx context.Context, booking *models.B
 		return fmt.Errorf("room is not available for the selected date")
 	}
 	//insert booking
-	query := `INSERT INTO bookings(id, user_id, room_id, room_type, check_in, check_out, guests, total_amount, status, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	query := `INSERT INTO bookings(id, user_id, room_id, room_type, rate_plan_id, guest_name, guest_email, source, check_in, check_out, guests, total_amount, status, created_at, updated_at) VALUES($1, $2, $3, $4, NULLIF($5, ''), $6, $7, COALESCE(NULLIF($8, ''), 'direct'), $9, $10, $11, $12, $13, $14, $15)`
 
 	//insert into db
 	_, err = tx.ExecContext(ctx, query,
@@ -46,6 +48,10 @@ func (r *BookingRepository) CreateBooking(ctx context.Context, booking *models.B
 		booking.UserId,
 		booking.RoomId,
 		booking.RoomType,
+		booking.RatePlanId,
+		booking.GuestName,
+		booking.GuestEmail,
+		booking.Source,
 		booking.CheckIn,
 		booking.CheckOut,
 		booking.Guest,
@@ -124,7 +130,6 @@ func (r *BookingRepository) GetAvailableRooms(ctx context.Context, req *models.A
 
 	for rows.Next() {
 		var room models.RoomAvailability
-		var pricePerNight float64
 
 		err := rows.Scan(
 			&room.RoomId,
@@ -137,8 +142,7 @@ func (r *BookingRepository) GetAvailableRooms(ctx context.Context, req *models.A
 			return nil, fmt.Errorf("failed to scan room: %w", err)
 		}
 
-		room.PricePerNight = pricePerNight
-		room.TotalPrice =pricePerNight * float64(nights)
+		room.TotalPrice = room.PricePerNight * float64(nights)
 		AvailableRooms = append(AvailableRooms, room)
 	}
 	return AvailableRooms, nil
@@ -146,39 +150,24 @@ func (r *BookingRepository) GetAvailableRooms(ctx context.Context, req *models.A
 
 //retrieves bookings by its Id
 func (r *BookingRepository) GetBookingById(ctx context.Context, id string) (*models.Booking, error) {
-	query := `
-	SELECT id, user_id, room_id, room_type, check_in, check_out, guests, total_amount, status, created_at, updated_at
-	FROM bookings WHERE id = $1
-	`
-	var booking models.Booking
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&booking.Id,
-		&booking.UserId,
-		&booking.RoomId,
-		&booking.RoomType,
-		&booking.CheckIn,
-		&booking.CheckOut,
-		&booking.Guest,
-		&booking.TotalAmount,
-		&booking.Status,
-		&booking.CreatedAt,
-		&booking.UpdatedAt,
-	)
+	query := `SELECT ` + bookingColumns + ` FROM bookings WHERE id = $1`
+
+	booking, err := scanBooking(r.db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("booking not found")
+		return nil, repositories.ErrBookingNotFound
 	}
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get booking: %w", err)
 	}
 
-	return &booking, nil
+	return booking, nil
 }
 
 // retrieves all bookinfs for a user
 func (r *BookingRepository) GetUserBookings(ctx context.Context, userId string) ([]models.Booking, error) {
 	query := `
-		SELECT id, user_id, room_id, room_type, check_in, check_out, guests, total_amount, status, created_at, updated_at
+		SELECT ` + bookingColumns + `
 		FROM bookings WHERE user_id = $1
 		ORDER BY created_at DESC
 	`
@@ -188,30 +177,7 @@ func (r *BookingRepository) GetUserBookings(ctx context.Context, userId string)
 	}
 	defer rows.Close()
 
-	var bookings []models.Booking
-		for rows.Next() {
-			var booking models.Booking
-			err := rows.Scan(
-				&booking.Id,
-				&booking.UserId,
-				&booking.RoomId,
-				&booking.RoomType,
-				&booking.CheckIn,
-				&booking.CheckOut,
-				&booking.Guest,
-				&booking.TotalAmount,
-				&booking.Status,
-				&booking.CreatedAt,
-				&booking.UpdatedAt,
-			)
-			if err != nil {
-				return nil, fmt.Errorf("failed to scan booking: %w", err)
-			}
-			bookings = append(bookings, booking)
-		}
-		
-	return bookings, nil
-	
+	return scanBookings(rows)
 }
 
 //update the status of a booking
@@ -230,8 +196,62 @@ func (r *BookingRepository) UpdateBookingStatus(ctx context.Context, id string,
 		}
 
 		if rowsAffected == 0 {
-			return fmt.Errorf("booking not found")
+			return repositories.ErrBookingNotFound
 		}
 
 		return nil
-}
\ No newline at end of file
+}
+
+// AnonymizeUserBookings strips the guest's personal details from their bookings.
+// The bookings themselves stay, they are financial records
+func (r *BookingRepository) AnonymizeUserBookings(ctx context.Context, userId string) (int, error) {
+	query := `UPDATE bookings SET guest_name = '', guest_email = '', updated_at = NOW() WHERE user_id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, userId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to anonymize bookings: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(rows), nil
+}
+
+func scanBooking(row rowScanner) (*models.Booking, error) {
+	var booking models.Booking
+	err := row.Scan(
+		&booking.Id,
+		&booking.UserId,
+		&booking.RoomId,
+		&booking.RoomType,
+		&booking.RatePlanId,
+		&booking.GuestName,
+		&booking.GuestEmail,
+		&booking.Source,
+		&booking.ExternalRef,
+		&booking.CheckIn,
+		&booking.CheckOut,
+		&booking.Guest,
+		&booking.TotalAmount,
+		&booking.Status,
+		&booking.CreatedAt,
+		&booking.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+func scanBookings(rows *sql.Rows) ([]models.Booking, error) {
+	var bookings []models.Booking
+	for rows.Next() {
+		booking, err := scanBooking(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
+		}
+		bookings = append(bookings, *booking)
+	}
+	return bookings, rows.Err()
+}
@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+)
+
+// searchCursor is the keyset position of the last row on a page
+type searchCursor struct {
+	Value string `json:"v"`
+	Id    string `json:"id"`
+}
+
+// searches bookings for staff with keyset (cursor) pagination
+func (r *BookingRepository) SearchBookings(ctx context.Context, filter *models.BookingSearchFilter) (*models.BookingSearchResult, error) {
+	var args []interface{}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conditions := filterConditions(filter, addArg)
+
+	sortBy := filter.SortBy
+	switch sortBy {
+	case models.SortByCheckIn, models.SortByTotalAmount:
+	default:
+		sortBy = models.SortByCreatedAt
+	}
+	sortColumn := string(sortBy)
+	comparator, direction := ">", "ASC"
+	if filter.Descending {
+		comparator, direction = "<", "DESC"
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		value, err := parseCursorValue(sortBy, cursor.Value)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s, %s)", sortColumn, comparator, addArg(value), addArg(cursor.Id)))
+	}
+
+	query := `SELECT ` + bookingColumns + ` FROM bookings`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %s", sortColumn, direction, direction, addArg(filter.Limit+1))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search bookings: %w", err)
+	}
+	defer rows.Close()
+
+	bookings, err := scanBookings(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.BookingSearchResult{Bookings: bookings}
+	if len(bookings) > filter.Limit {
+		result.Bookings = bookings[:filter.Limit]
+		last := result.Bookings[filter.Limit-1]
+		result.NextCursor = encodeCursor(searchCursor{
+			Value: formatCursorValue(sortBy, &last),
+			Id:    last.Id,
+		})
+	}
+	if result.Bookings == nil {
+		result.Bookings = []models.Booking{}
+	}
+	return result, nil
+}
+
+// filterConditions turns the filter into WHERE conditions, passing each value
+// through addArg. The date bounds are whole days and both ends are inclusive:
+// stay_to=2024-12-31 matches a stay that starts on the 31st and created_to
+// matches anything created on that day
+func filterConditions(filter *models.BookingSearchFilter, addArg func(interface{}) string) []string {
+	var conditions []string
+	if filter.StayFrom != nil {
+		conditions = append(conditions, "check_out > "+addArg(*filter.StayFrom))
+	}
+	if filter.StayTo != nil {
+		conditions = append(conditions, "check_in < "+addArg(dayAfter(*filter.StayTo)))
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = "+addArg(filter.Status))
+	}
+	if filter.RoomId != "" {
+		conditions = append(conditions, "room_id = "+addArg(filter.RoomId))
+	}
+	if filter.Guest != "" {
+		if strings.Contains(filter.Guest, "@") {
+			conditions = append(conditions, "LOWER(guest_email) = LOWER("+addArg(filter.Guest)+")")
+		} else {
+			conditions = append(conditions, "guest_name ILIKE "+addArg("%"+escapeLike(filter.Guest)+"%"))
+		}
+	}
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, "created_at >= "+addArg(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, "created_at < "+addArg(dayAfter(*filter.CreatedTo)))
+	}
+	return conditions
+}
+
+// dayAfter is the exclusive upper bound for an inclusive date
+func dayAfter(date time.Time) time.Time {
+	return date.AddDate(0, 0, 1)
+}
+
+func encodeCursor(cursor searchCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(encoded string) (*searchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var cursor searchCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.Id == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &cursor, nil
+}
+
+func formatCursorValue(sortBy models.BookingSortField, booking *models.Booking) string {
+	switch sortBy {
+	case models.SortByCheckIn:
+		return booking.CheckIn.Format(time.RFC3339Nano)
+	case models.SortByTotalAmount:
+		return strconv.FormatFloat(booking.TotalAmount, 'f', -1, 64)
+	default:
+		return booking.CreatedAt.Format(time.RFC3339Nano)
+	}
+}
+
+func parseCursorValue(sortBy models.BookingSortField, value string) (interface{}, error) {
+	if sortBy == models.SortByTotalAmount {
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+		return amount, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return t, nil
+}
+
+// escapeLike escapes LIKE wildcards in user input
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
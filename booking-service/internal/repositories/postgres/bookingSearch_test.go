@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchCursorRoundTrip(t *testing.T) {
+	created := time.Date(2026, 3, 14, 9, 26, 53, 589793000, time.UTC)
+	booking := &models.Booking{
+		Id:          "booking-1",
+		CheckIn:     time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		TotalAmount: 312.5,
+		CreatedAt:   created,
+	}
+	cases := []struct {
+		sortBy models.BookingSortField
+		want   interface{}
+	}{
+		{models.SortByCreatedAt, created},
+		{models.SortByCheckIn, booking.CheckIn},
+		{models.SortByTotalAmount, 312.5},
+		{"", created},
+	}
+	for _, tc := range cases {
+		t.Run(string(tc.sortBy), func(t *testing.T) {
+			encoded := encodeCursor(searchCursor{Value: formatCursorValue(tc.sortBy, booking), Id: booking.Id})
+
+			cursor, err := decodeCursor(encoded)
+			require.NoError(t, err)
+			assert.Equal(t, booking.Id, cursor.Id)
+			value, err := parseCursorValue(tc.sortBy, cursor.Value)
+			require.NoError(t, err)
+			if want, ok := tc.want.(time.Time); ok {
+				assert.True(t, want.Equal(value.(time.Time)), "got %v", value)
+			} else {
+				assert.Equal(t, tc.want, value)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejectsTampering(t *testing.T) {
+	cases := map[string]string{
+		"not base64":   "%%%",
+		"not json":     base64.RawURLEncoding.EncodeToString([]byte("created_at")),
+		"no id":        base64.RawURLEncoding.EncodeToString([]byte(`{"v":"2026-01-01T00:00:00Z"}`)),
+		"padded":       base64.URLEncoding.EncodeToString([]byte(`{"v":"x","id":"booking-1"}`)),
+		"empty object": base64.RawURLEncoding.EncodeToString([]byte(`{}`)),
+	}
+	for name, encoded := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodeCursor(encoded)
+			assert.EqualError(t, err, "invalid cursor")
+		})
+	}
+}
+
+func TestParseCursorValueRejectsTheWrongType(t *testing.T) {
+	cases := []struct {
+		sortBy models.BookingSortField
+		value  string
+	}{
+		{models.SortByTotalAmount, "2026-01-01T00:00:00Z"},
+		{models.SortByCreatedAt, "312.5"},
+		{models.SortByCheckIn, "'; DROP TABLE bookings; --"},
+	}
+	for _, tc := range cases {
+		_, err := parseCursorValue(tc.sortBy, tc.value)
+		assert.EqualError(t, err, "invalid cursor", "%s %q", tc.sortBy, tc.value)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `100\% \_off\\`, escapeLike(`100% _off\`))
+	assert.Equal(t, "Ada", escapeLike("Ada"))
+}
+
+func TestFilterConditionsDateBoundsAreInclusive(t *testing.T) {
+	from := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+	filter := &models.BookingSearchFilter{StayFrom: &from, StayTo: &to, CreatedFrom: &from, CreatedTo: &to}
+
+	var args []interface{}
+	conditions := filterConditions(filter, func(value interface{}) string {
+		args = append(args, value)
+		return "?"
+	})
+
+	assert.Equal(t, []string{"check_out > ?", "check_in < ?", "created_at >= ?", "created_at < ?"}, conditions)
+	// a stay starting on the 31st and a booking made late on the 31st both match
+	dayAfterTo := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []interface{}{from, dayAfterTo, from, dayAfterTo}, args)
+}
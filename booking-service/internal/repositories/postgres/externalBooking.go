@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/repositories"
+)
+
+// retrieves the active bookings on a room that end after the given time
+func (r *BookingRepository) GetRoomBookings(ctx context.Context, roomId string, from time.Time) ([]models.Booking, error) {
+	query := `
+		SELECT ` + bookingColumns + `
+		FROM bookings
+		WHERE room_id = $1 AND status IN ('pending', 'confirmed') AND check_out > $2
+		ORDER BY check_in
+	`
+	rows, err := r.db.QueryContext(ctx, query, roomId, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query room bookings: %w", err)
+	}
+	defer rows.Close()
+
+	return scanBookings(rows)
+}
+
+// inserts or updates a booking keyed by (source, external_ref).
+// Re-importing an unchanged reservation is a no-op
+func (r *BookingRepository) UpsertExternalBooking(ctx context.Context, booking *models.Booking) (created bool, updated bool, err error) {
+	query := `
+		INSERT INTO bookings(id, user_id, room_id, room_type, guest_name, guest_email, source, external_ref, check_in, check_out, guests, total_amount, status, created_at, updated_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (source, external_ref) WHERE external_ref IS NOT NULL DO UPDATE SET
+			room_id = EXCLUDED.room_id,
+			room_type = EXCLUDED.room_type,
+			guest_name = EXCLUDED.guest_name,
+			check_in = EXCLUDED.check_in,
+			check_out = EXCLUDED.check_out,
+			guests = EXCLUDED.guests,
+			total_amount = EXCLUDED.total_amount,
+			status = EXCLUDED.status,
+			updated_at = NOW()
+		WHERE bookings.room_id IS DISTINCT FROM EXCLUDED.room_id
+			OR bookings.guest_name IS DISTINCT FROM EXCLUDED.guest_name
+			OR bookings.check_in IS DISTINCT FROM EXCLUDED.check_in
+			OR bookings.check_out IS DISTINCT FROM EXCLUDED.check_out
+			OR bookings.guests IS DISTINCT FROM EXCLUDED.guests
+			OR bookings.total_amount IS DISTINCT FROM EXCLUDED.total_amount
+			OR bookings.status IS DISTINCT FROM EXCLUDED.status
+		RETURNING (xmax = 0)
+	`
+	var inserted bool
+	err = r.db.QueryRowContext(ctx, query,
+		booking.Id,
+		booking.UserId,
+		booking.RoomId,
+		booking.RoomType,
+		booking.GuestName,
+		booking.GuestEmail,
+		booking.Source,
+		booking.ExternalRef,
+		booking.CheckIn,
+		booking.CheckOut,
+		booking.Guest,
+		booking.TotalAmount,
+		booking.Status,
+		booking.CreatedAt,
+		booking.UpdatedAt,
+	).Scan(&inserted)
+	if err == sql.ErrNoRows {
+		// conflict row existed and nothing changed
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to upsert external booking: %w", err)
+	}
+	return inserted, !inserted, nil
+}
+
+// cancels the active future bookings from a source whose reference is no longer in keepRefs
+func (r *BookingRepository) CancelMissingExternalBookings(ctx context.Context, source string, keepRefs []string) (int, error) {
+	query := `
+		UPDATE bookings SET status = 'cancelled', updated_at = NOW()
+		WHERE source = $1
+		AND external_ref IS NOT NULL
+		AND NOT (external_ref = ANY($2))
+		AND status IN ('pending', 'confirmed')
+		AND check_out > NOW()
+	`
+	result, err := r.db.ExecContext(ctx, query, source, pq.Array(keepRefs))
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel missing external bookings: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(rows), nil
+}
+
+// retrieves a booking by the reference a channel gave it
+func (r *BookingRepository) GetBookingByExternalRef(ctx context.Context, source, externalRef string) (*models.Booking, error) {
+	query := `SELECT ` + bookingColumns + ` FROM bookings WHERE source = $1 AND external_ref = $2`
+
+	booking, err := scanBooking(r.db.QueryRowContext(ctx, query, source, externalRef))
+	if err == sql.ErrNoRows {
+		return nil, repositories.ErrBookingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking: %w", err)
+	}
+	return booking, nil
+}
+
+// counts active bookings per room type for each night in [from, to]
+func (r *BookingRepository) GetBookedRoomCounts(ctx context.Context, from, to time.Time) ([]models.InventoryCount, error) {
+	query := `
+		SELECT b.room_type, d.day::date, COUNT(*)
+		FROM generate_series($1::date, $2::date, INTERVAL '1 day') AS d(day)
+		JOIN bookings b ON b.status IN ('pending', 'confirmed')
+			AND b.check_in::date <= d.day::date AND b.check_out::date > d.day::date
+		GROUP BY b.room_type, d.day::date
+	`
+	rows, err := r.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count booked rooms: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []models.InventoryCount
+	for rows.Next() {
+		var count models.InventoryCount
+		var day time.Time
+		if err := rows.Scan(&count.RoomType, &day, &count.Booked); err != nil {
+			return nil, fmt.Errorf("failed to scan booked rooms: %w", err)
+		}
+		count.Date = day.Format("2006-01-02")
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
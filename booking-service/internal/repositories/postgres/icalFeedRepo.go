@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/repositories"
+)
+
+type ICalFeedRepository struct {
+	db *sql.DB
+}
+
+func NewICalFeedRepository(db *sql.DB) *ICalFeedRepository {
+	return &ICalFeedRepository{db: db}
+}
+
+var _ repositories.ICalFeedRepository = (*ICalFeedRepository)(nil)
+
+// registers a feed to poll for a room
+func (r *ICalFeedRepository) CreateFeed(ctx context.Context, feed *models.ICalFeed) error {
+	query := `INSERT INTO ical_feeds (id, room_id, name, url, created_at) VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := r.db.ExecContext(ctx, query, feed.Id, feed.RoomId, feed.Name, feed.URL, feed.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				return fmt.Errorf("feed is already registered for this room")
+			case "foreign_key_violation":
+				return fmt.Errorf("room not found")
+			}
+		}
+		return fmt.Errorf("failed to create feed: %w", err)
+	}
+	return nil
+}
+
+// retrieves all registered feeds
+func (r *ICalFeedRepository) GetAllFeeds(ctx context.Context) ([]models.ICalFeed, error) {
+	query := `SELECT id, room_id, name, url, last_synced_at, last_error, created_at FROM ical_feeds ORDER BY created_at`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feeds: %w", err)
+	}
+	defer rows.Close()
+
+	var feeds []models.ICalFeed
+	for rows.Next() {
+		var feed models.ICalFeed
+		var lastSynced sql.NullTime
+		if err := rows.Scan(&feed.Id, &feed.RoomId, &feed.Name, &feed.URL, &lastSynced, &feed.LastError, &feed.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan feed: %w", err)
+		}
+		if lastSynced.Valid {
+			feed.LastSyncedAt = &lastSynced.Time
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, rows.Err()
+}
+
+// records the outcome of the latest sync of a feed
+func (r *ICalFeedRepository) UpdateFeedSyncStatus(ctx context.Context, id string, syncedAt time.Time, syncErr string) error {
+	query := `UPDATE ical_feeds SET last_synced_at = $1, last_error = $2 WHERE id = $3`
+
+	if _, err := r.db.ExecContext(ctx, query, syncedAt, syncErr, id); err != nil {
+		return fmt.Errorf("failed to update feed status: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/repositories"
+)
+
+type RatePlanRepository struct {
+	db *sql.DB
+}
+
+func NewRatePlanRepository(db *sql.DB) *RatePlanRepository {
+	return &RatePlanRepository{db: db}
+}
+
+var _ repositories.RatePlanRepository = (*RatePlanRepository)(nil)
+
+const ratePlanColumns = `id, code, name, room_type, price_modifier_pct, price_modifier_flat, inclusions, cancellation_policy, cancellation_hours, payment_terms, active, created_at, updated_at`
+
+// creates a new rate plan (for admin purposes only)
+func (r *RatePlanRepository) CreateRatePlan(ctx context.Context, plan *models.RatePlan) error {
+	query := `INSERT INTO rate_plans (` + ratePlanColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		plan.Id,
+		plan.Code,
+		plan.Name,
+		plan.RoomType,
+		plan.PriceModifierPct,
+		plan.PriceModifierFlat,
+		pq.Array(plan.Inclusions),
+		plan.CancellationPolicy,
+		plan.CancellationHours,
+		plan.PaymentTerms,
+		plan.Active,
+		plan.CreatedAt,
+		plan.UpdatedAt,
+	)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code.Name() == "unique_violation" {
+				return fmt.Errorf("rate plan with this code already exists")
+			}
+		}
+		return fmt.Errorf("failed to create rate plan: %w", err)
+	}
+	return nil
+}
+
+// retrieves a rate plan by its Id
+func (r *RatePlanRepository) GetRatePlanById(ctx context.Context, id string) (*models.RatePlan, error) {
+	query := `SELECT ` + ratePlanColumns + ` FROM rate_plans WHERE id = $1`
+
+	plan, err := scanRatePlan(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("rate plan not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rate plan: %w", err)
+	}
+	return plan, nil
+}
+
+// retrieves the active rate plans offered for a room type
+func (r *RatePlanRepository) GetRatePlansByRoomType(ctx context.Context, roomType models.RoomType) ([]models.RatePlan, error) {
+	query := `SELECT ` + ratePlanColumns + ` FROM rate_plans WHERE room_type = $1 AND active = TRUE ORDER BY price_modifier_pct, price_modifier_flat, code`
+
+	rows, err := r.db.QueryContext(ctx, query, roomType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rate plans: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRatePlans(rows)
+}
+
+// retrieves all rate plans (for admin purposes only)
+func (r *RatePlanRepository) GetAllRatePlans(ctx context.Context) ([]models.RatePlan, error) {
+	query := `SELECT ` + ratePlanColumns + ` FROM rate_plans ORDER BY room_type, code`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rate plans: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRatePlans(rows)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRatePlan(row rowScanner) (*models.RatePlan, error) {
+	var plan models.RatePlan
+	err := row.Scan(
+		&plan.Id,
+		&plan.Code,
+		&plan.Name,
+		&plan.RoomType,
+		&plan.PriceModifierPct,
+		&plan.PriceModifierFlat,
+		pq.Array(&plan.Inclusions),
+		&plan.CancellationPolicy,
+		&plan.CancellationHours,
+		&plan.PaymentTerms,
+		&plan.Active,
+		&plan.CreatedAt,
+		&plan.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func scanRatePlans(rows *sql.Rows) ([]models.RatePlan, error) {
+	var plans []models.RatePlan
+	for rows.Next() {
+		plan, err := scanRatePlan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan rate plan: %w", err)
+		}
+		plans = append(plans, *plan)
+	}
+	return plans, rows.Err()
+}
@@ -21,11 +21,12 @@ var _ repositories.BookingRepository = (*BookingRepository)(nil)
 //retrieves room by its Id
 func (r *RoomRepository) GetRoomById(ctx context.Context, id string) (*models.Room, error) {
 	query := `
-		SELECT id, room_number, room_type, price_per_night, max_guests, available, description FROM rooms WHERE id = $1`
+		SELECT id, property_id, room_number, room_type, price_per_night, max_guests, available, description FROM rooms WHERE id = $1`
 
 		var room models.Room
 		err := r.db.QueryRowContext(ctx, query, id).Scan(
 			&room.Id,
+			&room.PropertyId,
 			&room.RoomNumber,
 			&room.RoomType,
 			&room.PricePerNight,
@@ -46,10 +47,11 @@ func (r *RoomRepository) GetRoomById(ctx context.Context, id string) (*models.Ro
 
 //creates a new room (for admin purposes only)
 func (r *RoomRepository) CreateRoom(ctx context.Context, room *models.Room) error {
-	query := `INSERT INTO rooms (id. room_number, room_type, price_per_night, max_guests, available, description) VALUES ($1, $2, $3, $4. $5, $6. $7)`
+	query := `INSERT INTO rooms (id, property_id, room_number, room_type, price_per_night, max_guests, available, description) VALUES ($1, COALESCE(NULLIF($2, ''), 'main'), $3, $4, $5, $6, $7, $8)`
 
 	_, err := r.db.ExecContext(ctx, query, 
 		room.Id,
+		room.PropertyId,
 		room.RoomNumber,
 		room.RoomType,
 		room.PricePerNight,
@@ -65,7 +67,7 @@ func (r *RoomRepository) CreateRoom(ctx context.Context, room *models.Room) erro
 
 //retrieves all rooms (for admin purposes only)
 func (r *RoomRepository) GetAllRooms(ctx context.Context) ([]models.Room, error) {
-	query := `SELECT id, room_number, room_type, price_per_night, max_guests, available, description FROM rooms ORDER BY room_number`
+	query := `SELECT id, property_id, room_number, room_type, price_per_night, max_guests, available, description FROM rooms ORDER BY room_number`
 
 	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
@@ -78,6 +80,7 @@ func (r *RoomRepository) GetAllRooms(ctx context.Context) ([]models.Room, error)
 		var room models.Room
 		err := rows.Scan(
 			&room.Id,
+			&room.PropertyId,
 			&room.RoomNumber,
 			&room.RoomType,
 			&room.PricePerNight,
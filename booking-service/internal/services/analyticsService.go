@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/repositories"
+)
+
+// maxAnalyticsRange bounds a single report or refresh so one request can't scan years of data
+const maxAnalyticsRange = 366 * 24 * time.Hour
+
+type AnalyticsService struct {
+	analyticsRepo repositories.AnalyticsRepository
+}
+
+func NewAnalyticsService(analyticsRepo repositories.AnalyticsRepository) *AnalyticsService {
+	return &AnalyticsService{
+		analyticsRepo: analyticsRepo,
+	}
+}
+
+// Rebuild the daily snapshot for a date range
+func (s *AnalyticsService) RefreshSnapshots(ctx context.Context, from, to time.Time) error {
+	if err := validateRange(from, to); err != nil {
+		return err
+	}
+	if err := s.analyticsRepo.RefreshDailySnapshots(ctx, from, to); err != nil {
+		return fmt.Errorf("failed to refresh snapshots: %w", err)
+	}
+	return nil
+}
+
+// Build a KPI report from the daily snapshot
+func (s *AnalyticsService) GetReport(ctx context.Context, query *models.AnalyticsQuery) (*models.AnalyticsReport, error) {
+	if err := validateRange(query.From, query.To); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.analyticsRepo.GetDailyStats(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily stats: %w", err)
+	}
+
+	totalQuery := *query
+	totalQuery.GroupBy = nil
+	totals, err := s.analyticsRepo.GetDailyStats(ctx, &totalQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily stats: %w", err)
+	}
+
+	report := &models.AnalyticsReport{
+		From: query.From.Format("2006-01-02"),
+		To:   query.To.Format("2006-01-02"),
+		Rows: make([]models.KPIRow, 0, len(rows)),
+	}
+	for _, row := range rows {
+		report.Rows = append(report.Rows, withRates(row))
+	}
+	if len(totals) > 0 {
+		report.Total = withRates(totals[0])
+	}
+	return report, nil
+}
+
+// Keep the snapshot fresh for a window around today until ctx is cancelled
+func (s *AnalyticsService) RunSnapshotJob(ctx context.Context, interval time.Duration, lookbackDays, lookaheadDays int) {
+	refresh := func() {
+		today := time.Now().Truncate(24 * time.Hour)
+		from := today.AddDate(0, 0, -lookbackDays)
+		to := today.AddDate(0, 0, lookaheadDays)
+		if err := s.analyticsRepo.RefreshDailySnapshots(ctx, from, to); err != nil {
+			log.Printf("Analytics snapshot refresh failed: %v", err)
+		}
+	}
+
+	refresh()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
+
+// withRates derives the ratio KPIs from the summed counts
+func withRates(row models.KPIRow) models.KPIRow {
+	if row.RoomNightsAvailable > 0 {
+		row.OccupancyRate = float64(row.RoomNightsSold) / float64(row.RoomNightsAvailable)
+		row.RevPAR = row.RoomRevenue / float64(row.RoomNightsAvailable)
+	}
+	if row.RoomNightsSold > 0 {
+		row.ADR = row.RoomRevenue / float64(row.RoomNightsSold)
+	}
+	if booked := row.RoomNightsSold + row.RoomNightsCancelled; booked > 0 {
+		row.CancellationRate = float64(row.RoomNightsCancelled) / float64(booked)
+	}
+	return row
+}
+
+func validateRange(from, to time.Time) error {
+	if to.Before(from) {
+		return fmt.Errorf("to date must not be before from date")
+	}
+	if to.Sub(from) > maxAnalyticsRange {
+		return fmt.Errorf("date range cannot exceed 366 days")
+	}
+	return nil
+}
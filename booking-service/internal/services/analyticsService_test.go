@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAnalyticsRepository struct {
+	mock.Mock
+}
+
+func (m *MockAnalyticsRepository) RefreshDailySnapshots(ctx context.Context, from, to time.Time) error {
+	args := m.Called(ctx, from, to)
+	return args.Error(0)
+}
+
+func (m *MockAnalyticsRepository) GetDailyStats(ctx context.Context, query *models.AnalyticsQuery) ([]models.KPIRow, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]models.KPIRow), args.Error(1)
+}
+
+func TestAnalyticsService_GetReport(t *testing.T) {
+	mockAnalyticsRepo := new(MockAnalyticsRepository)
+	service := NewAnalyticsService(mockAnalyticsRepo)
+
+	ctx := context.Background()
+	from := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	query := &models.AnalyticsQuery{From: from, To: from.AddDate(0, 0, 6), GroupBy: []models.AnalyticsDimension{models.DimensionRoomType}}
+	mockAnalyticsRepo.On("GetDailyStats", ctx, mock.MatchedBy(func(q *models.AnalyticsQuery) bool { return q.GroupBy != nil })).
+		Return([]models.KPIRow{
+			{RoomType: models.RoomTypeDouble, RoomNightsAvailable: 70, RoomNightsSold: 56, RoomNightsCancelled: 14, RoomRevenue: 6720},
+			{RoomType: models.RoomTypeSingle, RoomNightsAvailable: 35},
+		}, nil)
+	mockAnalyticsRepo.On("GetDailyStats", ctx, mock.MatchedBy(func(q *models.AnalyticsQuery) bool { return q.GroupBy == nil })).
+		Return([]models.KPIRow{{RoomNightsAvailable: 105, RoomNightsSold: 56, RoomNightsCancelled: 14, RoomRevenue: 6720}}, nil)
+
+	report, err := service.GetReport(ctx, query)
+
+	require.NoError(t, err)
+	assert.Equal(t, "2026-06-01", report.From)
+	assert.Equal(t, "2026-06-07", report.To)
+	require.Len(t, report.Rows, 2)
+
+	double := report.Rows[0]
+	assert.InDelta(t, 0.8, double.OccupancyRate, 0.0001)
+	assert.InDelta(t, 120, double.ADR, 0.0001)
+	assert.InDelta(t, 96, double.RevPAR, 0.0001)
+	assert.InDelta(t, 0.2, double.CancellationRate, 0.0001)
+
+	// nothing sold leaves the rates at zero rather than dividing by zero
+	single := report.Rows[1]
+	assert.Zero(t, single.OccupancyRate)
+	assert.Zero(t, single.ADR)
+	assert.Zero(t, single.CancellationRate)
+
+	assert.InDelta(t, 56.0/105, report.Total.OccupancyRate, 0.0001)
+	assert.InDelta(t, 64, report.Total.RevPAR, 0.0001)
+	assert.Equal(t, []models.AnalyticsDimension{models.DimensionRoomType}, query.GroupBy, "the caller's query is untouched")
+	mockAnalyticsRepo.AssertExpectations(t)
+}
+
+func TestAnalyticsService_ValidatesRange(t *testing.T) {
+	mockAnalyticsRepo := new(MockAnalyticsRepository)
+	service := NewAnalyticsService(mockAnalyticsRepo)
+	ctx := context.Background()
+	from := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := service.GetReport(ctx, &models.AnalyticsQuery{From: from, To: from.AddDate(0, 0, -1)})
+	assert.EqualError(t, err, "to date must not be before from date")
+
+	err = service.RefreshSnapshots(ctx, from, from.AddDate(2, 0, 0))
+	assert.EqualError(t, err, "date range cannot exceed 366 days")
+
+	mockAnalyticsRepo.AssertNotCalled(t, "GetDailyStats", mock.Anything, mock.Anything)
+	mockAnalyticsRepo.AssertNotCalled(t, "RefreshDailySnapshots", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAnalyticsService_RefreshSnapshots(t *testing.T) {
+	mockAnalyticsRepo := new(MockAnalyticsRepository)
+	service := NewAnalyticsService(mockAnalyticsRepo)
+	ctx := context.Background()
+	from := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 30)
+	mockAnalyticsRepo.On("RefreshDailySnapshots", ctx, from, to).Return(assert.AnError).Once()
+	mockAnalyticsRepo.On("RefreshDailySnapshots", ctx, from, to).Return(nil).Once()
+
+	err := service.RefreshSnapshots(ctx, from, to)
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Contains(t, err.Error(), "failed to refresh snapshots")
+
+	assert.NoError(t, service.RefreshSnapshots(ctx, from, to))
+	mockAnalyticsRepo.AssertExpectations(t)
+}
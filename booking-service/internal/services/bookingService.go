@@ -3,31 +3,65 @@ package services
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
 	"github.com/ollatomiwa/hotelsystem/booking-service/internal/repositories"
+	"github.com/ollatomiwa/hotelsystem/booking-service/pkg/guests"
 	"github.com/ollatomiwa/hotelsystem/booking-service/pkg/notifications"
 )
 type BookingService struct {
 	bookingRepo repositories.BookingRepository
 	roomRepo    repositories.RoomRepository
+	ratePlanRepo repositories.RatePlanRepository
 	notifyClient *notifications.Client
 	notificationsEnabled bool
+	inventoryListener InventoryListener
+	guestClient *guests.Client
+}
+
+// InventoryListener is told when a booking changes what a room type has left to sell
+type InventoryListener interface {
+	InventoryChanged(ctx context.Context, roomType models.RoomType, from, to time.Time)
 }
 
 // Change to accept interfaces
-func NewBookingService(bookingRepo repositories.BookingRepository, roomRepo repositories.RoomRepository,notifyClient *notifications.Client,
+func NewBookingService(bookingRepo repositories.BookingRepository, roomRepo repositories.RoomRepository,
+	ratePlanRepo repositories.RatePlanRepository, notifyClient *notifications.Client,
 	notificationsEnabled bool, ) *BookingService {
 	return &BookingService{
 		bookingRepo: bookingRepo,
 		roomRepo:    roomRepo,
+		ratePlanRepo: ratePlanRepo,
 		notifyClient: notifyClient,
 		notificationsEnabled: notificationsEnabled,
 	}
 }
 
+// Set who gets told about inventory changes, e.g. the channel manager
+func (s *BookingService) SetInventoryListener(listener InventoryListener) {
+	s.inventoryListener = listener
+}
+
+// Set where guest preferences are read from
+func (s *BookingService) SetGuestClient(client *guests.Client) {
+	s.guestClient = client
+}
+
+// Get the stay preferences of a booking's guest, so staff can pick a room that suits them.
+// Channel bookings have no guest account behind them, so no preferences
+func (s *BookingService) GetGuestPreferences(ctx context.Context, booking *models.Booking) (*guests.Preferences, error) {
+	if booking.Source != models.SourceDirect {
+		return &guests.Preferences{}, nil
+	}
+	if s.guestClient == nil {
+		return nil, fmt.Errorf("guest profiles are not configured")
+	}
+	return s.guestClient.GetPreferences(ctx, booking.UserId)
+}
+
 // Check room availability for a given criteria
 func (s *BookingService) CheckAvailability(ctx context.Context, req *models.AvailabilityRequest) (*models.AvailabilityResponse, error) {
 	// Validating dates
@@ -53,12 +87,42 @@ func (s *BookingService) CheckAvailability(ctx context.Context, req *models.Avai
 		return nil, fmt.Errorf("failed to check availability: %w", err)
 	}
 
+	// Expand each room into one offer per rate plan
+	ratePlans, err := s.ratePlanRepo.GetRatePlansByRoomType(ctx, req.RoomType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rate plans: %w", err)
+	}
+	offers := availableRooms
+	if len(ratePlans) > 0 {
+		nights := int(checkOut.Sub(checkIn).Hours() / 24)
+		offers = make([]models.RoomAvailability, 0, len(availableRooms)*len(ratePlans))
+		for _, room := range availableRooms {
+			for i := range ratePlans {
+				offers = append(offers, buildOffer(room, &ratePlans[i], nights))
+			}
+		}
+	}
+
 	return &models.AvailabilityResponse{
-		AvailableRooms: availableRooms,
+		AvailableRooms: offers,
 		TotalAvailable: len(availableRooms),
+		TotalOffers:    len(offers),
 	}, nil
 }
 
+// buildOffer prices an available room under a rate plan
+func buildOffer(room models.RoomAvailability, plan *models.RatePlan, nights int) models.RoomAvailability {
+	nightlyRate := plan.NightlyRate(room.PricePerNight)
+	room.PricePerNight = nightlyRate
+	room.TotalPrice = nightlyRate * float64(nights)
+	room.RatePlanId = plan.Id
+	room.RatePlanName = plan.Name
+	room.Inclusions = plan.Inclusions
+	room.CancellationPolicy = plan.CancellationPolicy
+	room.PaymentTerms = plan.PaymentTerms
+	return room
+}
+
 // Creates a new booking
 func (s *BookingService) CreateBooking(ctx context.Context, req *models.BookingRequest) (*models.Booking, error) {
 	// Validating dates
@@ -92,9 +156,20 @@ func (s *BookingService) CreateBooking(ctx context.Context, req *models.BookingR
 		return nil, fmt.Errorf("room is not available")
 	}
 
-	// Calculate total amount
+	// Calculate total amount, applying the chosen rate plan if any
 	nights := int(checkOut.Sub(checkIn).Hours() / 24)
-	totalAmount := room.PricePerNight * float64(nights)
+	pricePerNight := room.PricePerNight
+	if req.RatePlanId != "" {
+		plan, err := s.ratePlanRepo.GetRatePlanById(ctx, req.RatePlanId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rate plan: %w", err)
+		}
+		if !plan.Active || plan.RoomType != room.RoomType {
+			return nil, fmt.Errorf("rate plan is not offered for this room")
+		}
+		pricePerNight = plan.NightlyRate(room.PricePerNight)
+	}
+	totalAmount := pricePerNight * float64(nights)
 
 	// Create booking
 	booking := &models.Booking{
@@ -102,6 +177,10 @@ func (s *BookingService) CreateBooking(ctx context.Context, req *models.BookingR
 		UserId:      req.UserId,
 		RoomId:      req.RoomId,
 		RoomType:    room.RoomType,
+		RatePlanId:  req.RatePlanId,
+		GuestName:   req.GuestName,
+		GuestEmail:  req.UserEmail,
+		Source:      models.SourceDirect,
 		CheckIn:     checkIn,
 		CheckOut:    checkOut,
         Guest:      req.Guests, 
@@ -122,6 +201,10 @@ func (s *BookingService) CreateBooking(ctx context.Context, req *models.BookingR
 		go s.sendBookingConfirmation(context.Background(), booking, room, req.UserEmail)
 	}	
 
+	if s.inventoryListener != nil {
+		go s.inventoryListener.InventoryChanged(context.Background(), booking.RoomType, booking.CheckIn, booking.CheckOut)
+	}
+
 	return booking, nil
 }
 
@@ -143,6 +226,94 @@ func (s *BookingService) GetUserBooking(ctx context.Context, userId string) ([]m
 	return bookings, nil
 }
 
+// Remove a guest's personal details from their bookings when they delete their account
+func (s *BookingService) AnonymizeUserBookings(ctx context.Context, userId string) (int, error) {
+	count, err := s.bookingRepo.AnonymizeUserBookings(ctx, userId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to anonymize user bookings: %w", err)
+	}
+	return count, nil
+}
+
+// bookings without a rate plan, and plans that don't say, can be cancelled up to this many hours before check in
+const defaultCancellationHours = 24
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+	maxExportRows      = 10000
+)
+
+// Search bookings for staff, one page at a time
+func (s *BookingService) SearchBookings(ctx context.Context, filter *models.BookingSearchFilter) (*models.BookingSearchResult, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultSearchLimit
+	}
+	if filter.Limit > maxSearchLimit {
+		filter.Limit = maxSearchLimit
+	}
+
+	result, err := s.bookingRepo.SearchBookings(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search bookings: %w", err)
+	}
+	return result, nil
+}
+
+// Export every booking matching the filter, walking the cursor pages
+func (s *BookingService) ExportBookings(ctx context.Context, filter *models.BookingSearchFilter) ([]models.Booking, error) {
+	filter.Limit = maxSearchLimit
+	var bookings []models.Booking
+	for {
+		result, err := s.bookingRepo.SearchBookings(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export bookings: %w", err)
+		}
+		bookings = append(bookings, result.Bookings...)
+		if len(bookings) > maxExportRows {
+			return nil, fmt.Errorf("export exceeds %d bookings, narrow the filters", maxExportRows)
+		}
+		if result.NextCursor == "" {
+			return bookings, nil
+		}
+		filter.Cursor = result.NextCursor
+	}
+}
+
+// Check a guest out, completing their booking. The stay is reported for loyalty
+// points; completing an already completed booking reports it again, which only earns once
+func (s *BookingService) CompleteBooking(ctx context.Context, id string) (*models.Booking, error) {
+	booking, err := s.bookingRepo.GetBookingById(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking: %w", err)
+	}
+
+	switch booking.Status {
+	case models.StatusConfirmed:
+		if err := s.bookingRepo.UpdateBookingStatus(ctx, id, models.StatusCompleted); err != nil {
+			return nil, fmt.Errorf("failed to complete booking: %w", err)
+		}
+		booking.Status = models.StatusCompleted
+	case models.StatusCompleted:
+	default:
+		return nil, fmt.Errorf("only confirmed bookings can be completed, this one is %s", booking.Status)
+	}
+
+	if booking.Source == models.SourceDirect && s.guestClient != nil {
+		err := s.guestClient.RecordStay(ctx, guests.Stay{
+			UserId:      booking.UserId,
+			BookingId:   booking.Id,
+			AmountSpent: booking.TotalAmount,
+			Nights:      int(booking.CheckOut.Sub(booking.CheckIn).Hours() / 24),
+		})
+		if err != nil {
+			// the checkout stands; completing the booking again retries the points
+			log.Printf("Failed to record stay %s for loyalty: %v", booking.Id, err)
+		}
+	}
+	return booking, nil
+}
+
 // Cancel a booking
 func (s *BookingService) CancelBooking(ctx context.Context, id string) error {
 	// Get first to check if it can be canceled
@@ -151,9 +322,21 @@ func (s *BookingService) CancelBooking(ctx context.Context, id string) error {
 		return fmt.Errorf("failed to get booking: %w", err)
 	}
 
-	// Check if it can be canceled which will be only before 24hrs before checkin
-	if time.Until(booking.CheckIn) < 24*time.Hour {
-		return fmt.Errorf("booking can only be canceled at least 24 hours before check in")
+	// Check if it can be canceled which will be only before 24hrs before checkin,
+	// unless the booking's rate plan sets its own policy
+	cancellationHours := defaultCancellationHours
+	if booking.RatePlanId != "" {
+		plan, err := s.ratePlanRepo.GetRatePlanById(ctx, booking.RatePlanId)
+		if err != nil {
+			return fmt.Errorf("failed to get rate plan: %w", err)
+		}
+		if plan.CancellationPolicy == models.CancellationNonRefundable {
+			return fmt.Errorf("booking is non-refundable and cannot be canceled")
+		}
+		cancellationHours = plan.CancellationHours
+	}
+	if time.Until(booking.CheckIn) < time.Duration(cancellationHours)*time.Hour {
+		return fmt.Errorf("booking can only be canceled at least %d hours before check in", cancellationHours)
 	}
 
 	// Get room details for notification
@@ -173,6 +356,10 @@ func (s *BookingService) CancelBooking(ctx context.Context, id string) error {
 		go s.sendBookingCancellation(context.Background(), booking, room)
 	}
 
+	if s.inventoryListener != nil {
+		go s.inventoryListener.InventoryChanged(context.Background(), booking.RoomType, booking.CheckIn, booking.CheckOut)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,621 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockBookingRepository matches your postgres.BookingRepository
+type MockBookingRepository struct {
+	mock.Mock
+}
+
+func (m *MockBookingRepository) CreateBooking(ctx context.Context, booking *models.Booking) error {
+	args := m.Called(ctx, booking)
+	return args.Error(0)
+}
+
+func (m *MockBookingRepository) GetAvailableRooms(ctx context.Context, req *models.AvailabilityRequest) ([]models.RoomAvailability, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).([]models.RoomAvailability), args.Error(1)
+}
+
+func (m *MockBookingRepository) GetBookingById(ctx context.Context, id string) (*models.Booking, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Booking), args.Error(1)
+}
+
+func (m *MockBookingRepository) GetUserBookings(ctx context.Context, userId string) ([]models.Booking, error) {
+	args := m.Called(ctx, userId)
+	return args.Get(0).([]models.Booking), args.Error(1)
+}
+
+func (m *MockBookingRepository) UpdateBookingStatus(ctx context.Context, id string, status models.BookingStatus) error {
+	args := m.Called(ctx, id, status)
+	return args.Error(0)
+}
+
+func (m *MockBookingRepository) SearchBookings(ctx context.Context, filter *models.BookingSearchFilter) (*models.BookingSearchResult, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BookingSearchResult), args.Error(1)
+}
+
+func (m *MockBookingRepository) GetRoomBookings(ctx context.Context, roomId string, from time.Time) ([]models.Booking, error) {
+	args := m.Called(ctx, roomId, from)
+	return args.Get(0).([]models.Booking), args.Error(1)
+}
+
+func (m *MockBookingRepository) UpsertExternalBooking(ctx context.Context, booking *models.Booking) (bool, bool, error) {
+	args := m.Called(ctx, booking)
+	return args.Bool(0), args.Bool(1), args.Error(2)
+}
+
+func (m *MockBookingRepository) CancelMissingExternalBookings(ctx context.Context, source string, keepRefs []string) (int, error) {
+	args := m.Called(ctx, source, keepRefs)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBookingRepository) GetBookingByExternalRef(ctx context.Context, source, externalRef string) (*models.Booking, error) {
+	args := m.Called(ctx, source, externalRef)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Booking), args.Error(1)
+}
+
+func (m *MockBookingRepository) GetBookedRoomCounts(ctx context.Context, from, to time.Time) ([]models.InventoryCount, error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).([]models.InventoryCount), args.Error(1)
+}
+
+func (m *MockBookingRepository) AnonymizeUserBookings(ctx context.Context, userId string) (int, error) {
+	args := m.Called(ctx, userId)
+	return args.Int(0), args.Error(1)
+}
+
+// MockRoomRepository matches your postgres.RoomRepository  
+type MockRoomRepository struct {
+	mock.Mock
+}
+
+func (m *MockRoomRepository) GetRoomById(ctx context.Context, id string) (*models.Room, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Room), args.Error(1)
+}
+
+func (m *MockRoomRepository) CreateRoom(ctx context.Context, room *models.Room) error {
+	args := m.Called(ctx, room)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) GetAllRooms(ctx context.Context) ([]models.Room, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.Room), args.Error(1)
+}
+
+// MockRatePlanRepository matches your postgres.RatePlanRepository
+type MockRatePlanRepository struct {
+	mock.Mock
+}
+
+func (m *MockRatePlanRepository) CreateRatePlan(ctx context.Context, plan *models.RatePlan) error {
+	args := m.Called(ctx, plan)
+	return args.Error(0)
+}
+
+func (m *MockRatePlanRepository) GetRatePlanById(ctx context.Context, id string) (*models.RatePlan, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RatePlan), args.Error(1)
+}
+
+func (m *MockRatePlanRepository) GetRatePlansByRoomType(ctx context.Context, roomType models.RoomType) ([]models.RatePlan, error) {
+	args := m.Called(ctx, roomType)
+	return args.Get(0).([]models.RatePlan), args.Error(1)
+}
+
+func (m *MockRatePlanRepository) GetAllRatePlans(ctx context.Context) ([]models.RatePlan, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.RatePlan), args.Error(1)
+}
+
+func TestBookingService_CheckAvailability_Success(t *testing.T) {
+	// Create mocks
+	mockBookingRepo := new(MockBookingRepository)
+	mockRoomRepo := new(MockRoomRepository)
+	mockRatePlanRepo := new(MockRatePlanRepository)
+	
+	// Create service with mocks
+	service := &BookingService{
+		bookingRepo: mockBookingRepo,
+		roomRepo:    mockRoomRepo,
+		ratePlanRepo: mockRatePlanRepo,
+	}
+
+	ctx := context.Background()
+	checkIn := time.Now().AddDate(0, 0, 7)
+	req := &models.AvailabilityRequest{
+		RoomType: models.RoomTypeDouble,
+		CheckIn:  checkIn.Format("2006-01-02"),
+		CheckOut: checkIn.AddDate(0, 0, 5).Format("2006-01-02"),
+		Guests:   2,
+	}
+
+	expectedRooms := []models.RoomAvailability{
+		{
+			RoomId:        "room-1",
+			RoomNumber:    "101",
+			RoomType:      models.RoomTypeDouble,
+			PricePerNight: 150.0,
+			TotalPrice:    750.0,
+			MaxGuests:     2,
+		},
+	}
+
+	// Setup mock expectation
+	mockBookingRepo.On("GetAvailableRooms", ctx, req).Return(expectedRooms, nil)
+	mockRatePlanRepo.On("GetRatePlansByRoomType", ctx, models.RoomTypeDouble).Return([]models.RatePlan{}, nil)
+
+	// Call the method
+	response, err := service.CheckAvailability(ctx, req)
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.NotNil(t, response)
+	assert.Equal(t, 1, response.TotalAvailable)
+	assert.Equal(t, 1, response.TotalOffers)
+	assert.Equal(t, expectedRooms, response.AvailableRooms)
+	
+	// Verify mock was called
+	mockBookingRepo.AssertExpectations(t)
+}
+
+func TestBookingService_CheckAvailability_InvalidDates(t *testing.T) {
+	mockBookingRepo := new(MockBookingRepository)
+	mockRoomRepo := new(MockRoomRepository)
+	
+	service := &BookingService{
+		bookingRepo: mockBookingRepo,
+		roomRepo:    mockRoomRepo,
+	}
+
+	ctx := context.Background()
+	
+	// Test invalid dates (check-out before check-in)
+	checkIn := time.Now().AddDate(0, 0, 12)
+	req := &models.AvailabilityRequest{
+		RoomType: models.RoomTypeDouble,
+		CheckIn:  checkIn.Format("2006-01-02"),                  // Later date
+		CheckOut: checkIn.AddDate(0, 0, -5).Format("2006-01-02"), // Earlier date
+		Guests:   2,
+	}
+
+	response, err := service.CheckAvailability(ctx, req)
+
+	assert.Error(t, err)
+	assert.Nil(t, response)
+	assert.Contains(t, err.Error(), "check_out date must be after check_in date")
+}
+
+func TestBookingService_CreateBooking_Success(t *testing.T) {
+	mockBookingRepo := new(MockBookingRepository)
+	mockRoomRepo := new(MockRoomRepository)
+	
+	service := &BookingService{
+		bookingRepo: mockBookingRepo,
+		roomRepo:    mockRoomRepo,
+	}
+
+	ctx := context.Background()
+	
+	// Use future dates
+	checkIn := time.Now().AddDate(0, 0, 7)  // 7 days from now
+	checkOut := time.Now().AddDate(0, 0, 10) // 10 days from now
+	
+	req := &models.BookingRequest{
+		UserId:  "user-123",
+		RoomId:  "room-1", 
+		CheckIn: checkIn.Format("2006-01-02"),
+		CheckOut: checkOut.Format("2006-01-02"),
+		Guests:  2,
+	}
+
+	room := &models.Room{
+		Id:            "room-1",
+		RoomNumber:    "101",
+		RoomType:      models.RoomTypeDouble,
+		PricePerNight: 150.0,
+		MaxGuests:     2,
+		Available:     true,
+	}
+
+	// Setup mock expectations
+	mockRoomRepo.On("GetRoomById", ctx, "room-1").Return(room, nil)
+	mockBookingRepo.On("CreateBooking", ctx, mock.AnythingOfType("*models.Booking")).Return(nil)
+
+	// Call the method
+	booking, err := service.CreateBooking(ctx, req)
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.NotNil(t, booking)
+	assert.Equal(t, "user-123", booking.UserId)
+	assert.Equal(t, "room-1", booking.RoomId)
+	assert.Equal(t, models.RoomTypeDouble, booking.RoomType)
+	assert.Equal(t, 2, booking.Guest)
+	assert.Equal(t, 450.0, booking.TotalAmount) // 3 nights * 150
+	assert.Equal(t, models.StatusConfirmed, booking.Status)
+	
+	// Verify mocks were called
+	mockRoomRepo.AssertExpectations(t)
+	mockBookingRepo.AssertExpectations(t)
+}
+
+func TestBookingService_CreateBooking_RoomNotFound(t *testing.T) {
+	mockBookingRepo := new(MockBookingRepository)
+	mockRoomRepo := new(MockRoomRepository)
+	
+	service := &BookingService{
+		bookingRepo: mockBookingRepo,
+		roomRepo:    mockRoomRepo,
+	}
+
+	ctx := context.Background()
+	
+	checkIn := time.Now().AddDate(0, 0, 7)
+	checkOut := time.Now().AddDate(0, 0, 10)
+	
+	req := &models.BookingRequest{
+		UserId:  "user-123",
+		RoomId:  "non-existent-room",
+		CheckIn: checkIn.Format("2006-01-02"),
+		CheckOut: checkOut.Format("2006-01-02"),
+		Guests:  2,
+	}
+
+	// Mock returns error for non-existent room
+	mockRoomRepo.On("GetRoomById", ctx, "non-existent-room").Return(nil, assert.AnError)
+
+	booking, err := service.CreateBooking(ctx, req)
+
+	assert.Error(t, err)
+	assert.Nil(t, booking)
+	assert.Contains(t, err.Error(), "failed to get room")
+	mockRoomRepo.AssertExpectations(t)
+}
+
+func TestBookingService_CreateBooking_TooManyGuests(t *testing.T) {
+	mockBookingRepo := new(MockBookingRepository)
+	mockRoomRepo := new(MockRoomRepository)
+	
+	service := &BookingService{
+		bookingRepo: mockBookingRepo,
+		roomRepo:    mockRoomRepo,
+	}
+
+	ctx := context.Background()
+	
+	checkIn := time.Now().AddDate(0, 0, 7)
+	checkOut := time.Now().AddDate(0, 0, 10)
+	
+	req := &models.BookingRequest{
+		UserId:  "user-123",
+		RoomId:  "room-1",
+		CheckIn: checkIn.Format("2006-01-02"),
+		CheckOut: checkOut.Format("2006-01-02"),
+		Guests:  5, // More than room capacity
+	}
+
+	room := &models.Room{
+		Id:            "room-1",
+		RoomNumber:    "101", 
+		RoomType:      models.RoomTypeDouble,
+		PricePerNight: 150.0,
+		MaxGuests:     2, // Room only allows 2 guests
+		Available:     true,
+	}
+
+	mockRoomRepo.On("GetRoomById", ctx, "room-1").Return(room, nil)
+
+	booking, err := service.CreateBooking(ctx, req)
+
+	assert.Error(t, err)
+	assert.Nil(t, booking)
+	assert.Contains(t, err.Error(), "room can only accommodate 2 guests")
+	mockRoomRepo.AssertExpectations(t)
+}
+
+func TestBookingService_GetBooking_Success(t *testing.T) {
+	mockBookingRepo := new(MockBookingRepository)
+	mockRoomRepo := new(MockRoomRepository)
+	
+	service := &BookingService{
+		bookingRepo: mockBookingRepo,
+		roomRepo:    mockRoomRepo,
+	}
+
+	ctx := context.Background()
+	bookingId := "booking-123"
+
+	expectedBooking := &models.Booking{
+		Id:       bookingId,
+		UserId:   "user-123",
+		RoomId:   "room-1",
+		RoomType: models.RoomTypeDouble,
+		Status:   models.StatusConfirmed,
+	}
+
+	mockBookingRepo.On("GetBookingById", ctx, bookingId).Return(expectedBooking, nil)
+
+	booking, err := service.GetBooking(ctx, bookingId)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, booking)
+	assert.Equal(t, bookingId, booking.Id)
+	assert.Equal(t, "user-123", booking.UserId)
+	mockBookingRepo.AssertExpectations(t)
+}
+
+func TestBookingService_CancelBooking_Success(t *testing.T) {
+	mockBookingRepo := new(MockBookingRepository)
+	mockRoomRepo := new(MockRoomRepository)
+	
+	service := &BookingService{
+		bookingRepo: mockBookingRepo,
+		roomRepo:    mockRoomRepo,
+	}
+
+	ctx := context.Background()
+	bookingId := "booking-123"
+
+	booking := &models.Booking{
+		Id:      bookingId,
+		UserId:  "user-123",
+		CheckIn: time.Now().Add(48 * time.Hour), // 48 hours from now
+		Status:  models.StatusConfirmed,
+	}
+
+	mockBookingRepo.On("GetBookingById", ctx, bookingId).Return(booking, nil)
+	mockRoomRepo.On("GetRoomById", ctx, "").Return(&models.Room{}, nil)
+	mockBookingRepo.On("UpdateBookingStatus", ctx, bookingId, models.StatusCancelled).Return(nil)
+
+	err := service.CancelBooking(ctx, bookingId)
+
+	assert.NoError(t, err)
+	mockBookingRepo.AssertExpectations(t)
+}
+
+func TestBookingService_CancelBooking_TooLate(t *testing.T) {
+	mockBookingRepo := new(MockBookingRepository)
+	mockRoomRepo := new(MockRoomRepository)
+	
+	service := &BookingService{
+		bookingRepo: mockBookingRepo,
+		roomRepo:    mockRoomRepo,
+	}
+
+	ctx := context.Background()
+	bookingId := "booking-123"
+
+	booking := &models.Booking{
+		Id:      bookingId,
+		UserId:  "user-123", 
+		CheckIn: time.Now().Add(12 * time.Hour), // Only 12 hours from now
+		Status:  models.StatusConfirmed,
+	}
+
+	mockBookingRepo.On("GetBookingById", ctx, bookingId).Return(booking, nil)
+
+	err := service.CancelBooking(ctx, bookingId)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "booking can only be canceled at least 24 hours before check in")
+	mockBookingRepo.AssertExpectations(t)
+}
+
+var (
+	breakfastPlan = models.RatePlan{
+		Id: "plan-bb", Name: "Bed & Breakfast", RoomType: models.RoomTypeDouble, PriceModifierFlat: 20,
+		Inclusions: []string{"breakfast"}, CancellationPolicy: models.CancellationFlexible, CancellationHours: 48,
+		PaymentTerms: models.PaymentPayAtHotel, Active: true,
+	}
+	advancePlan = models.RatePlan{
+		Id: "plan-adv", Name: "Advance Purchase", RoomType: models.RoomTypeDouble, PriceModifierPct: -15,
+		CancellationPolicy: models.CancellationNonRefundable, PaymentTerms: models.PaymentPayNow, Active: true,
+	}
+)
+
+func TestBookingService_CheckAvailability_ExpandsRatePlans(t *testing.T) {
+	mockBookingRepo := new(MockBookingRepository)
+	mockRatePlanRepo := new(MockRatePlanRepository)
+	service := &BookingService{bookingRepo: mockBookingRepo, ratePlanRepo: mockRatePlanRepo}
+
+	ctx := context.Background()
+	checkIn := time.Now().AddDate(0, 0, 7)
+	req := &models.AvailabilityRequest{
+		RoomType: models.RoomTypeDouble,
+		CheckIn:  checkIn.Format("2006-01-02"),
+		CheckOut: checkIn.AddDate(0, 0, 3).Format("2006-01-02"),
+		Guests:   2,
+	}
+	rooms := []models.RoomAvailability{
+		{RoomId: "room-1", RoomNumber: "101", RoomType: models.RoomTypeDouble, PricePerNight: 100, TotalPrice: 300},
+		{RoomId: "room-2", RoomNumber: "102", RoomType: models.RoomTypeDouble, PricePerNight: 140, TotalPrice: 420},
+	}
+	mockBookingRepo.On("GetAvailableRooms", ctx, req).Return(rooms, nil)
+	mockRatePlanRepo.On("GetRatePlansByRoomType", ctx, models.RoomTypeDouble).
+		Return([]models.RatePlan{breakfastPlan, advancePlan}, nil)
+
+	response, err := service.CheckAvailability(ctx, req)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, response.TotalAvailable, "rooms")
+	assert.Equal(t, 4, response.TotalOffers, "one offer per room and plan")
+	require.Len(t, response.AvailableRooms, 4)
+
+	type offer struct {
+		room, plan     string
+		nightly, total float64
+		policy         models.CancellationPolicy
+		terms          models.PaymentTerms
+	}
+	var got []offer
+	for _, o := range response.AvailableRooms {
+		got = append(got, offer{o.RoomId, o.RatePlanId, o.PricePerNight, o.TotalPrice, o.CancellationPolicy, o.PaymentTerms})
+	}
+	assert.Equal(t, []offer{
+		{"room-1", "plan-bb", 120, 360, models.CancellationFlexible, models.PaymentPayAtHotel},
+		{"room-1", "plan-adv", 85, 255, models.CancellationNonRefundable, models.PaymentPayNow},
+		{"room-2", "plan-bb", 160, 480, models.CancellationFlexible, models.PaymentPayAtHotel},
+		{"room-2", "plan-adv", 119, 357, models.CancellationNonRefundable, models.PaymentPayNow},
+	}, got)
+	assert.Equal(t, []string{"breakfast"}, response.AvailableRooms[0].Inclusions)
+}
+
+func TestRatePlan_NightlyRate(t *testing.T) {
+	cases := []struct {
+		name string
+		plan models.RatePlan
+		want float64
+	}{
+		{"base price", models.RatePlan{}, 150},
+		{"percentage discount", models.RatePlan{PriceModifierPct: -20}, 120},
+		{"flat supplement", models.RatePlan{PriceModifierFlat: 25}, 175},
+		{"percentage then flat", models.RatePlan{PriceModifierPct: 10, PriceModifierFlat: -5}, 160},
+		{"never below zero", models.RatePlan{PriceModifierPct: -100, PriceModifierFlat: -10}, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.InDelta(t, tc.want, tc.plan.NightlyRate(150), 0.001)
+		})
+	}
+}
+
+func TestBookingService_CreateBooking_RatePlanPricing(t *testing.T) {
+	room := &models.Room{Id: "room-1", RoomNumber: "101", RoomType: models.RoomTypeDouble, PricePerNight: 100, MaxGuests: 2, Available: true}
+	inactive := advancePlan
+	inactive.Id, inactive.Active = "plan-old", false
+	deluxe := breakfastPlan
+	deluxe.Id, deluxe.RoomType = "plan-deluxe", models.RoomTypeDeluxe
+
+	checkIn := time.Now().AddDate(0, 0, 7)
+	cases := []struct {
+		name   string
+		plan   *models.RatePlan
+		total  float64
+		errMsg string
+	}{
+		{"no plan", nil, 300, ""},
+		{"flat supplement", &breakfastPlan, 360, ""},
+		{"percentage discount", &advancePlan, 255, ""},
+		{"inactive plan", &inactive, 0, "rate plan is not offered for this room"},
+		{"plan for another room type", &deluxe, 0, "rate plan is not offered for this room"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockBookingRepo := new(MockBookingRepository)
+			mockRoomRepo := new(MockRoomRepository)
+			mockRatePlanRepo := new(MockRatePlanRepository)
+			service := &BookingService{bookingRepo: mockBookingRepo, roomRepo: mockRoomRepo, ratePlanRepo: mockRatePlanRepo}
+
+			ctx := context.Background()
+			req := &models.BookingRequest{
+				UserId:   "user-123",
+				RoomId:   "room-1",
+				CheckIn:  checkIn.Format("2006-01-02"),
+				CheckOut: checkIn.AddDate(0, 0, 3).Format("2006-01-02"),
+				Guests:   2,
+			}
+			mockRoomRepo.On("GetRoomById", ctx, "room-1").Return(room, nil)
+			if tc.plan != nil {
+				req.RatePlanId = tc.plan.Id
+				mockRatePlanRepo.On("GetRatePlanById", ctx, tc.plan.Id).Return(tc.plan, nil)
+			}
+			if tc.errMsg == "" {
+				mockBookingRepo.On("CreateBooking", ctx, mock.AnythingOfType("*models.Booking")).Return(nil)
+			}
+
+			booking, err := service.CreateBooking(ctx, req)
+
+			if tc.errMsg != "" {
+				assert.EqualError(t, err, tc.errMsg)
+				mockBookingRepo.AssertNotCalled(t, "CreateBooking", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, tc.total, booking.TotalAmount, 0.001)
+			assert.Equal(t, req.RatePlanId, booking.RatePlanId)
+		})
+	}
+}
+
+func TestBookingService_CancelBooking_RatePlanPolicy(t *testing.T) {
+	flexibleAnytime := breakfastPlan
+	flexibleAnytime.Id, flexibleAnytime.CancellationHours = "plan-anytime", 0
+
+	cases := []struct {
+		name    string
+		plan    *models.RatePlan
+		checkIn time.Duration
+		errMsg  string
+	}{
+		{"default policy in time", nil, 30 * time.Hour, ""},
+		{"default policy too late", nil, 20 * time.Hour, "booking can only be canceled at least 24 hours before check in"},
+		{"plan hours in time", &breakfastPlan, 50 * time.Hour, ""},
+		{"plan hours too late", &breakfastPlan, 30 * time.Hour, "booking can only be canceled at least 48 hours before check in"},
+		{"cancel up to check in", &flexibleAnytime, time.Hour, ""},
+		{"non-refundable", &advancePlan, 30 * 24 * time.Hour, "booking is non-refundable and cannot be canceled"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockBookingRepo := new(MockBookingRepository)
+			mockRoomRepo := new(MockRoomRepository)
+			mockRatePlanRepo := new(MockRatePlanRepository)
+			service := &BookingService{bookingRepo: mockBookingRepo, roomRepo: mockRoomRepo, ratePlanRepo: mockRatePlanRepo}
+
+			ctx := context.Background()
+			booking := &models.Booking{
+				Id:      "booking-123",
+				RoomId:  "room-1",
+				CheckIn: time.Now().Add(tc.checkIn),
+				Status:  models.StatusConfirmed,
+			}
+			if tc.plan != nil {
+				booking.RatePlanId = tc.plan.Id
+				mockRatePlanRepo.On("GetRatePlanById", ctx, tc.plan.Id).Return(tc.plan, nil)
+			}
+			mockBookingRepo.On("GetBookingById", ctx, "booking-123").Return(booking, nil)
+			if tc.errMsg == "" {
+				mockRoomRepo.On("GetRoomById", ctx, "room-1").Return(&models.Room{Id: "room-1"}, nil)
+				mockBookingRepo.On("UpdateBookingStatus", ctx, "booking-123", models.StatusCancelled).Return(nil)
+			}
+
+			err := service.CancelBooking(ctx, "booking-123")
+
+			if tc.errMsg != "" {
+				assert.EqualError(t, err, tc.errMsg)
+				mockBookingRepo.AssertNotCalled(t, "UpdateBookingStatus", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			mockBookingRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/repositories"
+	"github.com/ollatomiwa/hotelsystem/booking-service/pkg/ical"
+)
+
+// maxFeedSize caps how much of a remote calendar we read
+const maxFeedSize = 5 * 1024 * 1024
+
+type CalendarService struct {
+	bookingRepo  repositories.BookingRepository
+	roomRepo     repositories.RoomRepository
+	feedRepo     repositories.ICalFeedRepository
+	httpClient   *http.Client
+	exportSecret string
+}
+
+func NewCalendarService(bookingRepo repositories.BookingRepository, roomRepo repositories.RoomRepository,
+	feedRepo repositories.ICalFeedRepository, exportSecret string) *CalendarService {
+	return &CalendarService{
+		bookingRepo:  bookingRepo,
+		roomRepo:     roomRepo,
+		feedRepo:     feedRepo,
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		exportSecret: exportSecret,
+	}
+}
+
+// Render a room's booked dates as an ICS document
+func (s *CalendarService) ExportRoomCalendar(ctx context.Context, roomId string) ([]byte, error) {
+	if _, err := s.roomRepo.GetRoomById(ctx, roomId); err != nil {
+		return nil, fmt.Errorf("failed to get room: %w", err)
+	}
+
+	bookings, err := s.bookingRepo.GetRoomBookings(ctx, roomId, time.Now().AddDate(0, 0, -1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room bookings: %w", err)
+	}
+
+	events := make([]ical.Event, 0, len(bookings))
+	for _, b := range bookings {
+		events = append(events, ical.Event{
+			UID:     b.Id + "@booking-service",
+			Summary: "Reserved",
+			Start:   b.CheckIn,
+			End:     b.CheckOut,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := ical.Encode(&buf, "-//Hotel System//Booking Service//EN", events); err != nil {
+		return nil, fmt.Errorf("failed to encode calendar: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Whether rooms' ICS feeds can be exported. They need a secret to sign their URLs with
+func (s *CalendarService) ExportEnabled() bool {
+	return s.exportSecret != ""
+}
+
+// Token that must accompany a room's public ICS export URL. Empty when export is disabled
+func (s *CalendarService) ExportToken(roomId string) string {
+	if !s.ExportEnabled() {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(s.exportSecret))
+	mac.Write([]byte(roomId))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// Check the token presented on an ICS export request. Nothing passes while export is disabled
+func (s *CalendarService) VerifyExportToken(roomId, token string) bool {
+	if !s.ExportEnabled() {
+		return false
+	}
+	return hmac.Equal([]byte(s.ExportToken(roomId)), []byte(token))
+}
+
+// Register an external calendar to import blocking bookings from
+func (s *CalendarService) CreateFeed(ctx context.Context, req *models.CreateICalFeedRequest) (*models.ICalFeed, error) {
+	feed := &models.ICalFeed{
+		Id:        uuid.New().String(),
+		RoomId:    req.RoomId,
+		Name:      req.Name,
+		URL:       req.URL,
+		CreatedAt: time.Now(),
+	}
+	if err := s.feedRepo.CreateFeed(ctx, feed); err != nil {
+		return nil, fmt.Errorf("failed to create feed: %w", err)
+	}
+	return feed, nil
+}
+
+// Retrieve all registered feeds
+func (s *CalendarService) ListFeeds(ctx context.Context) ([]models.ICalFeed, error) {
+	feeds, err := s.feedRepo.GetAllFeeds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feeds: %w", err)
+	}
+	return feeds, nil
+}
+
+// Import one feed. Each event becomes an "external" booking keyed by the feed
+// and the event UID, so re-importing the same calendar changes nothing. Events
+// that overlap another booking on the room are left out and counted as conflicts
+func (s *CalendarService) SyncFeed(ctx context.Context, feed *models.ICalFeed) (*models.FeedSyncResult, error) {
+	result, err := s.importFeed(ctx, feed)
+	syncErr := ""
+	if err != nil {
+		syncErr = err.Error()
+	} else if result.Conflicts > 0 {
+		syncErr = fmt.Sprintf("%d events overlap other bookings on this room and were not imported", result.Conflicts)
+	}
+	if statusErr := s.feedRepo.UpdateFeedSyncStatus(ctx, feed.Id, time.Now(), syncErr); statusErr != nil {
+		log.Printf("Failed to record sync status for feed %s: %v", feed.Id, statusErr)
+	}
+	return result, err
+}
+
+func (s *CalendarService) importFeed(ctx context.Context, feed *models.ICalFeed) (*models.FeedSyncResult, error) {
+	room, err := s.roomRepo.GetRoomById(ctx, feed.RoomId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room: %w", err)
+	}
+
+	events, err := s.fetchEvents(ctx, feed.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	// what the room already holds; this feed's own blocks are replaced as we go
+	roomBookings, err := s.bookingRepo.GetRoomBookings(ctx, room.Id, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room bookings: %w", err)
+	}
+
+	source := models.SourceICal + ":" + feed.Id
+	result := &models.FeedSyncResult{FeedId: feed.Id}
+	keepRefs := make([]string, 0, len(events))
+	for _, e := range events {
+		if e.Cancelled || !e.End.After(e.Start) || !e.End.After(time.Now()) {
+			continue
+		}
+		other := overlappingBooking(roomBookings, e.Start, e.End, func(b *models.Booking) bool { return b.Source == source })
+		if other != nil {
+			// importing would double-book the room; leaving the ref out also releases
+			// the dates a moved event used to block
+			log.Printf("iCal event %s from feed %s overlaps booking %s on room %s, not imported", e.UID, feed.Id, other.Id, room.Id)
+			result.Conflicts++
+			continue
+		}
+		keepRefs = append(keepRefs, e.UID)
+
+		booking := &models.Booking{
+			Id:          uuid.New().String(),
+			UserId:      source,
+			RoomId:      room.Id,
+			RoomType:    room.RoomType,
+			GuestName:   feed.Name + " block",
+			Source:      source,
+			ExternalRef: e.UID,
+			CheckIn:     e.Start,
+			CheckOut:    e.End,
+			Guest:       1,
+			TotalAmount: 0,
+			Status:      models.StatusConfirmed,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		}
+		created, updated, err := s.bookingRepo.UpsertExternalBooking(ctx, booking)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import event %s: %w", e.UID, err)
+		}
+		switch {
+		case created:
+			result.Created++
+		case updated:
+			result.Updated++
+		default:
+			result.Unchanged++
+		}
+	}
+
+	cancelled, err := s.bookingRepo.CancelMissingExternalBookings(ctx, source, keepRefs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release removed events: %w", err)
+	}
+	result.Cancelled = cancelled
+	return result, nil
+}
+
+// overlappingBooking returns a booking that shares a night with [start, end), other than those ignored
+func overlappingBooking(bookings []models.Booking, start, end time.Time, ignore func(*models.Booking) bool) *models.Booking {
+	for i := range bookings {
+		b := &bookings[i]
+		if !ignore(b) && b.CheckIn.Before(end) && b.CheckOut.After(start) {
+			return b
+		}
+	}
+	return nil
+}
+
+func (s *CalendarService) fetchEvents(ctx context.Context, url string) ([]ical.Event, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create feed request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Booking-Service/1.0")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("feed returned status %d", resp.StatusCode)
+	}
+
+	events, err := ical.Parse(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse feed: %w", err)
+	}
+	return events, nil
+}
+
+// Import every registered feed, logging failures per feed
+func (s *CalendarService) SyncAllFeeds(ctx context.Context) []models.FeedSyncResult {
+	feeds, err := s.feedRepo.GetAllFeeds(ctx)
+	if err != nil {
+		log.Printf("Failed to load iCal feeds: %v", err)
+		return nil
+	}
+
+	results := make([]models.FeedSyncResult, 0, len(feeds))
+	for i := range feeds {
+		result, err := s.SyncFeed(ctx, &feeds[i])
+		if err != nil {
+			log.Printf("iCal feed %s (%s) sync failed: %v", feeds[i].Id, feeds[i].Name, err)
+			results = append(results, models.FeedSyncResult{FeedId: feeds[i].Id, Error: err.Error()})
+			continue
+		}
+		results = append(results, *result)
+	}
+	return results
+}
+
+// Poll all feeds on an interval until ctx is cancelled
+func (s *CalendarService) RunFeedPoller(ctx context.Context, interval time.Duration) {
+	s.SyncAllFeeds(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.SyncAllFeeds(ctx)
+		}
+	}
+}
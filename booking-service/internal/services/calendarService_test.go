@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryBookingRepo keeps external bookings in memory, keyed like the postgres unique index
+type memoryBookingRepo struct {
+	repositories.BookingRepository
+	mu       sync.Mutex
+	bookings map[string]*models.Booking
+}
+
+func newMemoryBookingRepo() *memoryBookingRepo {
+	return &memoryBookingRepo{bookings: map[string]*models.Booking{}}
+}
+
+func (r *memoryBookingRepo) UpsertExternalBooking(ctx context.Context, booking *models.Booking) (bool, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := booking.Source + "|" + booking.ExternalRef
+	existing, ok := r.bookings[key]
+	if !ok {
+		copied := *booking
+		r.bookings[key] = &copied
+		return true, false, nil
+	}
+	if existing.RoomId == booking.RoomId && existing.CheckIn.Equal(booking.CheckIn) && existing.CheckOut.Equal(booking.CheckOut) &&
+		existing.Status == booking.Status {
+		return false, false, nil
+	}
+	existing.RoomId, existing.CheckIn, existing.CheckOut, existing.Status = booking.RoomId, booking.CheckIn, booking.CheckOut, booking.Status
+	return false, true, nil
+}
+
+func (r *memoryBookingRepo) CancelMissingExternalBookings(ctx context.Context, source string, keepRefs []string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keep := map[string]bool{}
+	for _, ref := range keepRefs {
+		keep[ref] = true
+	}
+	cancelled := 0
+	for _, b := range r.bookings {
+		if b.Source == source && !keep[b.ExternalRef] && b.Status != models.StatusCancelled {
+			b.Status = models.StatusCancelled
+			cancelled++
+		}
+	}
+	return cancelled, nil
+}
+
+func (r *memoryBookingRepo) GetRoomBookings(ctx context.Context, roomId string, from time.Time) ([]models.Booking, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []models.Booking
+	for _, b := range r.bookings {
+		if b.RoomId == roomId && b.Status != models.StatusCancelled && b.CheckOut.After(from) {
+			out = append(out, *b)
+		}
+	}
+	return out, nil
+}
+
+func (r *memoryBookingRepo) active() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, b := range r.bookings {
+		if b.Status != models.StatusCancelled {
+			n++
+		}
+	}
+	return n
+}
+
+type memoryRoomRepo struct {
+	repositories.RoomRepository
+}
+
+func (memoryRoomRepo) GetRoomById(ctx context.Context, id string) (*models.Room, error) {
+	return &models.Room{Id: id, RoomNumber: "101", RoomType: models.RoomTypeDouble, MaxGuests: 2, Available: true}, nil
+}
+
+type memoryFeedRepo struct {
+	repositories.ICalFeedRepository
+	lastError string
+}
+
+func (r *memoryFeedRepo) UpdateFeedSyncStatus(ctx context.Context, id string, syncedAt time.Time, syncErr string) error {
+	r.lastError = syncErr
+	return nil
+}
+
+func icsFeed(events ...string) string {
+	return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Stand-in OTA//EN\r\n" +
+		strings.Join(events, "") + "END:VCALENDAR\r\n"
+}
+
+func icsEvent(uid string, start, end time.Time) string {
+	return fmt.Sprintf("BEGIN:VEVENT\r\nUID:%s\r\nDTSTART;VALUE=DATE:%s\r\nDTEND;VALUE=DATE:%s\r\nSUMMARY:Not available\r\nEND:VEVENT\r\n",
+		uid, start.Format("20060102"), end.Format("20060102"))
+}
+
+func TestCalendarService_SyncFeed_IsIdempotent(t *testing.T) {
+	start := time.Now().AddDate(0, 0, 10).Truncate(24 * time.Hour)
+	feedBody := icsFeed(
+		icsEvent("ota-1", start, start.AddDate(0, 0, 2)),
+		icsEvent("ota-2", start.AddDate(0, 0, 5), start.AddDate(0, 0, 7)),
+	)
+
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "text/calendar")
+		fmt.Fprint(w, feedBody)
+	}))
+	defer server.Close()
+
+	bookingRepo := newMemoryBookingRepo()
+	feedRepo := &memoryFeedRepo{}
+	service := NewCalendarService(bookingRepo, memoryRoomRepo{}, feedRepo, "")
+	feed := &models.ICalFeed{Id: "feed-1", RoomId: "room-1", Name: "Stand-in OTA", URL: server.URL}
+	ctx := context.Background()
+
+	first, err := service.SyncFeed(ctx, feed)
+	require.NoError(t, err)
+	assert.Equal(t, 2, first.Created)
+	assert.Equal(t, 2, bookingRepo.active())
+
+	second, err := service.SyncFeed(ctx, feed)
+	require.NoError(t, err)
+	assert.Equal(t, 0, second.Created)
+	assert.Equal(t, 0, second.Updated)
+	assert.Equal(t, 2, second.Unchanged)
+	assert.Equal(t, 2, bookingRepo.active())
+
+	// the OTA moves one stay and drops the other
+	mu.Lock()
+	feedBody = icsFeed(icsEvent("ota-1", start.AddDate(0, 0, 1), start.AddDate(0, 0, 3)))
+	mu.Unlock()
+
+	third, err := service.SyncFeed(ctx, feed)
+	require.NoError(t, err)
+	assert.Equal(t, 1, third.Updated)
+	assert.Equal(t, 1, third.Cancelled)
+	assert.Equal(t, 1, bookingRepo.active())
+	assert.Empty(t, feedRepo.lastError)
+}
+
+func TestCalendarService_SyncFeed_RecordsFetchErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	bookingRepo := newMemoryBookingRepo()
+	feedRepo := &memoryFeedRepo{}
+	service := NewCalendarService(bookingRepo, memoryRoomRepo{}, feedRepo, "")
+
+	_, err := service.SyncFeed(context.Background(), &models.ICalFeed{Id: "feed-1", RoomId: "room-1", URL: server.URL})
+	assert.Error(t, err)
+	assert.Contains(t, feedRepo.lastError, "status 503")
+	assert.Equal(t, 0, bookingRepo.active())
+}
+
+func TestCalendarService_SyncFeed_SkipsConflicts(t *testing.T) {
+	start := time.Now().AddDate(0, 0, 10).Truncate(24 * time.Hour)
+	feedBody := icsFeed(
+		icsEvent("ota-1", start, start.AddDate(0, 0, 2)),
+		icsEvent("ota-2", start.AddDate(0, 0, 5), start.AddDate(0, 0, 7)),
+	)
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprint(w, feedBody)
+	}))
+	defer server.Close()
+
+	// a guest booked the second night of ota-1 directly
+	bookingRepo := newMemoryBookingRepo()
+	bookingRepo.bookings["direct|booking-1"] = &models.Booking{
+		Id: "booking-1", RoomId: "room-1", Source: models.SourceDirect, Status: models.StatusConfirmed,
+		CheckIn: start.AddDate(0, 0, 1), CheckOut: start.AddDate(0, 0, 3),
+	}
+	feedRepo := &memoryFeedRepo{}
+	service := NewCalendarService(bookingRepo, memoryRoomRepo{}, feedRepo, "")
+	feed := &models.ICalFeed{Id: "feed-1", RoomId: "room-1", Name: "Stand-in OTA", URL: server.URL}
+	ctx := context.Background()
+
+	first, err := service.SyncFeed(ctx, feed)
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.Created)
+	assert.Equal(t, 1, first.Conflicts)
+	assert.Equal(t, 2, bookingRepo.active(), "the direct booking and ota-2")
+	assert.Equal(t, "1 events overlap other bookings on this room and were not imported", feedRepo.lastError)
+
+	// ota-2 moves onto the direct booking: its old block is released, not moved
+	mu.Lock()
+	feedBody = icsFeed(icsEvent("ota-2", start.AddDate(0, 0, 2), start.AddDate(0, 0, 4)))
+	mu.Unlock()
+
+	second, err := service.SyncFeed(ctx, feed)
+	require.NoError(t, err)
+	assert.Equal(t, 1, second.Conflicts)
+	assert.Equal(t, 1, second.Cancelled)
+	assert.Equal(t, 0, second.Updated)
+	assert.Equal(t, 1, bookingRepo.active(), "only the direct booking")
+
+	// once the room is free the event imports again
+	mu.Lock()
+	feedBody = icsFeed(icsEvent("ota-2", start.AddDate(0, 0, 3), start.AddDate(0, 0, 4)))
+	mu.Unlock()
+
+	third, err := service.SyncFeed(ctx, feed)
+	require.NoError(t, err)
+	assert.Equal(t, 0, third.Conflicts)
+	assert.Equal(t, 1, third.Updated)
+	assert.Equal(t, 2, bookingRepo.active())
+	assert.Empty(t, feedRepo.lastError)
+}
+
+func TestCalendarService_ExportToken_FailsClosedWithoutSecret(t *testing.T) {
+	service := NewCalendarService(nil, nil, nil, "")
+	assert.False(t, service.ExportEnabled())
+	assert.Empty(t, service.ExportToken("room-1"))
+	assert.False(t, service.VerifyExportToken("room-1", ""), "an empty token must not match an empty secret")
+
+	service = NewCalendarService(nil, nil, nil, "export-secret")
+	token := service.ExportToken("room-1")
+	assert.True(t, service.VerifyExportToken("room-1", token))
+	assert.False(t, service.VerifyExportToken("room-2", token))
+	assert.False(t, service.VerifyExportToken("room-1", ""))
+}
@@ -0,0 +1,380 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/channels"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/repositories"
+)
+
+type ChannelService struct {
+	bookingRepo  repositories.BookingRepository
+	roomRepo     repositories.RoomRepository
+	ratePlanRepo repositories.RatePlanRepository
+	adapters     []channels.Adapter
+	horizonDays  int
+
+	mu          sync.Mutex
+	lastFetched map[string]time.Time
+}
+
+func NewChannelService(bookingRepo repositories.BookingRepository, roomRepo repositories.RoomRepository,
+	ratePlanRepo repositories.RatePlanRepository, horizonDays int, adapters ...channels.Adapter) *ChannelService {
+	return &ChannelService{
+		bookingRepo:  bookingRepo,
+		roomRepo:     roomRepo,
+		ratePlanRepo: ratePlanRepo,
+		adapters:     adapters,
+		horizonDays:  horizonDays,
+		lastFetched:  map[string]time.Time{},
+	}
+}
+
+// Build our availability, rates and inventory for every room type and night in [from, to]
+func (s *ChannelService) BuildARI(ctx context.Context, from, to time.Time) ([]models.ARIUpdate, error) {
+	rooms, err := s.roomRepo.GetAllRooms(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rooms: %w", err)
+	}
+
+	inventory := map[models.RoomType]int{}
+	basePrice := map[models.RoomType]float64{}
+	var roomTypes []models.RoomType
+	for _, room := range rooms {
+		if !room.Available {
+			continue
+		}
+		if _, seen := inventory[room.RoomType]; !seen {
+			roomTypes = append(roomTypes, room.RoomType)
+			basePrice[room.RoomType] = room.PricePerNight
+		}
+		inventory[room.RoomType]++
+		basePrice[room.RoomType] = math.Min(basePrice[room.RoomType], room.PricePerNight)
+	}
+
+	counts, err := s.bookingRepo.GetBookedRoomCounts(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count booked rooms: %w", err)
+	}
+	booked := map[string]int{}
+	for _, c := range counts {
+		booked[string(c.RoomType)+"|"+c.Date] = c.Booked
+	}
+
+	var updates []models.ARIUpdate
+	for _, roomType := range roomTypes {
+		plans, err := s.ratePlanRepo.GetRatePlansByRoomType(ctx, roomType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rate plans: %w", err)
+		}
+		rates := []models.RateUpdate{{Price: basePrice[roomType]}}
+		if len(plans) > 0 {
+			rates = make([]models.RateUpdate, 0, len(plans))
+			for i := range plans {
+				rates = append(rates, models.RateUpdate{RatePlanId: plans[i].Id, Price: plans[i].NightlyRate(basePrice[roomType])})
+			}
+		}
+
+		for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+			date := day.Format("2006-01-02")
+			available := inventory[roomType] - booked[string(roomType)+"|"+date]
+			if available < 0 {
+				available = 0
+			}
+			updates = append(updates, models.ARIUpdate{
+				RoomType:  roomType,
+				Date:      date,
+				Inventory: inventory[roomType],
+				Available: available,
+				Rates:     rates,
+			})
+		}
+	}
+	return updates, nil
+}
+
+// Push ARI for [from, to] to every channel
+func (s *ChannelService) PublishARI(ctx context.Context, from, to time.Time) error {
+	updates, err := s.BuildARI(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	return s.publish(ctx, updates)
+}
+
+func (s *ChannelService) publish(ctx context.Context, updates []models.ARIUpdate) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	var failed []string
+	for _, adapter := range s.adapters {
+		if err := adapter.PublishARI(ctx, updates); err != nil {
+			log.Printf("Failed to publish ARI to %s: %v", adapter.Name(), err)
+			failed = append(failed, adapter.Name())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to publish ARI to: %v", failed)
+	}
+	return nil
+}
+
+// InventoryChanged republishes a room type's ARI after a booking changes it
+func (s *ChannelService) InventoryChanged(ctx context.Context, roomType models.RoomType, from, to time.Time) {
+	if len(s.adapters) == 0 {
+		return
+	}
+	updates, err := s.BuildARI(ctx, from, to.AddDate(0, 0, -1))
+	if err != nil {
+		log.Printf("Failed to build ARI for %s: %v", roomType, err)
+		return
+	}
+	var changed []models.ARIUpdate
+	for _, u := range updates {
+		if u.RoomType == roomType {
+			changed = append(changed, u)
+		}
+	}
+	if err := s.publish(ctx, changed); err != nil {
+		log.Printf("ARI update for %s not fully published: %v", roomType, err)
+	}
+}
+
+// Pull new and changed reservations from every channel and store them as bookings
+// with the channel as source and the channel's reservation id as external reference
+func (s *ChannelService) IngestReservations(ctx context.Context) []models.IngestResult {
+	results := make([]models.IngestResult, 0, len(s.adapters))
+	for _, adapter := range s.adapters {
+		s.mu.Lock()
+		since := s.lastFetched[adapter.Name()]
+		s.mu.Unlock()
+		startedAt := time.Now()
+
+		result := models.IngestResult{Channel: adapter.Name()}
+		reservations, err := adapter.FetchReservations(ctx, since)
+		if err != nil {
+			result.Failed = append(result.Failed, "fetch: "+err.Error())
+			results = append(results, result)
+			continue
+		}
+
+		for i := range reservations {
+			created, updated, err := s.ingestReservation(ctx, adapter.Name(), &reservations[i])
+			switch {
+			case err != nil:
+				result.Failed = append(result.Failed, reservations[i].ExternalRef+": "+err.Error())
+			case created:
+				result.Created++
+			case updated:
+				result.Updated++
+			default:
+				result.Unchanged++
+			}
+		}
+
+		// only move the cursor when everything was stored, so failures are retried
+		if len(result.Failed) == 0 {
+			s.mu.Lock()
+			s.lastFetched[adapter.Name()] = startedAt
+			s.mu.Unlock()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func (s *ChannelService) ingestReservation(ctx context.Context, source string, r *models.ExternalReservation) (bool, bool, error) {
+	if r.ExternalRef == "" || !r.CheckOut.After(r.CheckIn) {
+		return false, false, fmt.Errorf("invalid reservation")
+	}
+	status := r.Status
+	if status == "" {
+		status = models.StatusConfirmed
+	}
+
+	existing, err := s.bookingRepo.GetBookingByExternalRef(ctx, source, r.ExternalRef)
+	if err != nil && !errors.Is(err, repositories.ErrBookingNotFound) {
+		return false, false, err
+	}
+
+	var roomId string
+	switch {
+	case existing != nil && existing.RoomType == r.RoomType &&
+		(status == models.StatusCancelled || existing.CheckIn.Equal(r.CheckIn) && existing.CheckOut.Equal(r.CheckOut)):
+		roomId = existing.RoomId
+	case existing != nil && existing.RoomType == r.RoomType:
+		// the stay moved, it keeps its room only if nobody else holds it on the new dates
+		free, err := s.roomFree(ctx, existing, r.CheckIn, r.CheckOut)
+		if err != nil {
+			return false, false, err
+		}
+		if free {
+			roomId = existing.RoomId
+		}
+	case status == models.StatusCancelled:
+		// nothing to release for a reservation we never stored
+		return false, false, nil
+	}
+	if roomId == "" {
+		rooms, err := s.bookingRepo.GetAvailableRooms(ctx, &models.AvailabilityRequest{
+			RoomType: r.RoomType,
+			CheckIn:  r.CheckIn.Format("2006-01-02"),
+			CheckOut: r.CheckOut.Format("2006-01-02"),
+			Guests:   max(r.Guests, 1),
+		})
+		if err != nil {
+			return false, false, err
+		}
+		if len(rooms) == 0 {
+			return false, false, fmt.Errorf("no %s room available, channel is overbooked", r.RoomType)
+		}
+		roomId = rooms[0].RoomId
+	}
+
+	booking := &models.Booking{
+		Id:          uuid.New().String(),
+		UserId:      source,
+		RoomId:      roomId,
+		RoomType:    r.RoomType,
+		GuestName:   r.GuestName,
+		GuestEmail:  r.GuestEmail,
+		Source:      source,
+		ExternalRef: r.ExternalRef,
+		CheckIn:     r.CheckIn,
+		CheckOut:    r.CheckOut,
+		Guest:       max(r.Guests, 1),
+		TotalAmount: r.TotalAmount,
+		Status:      status,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	created, updated, err := s.bookingRepo.UpsertExternalBooking(ctx, booking)
+	if err != nil {
+		return false, false, err
+	}
+	if created || updated {
+		s.InventoryChanged(ctx, r.RoomType, r.CheckIn, r.CheckOut)
+		if existing != nil {
+			// the nights the reservation held before are back on sale
+			s.InventoryChanged(ctx, existing.RoomType, existing.CheckIn, existing.CheckOut)
+		}
+	}
+	return created, updated, nil
+}
+
+// roomFree reports whether booking's room has no other active booking in [checkIn, checkOut)
+func (s *ChannelService) roomFree(ctx context.Context, booking *models.Booking, checkIn, checkOut time.Time) (bool, error) {
+	roomBookings, err := s.bookingRepo.GetRoomBookings(ctx, booking.RoomId, checkIn)
+	if err != nil {
+		return false, fmt.Errorf("failed to get room bookings: %w", err)
+	}
+	other := overlappingBooking(roomBookings, checkIn, checkOut, func(b *models.Booking) bool { return b.Id == booking.Id })
+	return other == nil, nil
+}
+
+// Compare what each channel shows against our ARI for [from, to] and republish what drifted
+func (s *ChannelService) Reconcile(ctx context.Context, from, to time.Time) ([]models.DriftItem, error) {
+	local, err := s.BuildARI(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	drift := []models.DriftItem{}
+	for _, adapter := range s.adapters {
+		remote, err := adapter.GetARI(ctx, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ARI from %s: %w", adapter.Name(), err)
+		}
+		remoteByKey := map[string]models.ARIUpdate{}
+		for _, u := range remote {
+			remoteByKey[string(u.RoomType)+"|"+u.Date] = u
+		}
+
+		var stale []models.ARIUpdate
+		for _, want := range local {
+			items := compareARI(adapter.Name(), want, remoteByKey[string(want.RoomType)+"|"+want.Date])
+			if len(items) > 0 {
+				drift = append(drift, items...)
+				stale = append(stale, want)
+			}
+		}
+
+		if len(stale) > 0 {
+			if err := adapter.PublishARI(ctx, stale); err != nil {
+				log.Printf("Failed to correct ARI drift on %s: %v", adapter.Name(), err)
+			}
+		}
+	}
+	return drift, nil
+}
+
+func compareARI(channel string, want, got models.ARIUpdate) []models.DriftItem {
+	item := func(field string, local, remote float64) models.DriftItem {
+		return models.DriftItem{Channel: channel, RoomType: want.RoomType, Date: want.Date, Field: field, Local: local, Remote: remote}
+	}
+	if got.Date == "" {
+		return []models.DriftItem{item("missing", float64(want.Available), 0)}
+	}
+
+	var items []models.DriftItem
+	if want.Available != got.Available {
+		items = append(items, item("available", float64(want.Available), float64(got.Available)))
+	}
+	if want.Inventory != got.Inventory {
+		items = append(items, item("inventory", float64(want.Inventory), float64(got.Inventory)))
+	}
+	remoteRates := map[string]float64{}
+	for _, r := range got.Rates {
+		remoteRates[r.RatePlanId] = r.Price
+	}
+	for _, r := range want.Rates {
+		remote, ok := remoteRates[r.RatePlanId]
+		if !ok || math.Abs(remote-r.Price) > 0.005 {
+			items = append(items, item("rate:"+r.RatePlanId, r.Price, remote))
+		}
+	}
+	return items
+}
+
+// Ingest reservations and reconcile ARI over the horizon on an interval until ctx is cancelled
+func (s *ChannelService) RunSyncJob(ctx context.Context, interval time.Duration) {
+	if len(s.adapters) == 0 {
+		return
+	}
+	sync := func() {
+		for _, result := range s.IngestReservations(ctx) {
+			if len(result.Failed) > 0 {
+				log.Printf("Channel %s ingest failures: %v", result.Channel, result.Failed)
+			}
+		}
+		from := time.Now().Truncate(24 * time.Hour)
+		drift, err := s.Reconcile(ctx, from, from.AddDate(0, 0, s.horizonDays))
+		if err != nil {
+			log.Printf("Channel reconciliation failed: %v", err)
+			return
+		}
+		if len(drift) > 0 {
+			log.Printf("Channel reconciliation corrected %d drifted values", len(drift))
+		}
+	}
+
+	sync()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sync()
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/channels"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var channelRooms = []models.Room{
+	{Id: "room-1", RoomNumber: "101", RoomType: models.RoomTypeDouble, PricePerNight: 120, MaxGuests: 2, Available: true},
+	{Id: "room-2", RoomNumber: "102", RoomType: models.RoomTypeDouble, PricePerNight: 100, MaxGuests: 2, Available: true},
+}
+
+// channelBookingRepo adds the room lookups ingest and ARI need on top of memoryBookingRepo
+type channelBookingRepo struct {
+	*memoryBookingRepo
+}
+
+func (r channelBookingRepo) GetBookingByExternalRef(ctx context.Context, source, ref string) (*models.Booking, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if b, ok := r.bookings[source+"|"+ref]; ok {
+		copied := *b
+		return &copied, nil
+	}
+	return nil, repositories.ErrBookingNotFound
+}
+
+func (r channelBookingRepo) GetAvailableRooms(ctx context.Context, req *models.AvailabilityRequest) ([]models.RoomAvailability, error) {
+	checkIn, _ := time.Parse("2006-01-02", req.CheckIn)
+	checkOut, _ := time.Parse("2006-01-02", req.CheckOut)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []models.RoomAvailability
+	for _, room := range channelRooms {
+		taken := false
+		for _, b := range r.bookings {
+			if b.RoomId == room.Id && b.Status != models.StatusCancelled && b.CheckIn.Before(checkOut) && b.CheckOut.After(checkIn) {
+				taken = true
+			}
+		}
+		if !taken && room.RoomType == req.RoomType {
+			out = append(out, models.RoomAvailability{RoomId: room.Id, RoomType: room.RoomType, PricePerNight: room.PricePerNight})
+		}
+	}
+	return out, nil
+}
+
+func (r channelBookingRepo) GetBookedRoomCounts(ctx context.Context, from, to time.Time) ([]models.InventoryCount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var counts []models.InventoryCount
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		booked := 0
+		for _, b := range r.bookings {
+			if b.Status != models.StatusCancelled && !b.CheckIn.After(day) && b.CheckOut.After(day) {
+				booked++
+			}
+		}
+		if booked > 0 {
+			counts = append(counts, models.InventoryCount{RoomType: models.RoomTypeDouble, Date: day.Format("2006-01-02"), Booked: booked})
+		}
+	}
+	return counts, nil
+}
+
+type channelRoomRepo struct {
+	repositories.RoomRepository
+}
+
+func (channelRoomRepo) GetAllRooms(ctx context.Context) ([]models.Room, error) {
+	return channelRooms, nil
+}
+
+type memoryRatePlanRepo struct {
+	repositories.RatePlanRepository
+}
+
+func (memoryRatePlanRepo) GetRatePlansByRoomType(ctx context.Context, roomType models.RoomType) ([]models.RatePlan, error) {
+	return []models.RatePlan{{Id: "bar", RoomType: roomType, Active: true}}, nil
+}
+
+func newTestChannelService(adapter channels.Adapter) (*ChannelService, *memoryBookingRepo) {
+	bookings := newMemoryBookingRepo()
+	return NewChannelService(channelBookingRepo{bookings}, channelRoomRepo{}, memoryRatePlanRepo{}, 30, adapter), bookings
+}
+
+func channelReservation(ref string, checkIn time.Time, status models.BookingStatus) models.ExternalReservation {
+	return models.ExternalReservation{
+		ExternalRef: ref,
+		RoomType:    models.RoomTypeDouble,
+		GuestName:   "OTA Guest",
+		CheckIn:     checkIn,
+		CheckOut:    checkIn.AddDate(0, 0, 2),
+		Guests:      2,
+		TotalAmount: 200,
+		Status:      status,
+	}
+}
+
+func TestChannelService_IngestReservations(t *testing.T) {
+	ctx := context.Background()
+	adapter := channels.NewFakeAdapter("fakeota")
+	svc, bookings := newTestChannelService(adapter)
+	checkIn := time.Now().AddDate(0, 1, 0).Truncate(24 * time.Hour)
+
+	adapter.AddReservation(channelReservation("R1", checkIn, models.StatusConfirmed))
+	adapter.AddReservation(channelReservation("R2", checkIn, models.StatusConfirmed))
+	adapter.AddReservation(channelReservation("R3", checkIn, models.StatusConfirmed))
+
+	results := svc.IngestReservations(ctx)
+	require.Len(t, results, 1)
+	assert.Equal(t, 2, results[0].Created)
+	assert.Len(t, results[0].Failed, 1, "only two double rooms exist, the third reservation is an overbooking")
+	assert.Equal(t, 2, bookings.active())
+
+	// a failed run keeps the cursor, so the same reservations come back and are not duplicated
+	results = svc.IngestReservations(ctx)
+	assert.Equal(t, 0, results[0].Created)
+	assert.Equal(t, 2, results[0].Unchanged)
+	assert.Equal(t, 2, bookings.active())
+
+	adapter.AddReservation(channelReservation("R1", checkIn, models.StatusCancelled))
+	results = svc.IngestReservations(ctx)
+	assert.Equal(t, 1, results[0].Updated)
+	assert.Equal(t, 1, bookings.active())
+
+	r1, _ := channelBookingRepo{bookings}.GetBookingByExternalRef(ctx, "fakeota", "R1")
+	require.NotNil(t, r1)
+	assert.Equal(t, "fakeota", r1.Source)
+	assert.Equal(t, models.StatusCancelled, r1.Status)
+}
+
+func TestChannelService_IngestReservationsMovedDates(t *testing.T) {
+	ctx := context.Background()
+	adapter := channels.NewFakeAdapter("fakeota")
+	svc, bookings := newTestChannelService(adapter)
+	checkIn := time.Now().AddDate(0, 1, 0).Truncate(24 * time.Hour)
+	later := checkIn.AddDate(0, 0, 10)
+
+	adapter.AddReservation(channelReservation("R1", checkIn, models.StatusConfirmed))
+	adapter.AddReservation(channelReservation("R2", later, models.StatusConfirmed))
+	results := svc.IngestReservations(ctx)
+	require.Empty(t, results[0].Failed)
+	repo := channelBookingRepo{bookings}
+	r1, err := repo.GetBookingByExternalRef(ctx, "fakeota", "R1")
+	require.NoError(t, err)
+	r2, err := repo.GetBookingByExternalRef(ctx, "fakeota", "R2")
+	require.NoError(t, err)
+	require.Equal(t, r1.RoomId, r2.RoomId, "both stays fit in the same room")
+
+	// a day later is still free on the same room
+	adapter.AddReservation(channelReservation("R1", checkIn.AddDate(0, 0, 1), models.StatusConfirmed))
+	results = svc.IngestReservations(ctx)
+	assert.Equal(t, 1, results[0].Updated)
+	moved, _ := repo.GetBookingByExternalRef(ctx, "fakeota", "R1")
+	assert.Equal(t, r1.RoomId, moved.RoomId)
+
+	// onto R2's nights the room is taken, so the stay moves to the other double
+	adapter.AddReservation(channelReservation("R1", later, models.StatusConfirmed))
+	results = svc.IngestReservations(ctx)
+	assert.Equal(t, 1, results[0].Updated)
+	moved, _ = repo.GetBookingByExternalRef(ctx, "fakeota", "R1")
+	assert.NotEqual(t, r2.RoomId, moved.RoomId)
+	assert.True(t, moved.CheckIn.Equal(later))
+
+	// with both doubles taken the move is refused and the stored stay is left alone
+	adapter.AddReservation(channelReservation("R3", checkIn, models.StatusConfirmed))
+	adapter.AddReservation(channelReservation("R3", later, models.StatusConfirmed))
+	results = svc.IngestReservations(ctx)
+	assert.Len(t, results[0].Failed, 1)
+	r3, _ := repo.GetBookingByExternalRef(ctx, "fakeota", "R3")
+	assert.True(t, r3.CheckIn.Equal(checkIn))
+}
+
+// failingLookupRepo fails every external ref lookup the way a dropped connection would
+type failingLookupRepo struct {
+	channelBookingRepo
+}
+
+func (failingLookupRepo) GetBookingByExternalRef(ctx context.Context, source, ref string) (*models.Booking, error) {
+	return nil, errors.New("connection reset")
+}
+
+func TestChannelService_IngestReservationsLookupError(t *testing.T) {
+	ctx := context.Background()
+	adapter := channels.NewFakeAdapter("fakeota")
+	bookings := newMemoryBookingRepo()
+	svc := NewChannelService(failingLookupRepo{channelBookingRepo{bookings}}, channelRoomRepo{}, memoryRatePlanRepo{}, 30, adapter)
+
+	adapter.AddReservation(channelReservation("R1", time.Now().AddDate(0, 1, 0).Truncate(24*time.Hour), models.StatusConfirmed))
+	results := svc.IngestReservations(ctx)
+	assert.Len(t, results[0].Failed, 1, "a lookup failure isn't treated as a new reservation")
+	assert.Equal(t, 0, bookings.active())
+}
+
+func TestChannelService_ReconcileDetectsAndCorrectsDrift(t *testing.T) {
+	ctx := context.Background()
+	adapter := channels.NewFakeAdapter("fakeota")
+	svc, _ := newTestChannelService(adapter)
+	from := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 6)
+
+	require.NoError(t, svc.PublishARI(ctx, from, to))
+	drift, err := svc.Reconcile(ctx, from, to)
+	require.NoError(t, err)
+	assert.Empty(t, drift)
+
+	adapter.SetARI(models.ARIUpdate{
+		RoomType:  models.RoomTypeDouble,
+		Date:      "2030-01-03",
+		Inventory: 2,
+		Available: 0,
+		Rates:     []models.RateUpdate{{RatePlanId: "bar", Price: 90}},
+	})
+
+	drift, err = svc.Reconcile(ctx, from, to)
+	require.NoError(t, err)
+	require.Len(t, drift, 2)
+	for _, item := range drift {
+		assert.Equal(t, "2030-01-03", item.Date)
+	}
+	assert.ElementsMatch(t, []string{"available", "rate:bar"}, []string{drift[0].Field, drift[1].Field})
+
+	// the drifted date was republished, so the channel is back in line
+	drift, err = svc.Reconcile(ctx, from, to)
+	require.NoError(t, err)
+	assert.Empty(t, drift)
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/repositories"
+	"github.com/ollatomiwa/hotelsystem/booking-service/pkg/guests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookingService_CompleteBooking_RecordsStay(t *testing.T) {
+	var stays []guests.Stay
+	userService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/internal/loyalty/stays", r.URL.Path)
+		var stay guests.Stay
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&stay))
+		stays = append(stays, stay)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer userService.Close()
+
+	mockBookingRepo := new(MockBookingRepository)
+	service := &BookingService{bookingRepo: mockBookingRepo}
+	service.SetGuestClient(guests.NewClient(userService.URL, "booking-key"))
+
+	ctx := context.Background()
+	checkIn := time.Date(2026, 5, 1, 14, 0, 0, 0, time.UTC)
+	booking := &models.Booking{
+		Id:          "booking-123",
+		UserId:      "user-123",
+		Source:      models.SourceDirect,
+		CheckIn:     checkIn,
+		CheckOut:    checkIn.AddDate(0, 0, 3),
+		TotalAmount: 450,
+		Status:      models.StatusConfirmed,
+	}
+	mockBookingRepo.On("GetBookingById", ctx, "booking-123").Return(booking, nil)
+	mockBookingRepo.On("UpdateBookingStatus", ctx, "booking-123", models.StatusCompleted).Return(nil)
+
+	completed, err := service.CompleteBooking(ctx, "booking-123")
+
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusCompleted, completed.Status)
+	require.Len(t, stays, 1)
+	assert.Equal(t, guests.Stay{UserId: "user-123", BookingId: "booking-123", AmountSpent: 450, Nights: 3}, stays[0])
+	mockBookingRepo.AssertExpectations(t)
+}
+
+func TestBookingService_CompleteBooking_RejectsCancelled(t *testing.T) {
+	mockBookingRepo := new(MockBookingRepository)
+	service := &BookingService{bookingRepo: mockBookingRepo}
+
+	ctx := context.Background()
+	mockBookingRepo.On("GetBookingById", ctx, "booking-123").
+		Return(&models.Booking{Id: "booking-123", Status: models.StatusCancelled}, nil)
+
+	_, err := service.CompleteBooking(ctx, "booking-123")
+
+	assert.Error(t, err)
+	mockBookingRepo.AssertNotCalled(t, "UpdateBookingStatus", ctx, "booking-123", models.StatusCompleted)
+}
+
+func TestBookingService_CompleteBooking_NotFound(t *testing.T) {
+	mockBookingRepo := new(MockBookingRepository)
+	service := &BookingService{bookingRepo: mockBookingRepo}
+
+	ctx := context.Background()
+	mockBookingRepo.On("GetBookingById", ctx, "missing").Return(nil, repositories.ErrBookingNotFound)
+
+	_, err := service.CompleteBooking(ctx, "missing")
+
+	assert.ErrorIs(t, err, repositories.ErrBookingNotFound)
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/repositories"
+)
+
+type RatePlanService struct {
+	ratePlanRepo repositories.RatePlanRepository
+}
+
+func NewRatePlanService(ratePlanRepo repositories.RatePlanRepository) *RatePlanService {
+	return &RatePlanService{
+		ratePlanRepo: ratePlanRepo,
+	}
+}
+
+// Creates a new rate plan for a room type
+func (s *RatePlanService) CreateRatePlan(ctx context.Context, req *models.CreateRatePlanRequest) (*models.RatePlan, error) {
+	switch req.CancellationPolicy {
+	case models.CancellationFlexible, models.CancellationNonRefundable:
+	default:
+		return nil, fmt.Errorf("cancellation_policy must be one of: flexible, non_refundable")
+	}
+
+	switch req.PaymentTerms {
+	case models.PaymentPayNow, models.PaymentPayAtHotel:
+	default:
+		return nil, fmt.Errorf("payment_terms must be one of: pay_now, pay_at_hotel")
+	}
+
+	inclusions := req.Inclusions
+	if inclusions == nil {
+		inclusions = []string{}
+	}
+
+	cancellationHours := defaultCancellationHours
+	if req.CancellationHours != nil {
+		cancellationHours = *req.CancellationHours
+	}
+
+	plan := &models.RatePlan{
+		Id:                 uuid.New().String(),
+		Code:               req.Code,
+		Name:               req.Name,
+		RoomType:           req.RoomType,
+		PriceModifierPct:   req.PriceModifierPct,
+		PriceModifierFlat:  req.PriceModifierFlat,
+		Inclusions:         inclusions,
+		CancellationPolicy: req.CancellationPolicy,
+		CancellationHours:  cancellationHours,
+		PaymentTerms:       req.PaymentTerms,
+		Active:             true,
+		CreatedAt:          time.Now(),
+		UpdatedAt:          time.Now(),
+	}
+
+	if err := s.ratePlanRepo.CreateRatePlan(ctx, plan); err != nil {
+		return nil, fmt.Errorf("failed to create rate plan: %w", err)
+	}
+	return plan, nil
+}
+
+// Retrieve rate plans, optionally only those for one room type
+func (s *RatePlanService) ListRatePlans(ctx context.Context, roomType models.RoomType) ([]models.RatePlan, error) {
+	var plans []models.RatePlan
+	var err error
+	if roomType != "" {
+		plans, err = s.ratePlanRepo.GetRatePlansByRoomType(ctx, roomType)
+	} else {
+		plans, err = s.ratePlanRepo.GetAllRatePlans(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rate plans: %w", err)
+	}
+	return plans, nil
+}
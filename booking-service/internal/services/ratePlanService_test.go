@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ollatomiwa/hotelsystem/booking-service/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRatePlanService_CreateRatePlan_CancellationHours(t *testing.T) {
+	zero, twelve := 0, 12
+	cases := []struct {
+		name  string
+		hours *int
+		want  int
+	}{
+		{"omitted uses the standard policy", nil, defaultCancellationHours},
+		{"zero allows cancelling up to check in", &zero, 0},
+		{"explicit hours", &twelve, 12},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRatePlanRepo := new(MockRatePlanRepository)
+			mockRatePlanRepo.On("CreateRatePlan", mock.Anything, mock.AnythingOfType("*models.RatePlan")).Return(nil)
+			service := NewRatePlanService(mockRatePlanRepo)
+
+			plan, err := service.CreateRatePlan(context.Background(), &models.CreateRatePlanRequest{
+				Code:               "BAR",
+				Name:               "Best Available",
+				RoomType:           models.RoomTypeDouble,
+				CancellationPolicy: models.CancellationFlexible,
+				CancellationHours:  tc.hours,
+				PaymentTerms:       models.PaymentPayAtHotel,
+			})
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, plan.CancellationHours)
+		})
+	}
+}
+
+func TestRatePlanService_CreateRatePlan_RejectsUnknownTerms(t *testing.T) {
+	service := NewRatePlanService(new(MockRatePlanRepository))
+
+	_, err := service.CreateRatePlan(context.Background(), &models.CreateRatePlanRequest{
+		Code: "BAR", Name: "Best Available", RoomType: models.RoomTypeDouble,
+		CancellationPolicy: "partial", PaymentTerms: models.PaymentPayAtHotel,
+	})
+	assert.EqualError(t, err, "cancellation_policy must be one of: flexible, non_refundable")
+
+	_, err = service.CreateRatePlan(context.Background(), &models.CreateRatePlanRequest{
+		Code: "BAR", Name: "Best Available", RoomType: models.RoomTypeDouble,
+		CancellationPolicy: models.CancellationFlexible, PaymentTerms: "deposit",
+	})
+	assert.EqualError(t, err, "payment_terms must be one of: pay_now, pay_at_hotel")
+}
@@ -3,12 +3,21 @@ package config
 import (
 	"os"
 	"strconv"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
 )
 
 type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
 	Notifications NotificationsConfig
+	Guests GuestsConfig
+	Auth auth.Config
+	Analytics AnalyticsConfig
+	ICal ICalConfig
+	Channels ChannelsConfig
+	Policies PoliciesConfig
 }
 
 type ServerConfig struct {
@@ -30,6 +39,31 @@ type NotificationsConfig struct {
 	Enabled bool
 }
 
+// GuestsConfig points at user-service, which keeps guest profiles and preferences
+type GuestsConfig struct {
+	BaseURL string
+}
+
+type ICalConfig struct {
+	PollInterval time.Duration
+	ExportSecret string
+}
+
+type ChannelsConfig struct {
+	SyncInterval time.Duration
+	HorizonDays int
+}
+
+type PoliciesConfig struct {
+	RequireVerifiedEmail bool
+}
+
+type AnalyticsConfig struct {
+	RefreshInterval time.Duration
+	LookbackDays int
+	LookaheadDays int
+}
+
 func Load() *Config {
 	return &Config{
 		Server: ServerConfig{
@@ -48,6 +82,26 @@ func Load() *Config {
 			BaseURL: getEnv("NOTIFICATION_SERVICE_URL", "http://localhost:8081"),
 			Enabled: getEnvBool("NOTIFICATIONS_ENABLED", true),
 		},
+		Guests: GuestsConfig{
+			BaseURL: getEnv("USER_SERVICE_URL", "http://localhost:8082"),
+		},
+		Auth: auth.LoadConfigFromEnv(),
+		Analytics: AnalyticsConfig{
+			RefreshInterval: getEnvDuration("ANALYTICS_REFRESH_INTERVAL", 1*time.Hour),
+			LookbackDays: getEnvInt("ANALYTICS_LOOKBACK_DAYS", 7),
+			LookaheadDays: getEnvInt("ANALYTICS_LOOKAHEAD_DAYS", 90),
+		},
+		ICal: ICalConfig{
+			PollInterval: getEnvDuration("ICAL_POLL_INTERVAL", 15*time.Minute),
+			ExportSecret: getEnv("ICAL_EXPORT_SECRET", ""),
+		},
+		Channels: ChannelsConfig{
+			SyncInterval: getEnvDuration("CHANNEL_SYNC_INTERVAL", 5*time.Minute),
+			HorizonDays: getEnvInt("CHANNEL_HORIZON_DAYS", 365),
+		},
+		Policies: PoliciesConfig{
+			RequireVerifiedEmail: getEnvBool("REQUIRE_VERIFIED_EMAIL", false),
+		},
 	}
 }
 
@@ -66,6 +120,15 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
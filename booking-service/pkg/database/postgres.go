@@ -66,10 +66,67 @@ func InitializeSchema(db *sql.DB) error {
             CONSTRAINT valid_dates CHECK (check_out > check_in)
         )`,
         
+        `CREATE TABLE IF NOT EXISTS rate_plans (
+            id TEXT PRIMARY KEY,
+            code TEXT UNIQUE NOT NULL,
+            name TEXT NOT NULL,
+            room_type TEXT NOT NULL CHECK (room_type IN ('single', 'double', 'suite', 'deluxe')),
+            price_modifier_pct DECIMAL(6,2) NOT NULL DEFAULT 0 CHECK (price_modifier_pct >= -100),
+            price_modifier_flat DECIMAL(10,2) NOT NULL DEFAULT 0,
+            inclusions TEXT[] NOT NULL DEFAULT '{}',
+            cancellation_policy TEXT NOT NULL CHECK (cancellation_policy IN ('flexible', 'non_refundable')) DEFAULT 'flexible',
+            cancellation_hours INTEGER NOT NULL DEFAULT 24 CHECK (cancellation_hours >= 0),
+            payment_terms TEXT NOT NULL CHECK (payment_terms IN ('pay_now', 'pay_at_hotel')) DEFAULT 'pay_at_hotel',
+            active BOOLEAN DEFAULT TRUE,
+            created_at TIMESTAMPTZ DEFAULT NOW(),
+            updated_at TIMESTAMPTZ DEFAULT NOW()
+        )`,
+
+        `ALTER TABLE bookings ADD COLUMN IF NOT EXISTS rate_plan_id TEXT REFERENCES rate_plans(id)`,
+        `ALTER TABLE rooms ADD COLUMN IF NOT EXISTS property_id TEXT NOT NULL DEFAULT 'main'`,
+        `ALTER TABLE bookings ADD COLUMN IF NOT EXISTS guest_name TEXT NOT NULL DEFAULT ''`,
+        `ALTER TABLE bookings ADD COLUMN IF NOT EXISTS guest_email TEXT NOT NULL DEFAULT ''`,
+        `ALTER TABLE bookings ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT 'direct'`,
+        `ALTER TABLE bookings ADD COLUMN IF NOT EXISTS external_ref TEXT`,
+
+        `CREATE TABLE IF NOT EXISTS ical_feeds (
+            id TEXT PRIMARY KEY,
+            room_id TEXT NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+            name TEXT NOT NULL,
+            url TEXT NOT NULL,
+            last_synced_at TIMESTAMPTZ,
+            last_error TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMPTZ DEFAULT NOW(),
+            UNIQUE (room_id, url)
+        )`,
+        
         `CREATE INDEX IF NOT EXISTS idx_bookings_dates ON bookings (check_in, check_out)`,
         `CREATE INDEX IF NOT EXISTS idx_bookings_room_dates ON bookings (room_id, check_in, check_out)`,
         `CREATE INDEX IF NOT EXISTS idx_bookings_user ON bookings (user_id)`,
         `CREATE INDEX IF NOT EXISTS idx_rooms_type_available ON rooms (room_type, available)`,
+        `CREATE INDEX IF NOT EXISTS idx_rate_plans_room_type ON rate_plans (room_type, active)`,
+        // Materialized daily KPI snapshot, refreshed by the analytics job
+        `CREATE TABLE IF NOT EXISTS daily_stats (
+            stat_date DATE NOT NULL,
+            property_id TEXT NOT NULL,
+            room_type TEXT NOT NULL,
+            rooms_available INTEGER NOT NULL DEFAULT 0,
+            rooms_sold INTEGER NOT NULL DEFAULT 0,
+            rooms_cancelled INTEGER NOT NULL DEFAULT 0,
+            room_nights_picked_up INTEGER NOT NULL DEFAULT 0,
+            room_revenue DECIMAL(12,2) NOT NULL DEFAULT 0,
+            refreshed_at TIMESTAMPTZ DEFAULT NOW(),
+            PRIMARY KEY (stat_date, property_id, room_type)
+        )`,
+        `CREATE INDEX IF NOT EXISTS idx_daily_stats_property_type ON daily_stats (property_id, room_type, stat_date)`,
+        `CREATE INDEX IF NOT EXISTS idx_bookings_status_check_in ON bookings (status, check_in, id)`,
+        `CREATE INDEX IF NOT EXISTS idx_bookings_created ON bookings (created_at, id)`,
+        `CREATE INDEX IF NOT EXISTS idx_bookings_total_amount ON bookings (total_amount, id)`,
+        `CREATE INDEX IF NOT EXISTS idx_bookings_guest_email ON bookings (LOWER(guest_email))`,
+        // Staff search matches guest names anywhere in the name with ILIKE, which needs trigrams
+        `CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+        `CREATE INDEX IF NOT EXISTS idx_bookings_guest_name_trgm ON bookings USING GIN (guest_name gin_trgm_ops)`,
+        `CREATE UNIQUE INDEX IF NOT EXISTS idx_bookings_external_ref ON bookings (source, external_ref) WHERE external_ref IS NOT NULL`,
     }
 
 	for _, query := range queries {
@@ -0,0 +1,109 @@
+package guests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
+)
+
+// Preferences is what user-service keeps about how a guest likes their room
+type Preferences struct {
+	UserId       string          `json:"userId"`
+	Preferences  StayPreferences `json:"preferences"`
+	DietaryNotes string          `json:"dietaryNotes,omitempty"`
+}
+
+type StayPreferences struct {
+	Bed    string `json:"bed,omitempty"`
+	Floor  string `json:"floor,omitempty"`
+	Pillow string `json:"pillow,omitempty"`
+}
+
+// Stay is a completed stay reported to user-service for loyalty points
+type Stay struct {
+	UserId      string  `json:"userId"`
+	BookingId   string  `json:"bookingId"`
+	AmountSpent float64 `json:"amountSpent"`
+	Nights      int     `json:"nights"`
+}
+
+// Client reads guest profiles from user-service and reports stays for loyalty
+type Client struct {
+	baseURL    string
+	serviceKey string
+	httpClient *http.Client
+}
+
+func NewClient(baseURL, serviceKey string) *Client {
+	return &Client{
+		baseURL:    baseURL,
+		serviceKey: serviceKey,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+// GetPreferences returns a guest's stay preferences for room assignment
+func (c *Client) GetPreferences(ctx context.Context, userId string) (*Preferences, error) {
+	endpoint := c.baseURL + "/api/v1/internal/users/" + url.PathEscape(userId) + "/preferences"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Booking-Service/1.0")
+	auth.SetServiceKey(req, c.serviceKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach user service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("user service returned status %d: %s", resp.StatusCode, string(body))
+	}
+	var preferences Preferences
+	if err := json.NewDecoder(resp.Body).Decode(&preferences); err != nil {
+		return nil, fmt.Errorf("failed to decode preferences: %w", err)
+	}
+	return &preferences, nil
+}
+
+// RecordStay reports a completed stay so the guest earns loyalty points. It is safe to
+// report a stay again, it only earns once. Guests who aren't loyalty members earn nothing
+func (c *Client) RecordStay(ctx context.Context, stay Stay) error {
+	body, err := json.Marshal(stay)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stay: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/internal/loyalty/stays", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Booking-Service/1.0")
+	auth.SetServiceKey(req, c.serviceKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach user service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNotFound:
+		return nil
+	default:
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("user service returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+}
@@ -0,0 +1,190 @@
+// Package ical reads and writes the small subset of iCalendar (RFC 5545)
+// used by OTA and owner calendar sync: all-day VEVENTs that block dates.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const dateLayout = "20060102"
+
+// Event is a single blocked date range. End is exclusive, like a check-out date
+type Event struct {
+	UID       string
+	Summary   string
+	Start     time.Time
+	End       time.Time
+	Cancelled bool
+}
+
+// Encode writes events as a VCALENDAR document
+func Encode(w io.Writer, prodID string, events []Event) error {
+	bw := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:" + prodID,
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+	}
+	for _, e := range events {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+escapeText(e.UID),
+			"DTSTAMP:"+stamp,
+			"DTSTART;VALUE=DATE:"+e.Start.Format(dateLayout),
+			"DTEND;VALUE=DATE:"+e.End.Format(dateLayout),
+			"SUMMARY:"+escapeText(e.Summary),
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := bw.WriteString(foldLine(line)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Parse reads the VEVENTs from a VCALENDAR document
+func Parse(r io.Reader) ([]Event, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var current *Event
+	for _, line := range lines {
+		name, params, value := splitProperty(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			current = &Event{}
+		case name == "END" && value == "VEVENT":
+			if current == nil {
+				return nil, fmt.Errorf("unexpected END:VEVENT")
+			}
+			if current.UID == "" || current.Start.IsZero() {
+				return nil, fmt.Errorf("event is missing UID or DTSTART")
+			}
+			if current.End.IsZero() {
+				current.End = current.Start.AddDate(0, 0, 1)
+			}
+			events = append(events, *current)
+			current = nil
+		case current == nil:
+			continue
+		case name == "UID":
+			current.UID = unescapeText(value)
+		case name == "SUMMARY":
+			current.Summary = unescapeText(value)
+		case name == "STATUS":
+			current.Cancelled = strings.EqualFold(value, "CANCELLED")
+		case name == "DTSTART", name == "DTEND":
+			t, err := parseDate(params, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %w", name, value, err)
+			}
+			if name == "DTSTART" {
+				current.Start = t
+			} else {
+				current.End = t
+			}
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("unterminated VEVENT")
+	}
+	return events, nil
+}
+
+// parseDate accepts DATE and DATE-TIME values and keeps only the calendar day
+func parseDate(params, value string) (time.Time, error) {
+	if len(value) < len(dateLayout) {
+		return time.Time{}, fmt.Errorf("value too short")
+	}
+	if strings.Contains(params, "VALUE=DATE") && !strings.Contains(params, "VALUE=DATE-TIME") {
+		return time.Parse(dateLayout, value)
+	}
+	if len(value) > len(dateLayout) {
+		for _, layout := range []string{"20060102T150405Z", "20060102T150405"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+			}
+		}
+	}
+	return time.Parse(dateLayout, value[:len(dateLayout)])
+}
+
+func unfoldLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// splitProperty splits "NAME;PARAM=X:value" into its name, params and value
+func splitProperty(line string) (string, string, string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return strings.ToUpper(line), "", ""
+	}
+	head, value := line[:colon], line[colon+1:]
+	name, params := head, ""
+	if semi := strings.Index(head, ";"); semi >= 0 {
+		name, params = head[:semi], strings.ToUpper(head[semi+1:])
+	}
+	return strings.ToUpper(name), params, value
+}
+
+// foldLine splits content lines longer than 75 octets, as RFC 5545 requires
+func foldLine(line string) string {
+	var b strings.Builder
+	for len(line) > 75 {
+		cut := 75
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
+
+var (
+	textEscaper   = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+	textUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func unescapeText(s string) string {
+	return textUnescaper.Replace(s)
+}
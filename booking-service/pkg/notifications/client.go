@@ -8,10 +8,13 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
 )
 
 type Client struct {
 	baseURL    string
+	serviceKey string
 	httpClient *http.Client
 }
 
@@ -24,9 +27,10 @@ type SendEmailRequest struct {
 	Data    map[string]interface{} `json:"data,omitempty"`
 }
 
-func NewClient(baseURL string) *Client {
+func NewClient(baseURL, serviceKey string) *Client {
 	return &Client{
 		baseURL: baseURL,
+		serviceKey: serviceKey,
 		httpClient: &http.Client{
 			Timeout: 15 * time.Second,
 		},
@@ -110,6 +114,7 @@ func (c *Client) sendEmailNotification(ctx context.Context, req SendEmailRequest
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("User-Agent", "Booking-Service/1.0")
+	auth.SetServiceKey(httpReq, c.serviceKey)
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -151,6 +156,7 @@ func (c *Client) HealthCheck(ctx context.Context) error {
 		return fmt.Errorf("failed to create health check request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	auth.SetServiceKey(req, c.serviceKey)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -23,6 +23,7 @@ import (
 	"github.com/ollatomiwa/hotelsystem/notification-service/pkg/logging"
 	"github.com/ollatomiwa/hotelsystem/notification-service/pkg/middleware"
 	"github.com/ollatomiwa/hotelsystem/notification-service/pkg/ratelimiter"
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
 )
 
 // initDB initializes the PostgreSQL database and creates tables
@@ -178,14 +179,25 @@ func setupRouter(cfg *config.Config) (*gin.Engine, error) {
 		c.JSON(200, gin.H{"status": "ready"})
 	})
 
-	// API routes
+	// API routes - services send with their service key, staff can look up status
+	authn := auth.NewFromConfig(cfg.Auth)
 	api := router.Group("/api/v1")
+	api.Use(authn.Authenticate())
 	{
 		notifications := api.Group("/notifications")
+		notifications.Use(authn.RequirePermission(auth.PermNotificationsSend))
 		{
 			notifications.POST("/email", notificationHandler.SendEmail)
 			notifications.GET("/:id", notificationHandler.GetNotificationStatus)
 		}
+
+		//internal routes for other services, user-service uses these for data export and erasure
+		internal := api.Group("/internal")
+		internal.Use(authn.RequireService())
+		{
+			internal.GET("/notifications", notificationHandler.ExportRecipientData)
+			internal.DELETE("/notifications", notificationHandler.EraseRecipientData)
+		}
 	}
 
 	log.Printf("Monitoring: Structured logging enabled")
@@ -74,6 +74,38 @@ func (h *NotificationHandler) GetNotificationStatus(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, notification)
 }
+//exportrecipientdata handler for other services, returns everything sent to ?email=
+func (h *NotificationHandler) ExportRecipientData(c *gin.Context) {
+	email := c.Query("email")
+	if email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+		return
+	}
+
+	notifications, err := h.notificationService.ExportRecipientData(c.Request.Context(), email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"notifications": notifications})
+}
+
+//eraserecipientdata handler for other services, deletes everything sent to ?email=
+func (h *NotificationHandler) EraseRecipientData(c *gin.Context) {
+	email := c.Query("email")
+	if email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+		return
+	}
+
+	count, err := h.notificationService.EraseRecipientData(c.Request.Context(), email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"notifications_deleted": count})
+}
+
 //healthcheck handler
 func (h *NotificationHandler) HealthCheck(c *gin.Context){
 	healthResponse := models.HealthResponse{
@@ -17,6 +17,8 @@ const (
 	TypeWelcomeEmail = "welcome_email"
 	TypePaymentReceipt = "payment_receipt"
 	TypePasswordReset = "password_reset"
+	TypeEmailVerification = "email_verification"
+	TypeEmailChange = "email_change"
 )
 
 //Notification represents an email notification in the system
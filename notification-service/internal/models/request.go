@@ -5,7 +5,7 @@ type SendEmailRequest struct {
 	To string `json:"to" binding:"required,email"`
 	Subject string `json:"subject" binding:"required,min=1,max=255"`
 	Body string `json:"body" binding:"required,min=1"`
-	Type string `json:"type" binding:"required,oneof=booking_confirmation welcome_email payment_receipt password_reset"`
+	Type string `json:"type" binding:"required,oneof=booking_confirmation welcome_email payment_receipt password_reset email_verification"`
 }
 
 //type sendemailresponse represents the Api response after sending an email
@@ -16,6 +16,8 @@ type NotificationRepository interface {
 	UpdateNotificationStatus (ctx context.Context, id string, status string) error
 	UpdateNotificationSent (ctx context.Context, id string, sentAt time.Time) error
 	UpdateNotificationFailed (ctx context.Context, id string, errorMsg string) error
+	GetNotificationsByRecipient (ctx context.Context, email string) ([]models.Notification, error)
+	DeleteNotificationsByRecipient (ctx context.Context, email string) (int, error)
 }
 
 //templaterepo defines the contract for email template operations
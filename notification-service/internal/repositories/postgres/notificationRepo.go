@@ -51,11 +51,69 @@ func (r *NotificationRepo) GetNotificationById(ctx context.Context, id string) (
         WHERE id = $1
     `
     
+    notification, err := scanNotification(r.db.QueryRowContext(ctx, query, id))
+    if err == sql.ErrNoRows {
+        return nil, repositories.ErrNotificationNotFound
+    }
+    
+    if err != nil {
+        return nil, fmt.Errorf("failed to get notification: %w", err)
+    }
+    
+    return notification, nil
+}
+
+// GetNotificationsByRecipient returns every notification sent to an address
+func (r *NotificationRepo) GetNotificationsByRecipient(ctx context.Context, email string) ([]models.Notification, error) {
+    query := `
+        SELECT id, to_email, subject, body, status, type, retry_count, sent_at, error
+        FROM notifications 
+        WHERE LOWER(to_email) = LOWER($1)
+    `
+    
+    rows, err := r.db.QueryContext(ctx, query, email)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query notifications: %w", err)
+    }
+    defer rows.Close()
+    
+    notifications := []models.Notification{}
+    for rows.Next() {
+        notification, err := scanNotification(rows)
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan notification: %w", err)
+        }
+        notifications = append(notifications, *notification)
+    }
+    return notifications, rows.Err()
+}
+
+// DeleteNotificationsByRecipient removes every notification sent to an address
+func (r *NotificationRepo) DeleteNotificationsByRecipient(ctx context.Context, email string) (int, error) {
+    query := `DELETE FROM notifications WHERE LOWER(to_email) = LOWER($1)`
+    
+    result, err := r.db.ExecContext(ctx, query, email)
+    if err != nil {
+        return 0, fmt.Errorf("failed to delete notifications: %w", err)
+    }
+    
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return 0, fmt.Errorf("failed to get rows affected: %w", err)
+    }
+    return int(rowsAffected), nil
+}
+
+type rowScanner interface {
+    Scan(dest ...interface{}) error
+}
+
+func scanNotification(row rowScanner) (*models.Notification, error) {
     var notification models.Notification
     var sentAtStr sql.NullString
     var errorStr sql.NullString
     
-    err := r.db.QueryRowContext(ctx, query, id).Scan(
+    err := row.Scan(
         &notification.Id,
         &notification.To,
         &notification.Subject,
@@ -66,13 +124,8 @@ func (r *NotificationRepo) GetNotificationById(ctx context.Context, id string) (
         &sentAtStr,
         &errorStr,
     )
-    
-    if err == sql.ErrNoRows {
-        return nil, repositories.ErrNotificationNotFound
-    }
-    
     if err != nil {
-        return nil, fmt.Errorf("failed to get notification: %w", err)
+        return nil, err
     }
     
     // Convert string to *time.Time if sent_at exists
@@ -115,6 +115,25 @@ func (s *NotificationService) GetRateLimitStatus(email string) int {
 }
 
 //generatedId creates unique ids for notifications
+//exportrecipientdata returns every notification sent to an address, for data export requests
+func (s *NotificationService) ExportRecipientData(ctx context.Context, email string) ([]models.Notification, error) {
+	notifications, err := s.repo.GetNotificationsByRecipient(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+//eraserecipientdata deletes every notification sent to an address when its owner deletes their account.
+//notifications are copies of what was emailed, nothing here has to be retained
+func (s *NotificationService) EraseRecipientData(ctx context.Context, email string) (int, error) {
+	count, err := s.repo.DeleteNotificationsByRecipient(ctx, email)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete notifications: %w", err)
+	}
+	return count, nil
+}
+
 func generateId() string {
 	return fmt.Sprintf("notif_%d", time.Now().UnixNano())
 }
\ No newline at end of file
@@ -5,7 +5,7 @@ import (
 	"os"
 	"strconv"
 
-	
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
 )
 
 type Config struct {
@@ -28,6 +28,9 @@ type Config struct {
 
 	//logging config
 	LogFormat string
+
+	//auth config, shared with the other services
+	Auth auth.Config
 }
 
 
@@ -60,6 +63,9 @@ func Load() *Config {
 
         // Logging configuration
         LogFormat: getEnv("LOG_FORMAT", "json"),
+
+        // Auth configuration
+        Auth: auth.LoadConfigFromEnv(),
     }
 }
 
@@ -36,7 +36,7 @@ func SecurityMiddleware(config *SecurityConfig) gin.HandlerFunc {
 		if config.AllowedOrigins == "*" || origin == config.AllowedOrigins {
 			c.Header("Access-Control-Allow-Origin", origin)
 			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
 		}
 
 		//handle preflight requests
@@ -6,12 +6,14 @@ import (
 	"os"
 	"os/signal"
 	"github.com/ollatomiwa/hotelsystem/payment-service/config"
-	"github.com/ollatomiwa/hotelsystem/payment-service/internal/handlers"
-	"github.com/ollatomiwa/hotelsystem/payment-service/internal/repository"
-	"github.com/ollatomiwa/hotelsystem/payment-service/internal/router"
-	"github.com/ollatomiwa/hotelsystem/payment-service/internal/service"
+	"github.com/ollatomiwa/hotelsystem/payment-service/internals/handlers"
+	"github.com/ollatomiwa/hotelsystem/payment-service/internals/repository"
+	"github.com/ollatomiwa/hotelsystem/payment-service/internals/router"
+	"github.com/ollatomiwa/hotelsystem/payment-service/internals/service"
 	"github.com/ollatomiwa/hotelsystem/payment-service/pkg/database"
+	"github.com/ollatomiwa/hotelsystem/payment-service/pkg/loyalty"
 	"github.com/ollatomiwa/hotelsystem/payment-service/pkg/paystack"
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
 	"syscall"
 	"time"
 
@@ -66,15 +68,22 @@ func main() {
 		logger,
 	)
 
+	// Loyalty points are spent and given back through user-service
+	var loyaltyClient *loyalty.Client
+	if cfg.Loyalty.UserServiceURL != "" {
+		loyaltyClient = loyalty.NewClient(cfg.Loyalty.UserServiceURL, cfg.Auth.ServiceKey)
+	}
+
 	// Initialize service
-	paymentService := service.NewPaymentService(repo, paystackClient, logger)
+	paymentService := service.NewPaymentService(repo, paystackClient, loyaltyClient, logger)
 
 	// Initialize handlers
 	paymentHandler := handlers.NewPaymentHandler(paymentService, logger, cfg.Paystack.SecretKey)
 	healthHandler := handlers.NewHealthHandler(db)
 
 	// Setup router
-	r := router.Setup(cfg, paymentHandler, healthHandler, logger)
+	authn := auth.NewFromConfig(cfg.Auth)
+	r := router.Setup(cfg, paymentHandler, healthHandler, authn, logger)
 
 	// Create HTTP server
 	srv := &http.Server{
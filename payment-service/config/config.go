@@ -1 +1,111 @@
 package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/joho/godotenv"
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
+)
+
+type Config struct {
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Paystack  PaystackConfig
+	RateLimit RateLimitConfig
+	Loyalty   LoyaltyConfig
+	// Auth verifies user-service tokens and accepts other services' keys
+	Auth auth.Config
+}
+
+type ServerConfig struct {
+	Port        string
+	Environment string
+}
+
+type DatabaseConfig struct {
+	Path string
+}
+
+type PaystackConfig struct {
+	SecretKey string
+	PublicKey string
+	BaseURL   string
+}
+
+// LoyaltyConfig points at user-service, which holds guests' loyalty points. Payments
+// can't be made with points when UserServiceURL is empty
+type LoyaltyConfig struct {
+	UserServiceURL string
+}
+
+// RateLimitConfig allows Requests per Window seconds from each IP
+type RateLimitConfig struct {
+	Requests int
+	Window   int
+}
+
+// Load reads the configuration from the environment, and from a .env file when one exists
+func Load() (*Config, error) {
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read .env: %w", err)
+	}
+
+	cfg := &Config{
+		Server: ServerConfig{
+			Port:        getEnv("PORT", "8080"),
+			Environment: getEnv("ENVIRONMENT", "development"),
+		},
+		Database: DatabaseConfig{
+			Path: getEnv("DATABASE_PATH", "./data/payment.db"),
+		},
+		Paystack: PaystackConfig{
+			SecretKey: getEnv("PAYSTACK_SECRET_KEY", ""),
+			PublicKey: getEnv("PAYSTACK_PUBLIC_KEY", ""),
+			BaseURL:   getEnv("PAYSTACK_BASE_URL", "https://api.paystack.co"),
+		},
+		RateLimit: RateLimitConfig{
+			Requests: getEnvInt("RATE_LIMIT_REQUESTS", 100),
+			Window:   getEnvInt("RATE_LIMIT_WINDOW", 60),
+		},
+		Loyalty: LoyaltyConfig{
+			UserServiceURL: getEnv("USER_SERVICE_URL", "http://localhost:8082"),
+		},
+		Auth: auth.LoadConfigFromEnv(),
+	}
+
+	if cfg.RateLimit.Requests <= 0 || cfg.RateLimit.Window <= 0 {
+		return nil, fmt.Errorf("RATE_LIMIT_REQUESTS and RATE_LIMIT_WINDOW must be positive")
+	}
+	if cfg.IsProduction() && cfg.Paystack.SecretKey == "" {
+		return nil, fmt.Errorf("PAYSTACK_SECRET_KEY is required in production")
+	}
+	return cfg, nil
+}
+
+func (c *Config) IsProduction() bool {
+	return c.Server.Environment == "production"
+}
+
+// EnsureDataDirectory creates the directory the SQLite database lives in
+func EnsureDataDirectory(dbPath string) error {
+	return os.MkdirAll(filepath.Dir(dbPath), 0o755)
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
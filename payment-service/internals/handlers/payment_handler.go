@@ -2,12 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"math"
 	"net/http"
 	"github.com/ollatomiwa/hotelsystem/payment-service/internals/models"
 	"github.com/ollatomiwa/hotelsystem/payment-service/internals/service"
+	"github.com/ollatomiwa/hotelsystem/payment-service/pkg/loyalty"
 	"github.com/ollatomiwa/hotelsystem/payment-service/pkg/paystack"
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -53,8 +56,23 @@ func (h *PaymentHandler) InitializePayment(c *gin.Context) {
 		req.IdempotencyKey = key.(string)
 	}
 
+	// Users spend their own points, only services pay on a guest's behalf
+	if claims, ok := auth.ClaimsFrom(c); ok && !claims.IsService() {
+		req.UserId = claims.UserId
+	}
+
 	transaction, err := h.service.InitializePayment(&req)
 	if err != nil {
+		if errors.Is(err, loyalty.ErrRefused) ||
+			errors.Is(err, service.ErrLoyaltyNeedsUser) ||
+			errors.Is(err, service.ErrPointsExceedAmount) ||
+			errors.Is(err, service.ErrDuplicateReference) {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
 		h.logger.Errorf("Failed to initialize payment: %v", err)
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Status:  "error",
@@ -148,6 +166,55 @@ func (h *PaymentHandler) GetPayment(c *gin.Context) {
 	})
 }
 
+// RefundPayment godoc
+// @Summary Refund payment
+// @Description Refund a successful payment in full, giving back any loyalty points spent on it
+// @Tags payments
+// @Produce json
+// @Param id path int true "Transaction ID"
+// @Success 200 {object} models.APIResponse
+// @Router /api/v1/payments/{id}/refund [post]
+func (h *PaymentHandler) RefundPayment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Status:  "error",
+			Message: "Invalid transaction ID",
+		})
+		return
+	}
+
+	transaction, err := h.service.RefundPayment(uint(id))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Status:  "error",
+				Message: "Transaction not found",
+			})
+			return
+		}
+		if errors.Is(err, service.ErrNotRefundable) {
+			c.JSON(http.StatusConflict, models.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		h.logger.Errorf("Failed to refund payment: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Status:  "error",
+			Message: "Failed to refund payment",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Status:  "success",
+		Message: "Payment refunded successfully",
+		Data:    transaction,
+	})
+}
+
 // ListPayments godoc
 // @Summary List payments
 // @Description List all payments with pagination
@@ -232,6 +299,78 @@ func (h *PaymentHandler) GetCustomer(c *gin.Context) {
 	})
 }
 
+// ExportCustomerData godoc
+// @Summary Export a customer's payment data
+// @Description Internal, for user-service data export requests
+// @Tags internal
+// @Produce json
+// @Param email query string true "Customer email"
+// @Success 200 {object} models.APIResponse
+// @Router /api/v1/internal/customers/data [get]
+func (h *PaymentHandler) ExportCustomerData(c *gin.Context) {
+	email := c.Query("email")
+	if email == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Status:  "error",
+			Message: "Email is required",
+		})
+		return
+	}
+
+	export, err := h.service.ExportCustomerData(email)
+	if err != nil {
+		h.logger.Errorf("Failed to export customer data: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Status:  "error",
+			Message: "Failed to export customer data",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Status:  "success",
+		Message: "Customer data exported successfully",
+		Data:    export,
+	})
+}
+
+// AnonymizeCustomerData godoc
+// @Summary Anonymize a customer's payment data
+// @Description Internal, for user-service account deletion. Transactions are kept under the placeholder email
+// @Tags internal
+// @Produce json
+// @Param email query string true "Customer email"
+// @Param placeholder query string true "Email to keep on retained transactions"
+// @Success 200 {object} models.APIResponse
+// @Router /api/v1/internal/customers/data [delete]
+func (h *PaymentHandler) AnonymizeCustomerData(c *gin.Context) {
+	email := c.Query("email")
+	placeholder := c.Query("placeholder")
+	if email == "" || placeholder == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Status:  "error",
+			Message: "Email and placeholder are required",
+		})
+		return
+	}
+
+	updated, err := h.service.AnonymizeCustomer(email, placeholder)
+	if err != nil {
+		h.logger.Errorf("Failed to anonymize customer data: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Status:  "error",
+			Message: "Failed to anonymize customer data",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Status:  "success",
+		Message: "Customer data anonymized successfully",
+		Data:    gin.H{"transactions_anonymized": updated},
+	})
+}
+
 // HandleWebhook godoc
 // @Summary Handle Paystack webhook
 // @Description Handle webhook events from Paystack
@@ -47,36 +47,6 @@ func RequestID() gin.HandlerFunc {
 	}
 }
 
-// APIKeyAuth validates API key authentication
-func APIKeyAuth(apiKey string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Skip auth for health check and webhook endpoints
-		if c.Request.URL.Path == "/api/v1/health" || c.Request.URL.Path == "/api/v1/webhooks/paystack" {
-			c.Next()
-			return
-		}
-
-		key := c.GetHeader("X-API-Key")
-		if key == "" {
-			key = c.GetHeader("Authorization")
-			if len(key) > 7 && key[:7] == "Bearer " {
-				key = key[7:]
-			}
-		}
-
-		if key != apiKey {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"status":  "error",
-				"message": "Invalid or missing API key",
-			})
-			c.Abort()
-			return
-		}
-
-		c.Next()
-	}
-}
-
 // RateLimiter implements rate limiting per IP
 type RateLimiter struct {
 	limiters map[string]*rate.Limiter
@@ -0,0 +1,125 @@
+package models
+
+import "time"
+
+type TransactionStatus string
+
+const (
+	StatusPending   TransactionStatus = "pending"
+	StatusSuccess   TransactionStatus = "success"
+	StatusFailed    TransactionStatus = "failed"
+	StatusAbandoned TransactionStatus = "abandoned"
+	StatusRefunded  TransactionStatus = "refunded"
+)
+
+// Transaction is a payment made through Paystack. Amounts are in the currency's
+// smallest unit (kobo for NGN). LoyaltyAmount is the part of Amount paid with the
+// user's loyalty points, Paystack charges the rest
+type Transaction struct {
+	ID             uint              `gorm:"primaryKey" json:"id"`
+	Reference      string            `gorm:"uniqueIndex;not null" json:"reference"`
+	Amount         int64             `gorm:"not null" json:"amount"`
+	Currency       string            `gorm:"default:NGN" json:"currency"`
+	Status         TransactionStatus `gorm:"index;default:pending" json:"status"`
+	CustomerEmail  string            `gorm:"index;not null" json:"customer_email"`
+	CustomerName   string            `json:"customer_name,omitempty"`
+	Metadata       string            `json:"metadata,omitempty"`
+	AuthURL        string            `json:"authorization_url,omitempty"`
+	AccessCode     string            `json:"access_code,omitempty"`
+	PaystackRef    string            `json:"paystack_reference,omitempty"`
+	IdempotencyKey string            `gorm:"index" json:"-"`
+	UserId         string            `gorm:"index" json:"user_id,omitempty"`
+	LoyaltyPoints  int64             `json:"loyalty_points,omitempty"`
+	LoyaltyAmount  int64             `json:"loyalty_amount,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+type Customer struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Email        string    `gorm:"uniqueIndex;not null" json:"email"`
+	Name         string    `json:"name,omitempty"`
+	CustomerCode string    `json:"customer_code,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Webhook is a Paystack event as received, kept until it has been processed
+type Webhook struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	EventType string    `gorm:"index" json:"event_type"`
+	Payload   string    `json:"payload"`
+	Processed bool      `gorm:"index;default:false" json:"processed"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type InitializePaymentRequest struct {
+	Email          string                 `json:"email" binding:"required,email"`
+	Amount         int64                  `json:"amount" binding:"required,gt=0"`
+	Currency       string                 `json:"currency"`
+	Reference      string                 `json:"reference"`
+	CallbackURL    string                 `json:"callback_url"`
+	Metadata       map[string]interface{} `json:"metadata"`
+	IdempotencyKey string                 `json:"-"`
+	// LoyaltyPoints are spent from UserId's account towards the amount. Users always
+	// spend their own points, only services may name the user
+	LoyaltyPoints int64  `json:"loyalty_points" binding:"omitempty,min=0"`
+	UserId        string `json:"user_id"`
+}
+
+type APIResponse struct {
+	Status  string      `json:"status"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+	Meta    *Meta       `json:"meta,omitempty"`
+}
+
+type Meta struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+type PaystackInitializeResponse struct {
+	Status  bool   `json:"status"`
+	Message string `json:"message"`
+	Data    struct {
+		AuthorizationURL string `json:"authorization_url"`
+		AccessCode       string `json:"access_code"`
+		Reference        string `json:"reference"`
+	} `json:"data"`
+}
+
+type PaystackVerifyResponse struct {
+	Status  bool   `json:"status"`
+	Message string `json:"message"`
+	Data    struct {
+		Status    string           `json:"status"`
+		Reference string           `json:"reference"`
+		Amount    int64            `json:"amount"`
+		Currency  string           `json:"currency"`
+		Customer  PaystackCustomer `json:"customer"`
+	} `json:"data"`
+}
+
+type PaystackRefundResponse struct {
+	Status  bool   `json:"status"`
+	Message string `json:"message"`
+}
+
+type PaystackCustomer struct {
+	Email        string `json:"email"`
+	CustomerCode string `json:"customer_code"`
+}
+
+type PaystackWebhookEvent struct {
+	Event string `json:"event"`
+	Data  struct {
+		Status    string           `json:"status"`
+		Reference string           `json:"reference"`
+		Amount    int64            `json:"amount"`
+		Currency  string           `json:"currency"`
+		Customer  PaystackCustomer `json:"customer"`
+	} `json:"data"`
+}
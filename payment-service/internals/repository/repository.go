@@ -1,7 +1,7 @@
 package repository
 
 import (
-	"github.com/ollatomiwa/hotelsystem/payment-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/payment-service/internals/models"
 
 	"gorm.io/gorm"
 )
@@ -119,6 +119,35 @@ func (r *Repository) GetOrCreateCustomer(email, name string) (*models.Customer,
 	return customer, nil
 }
 
+// GetTransactionsByEmail returns every transaction paid with an email, newest first
+func (r *Repository) GetTransactionsByEmail(email string) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.db.Where("customer_email = ?", email).
+		Order("created_at DESC").
+		Find(&transactions).Error
+	return transactions, err
+}
+
+// AnonymizeCustomer swaps an email for a placeholder on the customer's transactions,
+// which are kept for accounting, and blanks their customer profile
+func (r *Repository) AnonymizeCustomer(email, placeholder string) (int64, error) {
+	var updated int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Transaction{}).
+			Where("customer_email = ?", email).
+			Updates(map[string]interface{}{"customer_email": placeholder, "customer_name": ""})
+		if result.Error != nil {
+			return result.Error
+		}
+		updated = result.RowsAffected
+
+		return tx.Model(&models.Customer{}).
+			Where("email = ?", email).
+			Updates(map[string]interface{}{"email": placeholder, "name": "", "customer_code": ""}).Error
+	})
+	return updated, err
+}
+
 // Webhook Repository Methods
 
 func (r *Repository) CreateWebhook(webhook *models.Webhook) error {
@@ -4,6 +4,7 @@ import (
 	"github.com/ollatomiwa/hotelsystem/payment-service/config"
 	"github.com/ollatomiwa/hotelsystem/payment-service/internals/handlers"
 	"github.com/ollatomiwa/hotelsystem/payment-service/internals/middleware"
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -14,6 +15,7 @@ func Setup(
 	cfg *config.Config,
 	paymentHandler *handlers.PaymentHandler,
 	healthHandler *handlers.HealthHandler,
+	authn *auth.Middleware,
 	logger *logrus.Logger,
 ) *gin.Engine {
 	// Set Gin mode
@@ -59,25 +61,34 @@ func Setup(
 	// Webhook endpoint (no auth, uses signature verification)
 	v1.POST("/webhooks/paystack", paymentHandler.HandleWebhook)
 
-	// Protected routes
-	if cfg.Security.APIKey != "" {
-		v1.Use(middleware.APIKeyAuth(cfg.Security.APIKey))
-	}
+	// Protected routes: guest tokens from user-service or service keys
+	v1.Use(authn.Authenticate())
 
-	// Payment routes
+	// Payment routes. Looking payments up by id or listing them shows other
+	// customers' payments, so it is for staff and services
 	payments := v1.Group("/payments")
 	{
 		payments.POST("/initialize", paymentHandler.InitializePayment)
 		payments.GET("/verify/:reference", paymentHandler.VerifyPayment)
-		payments.GET("/:id", paymentHandler.GetPayment)
-		payments.GET("", paymentHandler.ListPayments)
+		payments.GET("/:id", authn.RequirePermission(auth.PermPaymentsRead), paymentHandler.GetPayment)
+		payments.POST("/:id/refund", authn.RequirePermission(auth.PermPaymentsRefund), paymentHandler.RefundPayment)
+		payments.GET("", authn.RequirePermission(auth.PermPaymentsRead), paymentHandler.ListPayments)
 	}
 
 	// Customer routes
 	customers := v1.Group("/customers")
+	customers.Use(authn.RequirePermission(auth.PermPaymentsRead))
 	{
 		customers.GET("/:email", paymentHandler.GetCustomer)
 	}
 
+	// Internal routes for other services, user-service uses these for data export and erasure
+	internal := v1.Group("/internal")
+	internal.Use(authn.RequireService())
+	{
+		internal.GET("/customers/data", paymentHandler.ExportCustomerData)
+		internal.DELETE("/customers/data", paymentHandler.AnonymizeCustomerData)
+	}
+
 	return r
 }
\ No newline at end of file
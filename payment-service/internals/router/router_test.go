@@ -0,0 +1,161 @@
+package router
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ollatomiwa/hotelsystem/payment-service/config"
+	"github.com/ollatomiwa/hotelsystem/payment-service/internals/handlers"
+	"github.com/ollatomiwa/hotelsystem/payment-service/internals/models"
+	"github.com/ollatomiwa/hotelsystem/payment-service/internals/repository"
+	"github.com/ollatomiwa/hotelsystem/payment-service/internals/service"
+	"github.com/ollatomiwa/hotelsystem/payment-service/pkg/database"
+	"github.com/ollatomiwa/hotelsystem/payment-service/pkg/paystack"
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
+	"github.com/sirupsen/logrus"
+)
+
+type staticKeys map[string]ed25519.PublicKey
+
+func (s staticKeys) PublicKey(kid string) (ed25519.PublicKey, bool) {
+	key, ok := s[kid]
+	return key, ok
+}
+
+// newTestRouter serves the real routes over a throwaway database, trusting tokens
+// signed with the returned key and the service key "booking-key"
+func newTestRouter(t *testing.T) (*gin.Engine, ed25519.PrivateKey, *repository.Repository) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	db, err := database.New(filepath.Join(t.TempDir(), "payment.db"), true)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.AutoMigrate(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	authn := auth.New(auth.NewKeyVerifier(staticKeys{"current": public}, auth.DefaultIssuer),
+		auth.WithServiceKeys(map[string]string{"booking-key": "booking-service"}))
+
+	repo := repository.New(db.DB)
+	paymentService := service.NewPaymentService(repo, paystack.NewClient("sk_test", "http://127.0.0.1:0", logger), nil, logger)
+	cfg := &config.Config{RateLimit: config.RateLimitConfig{Requests: 1000, Window: 1}}
+	r := Setup(cfg, handlers.NewPaymentHandler(paymentService, logger, "sk_test"), handlers.NewHealthHandler(db), authn, logger)
+	return r, private, repo
+}
+
+func serve(r *gin.Engine, method, path, header, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func bearer(t *testing.T, key ed25519.PrivateKey, claims auth.Claims) string {
+	t.Helper()
+	claims.Issuer = auth.DefaultIssuer
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Minute))
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, &claims)
+	token.Header["kid"] = "current"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return "Bearer " + signed
+}
+
+func TestRoutesRequireSharedAuth(t *testing.T) {
+	r, key, _ := newTestRouter(t)
+	guest := bearer(t, key, auth.Claims{UserId: "guest-1", Role: auth.RoleCustomer})
+	accountant := bearer(t, key, auth.Claims{UserId: "staff-1", Role: auth.RoleAccountant,
+		Permissions: []string{auth.PermPaymentsRead}})
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		value  string
+		want   int
+	}{
+		{"health is public", "/api/v1/health", "", "", http.StatusOK},
+		{"no credentials", "/api/v1/payments", "", "", http.StatusUnauthorized},
+		{"retired static key", "/api/v1/payments", "X-API-Key", "your_api_key", http.StatusUnauthorized},
+		{"guest listing payments", "/api/v1/payments", "Authorization", guest, http.StatusForbidden},
+		{"guest reading a customer", "/api/v1/customers/someone@example.com", "Authorization", guest, http.StatusForbidden},
+		{"staff with payments:read", "/api/v1/payments", "Authorization", accountant, http.StatusOK},
+		{"service key", "/api/v1/payments", "X-API-Key", "booking-key", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, tt.path, tt.header, tt.value)
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCustomerDataExportAndErasure(t *testing.T) {
+	r, key, repo := newTestRouter(t)
+	if _, err := repo.GetOrCreateCustomer("guest@example.com", "Ada Guest"); err != nil {
+		t.Fatalf("failed to seed customer: %v", err)
+	}
+	err := repo.CreateTransaction(&models.Transaction{Reference: "TXN_1", Amount: 50000, Currency: "NGN",
+		Status: models.StatusSuccess, CustomerEmail: "guest@example.com", CustomerName: "Ada Guest"})
+	if err != nil {
+		t.Fatalf("failed to seed transaction: %v", err)
+	}
+
+	const exportPath = "/api/v1/internal/customers/data?email=guest%40example.com"
+	admin := bearer(t, key, auth.Claims{UserId: "admin-1", Role: auth.RoleAdmin, Permissions: []string{auth.PermAll}})
+	if w := serve(r, http.MethodGet, exportPath, "Authorization", admin); w.Code != http.StatusForbidden {
+		t.Fatalf("expected internal routes to refuse user tokens, got %d", w.Code)
+	}
+
+	w := serve(r, http.MethodGet, exportPath, "X-API-Key", "booking-key")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"reference":"TXN_1"`) {
+		t.Fatalf("expected the transaction in the export, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = serve(r, http.MethodDelete, exportPath+"&placeholder=deleted-1%40deleted.invalid", "X-API-Key", "booking-key")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"transactions_anonymized":1`) {
+		t.Fatalf("expected one transaction anonymized, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// the transaction is kept for accounting, without the guest's email or name
+	tx, err := repo.GetTransactionByReference("TXN_1")
+	if err != nil {
+		t.Fatalf("transaction should be retained: %v", err)
+	}
+	if tx.CustomerEmail != "deleted-1@deleted.invalid" || tx.CustomerName != "" {
+		t.Errorf("transaction still identifies the guest: %q %q", tx.CustomerEmail, tx.CustomerName)
+	}
+	if _, err := repo.GetCustomerByEmail("guest@example.com"); err == nil {
+		t.Error("customer profile should no longer be found by the old email")
+	}
+	w = serve(r, http.MethodGet, exportPath, "X-API-Key", "booking-key")
+	if strings.Contains(w.Body.String(), "TXN_1") {
+		t.Errorf("erased data still exported: %s", w.Body.String())
+	}
+}
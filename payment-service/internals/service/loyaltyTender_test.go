@@ -0,0 +1,289 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ollatomiwa/hotelsystem/payment-service/internals/models"
+	"github.com/ollatomiwa/hotelsystem/payment-service/internals/repository"
+	"github.com/ollatomiwa/hotelsystem/payment-service/pkg/database"
+	"github.com/ollatomiwa/hotelsystem/payment-service/pkg/loyalty"
+	"github.com/ollatomiwa/hotelsystem/payment-service/pkg/paystack"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeUserService redeems points at 1 NGN per 10 points and records reversals
+type fakeUserService struct {
+	mu        sync.Mutex
+	redeemed  map[string]int64
+	reversed  []string
+	balance   int64
+	redeemErr int
+}
+
+func (f *fakeUserService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("X-API-Key") != "payment-key" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v1/internal/loyalty/redemptions":
+		if f.redeemErr != 0 {
+			w.WriteHeader(f.redeemErr)
+			io.WriteString(w, `{"error":"insufficient points"}`)
+			return
+		}
+		var req struct {
+			UserId    string `json:"userId"`
+			Points    int64  `json:"points"`
+			Reference string `json:"reference"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		f.redeemed[req.Reference] = req.Points
+		f.balance -= req.Points
+		json.NewEncoder(w).Encode(loyalty.Redemption{Points: req.Points, Amount: float64(req.Points) / 10, Balance: f.balance})
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/v1/internal/loyalty/redemptions/"):
+		reference := strings.TrimPrefix(r.URL.Path, "/api/v1/internal/loyalty/redemptions/")
+		if r.URL.Query().Get("userId") != "guest-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.reversed = append(f.reversed, reference)
+		io.WriteString(w, `{}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// fakePaystack records the amounts it was asked to charge and the references refunded
+type fakePaystack struct {
+	mu        sync.Mutex
+	charged   []int64
+	refunded  []string
+	failStart bool
+}
+
+func (f *fakePaystack) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	switch r.URL.Path {
+	case "/transaction/initialize":
+		if f.failStart {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"status":false,"message":"Invalid key"}`)
+			return
+		}
+		f.charged = append(f.charged, int64(body["amount"].(float64)))
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": true, "data": map[string]string{
+			"authorization_url": "https://checkout.example/x", "access_code": "x", "reference": body["reference"].(string)}})
+	case "/refund":
+		f.refunded = append(f.refunded, body["transaction"].(string))
+		io.WriteString(w, `{"status":true,"message":"Refund has been queued for processing"}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newLoyaltyTestService(t *testing.T) (*PaymentService, *fakeUserService, *fakePaystack, *repository.Repository) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	db, err := database.New(filepath.Join(t.TempDir(), "payment.db"), true)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.AutoMigrate(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	users := &fakeUserService{redeemed: map[string]int64{}, balance: 10000}
+	userServer := httptest.NewServer(users)
+	t.Cleanup(userServer.Close)
+	ps := &fakePaystack{}
+	paystackServer := httptest.NewServer(ps)
+	t.Cleanup(paystackServer.Close)
+
+	repo := repository.New(db.DB)
+	svc := NewPaymentService(repo, paystack.NewClient("sk_test", paystackServer.URL, logger),
+		loyalty.NewClient(userServer.URL, "payment-key"), logger)
+	return svc, users, ps, repo
+}
+
+func TestPointsPayPartOfThePayment(t *testing.T) {
+	svc, users, ps, _ := newLoyaltyTestService(t)
+
+	// 2000 points are worth NGN 200, Paystack charges the other NGN 300
+	tx, err := svc.InitializePayment(&models.InitializePaymentRequest{Email: "guest@example.com", Amount: 50000,
+		Reference: "TXN_1", UserId: "guest-1", LoyaltyPoints: 2000})
+	if err != nil {
+		t.Fatalf("InitializePayment: %v", err)
+	}
+	if tx.LoyaltyPoints != 2000 || tx.LoyaltyAmount != 20000 || tx.Status != models.StatusPending {
+		t.Errorf("unexpected transaction: points=%d amount=%d status=%s", tx.LoyaltyPoints, tx.LoyaltyAmount, tx.Status)
+	}
+	if len(ps.charged) != 1 || ps.charged[0] != 30000 {
+		t.Errorf("expected Paystack to charge 30000, got %v", ps.charged)
+	}
+	if users.redeemed["TXN_1"] != 2000 {
+		t.Errorf("expected the redemption keyed on the reference, got %v", users.redeemed)
+	}
+}
+
+func TestPointsCoveringTheWholeAmountSkipPaystack(t *testing.T) {
+	svc, _, ps, _ := newLoyaltyTestService(t)
+
+	tx, err := svc.InitializePayment(&models.InitializePaymentRequest{Email: "guest@example.com", Amount: 50000,
+		Reference: "TXN_1", UserId: "guest-1", LoyaltyPoints: 5000})
+	if err != nil {
+		t.Fatalf("InitializePayment: %v", err)
+	}
+	if tx.Status != models.StatusSuccess || len(ps.charged) != 0 {
+		t.Errorf("expected a settled payment without a charge, got status=%s charges=%v", tx.Status, ps.charged)
+	}
+}
+
+func TestPointsAreGivenBackWhenThePaymentCannotStart(t *testing.T) {
+	t.Run("worth more than the amount", func(t *testing.T) {
+		svc, users, _, _ := newLoyaltyTestService(t)
+		_, err := svc.InitializePayment(&models.InitializePaymentRequest{Email: "guest@example.com", Amount: 50000,
+			Reference: "TXN_1", UserId: "guest-1", LoyaltyPoints: 6000})
+		if !errors.Is(err, ErrPointsExceedAmount) {
+			t.Fatalf("expected ErrPointsExceedAmount, got %v", err)
+		}
+		if len(users.reversed) != 1 || users.reversed[0] != "TXN_1" {
+			t.Errorf("expected the redemption reversed, got %v", users.reversed)
+		}
+	})
+
+	t.Run("paystack refuses", func(t *testing.T) {
+		svc, users, ps, repo := newLoyaltyTestService(t)
+		ps.failStart = true
+		_, err := svc.InitializePayment(&models.InitializePaymentRequest{Email: "guest@example.com", Amount: 50000,
+			Reference: "TXN_1", UserId: "guest-1", LoyaltyPoints: 2000})
+		if err == nil {
+			t.Fatal("expected the payment to fail")
+		}
+		if len(users.reversed) != 1 {
+			t.Errorf("expected the redemption reversed, got %v", users.reversed)
+		}
+		if _, err := repo.GetTransactionByReference("TXN_1"); err == nil {
+			t.Error("no transaction should be stored")
+		}
+	})
+
+	t.Run("user service refuses", func(t *testing.T) {
+		svc, users, ps, _ := newLoyaltyTestService(t)
+		users.redeemErr = http.StatusConflict
+		_, err := svc.InitializePayment(&models.InitializePaymentRequest{Email: "guest@example.com", Amount: 50000,
+			Reference: "TXN_1", UserId: "guest-1", LoyaltyPoints: 2000})
+		if !errors.Is(err, loyalty.ErrRefused) {
+			t.Fatalf("expected loyalty.ErrRefused, got %v", err)
+		}
+		if len(ps.charged) != 0 {
+			t.Errorf("nothing should be charged, got %v", ps.charged)
+		}
+	})
+
+	t.Run("reused reference", func(t *testing.T) {
+		svc, users, _, _ := newLoyaltyTestService(t)
+		req := models.InitializePaymentRequest{Email: "guest@example.com", Amount: 50000,
+			Reference: "TXN_1", UserId: "guest-1", LoyaltyPoints: 2000}
+		if _, err := svc.InitializePayment(&req); err != nil {
+			t.Fatalf("InitializePayment: %v", err)
+		}
+		// reversing here would hand back the first payment's points
+		if _, err := svc.InitializePayment(&req); !errors.Is(err, ErrDuplicateReference) {
+			t.Fatalf("expected ErrDuplicateReference, got %v", err)
+		}
+		if len(users.reversed) != 0 {
+			t.Errorf("the first payment's points were given back: %v", users.reversed)
+		}
+	})
+}
+
+func TestFailedChargeGivesPointsBack(t *testing.T) {
+	svc, users, _, repo := newLoyaltyTestService(t)
+	if _, err := svc.InitializePayment(&models.InitializePaymentRequest{Email: "guest@example.com", Amount: 50000,
+		Reference: "TXN_1", UserId: "guest-1", LoyaltyPoints: 2000}); err != nil {
+		t.Fatalf("InitializePayment: %v", err)
+	}
+
+	event := &models.PaystackWebhookEvent{Event: "charge.failed"}
+	event.Data.Reference = "TXN_1"
+	if err := svc.HandleWebhook(event, "{}"); err != nil {
+		t.Fatalf("HandleWebhook: %v", err)
+	}
+	if len(users.reversed) != 1 || users.reversed[0] != "TXN_1" {
+		t.Errorf("expected the redemption reversed, got %v", users.reversed)
+	}
+	tx, _ := repo.GetTransactionByReference("TXN_1")
+	if tx.Status != models.StatusFailed {
+		t.Errorf("expected failed, got %s", tx.Status)
+	}
+}
+
+func TestRefundGivesPointsBack(t *testing.T) {
+	svc, users, ps, repo := newLoyaltyTestService(t)
+	tx, err := svc.InitializePayment(&models.InitializePaymentRequest{Email: "guest@example.com", Amount: 50000,
+		Reference: "TXN_1", UserId: "guest-1", LoyaltyPoints: 2000})
+	if err != nil {
+		t.Fatalf("InitializePayment: %v", err)
+	}
+	if _, err := svc.RefundPayment(tx.ID); !errors.Is(err, ErrNotRefundable) {
+		t.Fatalf("a pending payment shouldn't be refundable, got %v", err)
+	}
+
+	tx.Status = models.StatusSuccess
+	if err := repo.UpdateTransaction(tx); err != nil {
+		t.Fatalf("failed to settle transaction: %v", err)
+	}
+	refunded, err := svc.RefundPayment(tx.ID)
+	if err != nil {
+		t.Fatalf("RefundPayment: %v", err)
+	}
+	if refunded.Status != models.StatusRefunded {
+		t.Errorf("expected refunded, got %s", refunded.Status)
+	}
+	if len(ps.refunded) != 1 || ps.refunded[0] != "TXN_1" {
+		t.Errorf("expected the card charge refunded, got %v", ps.refunded)
+	}
+	if len(users.reversed) != 1 || users.reversed[0] != "TXN_1" {
+		t.Errorf("expected the redemption reversed, got %v", users.reversed)
+	}
+
+	// a second refund only retries giving the points back
+	if _, err := svc.RefundPayment(tx.ID); err != nil {
+		t.Fatalf("second RefundPayment: %v", err)
+	}
+	if len(ps.refunded) != 1 {
+		t.Errorf("the card was refunded twice: %v", ps.refunded)
+	}
+}
+
+func TestRefundOfPaymentMadeWithPointsOnlySkipsPaystack(t *testing.T) {
+	svc, users, ps, _ := newLoyaltyTestService(t)
+	tx, err := svc.InitializePayment(&models.InitializePaymentRequest{Email: "guest@example.com", Amount: 50000,
+		Reference: "TXN_1", UserId: "guest-1", LoyaltyPoints: 5000})
+	if err != nil {
+		t.Fatalf("InitializePayment: %v", err)
+	}
+	if _, err := svc.RefundPayment(tx.ID); err != nil {
+		t.Fatalf("RefundPayment: %v", err)
+	}
+	if len(ps.refunded) != 0 || len(users.reversed) != 1 {
+		t.Errorf("expected only the points given back, refunds=%v reversals=%v", ps.refunded, users.reversed)
+	}
+}
@@ -0,0 +1,391 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ollatomiwa/hotelsystem/payment-service/internals/models"
+	"github.com/ollatomiwa/hotelsystem/payment-service/internals/repository"
+	"github.com/ollatomiwa/hotelsystem/payment-service/pkg/loyalty"
+	"github.com/ollatomiwa/hotelsystem/payment-service/pkg/paystack"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrLoyaltyUnavailable = errors.New("loyalty points can't be redeemed, no user service is configured")
+	ErrLoyaltyNeedsUser   = errors.New("a user is required to redeem loyalty points")
+	ErrPointsExceedAmount = errors.New("loyalty points are worth more than the amount due")
+	ErrDuplicateReference = errors.New("a transaction with this reference already exists")
+	ErrNotRefundable      = errors.New("only successful payments can be refunded")
+)
+
+type PaymentService struct {
+	repo           *repository.Repository
+	paystackClient *paystack.Client
+	loyaltyClient  *loyalty.Client
+	logger         *logrus.Logger
+}
+
+// NewPaymentService creates the payment service. loyaltyClient may be nil, payments
+// then can't be made with loyalty points
+func NewPaymentService(repo *repository.Repository, paystackClient *paystack.Client, loyaltyClient *loyalty.Client, logger *logrus.Logger) *PaymentService {
+	return &PaymentService{
+		repo:           repo,
+		paystackClient: paystackClient,
+		loyaltyClient:  loyaltyClient,
+		logger:         logger,
+	}
+}
+
+// InitializePayment initializes a new payment
+func (s *PaymentService) InitializePayment(req *models.InitializePaymentRequest) (*models.Transaction, error) {
+	// Check for idempotency
+	if req.IdempotencyKey != "" {
+		existingTx, err := s.repo.GetTransactionByIdempotencyKey(req.IdempotencyKey)
+		if err == nil {
+			s.logger.Infof("Returning existing transaction for idempotency key: %s", req.IdempotencyKey)
+			return existingTx, nil
+		}
+		if err != gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("failed to check idempotency: %w", err)
+		}
+	}
+
+	// Generate reference if not provided
+	if req.Reference == "" {
+		req.Reference = "TXN_" + uuid.New().String()
+	}
+
+	// Set currency default
+	if req.Currency == "" {
+		req.Currency = "NGN"
+	}
+
+	// Get or create customer
+	customer, err := s.repo.GetOrCreateCustomer(req.Email, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get/create customer: %w", err)
+	}
+
+	// Serialize metadata
+	metadataJSON, _ := json.Marshal(req.Metadata)
+
+	// Create transaction record
+	transaction := &models.Transaction{
+		Reference:      req.Reference,
+		Amount:         req.Amount,
+		Currency:       req.Currency,
+		Status:         models.StatusPending,
+		CustomerEmail:  req.Email,
+		CustomerName:   customer.Name,
+		Metadata:       string(metadataJSON),
+		IdempotencyKey: req.IdempotencyKey,
+		UserId:         req.UserId,
+	}
+
+	// Points are spent first, Paystack charges whatever they don't cover
+	if req.LoyaltyPoints > 0 {
+		if err := s.redeemPoints(transaction, req.LoyaltyPoints); err != nil {
+			return nil, err
+		}
+	}
+
+	if transaction.LoyaltyAmount == transaction.Amount {
+		// Points paid the whole amount, there is nothing to charge
+		transaction.Status = models.StatusSuccess
+	} else {
+		// Initialize with Paystack
+		charge := *req
+		charge.Amount = transaction.Amount - transaction.LoyaltyAmount
+		paystackResp, err := s.paystackClient.InitializeTransaction(&charge)
+		if err != nil {
+			s.restorePoints(transaction)
+			return nil, fmt.Errorf("failed to initialize payment with Paystack: %w", err)
+		}
+		transaction.Reference = paystackResp.Data.Reference
+		transaction.AuthURL = paystackResp.Data.AuthorizationURL
+		transaction.AccessCode = paystackResp.Data.AccessCode
+	}
+
+	if err := s.repo.CreateTransaction(transaction); err != nil {
+		s.restorePoints(transaction)
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	s.logger.Infof("Payment initialized: reference=%s, amount=%d", transaction.Reference, transaction.Amount)
+	return transaction, nil
+}
+
+// VerifyPayment verifies a payment with Paystack
+func (s *PaymentService) VerifyPayment(reference string) (*models.Transaction, error) {
+	// Get transaction from database
+	transaction, err := s.repo.GetTransactionByReference(reference)
+	if err != nil {
+		return nil, fmt.Errorf("transaction not found: %w", err)
+	}
+
+	// If already successful, return it
+	if transaction.Status == models.StatusSuccess {
+		return transaction, nil
+	}
+
+	// Verify with Paystack
+	paystackResp, err := s.paystackClient.VerifyTransaction(reference)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify with Paystack: %w", err)
+	}
+
+	// Update transaction status
+	transaction.Status = s.mapPaystackStatus(paystackResp.Data.Status)
+	transaction.PaystackRef = paystackResp.Data.Reference
+
+	if err := s.repo.UpdateTransaction(transaction); err != nil {
+		return nil, fmt.Errorf("failed to update transaction: %w", err)
+	}
+
+	// The guest gets their points back when the card payment doesn't go through.
+	// Verifying again retries this, reversing is idempotent
+	if transaction.Status == models.StatusFailed || transaction.Status == models.StatusAbandoned {
+		if err := s.reverseRedemption(transaction); err != nil {
+			return nil, err
+		}
+	}
+
+	// Update customer info if available
+	if paystackResp.Data.Customer.CustomerCode != "" {
+		customer, err := s.repo.GetCustomerByEmail(transaction.CustomerEmail)
+		if err == nil {
+			customer.CustomerCode = paystackResp.Data.Customer.CustomerCode
+			_ = s.repo.UpdateCustomer(customer)
+		}
+	}
+
+	s.logger.Infof("Payment verified: reference=%s, status=%s", reference, transaction.Status)
+	return transaction, nil
+}
+
+// GetTransaction retrieves a transaction by ID
+func (s *PaymentService) GetTransaction(id uint) (*models.Transaction, error) {
+	return s.repo.GetTransactionByID(id)
+}
+
+// ListTransactions lists transactions with pagination
+func (s *PaymentService) ListTransactions(page, pageSize int, status, email string) ([]models.Transaction, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	return s.repo.ListTransactions(page, pageSize, status, email)
+}
+
+// GetCustomer retrieves a customer by email
+func (s *PaymentService) GetCustomer(email string) (*models.Customer, error) {
+	return s.repo.GetCustomerByEmail(email)
+}
+
+// CustomerDataExport is everything held about one customer
+type CustomerDataExport struct {
+	Customer     *models.Customer     `json:"customer,omitempty"`
+	Transactions []models.Transaction `json:"transactions"`
+}
+
+// ExportCustomerData gathers everything held about a customer for a data export request
+func (s *PaymentService) ExportCustomerData(email string) (*CustomerDataExport, error) {
+	transactions, err := s.repo.GetTransactionsByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+	export := &CustomerDataExport{Transactions: transactions}
+
+	customer, err := s.repo.GetCustomerByEmail(email)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+	export.Customer = customer
+	return export, nil
+}
+
+// AnonymizeCustomer removes a customer's email and name when they delete their account.
+// Transactions are financial records we must retain, so they stay under the placeholder
+func (s *PaymentService) AnonymizeCustomer(email, placeholder string) (int64, error) {
+	updated, err := s.repo.AnonymizeCustomer(email, placeholder)
+	if err != nil {
+		return 0, fmt.Errorf("failed to anonymize customer: %w", err)
+	}
+	s.logger.Infof("Anonymized customer data on %d transactions", updated)
+	return updated, nil
+}
+
+// HandleWebhook processes webhook events
+func (s *PaymentService) HandleWebhook(event *models.PaystackWebhookEvent, payload string) error {
+	// Store webhook
+	webhook := &models.Webhook{
+		EventType: event.Event,
+		Payload:   payload,
+		Processed: false,
+	}
+
+	if err := s.repo.CreateWebhook(webhook); err != nil {
+		return fmt.Errorf("failed to store webhook: %w", err)
+	}
+
+	// Process based on event type
+	switch event.Event {
+	case "charge.success":
+		return s.handleChargeSuccess(event, webhook.ID)
+	case "charge.failed":
+		return s.handleChargeFailed(event, webhook.ID)
+	default:
+		s.logger.Infof("Unhandled webhook event: %s", event.Event)
+	}
+
+	return s.repo.MarkWebhookProcessed(webhook.ID)
+}
+
+func (s *PaymentService) handleChargeSuccess(event *models.PaystackWebhookEvent, webhookID uint) error {
+	transaction, err := s.repo.GetTransactionByReference(event.Data.Reference)
+	if err != nil {
+		s.logger.Errorf("Transaction not found for webhook: %s", event.Data.Reference)
+		return err
+	}
+
+	transaction.Status = models.StatusSuccess
+	transaction.PaystackRef = event.Data.Reference
+
+	if err := s.repo.UpdateTransaction(transaction); err != nil {
+		return err
+	}
+
+	s.logger.Infof("Webhook processed: charge.success for %s", event.Data.Reference)
+	return s.repo.MarkWebhookProcessed(webhookID)
+}
+
+func (s *PaymentService) handleChargeFailed(event *models.PaystackWebhookEvent, webhookID uint) error {
+	transaction, err := s.repo.GetTransactionByReference(event.Data.Reference)
+	if err != nil {
+		s.logger.Errorf("Transaction not found for webhook: %s", event.Data.Reference)
+		return err
+	}
+
+	transaction.Status = models.StatusFailed
+
+	if err := s.repo.UpdateTransaction(transaction); err != nil {
+		return err
+	}
+
+	// Failing here has Paystack send the event again, which retries the reversal
+	if err := s.reverseRedemption(transaction); err != nil {
+		return err
+	}
+
+	s.logger.Infof("Webhook processed: charge.failed for %s", event.Data.Reference)
+	return s.repo.MarkWebhookProcessed(webhookID)
+}
+
+// RefundPayment refunds a successful payment: Paystack refunds the card charge and the
+// loyalty points spent on it go back to the guest. Refunding an already refunded
+// payment retries giving the points back
+func (s *PaymentService) RefundPayment(id uint) (*models.Transaction, error) {
+	transaction, err := s.repo.GetTransactionByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch transaction.Status {
+	case models.StatusRefunded:
+	case models.StatusSuccess:
+		// A payment made entirely with points never reached Paystack
+		if transaction.Amount > transaction.LoyaltyAmount {
+			if _, err := s.paystackClient.RefundTransaction(transaction.Reference); err != nil {
+				return nil, fmt.Errorf("failed to refund with Paystack: %w", err)
+			}
+		}
+		transaction.Status = models.StatusRefunded
+		if err := s.repo.UpdateTransaction(transaction); err != nil {
+			return nil, fmt.Errorf("failed to update transaction: %w", err)
+		}
+	default:
+		return nil, ErrNotRefundable
+	}
+
+	if err := s.reverseRedemption(transaction); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Payment refunded: reference=%s, amount=%d", transaction.Reference, transaction.Amount)
+	return transaction, nil
+}
+
+// redeemPoints spends the user's points towards the transaction. The redemption is
+// keyed on the transaction's reference, so it can be reversed if the payment fails
+func (s *PaymentService) redeemPoints(transaction *models.Transaction, points int64) error {
+	if s.loyaltyClient == nil {
+		return ErrLoyaltyUnavailable
+	}
+	if transaction.UserId == "" {
+		return ErrLoyaltyNeedsUser
+	}
+	// An earlier payment's redemption would be returned, and reversed if this one failed
+	if _, err := s.repo.GetTransactionByReference(transaction.Reference); err == nil {
+		return ErrDuplicateReference
+	} else if err != gorm.ErrRecordNotFound {
+		return fmt.Errorf("failed to check reference: %w", err)
+	}
+
+	redemption, err := s.loyaltyClient.RedeemPoints(transaction.UserId, points, transaction.Reference)
+	if err != nil {
+		return fmt.Errorf("failed to redeem loyalty points: %w", err)
+	}
+	transaction.LoyaltyPoints = redemption.Points
+	// user-service values points in currency units, transactions are in kobo
+	transaction.LoyaltyAmount = int64(math.Round(redemption.Amount * 100))
+
+	if transaction.LoyaltyAmount > transaction.Amount {
+		s.restorePoints(transaction)
+		return ErrPointsExceedAmount
+	}
+	return nil
+}
+
+// reverseRedemption gives back the loyalty points spent on a transaction
+func (s *PaymentService) reverseRedemption(transaction *models.Transaction) error {
+	if transaction.LoyaltyPoints == 0 {
+		return nil
+	}
+	if s.loyaltyClient == nil {
+		return ErrLoyaltyUnavailable
+	}
+	if err := s.loyaltyClient.ReverseRedemption(transaction.UserId, transaction.Reference); err != nil {
+		return fmt.Errorf("failed to restore loyalty points for %s: %w", transaction.Reference, err)
+	}
+	s.logger.Infof("Loyalty points restored: reference=%s, points=%d", transaction.Reference, transaction.LoyaltyPoints)
+	return nil
+}
+
+// restorePoints reverses the redemption for a payment that could not be started.
+// Nothing is stored to retry from, so a failure is logged for someone to fix by hand
+func (s *PaymentService) restorePoints(transaction *models.Transaction) {
+	if err := s.reverseRedemption(transaction); err != nil {
+		s.logger.Errorf("Loyalty points were not restored, user=%s: %v", transaction.UserId, err)
+	}
+}
+
+func (s *PaymentService) mapPaystackStatus(status string) models.TransactionStatus {
+	switch status {
+	case "success":
+		return models.StatusSuccess
+	case "failed":
+		return models.StatusFailed
+	case "abandoned":
+		return models.StatusAbandoned
+	default:
+		return models.StatusPending
+	}
+}
\ No newline at end of file
@@ -2,7 +2,7 @@ package database
 
 import (
 	"fmt"
-	"payment-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/payment-service/internals/models"
 	"time"
 
 	"gorm.io/driver/sqlite"
@@ -0,0 +1,119 @@
+package loyalty
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
+)
+
+// ErrRefused is returned when user-service turns a redemption down, e.g. the guest
+// isn't a member or doesn't have the points
+var ErrRefused = errors.New("loyalty redemption refused")
+
+var errNotFound = errors.New("not found")
+
+// Redemption is what a guest's points were worth towards a payment. Amount is in
+// currency units, not kobo
+type Redemption struct {
+	Points  int64   `json:"points"`
+	Amount  float64 `json:"amount"`
+	Balance int64   `json:"balance"`
+}
+
+// Client spends and restores loyalty points through user-service's internal API
+type Client struct {
+	baseURL    string
+	serviceKey string
+	httpClient *http.Client
+}
+
+func NewClient(baseURL, serviceKey string) *Client {
+	return &Client{
+		baseURL:    baseURL,
+		serviceKey: serviceKey,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// RedeemPoints spends a guest's points towards the payment with the given reference.
+// Redeeming again for the same reference returns the first redemption
+func (c *Client) RedeemPoints(userId string, points int64, reference string) (*Redemption, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"userId":    userId,
+		"points":    points,
+		"reference": reference,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal redemption: %w", err)
+	}
+
+	var redemption Redemption
+	if err := c.do(http.MethodPost, "/api/v1/internal/loyalty/redemptions", payload, &redemption); err != nil {
+		return nil, err
+	}
+	return &redemption, nil
+}
+
+// ReverseRedemption gives back the points spent on a payment that failed or was
+// refunded. Reversing twice, or a payment that spent no points, does nothing
+func (c *Client) ReverseRedemption(userId, reference string) error {
+	path := "/api/v1/internal/loyalty/redemptions/" + url.PathEscape(reference) + "?userId=" + url.QueryEscape(userId)
+	err := c.do(http.MethodDelete, path, nil, nil)
+	if errors.Is(err, errNotFound) {
+		// user-service has no redemption for this payment, so there is nothing to give back
+		return nil
+	}
+	return err
+}
+
+func (c *Client) do(method, path string, payload []byte, result interface{}) error {
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Payment-Service/1.0")
+	auth.SetServiceKey(req, c.serviceKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach user service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read user service response: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("user service rejected the service key: status %d", resp.StatusCode)
+	case resp.StatusCode == http.StatusNotFound && method == http.MethodDelete:
+		return errNotFound
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		var refusal struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(body, &refusal)
+		return fmt.Errorf("%w: %s", ErrRefused, refusal.Error)
+	default:
+		return fmt.Errorf("user service returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(body, result); err != nil {
+			return fmt.Errorf("failed to decode user service response: %w", err)
+		}
+	}
+	return nil
+}
@@ -76,6 +76,26 @@ func (c *Client) VerifyTransaction(reference string) (*models.PaystackVerifyResp
 	return &response, nil
 }
 
+// RefundTransaction refunds a successful transaction in full. It isn't retried, a
+// retry after a refund that went through would be refused as a duplicate anyway
+func (c *Client) RefundTransaction(reference string) (*models.PaystackRefundResponse, error) {
+	payload := map[string]interface{}{
+		"transaction": reference,
+	}
+
+	var response models.PaystackRefundResponse
+	err := c.doRequestWithRetry("POST", "/refund", payload, &response, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if !response.Status {
+		return nil, fmt.Errorf("paystack error: %s", response.Message)
+	}
+
+	return &response, nil
+}
+
 // doRequestWithRetry performs an HTTP request with exponential backoff retry
 func (c *Client) doRequestWithRetry(method, path string, payload interface{}, result interface{}, maxRetries int) error {
 	var lastErr error
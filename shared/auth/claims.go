@@ -0,0 +1,61 @@
+// Package auth verifies user-service access tokens and service credentials
+// for every hotelsystem service and exposes the caller as typed Claims.
+//
+// Revocation is only enforced where a RevocationCheck is configured, which today is
+// user-service alone. Every other service accepts a revoked token until it expires, so
+// the access token TTL is the longest a logout or suspension can take to apply there.
+package auth
+
+import (
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// DefaultIssuer is the iss claim user-service puts on access tokens
+	DefaultIssuer = "user-service"
+
+	RoleCustomer     = "customer"
+	RoleAdmin        = "admin"
+	RoleFrontDesk    = "front_desk"
+	RoleHousekeeping = "housekeeping"
+	RoleManager      = "manager"
+	RoleAccountant   = "accountant"
+	RoleOwner        = "owner"
+	// RoleService is given to callers that authenticated with a service key
+	RoleService = "service"
+)
+
+// Claims are the access token claims issued by user-service
+type Claims struct {
+	UserId string `json:"userId"`
+	Email  string `json:"email,omitempty"`
+	// EmailVerified reflects the account when the token was issued
+	EmailVerified bool   `json:"emailVerified,omitempty"`
+	Role          string `json:"role"`
+	SessionId     string `json:"sid,omitempty"`
+	// Permissions are the role's permissions when the token was issued
+	Permissions []string `json:"perms,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`
+	// Service is set instead of a user when the caller used a service key
+	Service string `json:"-"`
+	jwt.RegisteredClaims
+}
+
+func (c *Claims) IsService() bool {
+	return c.Service != ""
+}
+
+func (c *Claims) HasRole(roles ...string) bool {
+	return slices.Contains(roles, c.Role)
+}
+
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+// HasPermission reports whether the user's role grants permission, directly or through PermAll
+func (c *Claims) HasPermission(permission string) bool {
+	return slices.Contains(c.Permissions, permission) || slices.Contains(c.Permissions, PermAll)
+}
@@ -0,0 +1,269 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServiceKeyHeader carries a service-to-service credential
+const ServiceKeyHeader = "X-API-Key"
+
+const claimsKey = "auth.claims"
+
+// Config is the auth configuration every service reads from the environment
+type Config struct {
+	// JWKSURL is where user-service publishes its token signing keys
+	JWKSURL string
+	Issuer  string
+	// ServiceKeys maps accepted service keys to the calling service's name
+	ServiceKeys map[string]string
+	// ServiceKey is this service's own key for calling other services
+	ServiceKey string
+}
+
+// LoadConfigFromEnv reads AUTH_JWKS_URL, AUTH_ISSUER, SERVICE_API_KEYS
+// ("name=key,name=key") and SERVICE_API_KEY
+func LoadConfigFromEnv() Config {
+	cfg := Config{
+		JWKSURL:     os.Getenv("AUTH_JWKS_URL"),
+		Issuer:      os.Getenv("AUTH_ISSUER"),
+		ServiceKeys: map[string]string{},
+		ServiceKey:  os.Getenv("SERVICE_API_KEY"),
+	}
+	if cfg.JWKSURL == "" {
+		cfg.JWKSURL = "http://localhost:8082/.well-known/jwks.json"
+	}
+	if cfg.Issuer == "" {
+		cfg.Issuer = DefaultIssuer
+	}
+	for _, pair := range strings.Split(os.Getenv("SERVICE_API_KEYS"), ",") {
+		name, key, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && name != "" && key != "" {
+			cfg.ServiceKeys[key] = name
+		}
+	}
+	return cfg
+}
+
+// RevocationCheck reports whether otherwise valid claims were revoked
+type RevocationCheck func(ctx context.Context, claims *Claims) (bool, error)
+
+type Middleware struct {
+	verifier    Verifier
+	serviceKeys map[[32]byte]string
+	revoked     RevocationCheck
+}
+
+type Option func(*Middleware)
+
+// WithServiceKeys accepts the given keys (key -> service name) as service credentials
+func WithServiceKeys(keys map[string]string) Option {
+	return func(m *Middleware) {
+		for key, name := range keys {
+			m.serviceKeys[sha256.Sum256([]byte(key))] = name
+		}
+	}
+}
+
+// WithRevocationCheck rejects tokens the check reports as revoked
+func WithRevocationCheck(check RevocationCheck) Option {
+	return func(m *Middleware) {
+		m.revoked = check
+	}
+}
+
+func New(verifier Verifier, opts ...Option) *Middleware {
+	m := &Middleware{verifier: verifier, serviceKeys: map[[32]byte]string{}}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// NewFromConfig verifies tokens against the configured JWKS and accepts the configured service keys
+// Without WithRevocationCheck revoked tokens pass until they expire
+func NewFromConfig(cfg Config, opts ...Option) *Middleware {
+	verifier := NewKeyVerifier(NewJWKSClient(cfg.JWKSURL), cfg.Issuer)
+	return New(verifier, append([]Option{WithServiceKeys(cfg.ServiceKeys)}, opts...)...)
+}
+
+// Authenticate requires a valid bearer token or service key
+func (m *Middleware) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, status, message := m.authenticate(c)
+		if claims == nil {
+			abort(c, status, message)
+			return
+		}
+		setClaims(c, claims)
+		c.Next()
+	}
+}
+
+// Optional sets the claims when valid credentials are sent but lets anonymous requests through
+func (m *Middleware) Optional() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims, _, _ := m.authenticate(c); claims != nil {
+			setClaims(c, claims)
+		}
+		c.Next()
+	}
+}
+
+func (m *Middleware) authenticate(c *gin.Context) (*Claims, int, string) {
+	if key := c.GetHeader(ServiceKeyHeader); key != "" {
+		if name, ok := m.lookupServiceKey(key); ok {
+			return &Claims{UserId: "service:" + name, Role: RoleService, Service: name}, 0, ""
+		}
+		return nil, http.StatusUnauthorized, "Invalid service key"
+	}
+
+	header := c.GetHeader("Authorization")
+	if header == "" {
+		return nil, http.StatusUnauthorized, "Authorization header is required"
+	}
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return nil, http.StatusUnauthorized, "Invalid Authorization header format"
+	}
+
+	claims, err := m.verifier.Verify(c.Request.Context(), token)
+	if err != nil {
+		return nil, http.StatusUnauthorized, "Invalid or expired token"
+	}
+	if m.revoked != nil {
+		revoked, err := m.revoked(c.Request.Context(), claims)
+		if err != nil {
+			log.Printf("failed to check token revocation: %v", err)
+			return nil, http.StatusServiceUnavailable, "Unable to verify token"
+		}
+		if revoked {
+			return nil, http.StatusUnauthorized, "Token has been revoked"
+		}
+	}
+	return claims, 0, ""
+}
+
+func (m *Middleware) lookupServiceKey(key string) (string, bool) {
+	sum := sha256.Sum256([]byte(key))
+	for known, name := range m.serviceKeys {
+		if subtle.ConstantTimeCompare(sum[:], known[:]) == 1 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// RequireRole allows callers with any of the roles. Run it after Authenticate
+func (m *Middleware) RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := ClaimsFrom(c)
+		if !ok {
+			abort(c, http.StatusUnauthorized, "user not authenticated")
+			return
+		}
+		if !claims.HasRole(roles...) {
+			abort(c, http.StatusForbidden, "insufficient permissions")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission allows callers holding every listed permission. Service callers are
+// trusted with all permissions
+func (m *Middleware) RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := ClaimsFrom(c)
+		if !ok {
+			abort(c, http.StatusUnauthorized, "user not authenticated")
+			return
+		}
+		if !claims.IsService() {
+			for _, permission := range permissions {
+				if !claims.HasPermission(permission) {
+					abort(c, http.StatusForbidden, "missing permission: "+permission)
+					return
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireScope allows callers holding every listed scope. Service callers are trusted with all scopes
+func (m *Middleware) RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := ClaimsFrom(c)
+		if !ok {
+			abort(c, http.StatusUnauthorized, "user not authenticated")
+			return
+		}
+		if !claims.IsService() {
+			for _, scope := range scopes {
+				if !claims.HasScope(scope) {
+					abort(c, http.StatusForbidden, "missing scope: "+scope)
+					return
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireVerifiedEmail blocks users whose email was not verified when their token was issued
+func (m *Middleware) RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := ClaimsFrom(c)
+		if !ok {
+			abort(c, http.StatusUnauthorized, "user not authenticated")
+			return
+		}
+		if !claims.IsService() && !claims.EmailVerified {
+			abort(c, http.StatusForbidden, "email address must be verified")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireService only allows service-to-service callers
+func (m *Middleware) RequireService() gin.HandlerFunc {
+	return m.RequireRole(RoleService)
+}
+
+// ClaimsFrom returns the caller set by Authenticate or Optional
+func ClaimsFrom(c *gin.Context) (*Claims, bool) {
+	value, exists := c.Get(claimsKey)
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(*Claims)
+	return claims, ok
+}
+
+// setClaims also sets the plain userId/userRole/userEmail keys handlers have always read
+func setClaims(c *gin.Context, claims *Claims) {
+	c.Set(claimsKey, claims)
+	c.Set("userId", claims.UserId)
+	c.Set("userRole", claims.Role)
+	c.Set("userEmail", claims.Email)
+}
+
+func abort(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, gin.H{"error": message})
+}
+
+// SetServiceKey adds this service's credential to an outgoing request
+func SetServiceKey(req *http.Request, key string) {
+	if key != "" {
+		req.Header.Set(ServiceKeyHeader, key)
+	}
+}
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestRouter serves GET /check behind Authenticate and the given guards
+func newTestRouter(m *Middleware, guards ...gin.HandlerFunc) *gin.Engine {
+	router := gin.New()
+	handlers := append([]gin.HandlerFunc{m.Authenticate()}, guards...)
+	handlers = append(handlers, func(c *gin.Context) {
+		claims, _ := ClaimsFrom(c)
+		c.JSON(http.StatusOK, gin.H{"userId": claims.UserId, "legacyUserId": c.GetString("userId")})
+	})
+	router.GET("/check", handlers...)
+	return router
+}
+
+func request(router *gin.Engine, header, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/check", nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthenticate(t *testing.T) {
+	key := newKey(t)
+	m := New(NewKeyVerifier(staticKeys{"current": key.Public().(ed25519.PublicKey)}, DefaultIssuer),
+		WithServiceKeys(map[string]string{"booking-key": "booking-service"}))
+	router := newTestRouter(m)
+	user := Claims{UserId: "user-1", Role: RoleCustomer}
+
+	w := request(router, "Authorization", "Bearer "+signToken(t, key, "current", time.Minute, user))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"userId":"user-1","legacyUserId":"user-1"}`, w.Body.String())
+
+	w = request(router, ServiceKeyHeader, "booking-key")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"userId":"service:booking-service","legacyUserId":"service:booking-service"}`, w.Body.String())
+
+	cases := []struct {
+		name, header, value string
+	}{
+		{"no credentials", "", ""},
+		{"expired token", "Authorization", "Bearer " + signToken(t, key, "current", -time.Minute, user)},
+		{"unknown kid", "Authorization", "Bearer " + signToken(t, key, "gone", time.Minute, user)},
+		{"bad signature", "Authorization", "Bearer " + signToken(t, newKey(t), "current", time.Minute, user)},
+		{"not bearer", "Authorization", "Basic dXNlcjpwYXNz"},
+		{"empty bearer", "Authorization", "Bearer "},
+		{"unknown service key", ServiceKeyHeader, "guessed-key"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, http.StatusUnauthorized, request(router, tc.header, tc.value).Code)
+		})
+	}
+}
+
+func TestOptionalLetsAnonymousCallersThrough(t *testing.T) {
+	m := New(NewKeyVerifier(staticKeys{}, DefaultIssuer))
+	router := gin.New()
+	router.GET("/check", m.Optional(), func(c *gin.Context) {
+		_, ok := ClaimsFrom(c)
+		c.JSON(http.StatusOK, gin.H{"authenticated": ok})
+	})
+
+	w := request(router, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"authenticated":false}`, w.Body.String())
+
+	w = request(router, "Authorization", "Bearer not-a-token")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"authenticated":false}`, w.Body.String())
+}
+
+func TestGuards(t *testing.T) {
+	key := newKey(t)
+	m := New(NewKeyVerifier(staticKeys{"current": key.Public().(ed25519.PublicKey)}, DefaultIssuer),
+		WithServiceKeys(map[string]string{"booking-key": "booking-service"}))
+	bearer := func(claims Claims) string {
+		return "Bearer " + signToken(t, key, "current", time.Minute, claims)
+	}
+
+	manager := bearer(Claims{UserId: "manager", Role: RoleManager, Permissions: []string{PermBookingsRead, PermReportsRead}})
+	owner := bearer(Claims{UserId: "owner", Role: RoleOwner, Permissions: []string{PermAll}})
+	customer := bearer(Claims{UserId: "customer", Role: RoleCustomer})
+	scoped := bearer(Claims{UserId: "app", Role: RoleCustomer, Scopes: []string{"bookings:read"}})
+
+	cases := []struct {
+		name   string
+		guard  gin.HandlerFunc
+		header string
+		value  string
+		status int
+	}{
+		{"permission held", m.RequirePermission(PermBookingsRead, PermReportsRead), "Authorization", manager, http.StatusOK},
+		{"one permission missing", m.RequirePermission(PermBookingsRead, PermRatesManage), "Authorization", manager, http.StatusForbidden},
+		{"wildcard permission", m.RequirePermission(PermRolesManage), "Authorization", owner, http.StatusOK},
+		{"no permissions", m.RequirePermission(PermBookingsRead), "Authorization", customer, http.StatusForbidden},
+		{"service has every permission", m.RequirePermission(PermRolesManage), ServiceKeyHeader, "booking-key", http.StatusOK},
+		{"scope held", m.RequireScope("bookings:read"), "Authorization", scoped, http.StatusOK},
+		{"scope missing", m.RequireScope("bookings:write"), "Authorization", scoped, http.StatusForbidden},
+		{"permissions are not scopes", m.RequireScope("bookings:read"), "Authorization", manager, http.StatusForbidden},
+		{"service has every scope", m.RequireScope("bookings:write"), ServiceKeyHeader, "booking-key", http.StatusOK},
+		{"service route with service key", m.RequireService(), ServiceKeyHeader, "booking-key", http.StatusOK},
+		{"service route with owner token", m.RequireService(), "Authorization", owner, http.StatusForbidden},
+		{"role allowed", m.RequireRole(RoleManager, RoleOwner), "Authorization", manager, http.StatusOK},
+		{"role refused", m.RequireRole(RoleManager, RoleOwner), "Authorization", customer, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.status, request(newTestRouter(m, tc.guard), tc.header, tc.value).Code)
+		})
+	}
+}
+
+func TestGuardsWithoutAuthenticate(t *testing.T) {
+	m := New(NewKeyVerifier(staticKeys{}, DefaultIssuer))
+	for name, guard := range map[string]gin.HandlerFunc{
+		"permission": m.RequirePermission(PermBookingsRead),
+		"scope":      m.RequireScope("bookings:read"),
+		"service":    m.RequireService(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/check", guard, func(c *gin.Context) { c.Status(http.StatusOK) })
+			assert.Equal(t, http.StatusUnauthorized, request(router, "", "").Code)
+		})
+	}
+}
+
+func TestWithRevocationCheck(t *testing.T) {
+	key := newKey(t)
+	keys := staticKeys{"current": key.Public().(ed25519.PublicKey)}
+	token := "Bearer " + signToken(t, key, "current", time.Minute, Claims{UserId: "user-1", SessionId: "session-1"})
+
+	cases := []struct {
+		name   string
+		check  RevocationCheck
+		status int
+	}{
+		{"not revoked", func(ctx context.Context, claims *Claims) (bool, error) { return false, nil }, http.StatusOK},
+		{"revoked", func(ctx context.Context, claims *Claims) (bool, error) {
+			return claims.SessionId == "session-1", nil
+		}, http.StatusUnauthorized},
+		{"store down", func(ctx context.Context, claims *Claims) (bool, error) {
+			return false, errors.New("connection refused")
+		}, http.StatusServiceUnavailable},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := New(NewKeyVerifier(keys, DefaultIssuer), WithRevocationCheck(tc.check))
+			assert.Equal(t, tc.status, request(newTestRouter(m), "Authorization", token).Code)
+		})
+	}
+
+	// service keys aren't tokens and skip the check
+	m := New(NewKeyVerifier(keys, DefaultIssuer), WithServiceKeys(map[string]string{"booking-key": "booking-service"}),
+		WithRevocationCheck(func(ctx context.Context, claims *Claims) (bool, error) { return true, nil }))
+	assert.Equal(t, http.StatusOK, request(newTestRouter(m), ServiceKeyHeader, "booking-key").Code)
+}
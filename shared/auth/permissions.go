@@ -0,0 +1,26 @@
+package auth
+
+// Permissions granted to roles by user-service and carried in access tokens.
+// Services check them with RequirePermission rather than checking roles
+const (
+	PermAll = "*"
+
+	PermBookingsRead       = "bookings:read"
+	PermBookingsWrite      = "bookings:write"
+	PermRoomsRead          = "rooms:read"
+	PermRoomsManage        = "rooms:manage"
+	PermRatesManage        = "rates:manage"
+	PermHousekeepingUpdate = "housekeeping:update"
+	PermChannelsManage     = "channels:manage"
+	PermReportsRead        = "reports:read"
+	PermReportsManage      = "reports:manage"
+	PermPaymentsRead       = "payments:read"
+	PermPaymentsRefund     = "payments:refund"
+	PermNotificationsSend  = "notifications:send"
+	PermGuestsRead         = "guests:read"
+	PermGuestsManage       = "guests:manage"
+	PermUsersRead          = "users:read"
+	PermUsersManage        = "users:manage"
+	PermRolesManage        = "roles:manage"
+	PermAuditRead          = "audit:read"
+)
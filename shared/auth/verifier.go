@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Verifier checks an access token and returns its claims
+type Verifier interface {
+	Verify(ctx context.Context, token string) (*Claims, error)
+}
+
+// KeySource resolves the public key for a token's kid header
+type KeySource interface {
+	PublicKey(kid string) (ed25519.PublicKey, bool)
+}
+
+// KeyVerifier verifies EdDSA tokens against a KeySource
+type KeyVerifier struct {
+	keys   KeySource
+	issuer string
+}
+
+func NewKeyVerifier(keys KeySource, issuer string) *KeyVerifier {
+	return &KeyVerifier{keys: keys, issuer: issuer}
+}
+
+func (v *KeyVerifier) Verify(ctx context.Context, tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := v.keys.PublicKey(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key: %q", kid)
+		}
+		return key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}), jwt.WithIssuer(v.issuer), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid || claims.UserId == "" {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// JWKSClient is a KeySource backed by user-service's /.well-known/jwks.json.
+// Keys are cached for cacheFor, and an unknown kid triggers an early refresh
+// (at most once every minRefresh) so freshly rotated keys are picked up
+type JWKSClient struct {
+	url        string
+	httpClient *http.Client
+	cacheFor   time.Duration
+	minRefresh time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]ed25519.PublicKey
+	fetchedAt time.Time
+}
+
+func NewJWKSClient(url string) *JWKSClient {
+	return &JWKSClient{
+		url:        url,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		cacheFor:   5 * time.Minute,
+		minRefresh: 30 * time.Second,
+		keys:       map[string]ed25519.PublicKey{},
+	}
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+}
+
+func (j *JWKSClient) PublicKey(kid string) (ed25519.PublicKey, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	key, ok := j.keys[kid]
+	stale := time.Since(j.fetchedAt) > j.cacheFor
+	if (!ok || stale) && time.Since(j.fetchedAt) > j.minRefresh {
+		if err := j.refresh(); err == nil {
+			key, ok = j.keys[kid]
+		}
+	}
+	return key, ok
+}
+
+// refresh must be called with mu held
+func (j *JWKSClient) refresh() error {
+	j.fetchedAt = time.Now()
+
+	resp, err := j.httpClient.Get(j.url)
+	if err != nil {
+		return fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwks endpoint returned %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode jwks: %w", err)
+	}
+
+	keys := map[string]ed25519.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "OKP" || k.Crv != "Ed25519" {
+			continue
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			continue
+		}
+		keys[k.Kid] = ed25519.PublicKey(x)
+	}
+	j.keys = keys
+	return nil
+}
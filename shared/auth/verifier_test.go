@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticKeys map[string]ed25519.PublicKey
+
+func (s staticKeys) PublicKey(kid string) (ed25519.PublicKey, bool) {
+	key, ok := s[kid]
+	return key, ok
+}
+
+func newKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return private
+}
+
+// signToken issues a token the way user-service does, valid for ttl (negative for expired)
+func signToken(t *testing.T, key ed25519.PrivateKey, kid string, ttl time.Duration, claims Claims) string {
+	t.Helper()
+	claims.Issuer = DefaultIssuer
+	claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(ttl))
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, &claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestKeyVerifier(t *testing.T) {
+	key := newKey(t)
+	verifier := NewKeyVerifier(staticKeys{"current": key.Public().(ed25519.PublicKey)}, DefaultIssuer)
+	user := Claims{UserId: "user-1", Role: RoleManager}
+
+	claims, err := verifier.Verify(context.Background(), signToken(t, key, "current", time.Minute, user))
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.UserId)
+	assert.Equal(t, RoleManager, claims.Role)
+
+	hmac, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserId: "user-1", RegisteredClaims: jwt.RegisteredClaims{
+		Issuer: DefaultIssuer, ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}}).SignedString([]byte("guessed"))
+	require.NoError(t, err)
+
+	rejected := map[string]string{
+		"expired":          signToken(t, key, "current", -time.Minute, user),
+		"unknown kid":      signToken(t, key, "retired", time.Minute, user),
+		"wrong key":        signToken(t, newKey(t), "current", time.Minute, user),
+		"no user":          signToken(t, key, "current", time.Minute, Claims{Role: RoleManager}),
+		"not eddsa":        hmac,
+		"malformed":        "not-a-token",
+		"tampered payload": signToken(t, key, "current", time.Minute, user) + "x",
+	}
+	for name, token := range rejected {
+		t.Run(name, func(t *testing.T) {
+			_, err := verifier.Verify(context.Background(), token)
+			assert.Error(t, err)
+		})
+	}
+
+	other := NewKeyVerifier(staticKeys{"current": key.Public().(ed25519.PublicKey)}, "someone-else")
+	_, err = other.Verify(context.Background(), signToken(t, key, "current", time.Minute, user))
+	assert.Error(t, err, "wrong issuer")
+}
+
+// jwksServer publishes keys and counts how often it is asked for them
+type jwksServer struct {
+	mu      sync.Mutex
+	keys    map[string]ed25519.PublicKey
+	fetches int
+}
+
+func (s *jwksServer) publish(kid string, key ed25519.PrivateKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[kid] = key.Public().(ed25519.PublicKey)
+}
+
+func (s *jwksServer) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetches++
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	for kid, key := range s.keys {
+		set.Keys = append(set.Keys, jwk{Kty: "OKP", Crv: "Ed25519", Kid: kid, X: base64.RawURLEncoding.EncodeToString(key)})
+	}
+	json.NewEncoder(w).Encode(set)
+}
+
+func TestJWKSClientRefreshesAtMostOncePerInterval(t *testing.T) {
+	server := &jwksServer{keys: map[string]ed25519.PublicKey{}}
+	server.publish("first", newKey(t))
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	client := NewJWKSClient(httpServer.URL)
+	_, ok := client.PublicKey("first")
+	assert.True(t, ok)
+	assert.Equal(t, 1, server.fetchCount())
+
+	// unknown kids don't refetch until minRefresh has passed, so garbage tokens can't
+	// hammer user-service
+	server.publish("second", newKey(t))
+	for i := 0; i < 5; i++ {
+		_, ok = client.PublicKey("second")
+		assert.False(t, ok)
+	}
+	assert.Equal(t, 1, server.fetchCount())
+
+	client.mu.Lock()
+	client.fetchedAt = time.Now().Add(-client.minRefresh - time.Second)
+	client.mu.Unlock()
+	_, ok = client.PublicKey("second")
+	assert.True(t, ok, "rotated key is picked up once the throttle allows")
+	assert.Equal(t, 2, server.fetchCount())
+
+	// known kids are served from the cache until it goes stale
+	_, ok = client.PublicKey("first")
+	assert.True(t, ok)
+	assert.Equal(t, 2, server.fetchCount())
+
+	client.mu.Lock()
+	client.fetchedAt = time.Now().Add(-client.cacheFor - time.Second)
+	client.mu.Unlock()
+	_, ok = client.PublicKey("first")
+	assert.True(t, ok)
+	assert.Equal(t, 3, server.fetchCount())
+}
+
+func TestJWKSClientKeepsKeysWhenRefreshFails(t *testing.T) {
+	server := &jwksServer{keys: map[string]ed25519.PublicKey{}}
+	server.publish("first", newKey(t))
+	httpServer := httptest.NewServer(server)
+
+	client := NewJWKSClient(httpServer.URL)
+	_, ok := client.PublicKey("first")
+	require.True(t, ok)
+
+	httpServer.Close()
+	client.mu.Lock()
+	client.fetchedAt = time.Now().Add(-client.cacheFor - time.Second)
+	client.mu.Unlock()
+	_, ok = client.PublicKey("first")
+	assert.True(t, ok, "stale keys keep verifying while user-service is unreachable")
+}
@@ -1,21 +1,31 @@
 	package main
 
 import (
+	"context"
 	"log"
+	"time"
 
 	"github.com/ollatomiwa/hotelsystem/user-service/internal/handlers"
 	"github.com/ollatomiwa/hotelsystem/user-service/pkg/config"
 	"github.com/ollatomiwa/hotelsystem/user-service/pkg/database"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/internalapi"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/middleware"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/notifications"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/oidc"
 	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
 
 	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
 	"github.com/ollatomiwa/hotelsystem/user-service/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
 )
 
 func main() {
 	cfg :=config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("invalid configuration: ", err)
+	}
 
 	if cfg.Server.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -40,19 +50,220 @@ func main() {
 		log.Fatal("failed to initialize database schema:",err)
 	}
 
+	signingKeyBox, err := security.NewSecretBox(cfg.Security.SigningKeyEncryptionKey)
+	if err != nil {
+		log.Fatal("failed to set up signing key encryption:", err)
+	}
+	//retired keys stay trusted a little past the longest access token they could have signed
+	keyRing := security.NewKeyRing(postgres.NewSigningKeyRepository(db, signingKeyBox), cfg.Security.KeyRotationInterval,
+		cfg.Security.AccessTokenDuration+time.Hour)
+	if err := keyRing.Load(context.Background()); err != nil {
+		log.Fatal("failed to load signing keys:", err)
+	}
+	go keyRing.RunRotation(context.Background(), 10*time.Minute)
+
 	jwtManager := security.NewJWTManager(
-		cfg.Security.JWTSecretKey,
+		keyRing,
 		cfg.Security.JWTRefreshKey,
 		cfg.Security.AccessTokenDuration,
 		cfg.Security.RefreshToken,	
 	)
 
+	//revoked tokens and sessions are shared through redis when it is configured. Without
+	//it other instances would keep accepting revoked tokens, so an unreachable redis stops startup
+	var revocations security.RevocationStore = security.NewMemoryRevocationStore()
+	if cfg.Redis.Enabled {
+		redisStore := security.NewRedisRevocationStore(cfg.Redis.Host+":"+cfg.Redis.Port, cfg.Redis.Password, cfg.Redis.DB,
+			cfg.Redis.PoolSize)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := redisStore.Ping(ctx)
+		cancel()
+		if err != nil {
+			log.Fatal("failed to connect to redis:", err)
+		}
+		defer redisStore.Close()
+		revocations = redisStore
+	}
+
 	userRepo := postgres.NewUserRepository(db)
-	authService := services.NewAuthService(userRepo, jwtManager,cfg.Security.BCryptCost)
+	refreshTokenRepo := postgres.NewRefreshTokenRepository(db)
+	sessionRepo := postgres.NewSessionRepository(db)
+	//new passwords use the configured algorithm, existing hashes of the other one keep working
+	bcryptScheme := security.BcryptScheme{Cost: cfg.Security.BCryptCost}
+	argon2Scheme := security.Argon2idScheme{
+		Memory: uint32(cfg.Security.Argon2Memory),
+		Time: uint32(cfg.Security.Argon2Time),
+		Threads: uint8(cfg.Security.Argon2Threads),
+		SaltLen: 16,
+		KeyLen: 32,
+	}
+	var passwordHasher *security.PasswordHasher
+	switch cfg.Security.PasswordHashAlgorithm {
+	case "argon2id":
+		passwordHasher = security.NewPasswordHasher(argon2Scheme, bcryptScheme)
+	case "bcrypt":
+		passwordHasher = security.NewPasswordHasher(bcryptScheme, argon2Scheme)
+		if cfg.PasswordPolicy.MaxLength > 72 {
+			cfg.PasswordPolicy.MaxLength = 72
+		}
+	default:
+		log.Fatalf("unknown PASSWORD_HASH_ALGORITHM %q, use argon2id or bcrypt", cfg.Security.PasswordHashAlgorithm)
+	}
+	authService := services.NewAuthService(userRepo, refreshTokenRepo, sessionRepo, revocations, jwtManager, passwordHasher)
+
+	//the breached password list is optional, without it only the other rules apply
+	var breachedPasswords *security.BreachedPasswords
+	if cfg.PasswordPolicy.BreachedListFile != "" {
+		breachedPasswords, err = security.OpenBreachedPasswords(cfg.PasswordPolicy.BreachedListFile)
+		if err != nil {
+			log.Fatal("failed to load breached password list:", err)
+		}
+		defer breachedPasswords.Close()
+	}
+	authService.SetPasswordPolicy(security.NewPasswordPolicy(security.PasswordPolicyConfig{
+		MinLength: cfg.PasswordPolicy.MinLength,
+		MaxLength: cfg.PasswordPolicy.MaxLength,
+		RequireUpper: cfg.PasswordPolicy.RequireUpper,
+		RequireLower: cfg.PasswordPolicy.RequireLower,
+		RequireDigit: cfg.PasswordPolicy.RequireDigit,
+		RequireSymbol: cfg.PasswordPolicy.RequireSymbol,
+	}, breachedPasswords), cfg.PasswordPolicy.History)
+
+	//without notification-service the verification links are written to the log
+	var mailer *notifications.Client
+	if cfg.Notifications.Enabled {
+		mailer = notifications.NewClient(cfg.Notifications.BaseURL, cfg.Notifications.ServiceKey)
+	}
+	//verification and reset tokens share the table and signer, their purpose keeps them apart
+	oneTimeTokenRepo := postgres.NewOneTimeTokenRepository(db)
+	tokenSigner := security.NewTokenSigner(cfg.Verification.TokenSecret)
+	verificationService := services.NewVerificationService(userRepo, oneTimeTokenRepo, tokenSigner, mailer,
+		services.VerificationConfig{
+			TokenTTL: cfg.Verification.TokenTTL,
+			ResendInterval: cfg.Verification.ResendInterval,
+			VerifyURL: cfg.Verification.VerifyURL,
+		})
+	authService.SetVerificationService(verificationService)
+	//a few workers send reset emails, a flood of requests fills the queue instead of spawning goroutines
+	emailQueue := services.NewEmailQueue(4, 256)
+	passwordResetService := services.NewPasswordResetService(userRepo, oneTimeTokenRepo, tokenSigner, mailer, authService,
+		emailQueue, services.PasswordResetConfig{
+			TokenTTL: cfg.PasswordReset.TokenTTL,
+			ResendInterval: cfg.PasswordReset.ResendInterval,
+			ResetURL: cfg.PasswordReset.ResetURL,
+		})
+
+	secretBox, err := security.NewSecretBox(cfg.MFA.EncryptionKey)
+	if err != nil {
+		log.Fatal("failed to set up mfa encryption:", err)
+	}
+	mfaService := services.NewMFAService(postgres.NewMFARepository(db), userRepo, authService, secretBox, tokenSigner,
+		services.MFAConfig{
+			Issuer: cfg.MFA.Issuer,
+			RequiredRoles: cfg.MFA.RequiredRoles,
+			TokenTTL: cfg.MFA.TokenTTL,
+			MaxAttempts: cfg.MFA.MaxAttempts,
+			RecoveryCodes: cfg.MFA.RecoveryCodes,
+		})
+	authService.SetMFAService(mfaService)
+
+	auditService := services.NewAuditService(postgres.NewAuditRepository(db))
+	loginProtection := services.NewLoginProtection(userRepo, auditService, services.LoginProtectionConfig{
+		FailureWindow: cfg.LoginProtection.FailureWindow,
+		DelayAfter: cfg.LoginProtection.DelayAfter,
+		BaseDelay: cfg.LoginProtection.BaseDelay,
+		MaxDelay: cfg.LoginProtection.MaxDelay,
+		LockAfter: cfg.LoginProtection.LockAfter,
+		LockoutDuration: cfg.LoginProtection.LockoutDuration,
+		IPMaxFailures: cfg.LoginProtection.IPMaxFailures,
+	})
+	authService.SetLoginProtection(loginProtection)
+	authService.SetAuditService(auditService)
+	rbacService := services.NewRBACService(postgres.NewRoleRepository(db), userRepo, authService, auditService,
+		cfg.RBAC.PermissionCacheTTL)
+	authService.SetRBACService(rbacService)
+	userAdminService := services.NewUserAdminService(userRepo, authService, passwordResetService, rbacService, auditService)
+	emailChangeService := services.NewEmailChangeService(userRepo, oneTimeTokenRepo, tokenSigner, mailer, authService,
+		auditService, services.EmailChangeConfig{
+			TokenTTL: cfg.EmailChange.TokenTTL,
+			ConfirmURL: cfg.EmailChange.ConfirmURL,
+		})
+
+	//exports and deletion reach every service we have an address for
+	var privacyClients services.PrivacyClients
+	if cfg.Privacy.BookingServiceURL != "" {
+		privacyClients.Bookings = internalapi.NewClient("booking-service", cfg.Privacy.BookingServiceURL, cfg.Notifications.ServiceKey)
+	}
+	if cfg.Privacy.PaymentServiceURL != "" {
+		privacyClients.Payments = internalapi.NewClient("payment-service", cfg.Privacy.PaymentServiceURL, cfg.Notifications.ServiceKey)
+	}
+	if cfg.Notifications.Enabled {
+		privacyClients.Notifications = internalapi.NewClient("notification-service", cfg.Notifications.BaseURL, cfg.Notifications.ServiceKey)
+	}
+	identityRepo := postgres.NewIdentityRepository(db)
+	guestProfileRepo := postgres.NewGuestProfileRepository(db)
+	guestProfileService := services.NewGuestProfileService(guestProfileRepo, userRepo, auditService)
+	loyaltyService := services.NewLoyaltyService(postgres.NewLoyaltyRepository(db), guestProfileRepo, auditService,
+		services.LoyaltyConfig{
+			PointsPerUnit: cfg.Loyalty.PointsPerUnit,
+			PointsPerNight: cfg.Loyalty.PointsPerNight,
+			PointsPerRedemptionUnit: cfg.Loyalty.PointsPerRedemptionUnit,
+			MinRedemption: cfg.Loyalty.MinRedemption,
+			VoucherTTL: cfg.Loyalty.VoucherTTL,
+		})
+	privacyService := services.NewPrivacyService(userRepo, identityRepo, guestProfileRepo, loyaltyService, authService,
+		auditService, privacyClients)
+
+	var oidcProviders []*oidc.Provider
+	for _, p := range cfg.OIDC.Providers {
+		if p.Issuer == "" || p.ClientID == "" {
+			log.Printf("oidc provider %s has no issuer or client id, skipping it", p.Name)
+			continue
+		}
+		oidcProviders = append(oidcProviders, oidc.NewProvider(oidc.Config{
+			Name: p.Name,
+			Issuer: p.Issuer,
+			ClientID: p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL: p.RedirectURL,
+			Scopes: p.Scopes,
+		}))
+	}
+	oidcService := services.NewOIDCService(identityRepo, userRepo, authService, auditService,
+		cfg.OIDC.StateTTL, oidcProviders...)
+
+	//tokens are verified with the shared auth package, like every other service does
+	authCfg := auth.LoadConfigFromEnv()
+	authn := auth.New(
+		auth.NewKeyVerifier(keyRing, auth.DefaultIssuer),
+		auth.WithServiceKeys(authCfg.ServiceKeys),
+		auth.WithRevocationCheck(security.RevocationCheck(revocations)),
+	)
+
+	//browser clients can keep the refresh token in an HttpOnly cookie instead
+	var sessionCookies *handlers.SessionCookies
+	if cfg.SessionCookies.Enabled {
+		sessionCookies = handlers.NewSessionCookies(handlers.SessionCookieConfig{
+			RefreshCookie: cfg.SessionCookies.RefreshCookieName,
+			CSRFCookie: cfg.SessionCookies.CSRFCookieName,
+			Domain: cfg.SessionCookies.Domain,
+			Path: cfg.SessionCookies.Path,
+			Secure: cfg.SessionCookies.Secure,
+			SameSite: handlers.ParseSameSite(cfg.SessionCookies.SameSite),
+			MaxAge: cfg.Security.RefreshToken,
+		}, security.NewCSRFTokens(cfg.Security.CSRFSecret))
+	}
 
 	router := gin.Default()
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatal("invalid TRUSTED_PROXIES:", err)
+	}
 
-	handlers.SetupRoutes(router, authService, jwtManager)
+	handlers.SetupRoutes(router, authService, verificationService, passwordResetService, emailChangeService, mfaService,
+		loginProtection, auditService, rbacService, userAdminService, guestProfileService, loyaltyService,
+		privacyService, oidcService, authn, keyRing,
+		middleware.RateLimit(cfg.Security.RateLimitRequests, cfg.Security.RateLimitWindow),
+		middleware.CORS(cfg.Security.CORSAllowedOrigins), sessionCookies)
 
 	log.Printf("user service starting on port %s", cfg.Server.Port)
 	log.Printf("Environment: %s", cfg.Server.Env)
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/services"
+)
+
+type AdminHandler struct {
+	loginProtection *services.LoginProtection
+	auditService    *services.AuditService
+	rbacService     *services.RBACService
+	userAdmin       *services.UserAdminService
+}
+
+func NewAdminHandler(loginProtection *services.LoginProtection, auditService *services.AuditService,
+	rbacService *services.RBACService, userAdmin *services.UserAdminService) *AdminHandler {
+	return &AdminHandler{
+		loginProtection: loginProtection,
+		auditService:    auditService,
+		rbacService:     rbacService,
+		userAdmin:       userAdmin,
+	}
+}
+
+// ListUsers searches users by name, email or phone, optionally filtered by role and
+// status (active, suspended, locked)
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	response, err := h.userAdmin.ListUsers(c.Request.Context(), c.Query("q"), c.Query("role"), c.Query("status"), page, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to list users: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	user, err := h.userAdmin.GetUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AdminHandler) SuspendUser(c *gin.Context) {
+	var req models.SuspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	user, err := h.userAdmin.SuspendUser(c.Request.Context(), actorId(c), c.Param("id"), req.Reason, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, services.ErrOutranked) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to suspend user: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AdminHandler) ReactivateUser(c *gin.Context) {
+	user, err := h.userAdmin.ReactivateUser(c.Request.Context(), actorId(c), c.Param("id"), c.ClientIP())
+	if err != nil {
+		if errors.Is(err, services.ErrOutranked) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to reactivate user: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AdminHandler) ForcePasswordReset(c *gin.Context) {
+	if err := h.userAdmin.ForcePasswordReset(c.Request.Context(), actorId(c), c.Param("id"), c.ClientIP()); err != nil {
+		if errors.Is(err, services.ErrOutranked) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to force password reset: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "password reset required, a reset link was sent to the user"})
+}
+
+// UnlockUser clears a user's failed logins and lockout
+func (h *AdminHandler) UnlockUser(c *gin.Context) {
+	if err := h.loginProtection.Unlock(c.Request.Context(), actorId(c), c.Param("id"), c.ClientIP()); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "failed to unlock user: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "user unlocked"})
+}
+
+// ListAuditEvents searches the audit log, newest first. It filters on userId, actorId,
+// event, outcome and ip, and from and to take RFC 3339 times
+func (h *AdminHandler) ListAuditEvents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	filter := models.AuditFilter{
+		UserId:    c.Query("userId"),
+		ActorId:   c.Query("actorId"),
+		Event:     c.Query("event"),
+		Outcome:   c.Query("outcome"),
+		IPAddress: c.Query("ip"),
+	}
+	for param, bound := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param + ", use an RFC 3339 time"})
+				return
+			}
+			*bound = parsed
+		}
+	}
+
+	events, err := h.auditService.ListEvents(c.Request.Context(), filter, page, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to list audit events: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// VerifyAuditLog checks the audit log's hash chain for altered or removed events
+func (h *AdminHandler) VerifyAuditLog(c *gin.Context) {
+	report, err := h.auditService.VerifyChain(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify audit log: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *AdminHandler) ListRoles(c *gin.Context) {
+	roles, err := h.rbacService.ListRoles(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list roles: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+func (h *AdminHandler) ListPermissions(c *gin.Context) {
+	permissions, err := h.rbacService.ListPermissions(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list permissions: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, permissions)
+}
+
+func (h *AdminHandler) SetRolePermissions(c *gin.Context) {
+	var req models.SetRolePermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	err := h.rbacService.SetRolePermissions(c.Request.Context(), actorId(c), c.Param("name"), req.Permissions, c.ClientIP())
+	if errors.Is(err, services.ErrBuiltInRole) || errors.Is(err, services.ErrOutranked) || errors.Is(err, services.ErrEscalation) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to update role: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "role permissions updated"})
+}
+
+// AssignRole changes a user's role, which signs them out everywhere
+func (h *AdminHandler) AssignRole(c *gin.Context) {
+	var req models.AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	user, err := h.rbacService.AssignRole(c.Request.Context(), actorId(c), c.Param("id"), req.Role, c.ClientIP())
+	if errors.Is(err, services.ErrOutranked) || errors.Is(err, services.ErrEscalation) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to assign role: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// actorId is the admin making the request
+func actorId(c *gin.Context) string {
+	userId, _ := c.Get("userId")
+	id, _ := userId.(string)
+	return id
+}
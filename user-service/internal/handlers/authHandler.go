@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
@@ -10,11 +13,14 @@ import (
 
 type AuthHandler struct {
 	authService *services.AuthService
+	cookies *SessionCookies
 }
 
-func NewAuthHandler(authService *services.AuthService) *AuthHandler {
+// cookies is nil unless cookie mode is on
+func NewAuthHandler(authService *services.AuthService, cookies *SessionCookies) *AuthHandler {
 	return &AuthHandler{
 		authService: authService,
+		cookies: cookies,
 	}
 }
 
@@ -26,6 +32,10 @@ func (h *AuthHandler) Register(c *gin.Context){
 		return 
 	}
 	user, err := h.authService.Register(c.Request.Context(), &req)
+	if errors.Is(err, services.ErrWeakPassword) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "user with this email already exists" + err.Error()})
 		return 
@@ -41,22 +51,33 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return 
 	}	
 
-	response, err := h.authService.Login(c.Request.Context(), &req)
+	response, err := h.authService.Login(c.Request.Context(), &req, clientInfo(c))
+	if respondThrottled(c, err) {
+		return
+	}
+	if errors.Is(err, services.ErrAccountSuspended) || errors.Is(err, services.ErrPasswordResetRequired) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication failed: " + err.Error()})
 		return 
 	}
+	if err := h.cookies.issue(c, response); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start session: " + err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, response)
 }
 
 func (h *AuthHandler) GetProfile( c *gin.Context) {
-	userEmail, exists := c.Get("userEmail")
+	userId, exists := c.Get("userId")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticate"})
 		return 
 	}
 
-	user, err := h.authService.GetUserProfile(c.Request.Context(), userEmail.(string))
+	user, err := h.authService.GetUserProfile(c.Request.Context(), userId.(string))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found" + err.Error()})
 		return 
@@ -66,7 +87,7 @@ func (h *AuthHandler) GetProfile( c *gin.Context) {
 }
 
 func (h *AuthHandler) UpdateProfile(c *gin.Context) {
-	userEmail, exists := c.Get("userEmail")
+	userId, exists := c.Get("userId")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
 		return 
@@ -78,7 +99,7 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 		return 
 	}
 
-	user, err := h.authService.UpdateUserProfile(c.Request.Context(), userEmail.(string), &req)
+	user, err := h.authService.UpdateUserProfile(c.Request.Context(), userId.(string), &req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "update failed" + err.Error()})
 		return 
@@ -87,7 +108,7 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 }
 
 func (h *AuthHandler) ChangePassword(c *gin.Context) {
-	userEmail, exists := c.Get("userEmail")
+	userId, exists := c.Get("userId")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
 		return 
@@ -99,7 +120,12 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return 
 	}
 
-	if err := h.authService.ChangePassword(c.Request.Context(), userEmail.(string), &req); err != nil {
+	err := h.authService.ChangePassword(c.Request.Context(), userId.(string), &req, clientInfo(c))
+	if errors.Is(err, services.ErrWeakPassword) || errors.Is(err, services.ErrPasswordReused) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "password change failed" + err.Error()})
 		return 
 	}
@@ -107,17 +133,97 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 }
 
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	var req models.RefreshTokenRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request" + err.Error()})
-		return 
+	refreshToken, ok := h.bindRefreshToken(c)
+	if !ok {
+		return
 	}
 
-	response, err := h.authService.RefreshToken(c.Request.Context(), req.RefreshToken)
+	response, err := h.authService.RefreshToken(c.Request.Context(), refreshToken, clientInfo(c))
 	if err != nil {
+		h.cookies.clear(c)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh failed: " + err.Error()})
 		return 
 	}
+	if err := h.cookies.issue(c, response); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh session: " + err.Error()})
+		return
+	}
 	
 	c.JSON(http.StatusOK, response)
-}
\ No newline at end of file
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	refreshToken, ok := h.bindRefreshToken(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.Logout(c.Request.Context(), refreshToken); err != nil {
+		h.cookies.clear(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "logout failed: " + err.Error()})
+		return 
+	}
+	h.cookies.clear(c)
+	c.JSON(http.StatusOK, gin.H{"message":"logged out successfully"})
+}
+
+// bindRefreshToken reads the refresh token from the body, or in cookie mode from the
+// cookie when the body has none. It writes the error response itself
+func (h *AuthHandler) bindRefreshToken(c *gin.Context) (string, bool) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request" + err.Error()})
+		return "", false
+	}
+	refreshToken := h.cookies.refreshToken(c, req.RefreshToken)
+	if refreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh token is required"})
+		return "", false
+	}
+	return refreshToken, true
+}
+
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return 
+	}
+
+	if err := h.authService.LogoutAll(c.Request.Context(), userId.(string)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "logout failed: " + err.Error()})
+		return 
+	}
+	h.cookies.clear(c)
+	c.JSON(http.StatusOK, gin.H{"message":"logged out of all sessions"})
+}
+
+// CSRFToken hands a cookie mode client a fresh CSRF token, for when a page loads
+// with the cookies already set and needs the token for its next request
+func (h *AuthHandler) CSRFToken(c *gin.Context) {
+	token, err := h.cookies.setCSRF(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to issue csrf token: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"csrfToken": token})
+}
+
+// respondThrottled answers 429 with a Retry-After when a login was refused because of
+// earlier failures, and reports whether it did
+func respondThrottled(c *gin.Context, err error) bool {
+	var throttled *services.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(int(throttled.RetryAfter.Seconds())+1))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": throttled.Error(), "locked": throttled.Locked})
+	return true
+}
+
+func clientInfo(c *gin.Context) models.ClientInfo {
+	return models.ClientInfo{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
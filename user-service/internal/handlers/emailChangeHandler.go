@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/services"
+)
+
+type EmailChangeHandler struct {
+	emailChangeService *services.EmailChangeService
+}
+
+func NewEmailChangeHandler(emailChangeService *services.EmailChangeService) *EmailChangeHandler {
+	return &EmailChangeHandler{
+		emailChangeService: emailChangeService,
+	}
+}
+
+func (h *EmailChangeHandler) RequestChange(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req models.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	if err := h.emailChangeService.RequestChange(c.Request.Context(), userId.(string), &req); err != nil {
+		switch {
+		case errors.Is(err, services.ErrIncorrectPassword), errors.Is(err, services.ErrSameEmail):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to request email change: " + err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "a confirmation link has been sent to the new address"})
+}
+
+func (h *EmailChangeHandler) ConfirmChange(c *gin.Context) {
+	var req models.ConfirmEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	if err := h.emailChangeService.ConfirmChange(c.Request.Context(), req.Token, c.ClientIP()); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidEmailChangeToken):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrEmailInUse):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to change email: " + err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "email address changed, please log in again"})
+}
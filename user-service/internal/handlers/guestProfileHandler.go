@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/services"
+)
+
+type GuestProfileHandler struct {
+	guestProfileService *services.GuestProfileService
+}
+
+func NewGuestProfileHandler(guestProfileService *services.GuestProfileService) *GuestProfileHandler {
+	return &GuestProfileHandler{
+		guestProfileService: guestProfileService,
+	}
+}
+
+func (h *GuestProfileHandler) GetMyProfile(c *gin.Context) {
+	h.getProfile(c, actorId(c))
+}
+
+func (h *GuestProfileHandler) UpdateMyProfile(c *gin.Context) {
+	h.updateProfile(c, "", actorId(c))
+}
+
+func (h *GuestProfileHandler) EnrollMyLoyalty(c *gin.Context) {
+	h.enrollLoyalty(c, "", actorId(c))
+}
+
+// GetGuestProfile lets staff look up a guest's profile
+func (h *GuestProfileHandler) GetGuestProfile(c *gin.Context) {
+	h.getProfile(c, c.Param("id"))
+}
+
+// UpdateGuestProfile lets staff edit a guest's profile, e.g. at check-in
+func (h *GuestProfileHandler) UpdateGuestProfile(c *gin.Context) {
+	h.updateProfile(c, actorId(c), c.Param("id"))
+}
+
+func (h *GuestProfileHandler) EnrollGuestLoyalty(c *gin.Context) {
+	h.enrollLoyalty(c, actorId(c), c.Param("id"))
+}
+
+// GetPreferences returns a guest's stay preferences to other services for room assignment
+func (h *GuestProfileHandler) GetPreferences(c *gin.Context) {
+	preferences, err := h.guestProfileService.GetPreferences(c.Request.Context(), c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get preferences: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, preferences)
+}
+
+func (h *GuestProfileHandler) getProfile(c *gin.Context, userId string) {
+	profile, err := h.guestProfileService.GetProfile(c.Request.Context(), userId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *GuestProfileHandler) updateProfile(c *gin.Context, actor, userId string) {
+	var req models.UpdateGuestProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	profile, err := h.guestProfileService.UpdateProfile(c.Request.Context(), actor, userId, &req, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidGuestProfile) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "failed to update guest profile: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *GuestProfileHandler) enrollLoyalty(c *gin.Context, actor, userId string) {
+	membership, err := h.guestProfileService.EnrollLoyalty(c.Request.Context(), actor, userId, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "failed to enroll in loyalty: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, membership)
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/services"
+)
+
+type LoyaltyHandler struct {
+	loyaltyService *services.LoyaltyService
+}
+
+func NewLoyaltyHandler(loyaltyService *services.LoyaltyService) *LoyaltyHandler {
+	return &LoyaltyHandler{
+		loyaltyService: loyaltyService,
+	}
+}
+
+func (h *LoyaltyHandler) GetMyAccount(c *gin.Context) {
+	h.getAccount(c, actorId(c))
+}
+
+func (h *LoyaltyHandler) GetMyHistory(c *gin.Context) {
+	h.getHistory(c, actorId(c))
+}
+
+func (h *LoyaltyHandler) ListMyVouchers(c *gin.Context) {
+	vouchers, err := h.loyaltyService.ListVouchers(c.Request.Context(), actorId(c))
+	if err != nil {
+		loyaltyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"vouchers": vouchers})
+}
+
+// CreateVoucher turns some of the caller's points into a voucher code
+func (h *LoyaltyHandler) CreateVoucher(c *gin.Context) {
+	var req models.CreateVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	voucher, err := h.loyaltyService.CreateVoucher(c.Request.Context(), actorId(c), req.Points, c.ClientIP())
+	if err != nil {
+		loyaltyError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, voucher)
+}
+
+func (h *LoyaltyHandler) GetGuestAccount(c *gin.Context) {
+	h.getAccount(c, c.Param("id"))
+}
+
+func (h *LoyaltyHandler) GetGuestHistory(c *gin.Context) {
+	h.getHistory(c, c.Param("id"))
+}
+
+// AdjustPoints credits or debits a guest's points by hand
+func (h *LoyaltyHandler) AdjustPoints(c *gin.Context) {
+	var req models.AdjustPointsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	entry, err := h.loyaltyService.AdjustPoints(c.Request.Context(), actorId(c), c.Param("id"), &req, c.ClientIP())
+	if err != nil {
+		loyaltyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *LoyaltyHandler) ListTiers(c *gin.Context) {
+	tiers, err := h.loyaltyService.ListTiers(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tiers: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tiers": tiers})
+}
+
+func (h *LoyaltyHandler) UpdateTier(c *gin.Context) {
+	var req models.UpdateLoyaltyTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	tier, err := h.loyaltyService.UpdateTier(c.Request.Context(), actorId(c), c.Param("name"), &req, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to update tier: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tier)
+}
+
+// RecordStay earns points for a completed stay, called by booking-service on checkout
+func (h *LoyaltyHandler) RecordStay(c *gin.Context) {
+	var req models.StayCompletedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	entry, err := h.loyaltyService.EarnForStay(c.Request.Context(), &req)
+	if err != nil {
+		loyaltyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// RedeemPoints spends points as a payment tender, called by payment-service
+func (h *LoyaltyHandler) RedeemPoints(c *gin.Context) {
+	var req models.RedeemPointsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	redemption, err := h.loyaltyService.RedeemPoints(c.Request.Context(), &req)
+	if err != nil {
+		loyaltyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, redemption)
+}
+
+// ReverseRedemption refunds the points of a payment that failed or was refunded
+func (h *LoyaltyHandler) ReverseRedemption(c *gin.Context) {
+	entry, err := h.loyaltyService.ReverseRedemption(c.Request.Context(), c.Query("userId"), c.Param("reference"))
+	if err != nil {
+		loyaltyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// RedeemVoucher uses a voucher as payment, called by payment-service
+func (h *LoyaltyHandler) RedeemVoucher(c *gin.Context) {
+	var req models.RedeemVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	voucher, err := h.loyaltyService.RedeemVoucher(c.Request.Context(), c.Param("code"), req.Reference)
+	if err != nil {
+		loyaltyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, voucher)
+}
+
+func (h *LoyaltyHandler) getAccount(c *gin.Context, userId string) {
+	account, err := h.loyaltyService.GetAccount(c.Request.Context(), userId)
+	if err != nil {
+		loyaltyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+func (h *LoyaltyHandler) getHistory(c *gin.Context, userId string) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	history, err := h.loyaltyService.History(c.Request.Context(), userId, page, limit)
+	if err != nil {
+		loyaltyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+func loyaltyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotLoyaltyMember), errors.Is(err, services.ErrRedemptionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInsufficientPoints), errors.Is(err, services.ErrVoucherUnavailable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrBelowMinRedemption), errors.Is(err, services.ErrInvalidAdjustment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "loyalty request failed: " + err.Error()})
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/services"
+)
+
+type MFAHandler struct {
+	mfaService *services.MFAService
+	cookies    *SessionCookies
+}
+
+func NewMFAHandler(mfaService *services.MFAService, cookies *SessionCookies) *MFAHandler {
+	return &MFAHandler{
+		mfaService: mfaService,
+		cookies:    cookies,
+	}
+}
+
+// VerifyLogin is the second step of a login for users with two-factor authentication
+func (h *MFAHandler) VerifyLogin(c *gin.Context) {
+	var req models.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	response, err := h.mfaService.VerifyLogin(c.Request.Context(), req.MFAToken, req.Code, clientInfo(c))
+	if respondThrottled(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": "authentication failed: " + err.Error()})
+		return
+	}
+	if err := h.cookies.issue(c, response); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start session: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// BeginLoginEnrollment starts the enrollment a login is waiting on
+func (h *MFAHandler) BeginLoginEnrollment(c *gin.Context) {
+	var req models.MFAEnrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	response, err := h.mfaService.BeginEnrollmentWithToken(c.Request.Context(), req.MFAToken)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": "failed to start enrollment: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ConfirmLoginEnrollment confirms a required enrollment and finishes the login
+func (h *MFAHandler) ConfirmLoginEnrollment(c *gin.Context) {
+	var req models.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	response, err := h.mfaService.ConfirmEnrollmentWithToken(c.Request.Context(), req.MFAToken, req.Code, clientInfo(c))
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": "failed to confirm enrollment: " + err.Error()})
+		return
+	}
+	if err := h.cookies.issue(c, response.Login); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start session: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *MFAHandler) GetStatus(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	status, err := h.mfaService.Status(c.Request.Context(), userId.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get two-factor status: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *MFAHandler) BeginEnrollment(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	response, err := h.mfaService.BeginEnrollment(c.Request.Context(), userId.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to start enrollment: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *MFAHandler) ConfirmEnrollment(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	codes, err := h.mfaService.ConfirmEnrollment(c.Request.Context(), userId.(string), req.Code)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": "failed to confirm enrollment: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.MFAConfirmResponse{RecoveryCodes: codes})
+}
+
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(c.Request.Context(), userId.(string), req.Code)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": "failed to regenerate recovery codes: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.MFAConfirmResponse{RecoveryCodes: codes})
+}
+
+func (h *MFAHandler) Disable(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req models.MFADisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	if err := h.mfaService.Disable(c.Request.Context(), userId.(string), req.Password, req.Code); err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": "failed to disable two-factor authentication: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+func mfaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidMFAToken), errors.Is(err, services.ErrInvalidMFACode):
+		return http.StatusUnauthorized
+	case errors.Is(err, services.ErrMFARequiredByPolicy):
+		return http.StatusForbidden
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/services"
+)
+
+type OIDCHandler struct {
+	oidcService *services.OIDCService
+	cookies     *SessionCookies
+}
+
+func NewOIDCHandler(oidcService *services.OIDCService, cookies *SessionCookies) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+		cookies:     cookies,
+	}
+}
+
+func (h *OIDCHandler) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.oidcService.Providers()})
+}
+
+// Authorize returns the provider URL the frontend should send the browser to
+func (h *OIDCHandler) Authorize(c *gin.Context) {
+	response, err := h.oidcService.BeginLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownProvider) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to start login: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Callback takes the code and state the provider redirected back with and logs in,
+// answering like the password login does
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var req models.OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	response, err := h.oidcService.CompleteLogin(c.Request.Context(), c.Param("provider"), req.Code, req.State, clientInfo(c))
+	if err == nil {
+		err = h.cookies.issue(c, response)
+	}
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, response)
+	case errors.Is(err, services.ErrUnknownProvider):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidLoginState), errors.Is(err, services.ErrProviderEmailMissing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAccountNotLinkable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAccountSuspended), errors.Is(err, services.ErrPasswordResetRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrProviderLoginFailed):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "login failed: " + err.Error()})
+	}
+}
+
+func (h *OIDCHandler) ListIdentities(c *gin.Context) {
+	identities, err := h.oidcService.ListIdentities(c.Request.Context(), actorId(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list identities: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+func (h *OIDCHandler) Unlink(c *gin.Context) {
+	if err := h.oidcService.Unlink(c.Request.Context(), actorId(c), c.Param("id"), c.ClientIP()); err != nil {
+		if errors.Is(err, services.ErrLastLoginMethod) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "identity unlinked"})
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/services"
+)
+
+type PrivacyHandler struct {
+	privacyService *services.PrivacyService
+}
+
+func NewPrivacyHandler(privacyService *services.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyService: privacyService,
+	}
+}
+
+// ExportData downloads everything held about the caller as a JSON archive
+func (h *PrivacyHandler) ExportData(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	export, err := h.privacyService.ExportUserData(c.Request.Context(), userId.(string), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to export data: " + err.Error()})
+		return
+	}
+	filename := "account-export-" + time.Now().UTC().Format("2006-01-02") + ".json"
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.IndentedJSON(http.StatusOK, export)
+}
+
+// DeleteAccount erases the caller's personal data in every service and signs them out
+func (h *PrivacyHandler) DeleteAccount(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	if err := h.privacyService.DeleteAccount(c.Request.Context(), userId.(string), req.Password, c.ClientIP()); err != nil {
+		if errors.Is(err, services.ErrIncorrectPassword) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to delete account, please try again: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "account deleted"})
+}
@@ -7,47 +7,139 @@ import (
 	"github.com/ollatomiwa/hotelsystem/user-service/internal/services"
 	"github.com/ollatomiwa/hotelsystem/user-service/pkg/middleware"
 	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
+	sharedauth "github.com/ollatomiwa/hotelsystem/shared/auth"
 )
 
-func SetupRoutes(router *gin.Engine, authService *services.AuthService, jwtManager *security.JWTManager){
-	AuthHandler := NewAuthHandler(authService)
+func SetupRoutes(router *gin.Engine, authService *services.AuthService, verificationService *services.VerificationService,
+	passwordResetService *services.PasswordResetService, emailChangeService *services.EmailChangeService,
+	mfaService *services.MFAService,
+	loginProtection *services.LoginProtection, auditService *services.AuditService, rbacService *services.RBACService,
+	userAdminService *services.UserAdminService, guestProfileService *services.GuestProfileService,
+	loyaltyService *services.LoyaltyService,
+	privacyService *services.PrivacyService, oidcService *services.OIDCService,
+	authn *sharedauth.Middleware,
+	keyRing *security.KeyRing, authRateLimit gin.HandlerFunc, cors gin.HandlerFunc, sessionCookies *SessionCookies){
+	AuthHandler := NewAuthHandler(authService, sessionCookies)
+	verificationHandler := NewVerificationHandler(verificationService)
+	passwordResetHandler := NewPasswordResetHandler(passwordResetService)
+	emailChangeHandler := NewEmailChangeHandler(emailChangeService)
+	privacyHandler := NewPrivacyHandler(privacyService)
+	guestProfileHandler := NewGuestProfileHandler(guestProfileService)
+	loyaltyHandler := NewLoyaltyHandler(loyaltyService)
+	oidcHandler := NewOIDCHandler(oidcService, sessionCookies)
+	mfaHandler := NewMFAHandler(mfaService, sessionCookies)
+	adminHandler := NewAdminHandler(loginProtection, auditService, rbacService, userAdminService)
+	requireAuth := authn.Authenticate()
 
-	router.Use(middleware.CORS())
+	router.Use(cors)
 	router.Use(middleware.Logger())
 	router.Use(middleware.Recovery())
+	if sessionCookies != nil {
+		router.Use(sessionCookies.CSRF())
+	}
 
 	router.GET("/health", func (c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message":"healthy"})
 	})
 
+	// Public keys for verifying access tokens in other services
+	router.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, keyRing.JWKS())
+	})
+
 	v1 := router.Group("/api/v1")
 	{
 		// Auth routes - public
 		auth := v1.Group("/auth")
+		auth.Use(authRateLimit)
 		{
 			auth.POST("/register", AuthHandler.Register)
 			auth.POST("/login", AuthHandler.Login)
 			auth.POST("/refresh", AuthHandler.RefreshToken)
-			// Client will handle logout by removing tokens
+			auth.POST("/logout", AuthHandler.Logout)
+			auth.POST("/logout-all", requireAuth, AuthHandler.LogoutAll)
+			if sessionCookies != nil {
+				auth.GET("/csrf", AuthHandler.CSRFToken)
+			}
+			auth.POST("/verify-email", verificationHandler.VerifyEmail)
+			auth.POST("/resend-verification", verificationHandler.ResendVerification)
+			auth.POST("/forgot-password", passwordResetHandler.ForgotPassword)
+			auth.POST("/reset-password", passwordResetHandler.ResetPassword)
+			auth.POST("/confirm-email-change", emailChangeHandler.ConfirmChange)
+			auth.POST("/mfa/verify", mfaHandler.VerifyLogin)
+			auth.POST("/mfa/enroll", mfaHandler.BeginLoginEnrollment)
+			auth.POST("/mfa/enroll/confirm", mfaHandler.ConfirmLoginEnrollment)
+			auth.GET("/oidc/providers", oidcHandler.ListProviders)
+			auth.GET("/oidc/:provider/authorize", oidcHandler.Authorize)
+			auth.POST("/oidc/:provider/callback", oidcHandler.Callback)
 		}
 
 		// User routes - protected
 		users := v1.Group("/users")	
-		users.Use(middleware.AuthMiddleware(jwtManager))
+		users.Use(requireAuth)
 		{
 			users.GET("/profile", AuthHandler.GetProfile)
 			users.PUT("/profile", AuthHandler.UpdateProfile)
 			users.PUT("/change-password", AuthHandler.ChangePassword)
+			users.POST("/change-email", emailChangeHandler.RequestChange)
+			users.GET("/guest-profile", guestProfileHandler.GetMyProfile)
+			users.PUT("/guest-profile", guestProfileHandler.UpdateMyProfile)
+			users.POST("/guest-profile/loyalty", guestProfileHandler.EnrollMyLoyalty)
+			users.GET("/loyalty", loyaltyHandler.GetMyAccount)
+			users.GET("/loyalty/history", loyaltyHandler.GetMyHistory)
+			users.GET("/loyalty/vouchers", loyaltyHandler.ListMyVouchers)
+			users.POST("/loyalty/vouchers", loyaltyHandler.CreateVoucher)
+			users.GET("/me/export", privacyHandler.ExportData)
+			users.DELETE("/me", privacyHandler.DeleteAccount)
+			users.GET("/identities", oidcHandler.ListIdentities)
+			users.DELETE("/identities/:id", oidcHandler.Unlink)
+			users.GET("/sessions", AuthHandler.ListSessions)
+			users.DELETE("/sessions", AuthHandler.RevokeAllSessions)
+			users.DELETE("/sessions/:id", AuthHandler.RevokeSession)
+			users.GET("/mfa", mfaHandler.GetStatus)
+			users.POST("/mfa/enroll", mfaHandler.BeginEnrollment)
+			users.POST("/mfa/confirm", mfaHandler.ConfirmEnrollment)
+			users.POST("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+			users.DELETE("/mfa", mfaHandler.Disable)
 		}
 
-		// Admin routes - protected + admin role
+		// Admin routes - protected, each needs its own permission
 		admin := v1.Group("/admin")
-		admin.Use(middleware.AuthMiddleware(jwtManager))
-		admin.Use(middleware.RoleMiddleware("admin"))
+		admin.Use(requireAuth)
+		{
+			admin.GET("/users", authn.RequirePermission(sharedauth.PermUsersRead), adminHandler.ListUsers)
+			admin.GET("/users/:id", authn.RequirePermission(sharedauth.PermUsersRead), adminHandler.GetUser)
+			admin.POST("/users/:id/suspend", authn.RequirePermission(sharedauth.PermUsersManage), adminHandler.SuspendUser)
+			admin.POST("/users/:id/reactivate", authn.RequirePermission(sharedauth.PermUsersManage), adminHandler.ReactivateUser)
+			admin.POST("/users/:id/force-password-reset", authn.RequirePermission(sharedauth.PermUsersManage), adminHandler.ForcePasswordReset)
+			admin.POST("/users/:id/unlock", authn.RequirePermission(sharedauth.PermUsersManage), adminHandler.UnlockUser)
+			admin.GET("/users/:id/guest-profile", authn.RequirePermission(sharedauth.PermGuestsRead), guestProfileHandler.GetGuestProfile)
+			admin.PUT("/users/:id/guest-profile", authn.RequirePermission(sharedauth.PermGuestsManage), guestProfileHandler.UpdateGuestProfile)
+			admin.POST("/users/:id/loyalty", authn.RequirePermission(sharedauth.PermGuestsManage), guestProfileHandler.EnrollGuestLoyalty)
+			admin.GET("/users/:id/loyalty", authn.RequirePermission(sharedauth.PermGuestsRead), loyaltyHandler.GetGuestAccount)
+			admin.GET("/users/:id/loyalty/history", authn.RequirePermission(sharedauth.PermGuestsRead), loyaltyHandler.GetGuestHistory)
+			admin.POST("/users/:id/loyalty/adjust", authn.RequirePermission(sharedauth.PermGuestsManage), loyaltyHandler.AdjustPoints)
+			admin.GET("/loyalty/tiers", authn.RequirePermission(sharedauth.PermGuestsRead), loyaltyHandler.ListTiers)
+			admin.PUT("/loyalty/tiers/:name", authn.RequirePermission(sharedauth.PermGuestsManage), loyaltyHandler.UpdateTier)
+			admin.PUT("/users/:id/role", authn.RequirePermission(sharedauth.PermRolesManage), adminHandler.AssignRole)
+			admin.GET("/roles", authn.RequirePermission(sharedauth.PermRolesManage), adminHandler.ListRoles)
+			admin.PUT("/roles/:name/permissions", authn.RequirePermission(sharedauth.PermRolesManage), adminHandler.SetRolePermissions)
+			admin.GET("/permissions", authn.RequirePermission(sharedauth.PermRolesManage), adminHandler.ListPermissions)
+			admin.GET("/audit-log", authn.RequirePermission(sharedauth.PermAuditRead), adminHandler.ListAuditEvents)
+			admin.GET("/audit-log/verify", authn.RequirePermission(sharedauth.PermAuditRead), adminHandler.VerifyAuditLog)
+		}
+
+		// Internal routes for other services: booking-service reads preferences for room
+		// assignment and reports completed stays, payment-service redeems points and vouchers
+		internal := v1.Group("/internal")
+		internal.Use(requireAuth, authn.RequireService())
 		{
-			admin.GET("/users", func(c *gin.Context) {
-				c.JSON(http.StatusOK, gin.H{"message": "Admin access granted"})
-			})
+			internal.GET("/users/:userId/preferences", guestProfileHandler.GetPreferences)
+			internal.POST("/loyalty/stays", loyaltyHandler.RecordStay)
+			internal.POST("/loyalty/redemptions", loyaltyHandler.RedeemPoints)
+			internal.DELETE("/loyalty/redemptions/:reference", loyaltyHandler.ReverseRedemption)
+			internal.POST("/loyalty/vouchers/:code/redeem", loyaltyHandler.RedeemVoucher)
 		}
 	}
 
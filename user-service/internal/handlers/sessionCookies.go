@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/middleware"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
+)
+
+type SessionCookieConfig struct {
+	RefreshCookie string
+	CSRFCookie    string
+	Domain        string
+	// Path scopes the refresh cookie, it only needs to reach the auth routes
+	Path     string
+	Secure   bool
+	SameSite http.SameSite
+	MaxAge   time.Duration
+}
+
+// ParseSameSite turns strict, lax or none into a SameSite mode, defaulting to strict
+func ParseSameSite(mode string) http.SameSite {
+	switch strings.ToLower(mode) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteStrictMode
+	}
+}
+
+// SessionCookies is the cookie mode for browser clients. Refresh tokens are set as
+// HttpOnly cookies instead of being returned in the body, so page scripts never see
+// them, and a CSRF token is issued alongside for the double-submit check. A nil
+// *SessionCookies leaves tokens in the body
+type SessionCookies struct {
+	cfg  SessionCookieConfig
+	csrf *security.CSRFTokens
+}
+
+func NewSessionCookies(cfg SessionCookieConfig, csrf *security.CSRFTokens) *SessionCookies {
+	return &SessionCookies{
+		cfg:  cfg,
+		csrf: csrf,
+	}
+}
+
+// CSRF checks state-changing requests from clients holding the cookies
+func (s *SessionCookies) CSRF() gin.HandlerFunc {
+	return middleware.CSRF(s.cfg.CSRFCookie, []string{s.cfg.RefreshCookie}, s.csrf.Valid)
+}
+
+// issue moves a login's refresh token into a cookie and hands out a new CSRF
+// token. Responses without a refresh token, like an mfa challenge, are left alone
+func (s *SessionCookies) issue(c *gin.Context, response *models.LoginResponse) error {
+	if s == nil || response == nil || response.RefreshToken == "" {
+		return nil
+	}
+	csrfToken, err := s.setCSRF(c)
+	if err != nil {
+		return err
+	}
+	s.setCookie(c, s.cfg.RefreshCookie, response.RefreshToken, s.cfg.Path, s.cfg.MaxAge, true)
+	response.RefreshToken = ""
+	response.CSRFToken = csrfToken
+	return nil
+}
+
+// setCSRF sets a new CSRF cookie and returns its token. The cookie isn't HttpOnly
+// and covers the whole site, since the frontend reads it to send back in the header
+func (s *SessionCookies) setCSRF(c *gin.Context) (string, error) {
+	token, err := s.csrf.Generate()
+	if err != nil {
+		return "", err
+	}
+	s.setCookie(c, s.cfg.CSRFCookie, token, "/", s.cfg.MaxAge, false)
+	return token, nil
+}
+
+// refreshToken is the token from the request body, or else from the cookie
+func (s *SessionCookies) refreshToken(c *gin.Context, fromBody string) string {
+	if fromBody != "" || s == nil {
+		return fromBody
+	}
+	token, _ := c.Cookie(s.cfg.RefreshCookie)
+	return token
+}
+
+func (s *SessionCookies) clear(c *gin.Context) {
+	if s == nil {
+		return
+	}
+	s.setCookie(c, s.cfg.RefreshCookie, "", s.cfg.Path, -1, true)
+	s.setCookie(c, s.cfg.CSRFCookie, "", "/", -1, false)
+}
+
+func (s *SessionCookies) setCookie(c *gin.Context, name, value, path string, maxAge time.Duration, httpOnly bool) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   s.cfg.Domain,
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   s.cfg.Secure,
+		HttpOnly: httpOnly,
+		SameSite: s.cfg.SameSite,
+	}
+	if maxAge < 0 {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(c.Writer, cookie)
+}
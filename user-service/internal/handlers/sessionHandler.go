@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
+)
+
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var currentSessionId string
+	if claims, ok := auth.ClaimsFrom(c); ok {
+		currentSessionId = claims.SessionId
+	}
+	sessions, err := h.authService.ListSessions(c.Request.Context(), userId.(string), currentSessionId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	if err := h.authService.RevokeSession(c.Request.Context(), userId.(string), c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "failed to revoke session: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked"})
+}
+
+// RevokeAllSessions signs the user out everywhere, including the token used for this request
+func (h *AuthHandler) RevokeAllSessions(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	if err := h.authService.RevokeAllSessions(c.Request.Context(), userId.(string)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions: " + err.Error()})
+		return
+	}
+	if claims, ok := auth.ClaimsFrom(c); ok && claims.ExpiresAt != nil {
+		if err := h.authService.RevokeAccessToken(c.Request.Context(), claims.ID, claims.ExpiresAt.Time); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke token: " + err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "all sessions revoked", "revokedAt": time.Now()})
+}
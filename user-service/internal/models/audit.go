@@ -0,0 +1,95 @@
+package models
+
+import "time"
+
+// Audit event types
+const (
+	AuditLoginSucceeded       = "login_succeeded"
+	AuditLoginFailed          = "login_failed"
+	AuditTokenRefreshed       = "token_refreshed"
+	AuditRefreshTokenReused   = "refresh_token_reused"
+	AuditPasswordChanged      = "password_changed"
+	AuditPasswordReset        = "password_reset"
+	AuditAccountLocked        = "account_locked"
+	AuditAccountUnlocked      = "account_unlocked"
+	AuditIPBlocked            = "ip_blocked"
+	AuditRoleChanged          = "role_changed"
+	AuditRoleUpdated          = "role_permissions_updated"
+	AuditUserSuspended        = "user_suspended"
+	AuditUserReactivated      = "user_reactivated"
+	AuditPasswordResetForced  = "password_reset_forced"
+	AuditEmailChanged         = "email_changed"
+	AuditDataExported         = "data_exported"
+	AuditAccountDeleted       = "account_deleted"
+	AuditIdentityLinked       = "identity_linked"
+	AuditIdentityUnlinked     = "identity_unlinked"
+	AuditGuestProfileUpdated  = "guest_profile_updated"
+	AuditLoyaltyEnrolled      = "loyalty_enrolled"
+	AuditLoyaltyAdjusted      = "loyalty_points_adjusted"
+	AuditLoyaltyVoucherIssued = "loyalty_voucher_issued"
+	AuditLoyaltyTierUpdated   = "loyalty_tier_updated"
+)
+
+// Whether the audited action went through
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditEvent is a security relevant event. UserId is who it happened to and
+// ActorId who caused it, when that was someone else (an admin, say).
+// Events are chained: Hash covers the event and the hash of the one before it,
+// so editing or removing an event breaks every hash after it
+type AuditEvent struct {
+	Id        string                 `json:"id"`
+	Sequence  int64                  `json:"sequence"`
+	Event     string                 `json:"event"`
+	Outcome   string                 `json:"outcome"`
+	UserId    string                 `json:"userId,omitempty"`
+	ActorId   string                 `json:"actorId,omitempty"`
+	IPAddress string                 `json:"ipAddress,omitempty"`
+	UserAgent string                 `json:"userAgent,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	CreatedAt time.Time              `json:"createdAt"`
+	PrevHash  string                 `json:"prevHash"`
+	Hash      string                 `json:"hash"`
+}
+
+// AuditFilter narrows an audit log query, zero values match everything
+type AuditFilter struct {
+	UserId    string
+	ActorId   string
+	Event     string
+	Outcome   string
+	IPAddress string
+	From      time.Time
+	To        time.Time
+	Limit     int
+	Offset    int
+}
+
+type AuditEventList struct {
+	Events []AuditEvent `json:"events"`
+	Total  int          `json:"total"`
+	Page   int          `json:"page"`
+	Limit  int          `json:"limit"`
+}
+
+// AuditChainReport is the result of checking the audit log's hash chain. BrokenAt
+// is the sequence of the first event that doesn't match, when the chain is broken.
+// Head is the hash of the last event checked, worth keeping somewhere else so a
+// later check can show nothing was cut off the end
+type AuditChainReport struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`
+	Head     string `json:"head,omitempty"`
+	BrokenAt int64  `json:"brokenAt,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// LoginState is the failed login bookkeeping kept on a user
+type LoginState struct {
+	FailedAttempts int
+	LastFailedAt   *time.Time
+	LockedUntil    *time.Time
+}
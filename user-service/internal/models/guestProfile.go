@@ -0,0 +1,109 @@
+package models
+
+import "time"
+
+// Stay preference values
+const (
+	BedKing  = "king"
+	BedQueen = "queen"
+	BedTwin  = "twin"
+
+	FloorHigh = "high"
+	FloorLow  = "low"
+
+	PillowSoft           = "soft"
+	PillowFirm           = "firm"
+	PillowFeather        = "feather"
+	PillowHypoallergenic = "hypoallergenic"
+)
+
+// ID document types a guest can register with
+const (
+	DocumentPassport       = "passport"
+	DocumentNationalId     = "national_id"
+	DocumentDrivingLicence = "driving_licence"
+)
+
+// GuestProfile is what the hotel knows about a guest beyond their account. Countries
+// are ISO 3166 alpha-2 codes
+type GuestProfile struct {
+	UserId       string             `json:"userId"`
+	Nationality  string             `json:"nationality,omitempty"`
+	Address      GuestAddress       `json:"address"`
+	IdDocument   *IdDocument        `json:"idDocument,omitempty"`
+	Preferences  StayPreferences    `json:"preferences"`
+	DietaryNotes string             `json:"dietaryNotes,omitempty"`
+	Consents     MarketingConsents  `json:"consents"`
+	Loyalty      *LoyaltyMembership `json:"loyalty,omitempty"`
+	UpdatedAt    *time.Time         `json:"updatedAt,omitempty"`
+	UpdatedBy    string             `json:"updatedBy,omitempty"`
+}
+
+type GuestAddress struct {
+	Line1      string `json:"line1,omitempty"`
+	Line2      string `json:"line2,omitempty"`
+	City       string `json:"city,omitempty"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postalCode,omitempty"`
+	Country    string `json:"country,omitempty"`
+}
+
+// IdDocument describes the guest's ID without holding a copy of it; only the last
+// four characters of the number are kept, enough for staff to match it at check-in
+type IdDocument struct {
+	Type           string `json:"type"`
+	NumberLast4    string `json:"numberLast4"`
+	IssuingCountry string `json:"issuingCountry"`
+	ExpiresOn      string `json:"expiresOn,omitempty"`
+}
+
+// StayPreferences are used when assigning rooms. Empty means no preference
+type StayPreferences struct {
+	Bed    string `json:"bed,omitempty"`
+	Floor  string `json:"floor,omitempty"`
+	Pillow string `json:"pillow,omitempty"`
+}
+
+// MarketingConsents records what the guest agreed to be contacted about and when
+type MarketingConsents struct {
+	Email            bool       `json:"email"`
+	SMS              bool       `json:"sms"`
+	EmailConsentedAt *time.Time `json:"emailConsentedAt,omitempty"`
+	SMSConsentedAt   *time.Time `json:"smsConsentedAt,omitempty"`
+}
+
+type LoyaltyMembership struct {
+	Number     string    `json:"number"`
+	EnrolledAt time.Time `json:"enrolledAt"`
+}
+
+// UpdateGuestProfileRequest replaces the editable parts of a profile
+type UpdateGuestProfileRequest struct {
+	Nationality  string                   `json:"nationality"`
+	Address      GuestAddress             `json:"address"`
+	IdDocument   *IdDocumentRequest       `json:"idDocument"`
+	Preferences  StayPreferences          `json:"preferences"`
+	DietaryNotes string                   `json:"dietaryNotes" binding:"max=500"`
+	Consents     MarketingConsentsRequest `json:"consents"`
+}
+
+// IdDocumentRequest carries the full document number, which is cut down to its last
+// four characters before it is stored
+type IdDocumentRequest struct {
+	Type           string `json:"type" binding:"required"`
+	Number         string `json:"number" binding:"required,min=4"`
+	IssuingCountry string `json:"issuingCountry" binding:"required"`
+	ExpiresOn      string `json:"expiresOn"`
+}
+
+type MarketingConsentsRequest struct {
+	Email bool `json:"email"`
+	SMS   bool `json:"sms"`
+}
+
+// GuestPreferences is what other services read for room assignment
+type GuestPreferences struct {
+	UserId       string          `json:"userId"`
+	Preferences  StayPreferences `json:"preferences"`
+	DietaryNotes string          `json:"dietaryNotes,omitempty"`
+}
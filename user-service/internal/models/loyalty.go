@@ -0,0 +1,112 @@
+package models
+
+import "time"
+
+// Loyalty ledger entry kinds
+const (
+	LedgerEarn       = "earn"
+	LedgerRedeem     = "redeem"
+	LedgerVoucher    = "voucher"
+	LedgerReversal   = "reversal"
+	LedgerAdjustment = "adjustment"
+)
+
+// LoyaltyTier is reached once a member's lifetime points pass its threshold
+type LoyaltyTier struct {
+	Name           string   `json:"name"`
+	Threshold      int64    `json:"threshold"`
+	EarnMultiplier float64  `json:"earnMultiplier"`
+	Benefits       []string `json:"benefits"`
+}
+
+// LoyaltyAccount is a member's points summary
+type LoyaltyAccount struct {
+	UserId           string       `json:"userId"`
+	Number           string       `json:"number"`
+	Balance          int64        `json:"balance"`
+	LifetimePoints   int64        `json:"lifetimePoints"`
+	Tier             LoyaltyTier  `json:"tier"`
+	NextTier         *LoyaltyTier `json:"nextTier,omitempty"`
+	PointsToNextTier int64        `json:"pointsToNextTier,omitempty"`
+}
+
+// LedgerEntry is one change to a member's points. Reference ties it to what caused
+// it (a booking, a payment, a voucher) and is unique per user and kind
+type LedgerEntry struct {
+	Id           string    `json:"id"`
+	UserId       string    `json:"-"`
+	Kind         string    `json:"kind"`
+	Points       int64     `json:"points"`
+	BalanceAfter int64     `json:"balanceAfter"`
+	Reference    string    `json:"reference,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	CreatedBy    string    `json:"createdBy,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type LedgerPage struct {
+	Entries []LedgerEntry `json:"entries"`
+	Total   int           `json:"total"`
+	Page    int           `json:"page"`
+	Limit   int           `json:"limit"`
+}
+
+// LoyaltyVoucher is points turned into a code worth Value at payment
+type LoyaltyVoucher struct {
+	Code              string     `json:"code"`
+	UserId            string     `json:"-"`
+	Points            int64      `json:"points"`
+	Value             float64    `json:"value"`
+	ExpiresAt         time.Time  `json:"expiresAt"`
+	RedeemedAt        *time.Time `json:"redeemedAt,omitempty"`
+	RedeemedReference string     `json:"redeemedReference,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+}
+
+// StayCompletedRequest is sent by booking-service when a guest checks out
+type StayCompletedRequest struct {
+	UserId      string  `json:"userId" binding:"required"`
+	BookingId   string  `json:"bookingId" binding:"required"`
+	AmountSpent float64 `json:"amountSpent" binding:"min=0"`
+	Nights      int     `json:"nights" binding:"min=0"`
+}
+
+// RedeemPointsRequest pays part of a bill with points. Reference is the payment it
+// belongs to, so retries don't redeem twice
+type RedeemPointsRequest struct {
+	UserId    string `json:"userId" binding:"required"`
+	Points    int64  `json:"points" binding:"required,min=1"`
+	Reference string `json:"reference" binding:"required"`
+}
+
+// LoyaltyExport is a member's loyalty data in a data export
+type LoyaltyExport struct {
+	Account  *LoyaltyAccount  `json:"account"`
+	History  []LedgerEntry    `json:"history"`
+	Vouchers []LoyaltyVoucher `json:"vouchers"`
+}
+
+type RedemptionResponse struct {
+	Points  int64   `json:"points"`
+	Amount  float64 `json:"amount"`
+	Balance int64   `json:"balance"`
+}
+
+type CreateVoucherRequest struct {
+	Points int64 `json:"points" binding:"required,min=1"`
+}
+
+type RedeemVoucherRequest struct {
+	Reference string `json:"reference" binding:"required"`
+}
+
+type AdjustPointsRequest struct {
+	Points int64  `json:"points" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+type UpdateLoyaltyTierRequest struct {
+	Threshold      int64    `json:"threshold" binding:"min=0"`
+	EarnMultiplier float64  `json:"earnMultiplier" binding:"required,gt=0"`
+	Benefits       []string `json:"benefits"`
+}
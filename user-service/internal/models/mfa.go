@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// MFA token purposes: verify a second factor, or enroll one because policy requires it
+const (
+	MFAPurposeVerify = "verify"
+	MFAPurposeEnroll = "enroll"
+)
+
+// UserMFA is a user's TOTP enrollment. The secret is stored encrypted and the
+// enrollment only counts once the user has confirmed a code from their app
+type UserMFA struct {
+	UserId          string
+	SecretEncrypted string
+	Enabled         bool
+	EnabledAt       *time.Time
+	LastUsedStep    int64
+	CreatedAt       time.Time
+}
+
+type MFAStatusResponse struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabledAt,omitempty"`
+	Required               bool       `json:"required"`
+	RecoveryCodesRemaining int        `json:"recoveryCodesRemaining"`
+}
+
+type MFAEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+}
+
+type MFAEnrollRequest struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFAConfirmResponse carries the recovery codes, shown only once. When enrollment
+// finished a login, it also carries the tokens for that login
+type MFAConfirmResponse struct {
+	RecoveryCodes []string       `json:"recoveryCodes"`
+	Login         *LoginResponse `json:"login,omitempty"`
+}
+
+// MFAVerifyRequest completes a login or a required enrollment. Code is a TOTP code,
+// or when logging in, a recovery code
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type MFADisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DataExport is everything we hold about a user, for privacy law access requests.
+// Bookings, payments and notifications are passed through as the owning service
+// returned them
+type DataExport struct {
+	ExportedAt     time.Time       `json:"exportedAt"`
+	Profile        *User           `json:"profile"`
+	GuestProfile   *GuestProfile   `json:"guestProfile,omitempty"`
+	Loyalty        *LoyaltyExport  `json:"loyalty,omitempty"`
+	Sessions       []Session       `json:"sessions"`
+	Identities     []Identity      `json:"identities"`
+	SecurityEvents []AuditEvent    `json:"securityEvents"`
+	Bookings       json.RawMessage `json:"bookings,omitempty"`
+	Payments       json.RawMessage `json:"payments,omitempty"`
+	Notifications  json.RawMessage `json:"notifications,omitempty"`
+	// Services that aren't configured here, so their data isn't included
+	Omitted []string `json:"omitted,omitempty"`
+}
+
+// DeleteAccountRequest needs the password, since deletion can't be undone
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
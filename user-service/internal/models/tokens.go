@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// RefreshToken is the server-side record of an issued refresh token.
+// Only the SHA-256 of the token is stored. Every token issued from one
+// login shares a FamilyId so the whole chain can be revoked together
+type RefreshToken struct {
+	Id         string
+	UserId     string
+	FamilyId   string
+	TokenHash  string
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+	RevokedAt  *time.Time
+	ReplacedBy string
+}
+
+// Session is one login on one device. Its id is the refresh token family id
+type Session struct {
+	Id         string     `json:"id"`
+	UserId     string     `json:"-"`
+	UserAgent  string     `json:"userAgent"`
+	IPAddress  string     `json:"ipAddress"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt time.Time  `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"-"`
+	Current    bool       `json:"current"`
+}
+
+// ClientInfo describes where a request came from
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+type TokenPurpose string
+
+const (
+	TokenPurposeEmailVerification TokenPurpose = "email_verification"
+	TokenPurposePasswordReset     TokenPurpose = "password_reset"
+	TokenPurposeEmailChange       TokenPurpose = "email_change"
+)
+
+// OneTimeToken is a single-use token sent to the user, e.g. in an email link.
+// Only a keyed hash of the token is stored. NewEmail is set for email changes,
+// it is the address the link was sent to
+type OneTimeToken struct {
+	Id        string
+	UserId    string
+	Purpose   TokenPurpose
+	TokenHash string
+	NewEmail  string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
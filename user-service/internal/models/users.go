@@ -1,7 +1,7 @@
 package models
 
 import (
-	
+	"time"
 )
 
 type UserRole string 
@@ -9,6 +9,11 @@ type UserRole string
 const (
 	RoleCustomer UserRole = "customer"
 	RoleAdmin UserRole = "admin"
+	RoleFrontDesk UserRole = "front_desk"
+	RoleHousekeeping UserRole = "housekeeping"
+	RoleManager UserRole = "manager"
+	RoleAccountant UserRole = "accountant"
+	RoleOwner UserRole = "owner"
 )
 
 type User struct {
@@ -19,11 +24,20 @@ type User struct {
 	LastName string `json:"lastName"`
 	Phone string `json:"phone,omitempty"`
 	Role UserRole `json:"role"`
+	EmailVerified bool `json:"emailVerified"`
+	Suspended bool `json:"suspended,omitempty"`
+	PasswordResetRequired bool `json:"passwordResetRequired,omitempty"`
+
+	// Only filled in for admins
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	SuspendedAt *time.Time `json:"suspendedAt,omitempty"`
+	SuspensionReason string `json:"suspensionReason,omitempty"`
+	LockedUntil *time.Time `json:"lockedUntil,omitempty"`
 }
 
 type CreateUserRequest struct {
 	Email string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=8"`
+	Password string `json:"password" binding:"required"`
 	FirstName string `json:"firstName" binding:"required"`  
 	LastName string `json:"lastName" binding:"required"` 
 	Phone string `json:"phone,omitempty"`
@@ -31,26 +45,87 @@ type CreateUserRequest struct {
 
 type LoginRequest struct {
 	Email string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=8"` 
+	Password string `json:"password" binding:"required"` 
 }
 
+// LoginResponse either carries tokens, or when a second factor is needed, an
+// MFA token to finish the login with
 type LoginResponse struct {
-	AccessToken string `json:"accessToken"`
-	RefreshToken string `json:"refreshToken"`
+	AccessToken string `json:"accessToken,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	// CSRFToken is set in cookie mode, where the refresh token is a cookie instead
+	CSRFToken string `json:"csrfToken,omitempty"`
 	User User `json:"user"`
+	MFARequired bool `json:"mfaRequired,omitempty"`
+	MFAEnrollmentRequired bool `json:"mfaEnrollmentRequired,omitempty"`
+	MFAToken string `json:"mfaToken,omitempty"`
 }
 
 type UpdateUserRequest struct {
 	FirstName string `json:"firstName,omitempty" `  
-	LastName string `json:"lasyName,omitempty"` 
+	LastName string `json:"lastName,omitempty"` 
 	Phone string `json:"phone,omitempty"`
 }
 
 type ChangePasswordRequest struct {
 	CurrentPassword string `json:"currentPassword" binding:"required"`
-	NewPassword string `json:"newPassword" binding:"required,min=8"`
+	NewPassword string `json:"newPassword" binding:"required"`
 }
 
+// RefreshTokenRequest may be empty in cookie mode, the token comes from the cookie
 type RefreshTokenRequest struct {
-	RefreshToken string `json:"refreshToken" binding:"required"`
-}
\ No newline at end of file
+	RefreshToken string `json:"refreshToken"`
+}
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ChangeEmailRequest needs the current password, so a stolen session alone can't
+// move the account to an attacker's address
+type ChangeEmailRequest struct {
+	NewEmail string `json:"newEmail" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
+// Account states admins can filter users by
+const (
+	UserStatusActive = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusLocked = "locked"
+)
+
+type UserFilter struct {
+	Query string
+	Role string
+	Status string
+	Limit int
+	Offset int
+}
+
+type UserListResponse struct {
+	Users []User `json:"users"`
+	Total int `json:"total"`
+	Page int `json:"page"`
+	Limit int `json:"limit"`
+}
+
+type SuspendUserRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
@@ -0,0 +1,241 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+)
+
+// auditChainLock is the advisory lock that makes appends to the audit log take
+// turns, so every event is chained to the one committed before it
+const auditChainLock = 0x61756469746c6f67
+
+const auditColumns = `id, seq, event, outcome, COALESCE(user_id, ''), COALESCE(actor_id, ''), COALESCE(ip_address, ''),
+	COALESCE(user_agent, ''), details, created_at, prev_hash, hash`
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// RecordEvent appends an event to the audit log, filling in its sequence and hashes
+func (r *AuditRepository) RecordEvent(ctx context.Context, event *models.AuditEvent) error {
+	details, err := canonicalDetails(event.Details)
+	if err != nil {
+		return err
+	}
+	//postgres keeps microseconds, hash what will be read back
+	event.CreatedAt = event.CreatedAt.UTC().Truncate(time.Microsecond)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLock); err != nil {
+		return fmt.Errorf("failed to lock audit log: %w", err)
+	}
+	var lastSeq int64
+	var lastHash string
+	err = tx.QueryRowContext(ctx, `SELECT seq, hash FROM audit_log WHERE seq IS NOT NULL ORDER BY seq DESC LIMIT 1`).
+		Scan(&lastSeq, &lastHash)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read audit log head: %w", err)
+	}
+	event.Sequence = lastSeq + 1
+	event.PrevHash = lastHash
+	event.Hash = auditHash(event, details)
+
+	query := `
+		INSERT INTO audit_log (id, seq, event, outcome, user_id, actor_id, ip_address, user_agent, details, created_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), $9, $10, $11, $12)
+	`
+	_, err = tx.ExecContext(ctx, query, event.Id, event.Sequence, event.Event, event.Outcome, event.UserId, event.ActorId,
+		event.IPAddress, event.UserAgent, details, event.CreatedAt, event.PrevHash, event.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListEvents returns the events matching the filter, newest first, and how many
+// match in total
+func (r *AuditRepository) ListEvents(ctx context.Context, filter *models.AuditFilter) ([]models.AuditEvent, int, error) {
+	where := []string{"1 = 1"}
+	args := []interface{}{}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.UserId != "" {
+		where = append(where, "user_id = "+addArg(filter.UserId))
+	}
+	if filter.ActorId != "" {
+		where = append(where, "actor_id = "+addArg(filter.ActorId))
+	}
+	if filter.Event != "" {
+		where = append(where, "event = "+addArg(filter.Event))
+	}
+	if filter.Outcome != "" {
+		where = append(where, "outcome = "+addArg(filter.Outcome))
+	}
+	if filter.IPAddress != "" {
+		where = append(where, "ip_address = "+addArg(filter.IPAddress))
+	}
+	if !filter.From.IsZero() {
+		where = append(where, "created_at >= "+addArg(filter.From.UTC()))
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "created_at < "+addArg(filter.To.UTC()))
+	}
+	conditions := strings.Join(where, " AND ")
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log WHERE `+conditions, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit events: %w", err)
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log WHERE ` + conditions +
+		` ORDER BY created_at DESC, seq DESC NULLS LAST LIMIT ` + addArg(filter.Limit) + ` OFFSET ` + addArg(filter.Offset)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		event, _, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, *event)
+	}
+	return events, total, rows.Err()
+}
+
+// VerifyChain walks the whole chain in order and reports the first event whose
+// sequence, link or hash doesn't hold. Events from before the chain existed aren't
+// covered. Cutting events off the end leaves a valid, shorter chain, which only
+// comparing the head with a copy kept elsewhere shows
+func (r *AuditRepository) VerifyChain(ctx context.Context) (*models.AuditChainReport, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+auditColumns+` FROM audit_log WHERE seq IS NOT NULL ORDER BY seq`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer rows.Close()
+
+	report := &models.AuditChainReport{Valid: true}
+	var lastSeq int64
+	var lastHash string
+	for rows.Next() {
+		event, details, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		broken := ""
+		switch {
+		case event.Sequence != lastSeq+1:
+			broken = fmt.Sprintf("expected sequence %d", lastSeq+1)
+		case event.PrevHash != lastHash:
+			broken = "previous hash does not match the event before it"
+		case event.Hash != auditHash(event, details):
+			broken = "hash does not match the event"
+		}
+		if broken != "" {
+			report.Valid = false
+			report.BrokenAt = event.Sequence
+			report.Reason = broken
+			return report, nil
+		}
+		report.Checked++
+		report.Head = event.Hash
+		lastSeq = event.Sequence
+		lastHash = event.Hash
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return report, nil
+}
+
+// scanAuditEvent also returns the details in the canonical form they were hashed in
+func scanAuditEvent(row rowScanner) (*models.AuditEvent, []byte, error) {
+	var event models.AuditEvent
+	var seq sql.NullInt64
+	var raw []byte
+	err := row.Scan(&event.Id, &seq, &event.Event, &event.Outcome, &event.UserId, &event.ActorId, &event.IPAddress,
+		&event.UserAgent, &raw, &event.CreatedAt, &event.PrevHash, &event.Hash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to scan audit event: %w", err)
+	}
+	event.Sequence = seq.Int64
+	if err := json.Unmarshal(raw, &event.Details); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode audit details: %w", err)
+	}
+	details, err := canonicalDetails(event.Details)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &event, details, nil
+}
+
+// canonicalDetails encodes details the same way whether they come from the caller
+// or back out of the jsonb column, which reorders keys and forgets Go types
+func canonicalDetails(details map[string]interface{}) ([]byte, error) {
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	encoded, err := json.Marshal(details)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit details: %w", err)
+	}
+	var generic map[string]interface{}
+	if err := json.Unmarshal(encoded, &generic); err != nil {
+		return nil, fmt.Errorf("failed to marshal audit details: %w", err)
+	}
+	return json.Marshal(generic)
+}
+
+// auditHash is the SHA-256 of the previous hash and every field of the event, each
+// length prefixed so no two different events encode the same
+func auditHash(event *models.AuditEvent, details []byte) string {
+	h := sha256.New()
+	for _, field := range []string{
+		event.PrevHash,
+		strconv.FormatInt(event.Sequence, 10),
+		event.Id,
+		event.Event,
+		event.Outcome,
+		event.UserId,
+		event.ActorId,
+		event.IPAddress,
+		event.UserAgent,
+		string(details),
+		event.CreatedAt.UTC().Format(time.RFC3339Nano),
+	} {
+		var length [8]byte
+		binary.BigEndian.PutUint64(length[:], uint64(len(field)))
+		h.Write(length[:])
+		h.Write([]byte(field))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
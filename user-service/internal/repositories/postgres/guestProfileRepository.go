@@ -0,0 +1,174 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+)
+
+type GuestProfileRepository struct {
+	db *sql.DB
+}
+
+func NewGuestProfileRepository(db *sql.DB) *GuestProfileRepository {
+	return &GuestProfileRepository{db: db}
+}
+
+// GetGuestProfile returns the user's profile, nil if they haven't got one yet
+func (r *GuestProfileRepository) GetGuestProfile(ctx context.Context, userId string) (*models.GuestProfile, error) {
+	query := `
+		SELECT user_id, nationality, address_line1, address_line2, city, region, postal_code, country,
+			id_document_type, id_document_last4, id_document_country, id_document_expires_on,
+			bed_preference, floor_preference, pillow_preference, dietary_notes,
+			marketing_email_at, marketing_sms_at, loyalty_number, loyalty_enrolled_at, updated_at, updated_by
+		FROM guest_profiles WHERE user_id = $1
+	`
+	var profile models.GuestProfile
+	var documentType, documentLast4, documentCountry, loyaltyNumber, updatedBy sql.NullString
+	var documentExpiresOn, emailConsentedAt, smsConsentedAt, loyaltyEnrolledAt sql.NullTime
+	var updatedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, userId).Scan(
+		&profile.UserId,
+		&profile.Nationality,
+		&profile.Address.Line1,
+		&profile.Address.Line2,
+		&profile.Address.City,
+		&profile.Address.Region,
+		&profile.Address.PostalCode,
+		&profile.Address.Country,
+		&documentType,
+		&documentLast4,
+		&documentCountry,
+		&documentExpiresOn,
+		&profile.Preferences.Bed,
+		&profile.Preferences.Floor,
+		&profile.Preferences.Pillow,
+		&profile.DietaryNotes,
+		&emailConsentedAt,
+		&smsConsentedAt,
+		&loyaltyNumber,
+		&loyaltyEnrolledAt,
+		&updatedAt,
+		&updatedBy,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guest profile: %w", err)
+	}
+
+	if documentType.Valid {
+		profile.IdDocument = &models.IdDocument{
+			Type:           documentType.String,
+			NumberLast4:    documentLast4.String,
+			IssuingCountry: documentCountry.String,
+		}
+		if documentExpiresOn.Valid {
+			profile.IdDocument.ExpiresOn = documentExpiresOn.Time.Format("2006-01-02")
+		}
+	}
+	if emailConsentedAt.Valid {
+		profile.Consents.Email = true
+		profile.Consents.EmailConsentedAt = &emailConsentedAt.Time
+	}
+	if smsConsentedAt.Valid {
+		profile.Consents.SMS = true
+		profile.Consents.SMSConsentedAt = &smsConsentedAt.Time
+	}
+	if loyaltyNumber.Valid {
+		profile.Loyalty = &models.LoyaltyMembership{Number: loyaltyNumber.String, EnrolledAt: loyaltyEnrolledAt.Time}
+	}
+	if updatedAt.Valid {
+		profile.UpdatedAt = &updatedAt.Time
+	}
+	profile.UpdatedBy = updatedBy.String
+	return &profile, nil
+}
+
+// SaveGuestProfile creates or replaces the editable parts of a profile. Loyalty
+// membership is left alone, it only changes through EnrollLoyalty
+func (r *GuestProfileRepository) SaveGuestProfile(ctx context.Context, profile *models.GuestProfile) error {
+	query := `
+		INSERT INTO guest_profiles (
+			user_id, nationality, address_line1, address_line2, city, region, postal_code, country,
+			id_document_type, id_document_last4, id_document_country, id_document_expires_on,
+			bed_preference, floor_preference, pillow_preference, dietary_notes,
+			marketing_email_at, marketing_sms_at, updated_at, updated_by
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, NOW(), $19)
+		ON CONFLICT (user_id) DO UPDATE SET
+			nationality = EXCLUDED.nationality,
+			address_line1 = EXCLUDED.address_line1,
+			address_line2 = EXCLUDED.address_line2,
+			city = EXCLUDED.city,
+			region = EXCLUDED.region,
+			postal_code = EXCLUDED.postal_code,
+			country = EXCLUDED.country,
+			id_document_type = EXCLUDED.id_document_type,
+			id_document_last4 = EXCLUDED.id_document_last4,
+			id_document_country = EXCLUDED.id_document_country,
+			id_document_expires_on = EXCLUDED.id_document_expires_on,
+			bed_preference = EXCLUDED.bed_preference,
+			floor_preference = EXCLUDED.floor_preference,
+			pillow_preference = EXCLUDED.pillow_preference,
+			dietary_notes = EXCLUDED.dietary_notes,
+			marketing_email_at = EXCLUDED.marketing_email_at,
+			marketing_sms_at = EXCLUDED.marketing_sms_at,
+			updated_at = NOW(),
+			updated_by = EXCLUDED.updated_by
+	`
+	var documentType, documentLast4, documentCountry, documentExpiresOn sql.NullString
+	if doc := profile.IdDocument; doc != nil {
+		documentType = sql.NullString{String: doc.Type, Valid: true}
+		documentLast4 = sql.NullString{String: doc.NumberLast4, Valid: true}
+		documentCountry = sql.NullString{String: doc.IssuingCountry, Valid: true}
+		documentExpiresOn = sql.NullString{String: doc.ExpiresOn, Valid: doc.ExpiresOn != ""}
+	}
+
+	_, err := r.db.ExecContext(ctx, query,
+		profile.UserId,
+		profile.Nationality,
+		profile.Address.Line1,
+		profile.Address.Line2,
+		profile.Address.City,
+		profile.Address.Region,
+		profile.Address.PostalCode,
+		profile.Address.Country,
+		documentType,
+		documentLast4,
+		documentCountry,
+		documentExpiresOn,
+		profile.Preferences.Bed,
+		profile.Preferences.Floor,
+		profile.Preferences.Pillow,
+		profile.DietaryNotes,
+		profile.Consents.EmailConsentedAt,
+		profile.Consents.SMSConsentedAt,
+		sql.NullString{String: profile.UpdatedBy, Valid: profile.UpdatedBy != ""},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save guest profile: %w", err)
+	}
+	return nil
+}
+
+// EnrollLoyalty gives the user a membership number, creating an empty profile if
+// needed. Enrolling twice keeps the first number
+func (r *GuestProfileRepository) EnrollLoyalty(ctx context.Context, userId string) (*models.LoyaltyMembership, error) {
+	query := `
+		INSERT INTO guest_profiles (user_id, loyalty_number, loyalty_enrolled_at)
+		VALUES ($1, 'HS' || nextval('loyalty_number_seq'), NOW())
+		ON CONFLICT (user_id) DO UPDATE SET
+			loyalty_number = COALESCE(guest_profiles.loyalty_number, EXCLUDED.loyalty_number),
+			loyalty_enrolled_at = COALESCE(guest_profiles.loyalty_enrolled_at, EXCLUDED.loyalty_enrolled_at)
+		RETURNING loyalty_number, loyalty_enrolled_at
+	`
+	var membership models.LoyaltyMembership
+	if err := r.db.QueryRowContext(ctx, query, userId).Scan(&membership.Number, &membership.EnrolledAt); err != nil {
+		return nil, fmt.Errorf("failed to enroll in loyalty: %w", err)
+	}
+	return &membership, nil
+}
@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+)
+
+// ErrIdentityTaken is returned when a provider account is already linked to someone
+var ErrIdentityTaken = errors.New("identity is already linked to an account")
+
+type IdentityRepository struct {
+	db *sql.DB
+}
+
+func NewIdentityRepository(db *sql.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+func (r *IdentityRepository) CreateIdentity(ctx context.Context, identity *models.Identity) error {
+	query := `
+		INSERT INTO identities (id, user_id, provider, subject, email, created_at, last_login_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.ExecContext(ctx, query, identity.Id, identity.UserId, identity.Provider, identity.Subject,
+		identity.Email, identity.CreatedAt, identity.LastLoginAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			return ErrIdentityTaken
+		}
+		return fmt.Errorf("failed to create identity: %w", err)
+	}
+	return nil
+}
+
+// GetIdentity finds the identity for a provider account, nil if it isn't linked
+func (r *IdentityRepository) GetIdentity(ctx context.Context, provider, subject string) (*models.Identity, error) {
+	query := `SELECT ` + identityColumns + ` FROM identities WHERE provider = $1 AND subject = $2`
+
+	identity, err := scanIdentity(r.db.QueryRowContext(ctx, query, provider, subject))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+	return identity, nil
+}
+
+func (r *IdentityRepository) ListIdentities(ctx context.Context, userId string) ([]models.Identity, error) {
+	query := `SELECT ` + identityColumns + ` FROM identities WHERE user_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	defer rows.Close()
+
+	identities := []models.Identity{}
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan identity: %w", err)
+		}
+		identities = append(identities, *identity)
+	}
+	return identities, rows.Err()
+}
+
+// TouchIdentity records a login and the email the provider has for the account now
+func (r *IdentityRepository) TouchIdentity(ctx context.Context, id, email string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE identities SET last_login_at = NOW(), email = $2 WHERE id = $1`, id, email)
+	if err != nil {
+		return fmt.Errorf("failed to update identity: %w", err)
+	}
+	return nil
+}
+
+func (r *IdentityRepository) DeleteIdentity(ctx context.Context, userId, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM identities WHERE id = $1 AND user_id = $2`, id, userId)
+	if err != nil {
+		return fmt.Errorf("failed to delete identity: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("identity not found")
+	}
+	return nil
+}
+
+func (r *IdentityRepository) SaveLoginState(ctx context.Context, state *models.OIDCLoginState) error {
+	query := `
+		INSERT INTO oidc_login_states (state_hash, provider, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := r.db.ExecContext(ctx, query, state.StateHash, state.Provider, state.Nonce, state.CodeVerifier, state.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save login state: %w", err)
+	}
+	return nil
+}
+
+// ConsumeLoginState deletes and returns an unexpired login state, so each one can
+// complete a login only once. Expired states are swept out on the way
+func (r *IdentityRepository) ConsumeLoginState(ctx context.Context, stateHash string) (*models.OIDCLoginState, error) {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM oidc_login_states WHERE expires_at < NOW()`); err != nil {
+		return nil, fmt.Errorf("failed to sweep login states: %w", err)
+	}
+
+	query := `
+		DELETE FROM oidc_login_states WHERE state_hash = $1 AND expires_at > NOW()
+		RETURNING state_hash, provider, nonce, code_verifier, expires_at
+	`
+	var state models.OIDCLoginState
+	err := r.db.QueryRowContext(ctx, query, stateHash).Scan(
+		&state.StateHash,
+		&state.Provider,
+		&state.Nonce,
+		&state.CodeVerifier,
+		&state.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("login state is invalid or expired")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume login state: %w", err)
+	}
+	return &state, nil
+}
+
+const identityColumns = `id, user_id, provider, subject, email, created_at, last_login_at`
+
+func scanIdentity(row rowScanner) (*models.Identity, error) {
+	var identity models.Identity
+	var lastLoginAt sql.NullTime
+	err := row.Scan(
+		&identity.Id,
+		&identity.UserId,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+		&lastLoginAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if lastLoginAt.Valid {
+		identity.LastLoginAt = &lastLoginAt.Time
+	}
+	return &identity, nil
+}
@@ -0,0 +1,320 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+)
+
+var (
+	ErrInsufficientPoints   = errors.New("not enough points")
+	ErrDuplicateLedgerEntry = errors.New("ledger entry with this reference already exists")
+	ErrVoucherUnavailable   = errors.New("voucher is unknown, expired or already used")
+)
+
+type LoyaltyRepository struct {
+	db *sql.DB
+}
+
+func NewLoyaltyRepository(db *sql.DB) *LoyaltyRepository {
+	return &LoyaltyRepository{db: db}
+}
+
+// GetBalance returns the user's points balance and lifetime points, zero for users
+// who never earned any
+func (r *LoyaltyRepository) GetBalance(ctx context.Context, userId string) (int64, int64, error) {
+	var balance, lifetime int64
+	err := r.db.QueryRowContext(ctx, `SELECT balance, lifetime_points FROM loyalty_accounts WHERE user_id = $1`, userId).
+		Scan(&balance, &lifetime)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, 0, fmt.Errorf("failed to get loyalty balance: %w", err)
+	}
+	return balance, lifetime, nil
+}
+
+// PostEntry adds an entry to the ledger and moves the balance by its points. Entries
+// for one user are serialised on their account row, so concurrent earns and
+// redemptions can't lose updates or take the balance below zero
+func (r *LoyaltyRepository) PostEntry(ctx context.Context, entry *models.LedgerEntry) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.post(ctx, tx, entry); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *LoyaltyRepository) post(ctx context.Context, tx *sql.Tx, entry *models.LedgerEntry) error {
+	if _, err := tx.ExecContext(ctx, `INSERT INTO loyalty_accounts (user_id) VALUES ($1) ON CONFLICT DO NOTHING`,
+		entry.UserId); err != nil {
+		return fmt.Errorf("failed to create loyalty account: %w", err)
+	}
+	var balance int64
+	err := tx.QueryRowContext(ctx, `SELECT balance FROM loyalty_accounts WHERE user_id = $1 FOR UPDATE`, entry.UserId).
+		Scan(&balance)
+	if err != nil {
+		return fmt.Errorf("failed to lock loyalty account: %w", err)
+	}
+
+	// checked under the lock so a retry is reported as a duplicate, not as a new
+	// entry the balance may no longer cover
+	if entry.Reference != "" {
+		var exists bool
+		err := tx.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM loyalty_ledger WHERE user_id = $1 AND kind = $2 AND reference = $3)`,
+			entry.UserId, entry.Kind, entry.Reference).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check ledger: %w", err)
+		}
+		if exists {
+			return ErrDuplicateLedgerEntry
+		}
+	}
+	if balance+entry.Points < 0 {
+		return ErrInsufficientPoints
+	}
+
+	entry.BalanceAfter = balance + entry.Points
+	entry.CreatedAt = time.Now()
+	query := `
+		INSERT INTO loyalty_ledger (id, user_id, kind, points, balance_after, reference, description, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err = tx.ExecContext(ctx, query, entry.Id, entry.UserId, entry.Kind, entry.Points, entry.BalanceAfter,
+		entry.Reference, entry.Description, sql.NullString{String: entry.CreatedBy, Valid: entry.CreatedBy != ""},
+		entry.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			return ErrDuplicateLedgerEntry
+		}
+		return fmt.Errorf("failed to add ledger entry: %w", err)
+	}
+
+	// only points earned from stays count towards tiers
+	var earned int64
+	if entry.Kind == models.LedgerEarn {
+		earned = entry.Points
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE loyalty_accounts SET balance = $2, lifetime_points = lifetime_points + $3, updated_at = NOW()
+		WHERE user_id = $1
+	`, entry.UserId, entry.BalanceAfter, earned)
+	if err != nil {
+		return fmt.Errorf("failed to update loyalty balance: %w", err)
+	}
+	return nil
+}
+
+// GetEntryByReference finds the entry a reference already produced, nil if none
+func (r *LoyaltyRepository) GetEntryByReference(ctx context.Context, userId, kind, reference string) (*models.LedgerEntry, error) {
+	query := `SELECT ` + ledgerColumns + ` FROM loyalty_ledger WHERE user_id = $1 AND kind = $2 AND reference = $3`
+
+	entry, err := scanLedgerEntry(r.db.QueryRowContext(ctx, query, userId, kind, reference))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger entry: %w", err)
+	}
+	return entry, nil
+}
+
+// ListEntries returns one page of the user's ledger, newest first, and the total count
+func (r *LoyaltyRepository) ListEntries(ctx context.Context, userId string, limit, offset int) ([]models.LedgerEntry, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM loyalty_ledger WHERE user_id = $1`, userId).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count ledger entries: %w", err)
+	}
+
+	query := `SELECT ` + ledgerColumns + ` FROM loyalty_ledger WHERE user_id = $1
+		ORDER BY created_at DESC, id LIMIT $2 OFFSET $3`
+	rows, err := r.db.QueryContext(ctx, query, userId, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list ledger entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.LedgerEntry{}
+	for rows.Next() {
+		entry, err := scanLedgerEntry(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, total, rows.Err()
+}
+
+// CreateVoucher debits the voucher's points and stores it in one transaction
+func (r *LoyaltyRepository) CreateVoucher(ctx context.Context, voucher *models.LoyaltyVoucher, entry *models.LedgerEntry) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.post(ctx, tx, entry); err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO loyalty_vouchers (code, user_id, points, value, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err = tx.ExecContext(ctx, query, voucher.Code, voucher.UserId, voucher.Points, voucher.Value,
+		voucher.ExpiresAt, voucher.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create voucher: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *LoyaltyRepository) ListVouchers(ctx context.Context, userId string) ([]models.LoyaltyVoucher, error) {
+	query := `SELECT ` + voucherColumns + ` FROM loyalty_vouchers WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vouchers: %w", err)
+	}
+	defer rows.Close()
+
+	vouchers := []models.LoyaltyVoucher{}
+	for rows.Next() {
+		voucher, err := scanVoucher(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan voucher: %w", err)
+		}
+		vouchers = append(vouchers, *voucher)
+	}
+	return vouchers, rows.Err()
+}
+
+// RedeemVoucher marks an unexpired voucher used by a payment. Redeeming it again for
+// the same payment returns the voucher, so payment retries are safe
+func (r *LoyaltyRepository) RedeemVoucher(ctx context.Context, code, reference string) (*models.LoyaltyVoucher, error) {
+	query := `
+		UPDATE loyalty_vouchers SET redeemed_at = NOW(), redeemed_reference = $2
+		WHERE code = $1 AND redeemed_at IS NULL AND expires_at > NOW()
+		RETURNING ` + voucherColumns
+	voucher, err := scanVoucher(r.db.QueryRowContext(ctx, query, code, reference))
+	if err == nil {
+		return voucher, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to redeem voucher: %w", err)
+	}
+
+	query = `SELECT ` + voucherColumns + ` FROM loyalty_vouchers WHERE code = $1 AND redeemed_reference = $2`
+	voucher, err = scanVoucher(r.db.QueryRowContext(ctx, query, code, reference))
+	if err == sql.ErrNoRows {
+		return nil, ErrVoucherUnavailable
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get voucher: %w", err)
+	}
+	return voucher, nil
+}
+
+// DeleteOpenVouchers removes the user's unused vouchers, used when their account is deleted
+func (r *LoyaltyRepository) DeleteOpenVouchers(ctx context.Context, userId string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM loyalty_vouchers WHERE user_id = $1 AND redeemed_at IS NULL`, userId)
+	if err != nil {
+		return fmt.Errorf("failed to delete vouchers: %w", err)
+	}
+	return nil
+}
+
+// ListTiers returns the tiers from lowest threshold to highest
+func (r *LoyaltyRepository) ListTiers(ctx context.Context) ([]models.LoyaltyTier, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT name, threshold, earn_multiplier, benefits FROM loyalty_tiers ORDER BY threshold`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list loyalty tiers: %w", err)
+	}
+	defer rows.Close()
+
+	tiers := []models.LoyaltyTier{}
+	for rows.Next() {
+		var tier models.LoyaltyTier
+		if err := rows.Scan(&tier.Name, &tier.Threshold, &tier.EarnMultiplier, pq.Array(&tier.Benefits)); err != nil {
+			return nil, fmt.Errorf("failed to scan loyalty tier: %w", err)
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, rows.Err()
+}
+
+func (r *LoyaltyRepository) UpdateTier(ctx context.Context, tier *models.LoyaltyTier) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE loyalty_tiers SET threshold = $2, earn_multiplier = $3, benefits = $4 WHERE name = $1`,
+		tier.Name, tier.Threshold, tier.EarnMultiplier, pq.Array(tier.Benefits))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			return fmt.Errorf("another tier already has threshold %d", tier.Threshold)
+		}
+		return fmt.Errorf("failed to update loyalty tier: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("loyalty tier not found")
+	}
+	return nil
+}
+
+const ledgerColumns = `id, user_id, kind, points, balance_after, reference, description, COALESCE(created_by, ''), created_at`
+
+func scanLedgerEntry(row rowScanner) (*models.LedgerEntry, error) {
+	var entry models.LedgerEntry
+	err := row.Scan(
+		&entry.Id,
+		&entry.UserId,
+		&entry.Kind,
+		&entry.Points,
+		&entry.BalanceAfter,
+		&entry.Reference,
+		&entry.Description,
+		&entry.CreatedBy,
+		&entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+const voucherColumns = `code, user_id, points, value, expires_at, redeemed_at, COALESCE(redeemed_reference, ''), created_at`
+
+func scanVoucher(row rowScanner) (*models.LoyaltyVoucher, error) {
+	var voucher models.LoyaltyVoucher
+	var redeemedAt sql.NullTime
+	err := row.Scan(
+		&voucher.Code,
+		&voucher.UserId,
+		&voucher.Points,
+		&voucher.Value,
+		&voucher.ExpiresAt,
+		&redeemedAt,
+		&voucher.RedeemedReference,
+		&voucher.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if redeemedAt.Valid {
+		voucher.RedeemedAt = &redeemedAt.Time
+	}
+	return &voucher, nil
+}
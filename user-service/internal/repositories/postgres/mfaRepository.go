@@ -0,0 +1,183 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+)
+
+type MFARepository struct {
+	db *sql.DB
+}
+
+func NewMFARepository(db *sql.DB) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+// SavePendingEnrollment stores a new, not yet confirmed secret. It never replaces an
+// enabled enrollment
+func (r *MFARepository) SavePendingEnrollment(ctx context.Context, userId, secretEncrypted string) error {
+	query := `
+		INSERT INTO user_mfa (user_id, secret_encrypted, enabled, last_used_step, created_at)
+		VALUES ($1, $2, FALSE, 0, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_encrypted = EXCLUDED.secret_encrypted, last_used_step = 0, created_at = NOW()
+		WHERE user_mfa.enabled = FALSE
+	`
+	result, err := r.db.ExecContext(ctx, query, userId, secretEncrypted)
+	if err != nil {
+		return fmt.Errorf("failed to save mfa enrollment: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("two-factor authentication is already enabled")
+	}
+	return nil
+}
+
+func (r *MFARepository) GetMFA(ctx context.Context, userId string) (*models.UserMFA, error) {
+	query := `SELECT user_id, secret_encrypted, enabled, enabled_at, last_used_step, created_at FROM user_mfa WHERE user_id = $1`
+
+	var mfa models.UserMFA
+	var enabledAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, userId).Scan(
+		&mfa.UserId,
+		&mfa.SecretEncrypted,
+		&mfa.Enabled,
+		&enabledAt,
+		&mfa.LastUsedStep,
+		&mfa.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("mfa not configured")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mfa: %w", err)
+	}
+	if enabledAt.Valid {
+		mfa.EnabledAt = &enabledAt.Time
+	}
+	return &mfa, nil
+}
+
+// EnableMFA turns on a confirmed enrollment and replaces the user's recovery codes
+func (r *MFARepository) EnableMFA(ctx context.Context, userId string, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE user_mfa SET enabled = TRUE, enabled_at = NOW(), last_used_step = $2 WHERE user_id = $1 AND enabled = FALSE`,
+		userId, step)
+	if err != nil {
+		return fmt.Errorf("failed to enable mfa: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("no pending mfa enrollment")
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userId, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *MFARepository) ReplaceRecoveryCodes(ctx context.Context, userId string, recoveryCodeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userId, recoveryCodeHashes); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userId string, hashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userId); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	for _, hash := range hashes {
+		_, err := tx.ExecContext(ctx, `INSERT INTO mfa_recovery_codes (id, user_id, code_hash) VALUES ($1, $2, $3)`,
+			uuid.New().String(), userId, hash)
+		if err != nil {
+			return fmt.Errorf("failed to store recovery code: %w", err)
+		}
+	}
+	return nil
+}
+
+// DisableMFA removes the enrollment and its recovery codes
+func (r *MFARepository) DisableMFA(ctx context.Context, userId string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userId); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userId); err != nil {
+		return fmt.Errorf("failed to disable mfa: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// UseStep records a TOTP step as used. It returns false if that step or a later one
+// was already used, so each code only works once
+func (r *MFARepository) UseStep(ctx context.Context, userId string, step int64) (bool, error) {
+	query := `UPDATE user_mfa SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`
+
+	result, err := r.db.ExecContext(ctx, query, userId, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to record mfa code: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record mfa code: %w", err)
+	}
+	return rows == 1, nil
+}
+
+// ConsumeRecoveryCode marks an unused recovery code as used, returning false if
+// there is no such code
+func (r *MFARepository) ConsumeRecoveryCode(ctx context.Context, userId, codeHash string) (bool, error) {
+	query := `UPDATE mfa_recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, userId, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	return rows == 1, nil
+}
+
+func (r *MFARepository) CountRecoveryCodes(ctx context.Context, userId string) (int, error) {
+	query := `SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, userId).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
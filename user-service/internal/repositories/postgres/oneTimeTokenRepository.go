@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+)
+
+type OneTimeTokenRepository struct {
+	db *sql.DB
+}
+
+func NewOneTimeTokenRepository(db *sql.DB) *OneTimeTokenRepository {
+	return &OneTimeTokenRepository{db: db}
+}
+
+func (r *OneTimeTokenRepository) CreateToken(ctx context.Context, token *models.OneTimeToken) error {
+	query := `
+		INSERT INTO one_time_tokens (id, user_id, purpose, token_hash, expires_at, created_at, new_email)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+	`
+	_, err := r.db.ExecContext(ctx, query, token.Id, token.UserId, token.Purpose, token.TokenHash, token.ExpiresAt,
+		token.CreatedAt, token.NewEmail)
+	if err != nil {
+		return fmt.Errorf("failed to store token: %w", err)
+	}
+	return nil
+}
+
+// GetToken returns an unused, unexpired token without consuming it, so a request
+// can be validated before the token is spent
+func (r *OneTimeTokenRepository) GetToken(ctx context.Context, purpose models.TokenPurpose, tokenHash string) (*models.OneTimeToken, error) {
+	query := `
+		SELECT id, user_id, purpose, token_hash, expires_at, created_at, COALESCE(new_email, '')
+		FROM one_time_tokens
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+	`
+	var token models.OneTimeToken
+	err := r.db.QueryRowContext(ctx, query, tokenHash, purpose).Scan(
+		&token.Id,
+		&token.UserId,
+		&token.Purpose,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&token.NewEmail,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("token is invalid, expired or already used")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+	return &token, nil
+}
+
+// ConsumeToken marks an unused, unexpired token as used and returns it.
+// A token can only be consumed once, even by concurrent requests
+func (r *OneTimeTokenRepository) ConsumeToken(ctx context.Context, purpose models.TokenPurpose, tokenHash string) (*models.OneTimeToken, error) {
+	query := `
+		UPDATE one_time_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, purpose, token_hash, expires_at, created_at, used_at, COALESCE(new_email, '')
+	`
+	var token models.OneTimeToken
+	var usedAt time.Time
+	err := r.db.QueryRowContext(ctx, query, tokenHash, purpose).Scan(
+		&token.Id,
+		&token.UserId,
+		&token.Purpose,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&usedAt,
+		&token.NewEmail,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("token is invalid, expired or already used")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume token: %w", err)
+	}
+	token.UsedAt = &usedAt
+	return &token, nil
+}
+
+// InvalidateTokens burns every outstanding token of a purpose for the user
+func (r *OneTimeTokenRepository) InvalidateTokens(ctx context.Context, userId string, purpose models.TokenPurpose) error {
+	query := `UPDATE one_time_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, userId, purpose)
+	if err != nil {
+		return fmt.Errorf("failed to invalidate tokens: %w", err)
+	}
+	return nil
+}
+
+// LastIssuedAt returns when the newest token of a purpose was issued to the user
+func (r *OneTimeTokenRepository) LastIssuedAt(ctx context.Context, userId string, purpose models.TokenPurpose) (time.Time, error) {
+	query := `SELECT COALESCE(MAX(created_at), 'epoch') FROM one_time_tokens WHERE user_id = $1 AND purpose = $2`
+
+	var issuedAt time.Time
+	if err := r.db.QueryRowContext(ctx, query, userId, purpose).Scan(&issuedAt); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get last token: %w", err)
+	}
+	return issuedAt, nil
+}
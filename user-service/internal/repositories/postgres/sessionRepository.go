@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+)
+
+type SessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	query := `
+		INSERT INTO sessions (id, user_id, user_agent, ip_address, created_at, last_used_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.ExecContext(ctx, query, session.Id, session.UserId, session.UserAgent, session.IPAddress, session.CreatedAt, session.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// records the latest use of a session, e.g. on refresh
+func (r *SessionRepository) TouchSession(ctx context.Context, id string, client models.ClientInfo) error {
+	query := `UPDATE sessions SET last_used_at = NOW(), ip_address = $2, user_agent = $3 WHERE id = $1`
+
+	_, err := r.db.ExecContext(ctx, query, id, client.IPAddress, client.UserAgent)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+func (r *SessionRepository) GetSessionById(ctx context.Context, id string) (*models.Session, error) {
+	query := `
+		SELECT id, user_id, user_agent, ip_address, created_at, last_used_at, revoked_at
+		FROM sessions
+		WHERE id = $1
+	`
+	session, err := scanSession(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return session, nil
+}
+
+func (r *SessionRepository) GetActiveSessions(ctx context.Context, userId string) ([]models.Session, error) {
+	query := `
+		SELECT id, user_id, user_agent, ip_address, created_at, last_used_at, revoked_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY last_used_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, *session)
+	}
+	return sessions, rows.Err()
+}
+
+func (r *SessionRepository) RevokeSession(ctx context.Context, id string) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSession(row rowScanner) (*models.Session, error) {
+	var session models.Session
+	var userAgent, ipAddress sql.NullString
+	var revokedAt sql.NullTime
+	err := row.Scan(
+		&session.Id,
+		&session.UserId,
+		&userAgent,
+		&ipAddress,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	session.UserAgent = userAgent.String
+	session.IPAddress = ipAddress.String
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+	return &session, nil
+}
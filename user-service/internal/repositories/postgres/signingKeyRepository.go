@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
+)
+
+// signingKeyLock is the advisory lock instances take turns under to rotate keys
+const signingKeyLock = 0x7369676e696e676b
+
+// SigningKeyRepository stores signing keys with their private halves encrypted
+type SigningKeyRepository struct {
+	db  *sql.DB
+	box *security.SecretBox
+}
+
+func NewSigningKeyRepository(db *sql.DB, box *security.SecretBox) *SigningKeyRepository {
+	return &SigningKeyRepository{db: db, box: box}
+}
+
+var _ security.KeyStore = (*SigningKeyRepository)(nil)
+
+// LoadKeys returns every key, newest first. Keys saved before they were encrypted are
+// encrypted in place as they are read
+func (r *SigningKeyRepository) LoadKeys(ctx context.Context) ([]security.SigningKey, error) {
+	query := `SELECT id, private_key, created_at, retired_at FROM signing_keys ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []security.SigningKey
+	plaintext := map[string]string{}
+	for rows.Next() {
+		var key security.SigningKey
+		var stored string
+		var retiredAt sql.NullTime
+		if err := rows.Scan(&key.Id, &stored, &key.CreatedAt, &retiredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan signing key: %w", err)
+		}
+		encoded := stored
+		if strings.HasPrefix(stored, "-----BEGIN") {
+			plaintext[key.Id] = stored
+		} else if encoded, err = r.box.Open(stored); err != nil {
+			return nil, fmt.Errorf("failed to decrypt signing key %s: %w", key.Id, err)
+		}
+		if key.PrivateKey, err = security.DecodePrivateKey(encoded); err != nil {
+			return nil, fmt.Errorf("failed to decode signing key %s: %w", key.Id, err)
+		}
+		if retiredAt.Valid {
+			key.RetiredAt = &retiredAt.Time
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for id, encoded := range plaintext {
+		if err := r.encryptStoredKey(ctx, id, encoded); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+func (r *SigningKeyRepository) encryptStoredKey(ctx context.Context, id, encoded string) error {
+	sealed, err := r.box.Seal(encoded)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt signing key %s: %w", id, err)
+	}
+	query := `UPDATE signing_keys SET private_key = $2 WHERE id = $1 AND private_key = $3`
+	if _, err := r.db.ExecContext(ctx, query, id, sealed, encoded); err != nil {
+		return fmt.Errorf("failed to encrypt signing key %s: %w", id, err)
+	}
+	return nil
+}
+
+// RotateKey retires every current key and saves the new one in a single transaction,
+// under an advisory lock so instances rotating together can't leave two current keys
+func (r *SigningKeyRepository) RotateKey(ctx context.Context, key *security.SigningKey, since time.Time) (bool, error) {
+	encoded, err := security.EncodePrivateKey(key.PrivateKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode signing key: %w", err)
+	}
+	sealed, err := r.box.Seal(encoded)
+	if err != nil {
+		return false, fmt.Errorf("failed to encrypt signing key: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, signingKeyLock); err != nil {
+		return false, fmt.Errorf("failed to lock signing keys: %w", err)
+	}
+	var fresh bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM signing_keys WHERE retired_at IS NULL AND created_at > $1)`,
+		since).Scan(&fresh)
+	if err != nil {
+		return false, fmt.Errorf("failed to check signing keys: %w", err)
+	}
+	if fresh {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE signing_keys SET retired_at = $1 WHERE retired_at IS NULL`, key.CreatedAt); err != nil {
+		return false, fmt.Errorf("failed to retire signing keys: %w", err)
+	}
+	query := `INSERT INTO signing_keys (id, algorithm, private_key, created_at) VALUES ($1, 'EdDSA', $2, $3)`
+	if _, err := tx.ExecContext(ctx, query, key.Id, sealed, key.CreatedAt); err != nil {
+		return false, fmt.Errorf("failed to save signing key: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
@@ -3,12 +3,18 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
 	"github.com/lib/pq"
 )
 
+// ErrEmailTaken is returned when an email change collides with another account
+var ErrEmailTaken = errors.New("email address is already in use")
+
 type UserRepository struct {
 	db *sql.DB
 }
@@ -35,20 +41,23 @@ func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) erro
 		return nil
 }
 
-func (r *UserRepository) GetUserByEmail(ctx context.Context, userId string)(*models.User, error) {
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string)(*models.User, error) {
 	query := `
-		SELECT id, email, first_name, last_name, role 
+		SELECT id, email, first_name, last_name, role, email_verified, suspended_at IS NOT NULL, password_reset_required
 		FROM Users
 		WHERE email = $1
 	`
 
 	var user models.User
-	err := r.db.QueryRowContext(ctx, query, userId).Scan(
+	err := r.db.QueryRowContext(ctx, query, email).Scan(
 		&user.Id,
 		&user.Email,
 		&user.FirstName,
 		&user.LastName,
 		&user.Role,
+		&user.EmailVerified,
+		&user.Suspended,
+		&user.PasswordResetRequired,
 	)
 
 	if err == sql.ErrNoRows {
@@ -62,7 +71,8 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, userId string)(*mod
 
 func (r *UserRepository) GetUserByEmailAuth(ctx context.Context, email string)(*models.User, error) {
 	query := `
-		SELECT id, email, password_hash, first_name, last_name, phone, role 
+		SELECT id, email, password_hash, first_name, last_name, COALESCE(phone, ''), role, email_verified,
+			suspended_at IS NOT NULL, password_reset_required
 		FROM Users
 		WHERE email = $1
 	`
@@ -76,6 +86,9 @@ func (r *UserRepository) GetUserByEmailAuth(ctx context.Context, email string)(*
 		&user.LastName,
 		&user.Phone,
 		&user.Role,
+		&user.EmailVerified,
+		&user.Suspended,
+		&user.PasswordResetRequired,
 	)
 
 	if err == sql.ErrNoRows {
@@ -88,27 +101,100 @@ func (r *UserRepository) GetUserByEmailAuth(ctx context.Context, email string)(*
 }
 
 func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
-	query := `UPDATE users SET first_name = $1, last_name = $2, phone = $3 WHERE email = $4`
+	query := `UPDATE users SET first_name = $1, last_name = $2, phone = $3 WHERE id = $4`
 
-	_, err := r.db.ExecContext(ctx, query, user.FirstName, user.LastName, user.Phone, user.Email,)
+	_, err := r.db.ExecContext(ctx, query, user.FirstName, user.LastName, user.Phone, user.Id,)
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 	return nil 
 }
 
-func (r *UserRepository) UpdatePassword(ctx context.Context, email, passwordHash string) error {
-	query := `UPDATE users SET password_hash = $1 WHERE email =$2`
+// UpdatePassword sets a new password hash. The one it replaces goes into the
+// password history, which is trimmed to the keepHistory most recent
+func (r *UserRepository) UpdatePassword(ctx context.Context, id, passwordHash string, keepHistory int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	_, err := r.db.ExecContext(ctx, query, passwordHash, email)
+	if keepHistory > 0 {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO password_history (user_id, password_hash)
+			SELECT id, password_hash FROM users WHERE id = $1 AND password_hash <> ''
+		`, id)
+		if err != nil {
+			return fmt.Errorf("failed to save password history: %w", err)
+		}
+	}
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM password_history WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2
+		)
+	`, id, keepHistory)
 	if err != nil {
+		return fmt.Errorf("failed to trim password history: %w", err)
+	}
+
+	query := `UPDATE users SET password_hash = $1, password_reset_required = FALSE WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, query, passwordHash, id); err != nil {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
+// RehashPassword swaps in a stronger hash of the same password. It does nothing if
+// the password was changed in the meantime
+func (r *UserRepository) RehashPassword(ctx context.Context, id, oldHash, newHash string) error {
+	query := `UPDATE users SET password_hash = $1 WHERE id = $2 AND password_hash = $3`
+
+	if _, err := r.db.ExecContext(ctx, query, newHash, id, oldHash); err != nil {
+		return fmt.Errorf("failed to rehash password: %w", err)
+	}
+	return nil
+}
+
+// GetPasswordHistory returns the user's earlier password hashes, newest first
+func (r *UserRepository) GetPasswordHistory(ctx context.Context, id string, limit int) ([]string, error) {
+	query := `SELECT password_hash FROM password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get password history: %w", err)
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("failed to scan password history: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+func (r *UserRepository) GetPasswordHash(ctx context.Context, id string) (string, error) {
+	var passwordHash string
+	err := r.db.QueryRowContext(ctx, `SELECT password_hash FROM users WHERE id = $1`, id).Scan(&passwordHash)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get user: %w", err)
+	}
+	return passwordHash, nil
+}
+
 func (r *UserRepository) GetUserById(ctx context.Context, id string) (*models.User, error) {
-    query := `SELECT id, email, first_name, last_name, phone, role FROM users WHERE id = $1`
+    query := `SELECT id, email, first_name, last_name, COALESCE(phone, ''), role, email_verified, suspended_at IS NOT NULL,
+        password_reset_required FROM users WHERE id = $1`
     
     var user models.User
     err := r.db.QueryRowContext(ctx, query, id).Scan(
@@ -118,6 +204,9 @@ func (r *UserRepository) GetUserById(ctx context.Context, id string) (*models.Us
         &user.LastName,
         &user.Phone,
         &user.Role,
+        &user.EmailVerified,
+        &user.Suspended,
+        &user.PasswordResetRequired,
     )
     
     if err == sql.ErrNoRows {
@@ -129,3 +218,286 @@ func (r *UserRepository) GetUserById(ctx context.Context, id string) (*models.Us
     return &user, nil
 }
 
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, id string) error {
+	query := `UPDATE users SET email_verified = TRUE, email_verified_at = NOW() WHERE id = $1`
+
+	_, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark email verified: %w", err)
+	}
+	return nil
+}
+
+// ChangeEmail switches the user to a new, already verified address in one statement.
+// The unique constraint on email decides races between two accounts claiming it
+func (r *UserRepository) ChangeEmail(ctx context.Context, id, newEmail string) error {
+	query := `UPDATE users SET email = $1, email_verified = TRUE, email_verified_at = NOW() WHERE id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, newEmail, id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			return ErrEmailTaken
+		}
+		return fmt.Errorf("failed to change email: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// AnonymizeUser erases a deleted account's personal data in place. The row stays so
+// ids held by other services and the audit log still resolve, but it can never log in
+// again: the password hash is blanked, provider logins unlinked and the account
+// suspended. MFA secrets, old password hashes, outstanding email tokens and where
+// sessions came from go with it
+func (r *UserRepository) AnonymizeUser(ctx context.Context, id, placeholderEmail string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE users SET
+			email = $2, password_hash = '', first_name = 'Deleted', last_name = 'User', phone = NULL,
+			email_verified = FALSE, email_verified_at = NULL, password_reset_required = FALSE,
+			suspended_at = COALESCE(suspended_at, NOW()), suspension_reason = 'account deleted',
+			deleted_at = COALESCE(deleted_at, NOW())
+		WHERE id = $1
+	`, id, placeholderEmail)
+	if err != nil {
+		return fmt.Errorf("failed to anonymize user: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	for _, query := range []string{
+		`DELETE FROM mfa_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM user_mfa WHERE user_id = $1`,
+		`DELETE FROM one_time_tokens WHERE user_id = $1`,
+		`DELETE FROM identities WHERE user_id = $1`,
+		`DELETE FROM guest_profiles WHERE user_id = $1`,
+		`DELETE FROM password_history WHERE user_id = $1`,
+		`UPDATE sessions SET user_agent = NULL, ip_address = NULL WHERE user_id = $1`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("failed to delete user data: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *UserRepository) GetLoginState(ctx context.Context, id string) (*models.LoginState, error) {
+	query := `SELECT failed_login_attempts, last_failed_login_at, locked_until FROM users WHERE id = $1`
+
+	var state models.LoginState
+	var lastFailedAt, lockedUntil sql.NullTime
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&state.FailedAttempts, &lastFailedAt, &lockedUntil); err != nil {
+		return nil, fmt.Errorf("failed to get login state: %w", err)
+	}
+	if lastFailedAt.Valid {
+		state.LastFailedAt = &lastFailedAt.Time
+	}
+	if lockedUntil.Valid {
+		state.LockedUntil = &lockedUntil.Time
+	}
+	return &state, nil
+}
+
+// RecordFailedLogin counts a failed login. Failures older than window no longer count,
+// and reaching lockAfter failures locks the account for lockFor
+func (r *UserRepository) RecordFailedLogin(ctx context.Context, id string, window time.Duration, lockAfter int,
+	lockFor time.Duration) (*models.LoginState, error) {
+	query := `
+		UPDATE users SET
+			failed_login_attempts = CASE
+				WHEN last_failed_login_at IS NULL OR last_failed_login_at < NOW() - make_interval(secs => $2) THEN 1
+				ELSE failed_login_attempts + 1
+			END,
+			last_failed_login_at = NOW()
+		WHERE id = $1
+		RETURNING failed_login_attempts
+	`
+	var attempts int
+	if err := r.db.QueryRowContext(ctx, query, id, window.Seconds()).Scan(&attempts); err != nil {
+		return nil, fmt.Errorf("failed to record failed login: %w", err)
+	}
+
+	if attempts >= lockAfter {
+		lockQuery := `UPDATE users SET locked_until = NOW() + make_interval(secs => $2) WHERE id = $1`
+		if _, err := r.db.ExecContext(ctx, lockQuery, id, lockFor.Seconds()); err != nil {
+			return nil, fmt.Errorf("failed to lock account: %w", err)
+		}
+	}
+	return r.GetLoginState(ctx, id)
+}
+
+// ResetFailedLogins clears failed attempts and any lock
+func (r *UserRepository) ResetFailedLogins(ctx context.Context, id string) error {
+	query := `UPDATE users SET failed_login_attempts = 0, last_failed_login_at = NULL, locked_until = NULL WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to reset failed logins: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+func (r *UserRepository) UpdateRole(ctx context.Context, id string, role models.UserRole) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE users SET role = $1 WHERE id = $2`, role, id)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+const adminUserColumns = `id, email, first_name, last_name, COALESCE(phone, ''), role, email_verified, created_at,
+	suspended_at, COALESCE(suspension_reason, ''), password_reset_required, locked_until`
+
+func scanAdminUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	var createdAt, suspendedAt, lockedUntil sql.NullTime
+	err := row.Scan(
+		&user.Id,
+		&user.Email,
+		&user.FirstName,
+		&user.LastName,
+		&user.Phone,
+		&user.Role,
+		&user.EmailVerified,
+		&createdAt,
+		&suspendedAt,
+		&user.SuspensionReason,
+		&user.PasswordResetRequired,
+		&lockedUntil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if createdAt.Valid {
+		user.CreatedAt = &createdAt.Time
+	}
+	if suspendedAt.Valid {
+		user.Suspended = true
+		user.SuspendedAt = &suspendedAt.Time
+	}
+	if lockedUntil.Valid && lockedUntil.Time.After(time.Now()) {
+		user.LockedUntil = &lockedUntil.Time
+	}
+	return &user, nil
+}
+
+// GetUserDetails returns a user with the account state admins see
+func (r *UserRepository) GetUserDetails(ctx context.Context, id string) (*models.User, error) {
+	query := `SELECT ` + adminUserColumns + ` FROM users WHERE id = $1`
+
+	user, err := scanAdminUser(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// SearchUsers returns one page of users matching the filter, newest first, and how
+// many users match in total
+func (r *UserRepository) SearchUsers(ctx context.Context, filter *models.UserFilter) ([]models.User, int, error) {
+	where := []string{"1 = 1"}
+	args := []interface{}{}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Query != "" {
+		//% and _ in the query are matched literally
+		p := addArg("%" + escapeLike(strings.ToLower(filter.Query)) + "%")
+		where = append(where, fmt.Sprintf(`(LOWER(email) LIKE %[1]s ESCAPE '\' OR LOWER(first_name || ' ' || last_name) LIKE %[1]s ESCAPE '\' `+
+			`OR phone LIKE %[1]s ESCAPE '\')`, p))
+	}
+	if filter.Role != "" {
+		where = append(where, "role = "+addArg(filter.Role))
+	}
+	switch filter.Status {
+	case models.UserStatusActive:
+		where = append(where, "suspended_at IS NULL")
+	case models.UserStatusSuspended:
+		where = append(where, "suspended_at IS NOT NULL")
+	case models.UserStatusLocked:
+		where = append(where, "locked_until > NOW()")
+	}
+	conditions := strings.Join(where, " AND ")
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE `+conditions, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	query := `SELECT ` + adminUserColumns + ` FROM users WHERE ` + conditions +
+		` ORDER BY created_at DESC, id LIMIT ` + addArg(filter.Limit) + ` OFFSET ` + addArg(filter.Offset)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanAdminUser(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *user)
+	}
+	return users, total, rows.Err()
+}
+
+// escapeLike escapes LIKE wildcards in user input, for patterns with ESCAPE '\'
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// SetSuspended suspends an account with a reason, or reactivates it
+func (r *UserRepository) SetSuspended(ctx context.Context, id string, suspended bool, reason string) error {
+	query := `UPDATE users SET suspended_at = NULL, suspension_reason = NULL WHERE id = $1`
+	args := []interface{}{id}
+	if suspended {
+		query = `UPDATE users SET suspended_at = COALESCE(suspended_at, NOW()), suspension_reason = $2 WHERE id = $1`
+		args = append(args, reason)
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update suspension: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// RequirePasswordReset stops the user logging in until they reset their password
+func (r *UserRepository) RequirePasswordReset(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE users SET password_reset_required = TRUE WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to require password reset: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `100\% \_off\\`, escapeLike(`100% _off\`))
+	assert.Equal(t, "ada@example.com", escapeLike("ada@example.com"))
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
+)
+
+// AuditService records security events. Recording never fails the action being
+// audited, errors are only logged
+type AuditService struct {
+	auditRepo *postgres.AuditRepository
+}
+
+func NewAuditService(auditRepo *postgres.AuditRepository) *AuditService {
+	return &AuditService{auditRepo: auditRepo}
+}
+
+// Record records an action that went through, by userId or by actorId on them
+func (s *AuditService) Record(ctx context.Context, event, userId, actorId, ipAddress string, details map[string]interface{}) {
+	s.record(ctx, &models.AuditEvent{
+		Event:     event,
+		Outcome:   models.AuditOutcomeSuccess,
+		UserId:    userId,
+		ActorId:   actorId,
+		IPAddress: ipAddress,
+		Details:   details,
+	})
+}
+
+// RecordAttempt records something a client tried, with its user agent and
+// whether it worked. userId is empty when the attempt couldn't be tied to a user
+func (s *AuditService) RecordAttempt(ctx context.Context, event, userId string, client models.ClientInfo, outcome string,
+	details map[string]interface{}) {
+	s.record(ctx, &models.AuditEvent{
+		Event:     event,
+		Outcome:   outcome,
+		UserId:    userId,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Details:   details,
+	})
+}
+
+func (s *AuditService) record(ctx context.Context, record *models.AuditEvent) {
+	record.Id = uuid.New().String()
+	record.CreatedAt = time.Now()
+	if err := s.auditRepo.RecordEvent(ctx, record); err != nil {
+		log.Printf("failed to record %s audit event for user %s: %v", record.Event, record.UserId, err)
+	}
+}
+
+const maxAuditPageSize = 500
+
+// ListEvents returns a page of events matching the filter, newest first
+func (s *AuditService) ListEvents(ctx context.Context, filter models.AuditFilter, page, limit int) (*models.AuditEventList, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit <= 0 || limit > maxAuditPageSize {
+		limit = 100
+	}
+	switch filter.Outcome {
+	case "", models.AuditOutcomeSuccess, models.AuditOutcomeFailure:
+	default:
+		return nil, fmt.Errorf("invalid outcome: %s", filter.Outcome)
+	}
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
+
+	events, total, err := s.auditRepo.ListEvents(ctx, &filter)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AuditEventList{
+		Events: events,
+		Total:  total,
+		Page:   page,
+		Limit:  limit,
+	}, nil
+}
+
+// ListAllEvents returns every event matching the filter, newest first, reading the log
+// a page at a time. Events recorded after the call starts are left out so the pages
+// don't shift under it
+func (s *AuditService) ListAllEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	if filter.To.IsZero() {
+		filter.To = time.Now()
+	}
+	events := []models.AuditEvent{}
+	for page := 1; ; page++ {
+		list, err := s.ListEvents(ctx, filter, page, maxAuditPageSize)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, list.Events...)
+		if len(list.Events) < maxAuditPageSize || len(events) >= list.Total {
+			return events, nil
+		}
+	}
+}
+
+// VerifyChain checks that no event in the audit log was altered or removed
+func (s *AuditService) VerifyChain(ctx context.Context) (*models.AuditChainReport, error) {
+	return s.auditRepo.VerifyChain(ctx)
+}
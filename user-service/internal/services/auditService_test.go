@@ -0,0 +1,101 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/services"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAuditService(t *testing.T) (*services.AuditService, *sql.DB) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set, skipping integration test")
+	}
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, database.InitializeSchema(db))
+	return services.NewAuditService(postgres.NewAuditRepository(db)), db
+}
+
+func TestAuditEventsAreChainedAndFiltered(t *testing.T) {
+	audit, _ := newAuditService(t)
+	ctx := context.Background()
+	userId := uuid.New().String()
+	client := models.ClientInfo{IPAddress: "203.0.113.7", UserAgent: "audit-test/1.0"}
+	start := time.Now().Add(-time.Second)
+
+	audit.RecordAttempt(ctx, models.AuditLoginFailed, userId, client, models.AuditOutcomeFailure,
+		map[string]interface{}{"reason": "incorrect_password", "failures": 1})
+	audit.RecordAttempt(ctx, models.AuditLoginSucceeded, userId, client, models.AuditOutcomeSuccess, nil)
+	audit.Record(ctx, models.AuditRoleChanged, userId, "admin-123", client.IPAddress,
+		map[string]interface{}{"from": "customer", "to": "front_desk"})
+
+	all, err := audit.ListEvents(ctx, models.AuditFilter{UserId: userId}, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, 3, all.Total)
+	assert.Equal(t, models.AuditRoleChanged, all.Events[0].Event)
+	assert.Equal(t, "admin-123", all.Events[0].ActorId)
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, all.Events[i+1].Hash, all.Events[i].PrevHash)
+		assert.Equal(t, all.Events[i+1].Sequence+1, all.Events[i].Sequence)
+	}
+
+	failures, err := audit.ListEvents(ctx, models.AuditFilter{UserId: userId, Outcome: models.AuditOutcomeFailure, From: start}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, failures.Events, 1)
+	assert.Equal(t, models.AuditLoginFailed, failures.Events[0].Event)
+	assert.Equal(t, "audit-test/1.0", failures.Events[0].UserAgent)
+
+	byActor, err := audit.ListEvents(ctx, models.AuditFilter{UserId: userId, ActorId: "admin-123"}, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, byActor.Total)
+
+	report, err := audit.VerifyChain(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.Valid, report.Reason)
+	assert.GreaterOrEqual(t, report.Checked, int64(3))
+}
+
+func TestAuditLogIsAppendOnly(t *testing.T) {
+	audit, db := newAuditService(t)
+	ctx := context.Background()
+	userId := uuid.New().String()
+	audit.RecordAttempt(ctx, models.AuditPasswordChanged, userId, models.ClientInfo{}, models.AuditOutcomeSuccess, nil)
+
+	_, err := db.ExecContext(ctx, `UPDATE audit_log SET outcome = 'failure' WHERE user_id = $1`, userId)
+	assert.Error(t, err)
+	_, err = db.ExecContext(ctx, `DELETE FROM audit_log WHERE user_id = $1`, userId)
+	assert.Error(t, err)
+
+	events, err := audit.ListEvents(ctx, models.AuditFilter{UserId: userId}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, events.Events, 1)
+	assert.Equal(t, models.AuditOutcomeSuccess, events.Events[0].Outcome)
+}
+
+func TestListAllEventsReadsPastOnePage(t *testing.T) {
+	audit, _ := newAuditService(t)
+	ctx := context.Background()
+	userId := uuid.New().String()
+	for i := 0; i < 501; i++ {
+		audit.Record(ctx, models.AuditLoginSucceeded, userId, "", "203.0.113.7", nil)
+	}
+
+	events, err := audit.ListAllEvents(ctx, models.AuditFilter{UserId: userId})
+	require.NoError(t, err)
+	require.Len(t, events, 501)
+	assert.Greater(t, events[0].Sequence, events[500].Sequence, "newest first")
+}
@@ -2,39 +2,101 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
 	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
 	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 
 )
 
+var (
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected, all sessions from this login were revoked")
+	ErrAccountSuspended = errors.New("account is suspended")
+	ErrPasswordResetRequired = errors.New("a password reset is required, use forgot password to choose a new one")
+	ErrWeakPassword = security.ErrWeakPassword
+	ErrPasswordReused = errors.New("password was used recently, choose one you haven't used before")
+)
+
 type AuthService struct {
 	userRepo *postgres.UserRepository
+	refreshTokenRepo *postgres.RefreshTokenRepository
+	sessionRepo *postgres.SessionRepository
+	revocations security.RevocationStore
 	security *security.JWTManager
-	bcryptCost int
+	passwords *security.PasswordHasher
+	verification *VerificationService
+	mfa *MFAService
+	protection *LoginProtection
+	rbac *RBACService
+	passwordPolicy *security.PasswordPolicy
+	passwordHistory int
+	audit *AuditService
 }
 
-func NewAuthService(userRepo *postgres.UserRepository, security *security.JWTManager, bcryptCost int ) *AuthService{
+func NewAuthService(userRepo *postgres.UserRepository, refreshTokenRepo *postgres.RefreshTokenRepository,
+	sessionRepo *postgres.SessionRepository, revocations security.RevocationStore,
+	security *security.JWTManager, passwords *security.PasswordHasher) *AuthService{
 	return &AuthService {
 		userRepo: userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		sessionRepo: sessionRepo,
+		revocations: revocations,
 		security: security,
-		bcryptCost: bcryptCost,
+		passwords: passwords,
 		
 	}
 }
 
+// SetVerificationService makes Register send a verification email to new users
+func (s *AuthService) SetVerificationService(verification *VerificationService) {
+	s.verification = verification
+}
+
+// SetMFAService makes Login ask for a second factor where one is enabled or required
+func (s *AuthService) SetMFAService(mfa *MFAService) {
+	s.mfa = mfa
+}
+
+// SetLoginProtection makes Login throttle and lock out repeated failures
+func (s *AuthService) SetLoginProtection(protection *LoginProtection) {
+	s.protection = protection
+}
+
+// SetRBACService makes access tokens carry the permissions of the user's role
+func (s *AuthService) SetRBACService(rbac *RBACService) {
+	s.rbac = rbac
+}
+
+// SetAuditService records logins, token refreshes and password changes in the audit log
+func (s *AuthService) SetAuditService(audit *AuditService) {
+	s.audit = audit
+}
+
+// SetPasswordPolicy checks new passwords against the policy, and stops users going
+// back to any of their last history passwords
+func (s *AuthService) SetPasswordPolicy(policy *security.PasswordPolicy, history int) {
+	s.passwordPolicy = policy
+	s.passwordHistory = history
+}
+
 func (s *AuthService) Register(ctx context.Context, req *models.CreateUserRequest) (*models.User, error) {
 	existingUser, _ := s.userRepo.GetUserByEmail(ctx, req.Email)
 	if existingUser != nil {
 		return nil, errors.New("user with this email already exists")
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password),s.bcryptCost)
+	if err := s.checkNewPassword(ctx, &models.User{Email: req.Email, FirstName: req.FirstName, LastName: req.LastName}, req.Password); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := s.passwords.Hash(req.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -43,7 +105,7 @@ func (s *AuthService) Register(ctx context.Context, req *models.CreateUserReques
 	user := &models.User{
 		Id: uuid.New().String(),
 		Email: req.Email,
-		PasswordHash: string(hashedPassword),
+		PasswordHash: hashedPassword,
 		FirstName: req.FirstName,
 		LastName: req.LastName,
 		Phone: req.Phone,
@@ -54,30 +116,125 @@ func (s *AuthService) Register(ctx context.Context, req *models.CreateUserReques
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	//the account exists even if the email can't be sent, the user can ask for a new link
+	if s.verification != nil {
+		go func(user models.User) {
+			if err := s.verification.SendVerification(context.Background(), &user); err != nil {
+				log.Printf("failed to send verification email to user %s: %v", user.Id, err)
+			}
+		}(*user)
+	}
+
 	user.PasswordHash = ""
 	return user, nil
 }
 
-func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*models.LoginResponse, error) {
+func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest, client models.ClientInfo) (*models.LoginResponse, error) {
+	if s.protection != nil {
+		if err := s.protection.CheckIP(client.IPAddress); err != nil {
+			s.recordAttempt(ctx, models.AuditLoginFailed, "", client, models.AuditOutcomeFailure,
+				map[string]interface{}{"email": req.Email, "reason": "ip_throttled"})
+			return nil, err
+		}
+	}
+
 	user, err := s.userRepo.GetUserByEmailAuth(ctx, req.Email)
 	if err != nil {
+		if s.protection != nil {
+			s.protection.RecordFailure(ctx, "", req.Email, "", client)
+		}
 		return nil, errors.New("invalid email or password")
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+	//a locked or throttled account is refused before the password is checked
+	if s.protection != nil {
+		if err := s.protection.CheckAccount(ctx, user.Id); err != nil {
+			s.recordAttempt(ctx, models.AuditLoginFailed, user.Id, client, models.AuditOutcomeFailure,
+				map[string]interface{}{"reason": "account_locked"})
+			return nil, err
+		}
+	}
+
+	if !s.passwords.Verify(user.PasswordHash, req.Password) {
+		if s.protection != nil {
+			s.protection.RecordFailure(ctx, user.Id, req.Email, "incorrect_password", client)
+		}
 		return nil, errors.New("invalid email or password")
 	}
 
+	s.upgradeHash(ctx, user.Id, user.PasswordHash, req.Password)
+
+	//suspension and the like are only reported once the password is right, so they reveal nothing
+	return s.completeLogin(ctx, user, client)
+}
+
+// completeLogin finishes a login once the user has proven who they are, with a
+// password or through an identity provider
+func (s *AuthService) completeLogin(ctx context.Context, user *models.User, client models.ClientInfo) (*models.LoginResponse, error) {
+	if user.Suspended {
+		s.recordAttempt(ctx, models.AuditLoginFailed, user.Id, client, models.AuditOutcomeFailure,
+			map[string]interface{}{"reason": "suspended"})
+		return nil, ErrAccountSuspended
+	}
+	if user.PasswordResetRequired {
+		s.recordAttempt(ctx, models.AuditLoginFailed, user.Id, client, models.AuditOutcomeFailure,
+			map[string]interface{}{"reason": "password_reset_required"})
+		return nil, ErrPasswordResetRequired
+	}
+
+	//users with a second factor, or who must enroll one, get an mfa token instead of a session
+	if s.mfa != nil {
+		challenge, err := s.mfa.loginChallenge(ctx, user)
+		if err != nil || challenge != nil {
+			return challenge, err
+		}
+	}
+
+	return s.startSession(ctx, user, client)
+}
+
+// startSession issues the tokens for a fully authenticated login
+func (s *AuthService) startSession(ctx context.Context, user *models.User, client models.ClientInfo) (*models.LoginResponse, error) {
+	//failures only clear once every factor is proven, or guessing second factor codes
+	//would be forgiven by each correct password
+	if s.protection != nil {
+		if err := s.protection.RecordSuccess(ctx, user.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	//each login starts a new session, which is also the refresh token family
+	session := &models.Session{
+		Id: uuid.New().String(),
+		UserId: user.Id,
+		UserAgent: client.UserAgent,
+		IPAddress: client.IPAddress,
+		CreatedAt: time.Now(),
+		LastUsedAt: time.Now(),
+	}
+	if err := s.sessionRepo.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
 	//generate tokens
-	accessToken, err := s.security.GenerateAccessToken(user.Id, string(user.Role))
+	claims, err := s.accessClaims(ctx, user, session.Id)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := s.security.GenerateAccessToken(claims)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	refreshToken, err := s.security.GenerateRefreshToken(user.Id)
+	refreshToken, record, err := s.newRefreshToken(user.Id, session.Id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+		return nil, err
 	}
+	if err := s.refreshTokenRepo.CreateRefreshToken(ctx, record); err != nil {
+		return nil, err
+	}
+	s.recordAttempt(ctx, models.AuditLoginSucceeded, user.Id, client, models.AuditOutcomeSuccess,
+		map[string]interface{}{"sessionId": session.Id})
 
 	user.PasswordHash = ""
 	return &models.LoginResponse{
@@ -87,8 +244,8 @@ func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*mod
 	}, nil
 }
 
-func (s *AuthService) GetUserProfile(ctx context.Context, email string) (*models.User, error) {
-	user, err := s.userRepo.GetUserByEmail(ctx, email)
+func (s *AuthService) GetUserProfile(ctx context.Context, userId string) (*models.User, error) {
+	user, err := s.userRepo.GetUserById(ctx, userId)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user profile: %w", err)
 	}
@@ -98,8 +255,8 @@ func (s *AuthService) GetUserProfile(ctx context.Context, email string) (*models
 	return user, nil
 }
 
-func (s *AuthService) UpdateUserProfile(ctx context.Context, email string, req *models.UpdateUserRequest) (*models.User, error) {
-	user, err := s.userRepo.GetUserByEmail(ctx, email)
+func (s *AuthService) UpdateUserProfile(ctx context.Context, userId string, req *models.UpdateUserRequest) (*models.User, error) {
+	user, err := s.userRepo.GetUserById(ctx, userId)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -124,56 +281,251 @@ func (s *AuthService) UpdateUserProfile(ctx context.Context, email string, req *
 	return user, nil
 }
 
-func (s *AuthService) ChangePassword(ctx context.Context, email string, req *models.ChangePasswordRequest) error {
-	user, err := s.userRepo.GetUserByEmailAuth(ctx, email)
+func (s *AuthService) ChangePassword(ctx context.Context, userId string, req *models.ChangePasswordRequest, client models.ClientInfo) error {
+	user, err := s.userRepo.GetUserById(ctx, userId)
 	if err != nil {
 		return fmt.Errorf("failed to get user: %w", err)
 	}
-
-	
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+	matches, err := s.verifyPassword(ctx, userId, req.CurrentPassword)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !matches {
+		s.recordAttempt(ctx, models.AuditPasswordChanged, userId, client, models.AuditOutcomeFailure,
+			map[string]interface{}{"reason": "incorrect_password"})
 		return errors.New("current password is incorrect")
 	}
 
-	
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err := s.checkNewPassword(ctx, user, req.NewPassword); err != nil {
+		return err
+	}
+	if err := s.setPassword(ctx, userId, req.NewPassword); err != nil {
+		return err
+	}
+	s.recordAttempt(ctx, models.AuditPasswordChanged, userId, client, models.AuditOutcomeSuccess, nil)
+	return nil
+}
+
+// checkNewPassword applies the password policy to a password the user is choosing,
+// and refuses it if it matches their current or a recent one. A user without an id
+// is still registering, so has no history
+func (s *AuthService) checkNewPassword(ctx context.Context, user *models.User, password string) error {
+	if s.passwordPolicy != nil {
+		if err := s.passwordPolicy.Check(password, user.Email, user.FirstName, user.LastName); err != nil {
+			return err
+		}
+	}
+	if s.passwordHistory <= 0 || user.Id == "" {
+		return nil
+	}
+
+	current, err := s.userRepo.GetPasswordHash(ctx, user.Id)
 	if err != nil {
-		return fmt.Errorf("failed to hash password: %w", err)
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	history, err := s.userRepo.GetPasswordHistory(ctx, user.Id, s.passwordHistory)
+	if err != nil {
+		return err
+	}
+	for _, hash := range append([]string{current}, history...) {
+		if s.passwords.Verify(hash, password) {
+			return ErrPasswordReused
+		}
 	}
+	return nil
+}
 
-	// Update password
-	if err := s.userRepo.UpdatePassword(ctx, email, string(hashedPassword)); err != nil {
+// setPassword stores a new password, which must have passed checkNewPassword. The
+// old one is kept in the history
+func (s *AuthService) setPassword(ctx context.Context, userId, password string) error {
+	hashedPassword, err := s.passwords.Hash(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if err := s.userRepo.UpdatePassword(ctx, userId, hashedPassword, s.passwordHistory); err != nil {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
-
 	return nil
 }
 
-func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*models.LoginResponse, error) {
-    claims, err := s.security.VerifyRefreshToken(refreshToken)
-    if err != nil {
-        return nil, errors.New("invalid refresh token")
-    }
-
-    user, err := s.userRepo.GetUserById(ctx, claims.UserId) 
-    if err != nil {
-        return nil, errors.New("user not found")
-    }
-
-    accessToken, err := s.security.GenerateAccessToken(user.Id, string(user.Role))
-    if err != nil {
-        return nil, fmt.Errorf("failed to generate access token: %w", err)
-    }
-
-    newRefreshToken, err := s.security.GenerateRefreshToken(user.Id)
-    if err != nil {
-        return nil, fmt.Errorf("failed to generate refresh token: %w", err)
-    }
-
-    user.PasswordHash = ""
-    return &models.LoginResponse{
-        AccessToken:  accessToken,
-        RefreshToken: newRefreshToken,
-        User:         *user,
-    }, nil
+// recordAttempt audits an attempt when an audit service is set
+func (s *AuthService) recordAttempt(ctx context.Context, event, userId string, client models.ClientInfo, outcome string,
+	details map[string]interface{}) {
+	if s.audit != nil {
+		s.audit.RecordAttempt(ctx, event, userId, client, outcome, details)
+	}
+}
+
+// verifyPassword reports whether password is the user's current password
+func (s *AuthService) verifyPassword(ctx context.Context, userId, password string) (bool, error) {
+	passwordHash, err := s.userRepo.GetPasswordHash(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	return s.passwords.Verify(passwordHash, password), nil
+}
+
+// upgradeHash rehashes a password that was just checked if it was stored with a
+// legacy scheme or old parameters, so users move to the current hashing as they log in
+func (s *AuthService) upgradeHash(ctx context.Context, userId, passwordHash, password string) {
+	if !s.passwords.NeedsRehash(passwordHash) {
+		return
+	}
+	rehashed, err := s.passwords.Hash(password)
+	if err != nil {
+		log.Printf("failed to rehash password for user %s: %v", userId, err)
+		return
+	}
+	if err := s.userRepo.RehashPassword(ctx, userId, passwordHash, rehashed); err != nil {
+		log.Printf("failed to rehash password for user %s: %v", userId, err)
+	}
+}
+
+// RefreshToken rotates a refresh token: the presented token is retired and a new one
+// from the same family is returned. Presenting a retired token again means it was
+// stolen or replayed, so the whole family is revoked
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, client models.ClientInfo) (*models.LoginResponse, error) {
+	claims, err := s.security.VerifyRefreshToken(refreshToken)
+	if err != nil {
+		s.recordAttempt(ctx, models.AuditTokenRefreshed, "", client, models.AuditOutcomeFailure,
+			map[string]interface{}{"reason": "invalid_token"})
+		return nil, errors.New("invalid refresh token")
+	}
+
+	current, err := s.refreshTokenRepo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil || current.UserId != claims.UserId {
+		s.recordAttempt(ctx, models.AuditTokenRefreshed, claims.UserId, client, models.AuditOutcomeFailure,
+			map[string]interface{}{"reason": "unknown_token"})
+		return nil, errors.New("invalid refresh token")
+	}
+	if current.RevokedAt != nil {
+		if refreshTokenReused(current) {
+			return nil, s.revokeReusedFamily(ctx, current, client)
+		}
+		s.recordAttempt(ctx, models.AuditTokenRefreshed, current.UserId, client, models.AuditOutcomeFailure,
+			map[string]interface{}{"reason": "revoked", "sessionId": current.FamilyId})
+		return nil, errors.New("refresh token has been revoked")
+	}
+
+	user, err := s.userRepo.GetUserById(ctx, claims.UserId)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.Suspended || user.PasswordResetRequired {
+		if err := s.revokeSession(ctx, current.FamilyId); err != nil {
+			return nil, err
+		}
+		reason, err := "password_reset_required", ErrPasswordResetRequired
+		if user.Suspended {
+			reason, err = "suspended", ErrAccountSuspended
+		}
+		s.recordAttempt(ctx, models.AuditTokenRefreshed, user.Id, client, models.AuditOutcomeFailure,
+			map[string]interface{}{"reason": reason, "sessionId": current.FamilyId})
+		return nil, err
+	}
+
+	subject, err := s.accessClaims(ctx, user, current.FamilyId)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := s.security.GenerateAccessToken(subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	newRefreshToken, next, err := s.newRefreshToken(user.Id, current.FamilyId)
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := s.refreshTokenRepo.RotateRefreshToken(ctx, current.Id, next)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		//lost a race with another use of the same token
+		return nil, s.revokeReusedFamily(ctx, current, client)
+	}
+	if err := s.sessionRepo.TouchSession(ctx, current.FamilyId, client); err != nil {
+		return nil, err
+	}
+	s.recordAttempt(ctx, models.AuditTokenRefreshed, user.Id, client, models.AuditOutcomeSuccess,
+		map[string]interface{}{"sessionId": current.FamilyId})
+
+	user.PasswordHash = ""
+	return &models.LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
+		User:         *user,
+	}, nil
+}
+
+// Logout revokes the session the refresh token belongs to
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	if _, err := s.security.VerifyRefreshToken(refreshToken); err != nil {
+		return errors.New("invalid refresh token")
+	}
+	current, err := s.refreshTokenRepo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return errors.New("invalid refresh token")
+	}
+	return s.revokeSession(ctx, current.FamilyId)
+}
+
+// LogoutAll revokes every session the user has
+func (s *AuthService) LogoutAll(ctx context.Context, userId string) error {
+	return s.RevokeAllSessions(ctx, userId)
+}
+
+// refreshTokenReused reports a token that was already rotated being presented again.
+// Tokens revoked by logout have no successor and are just refused
+func refreshTokenReused(token *models.RefreshToken) bool {
+	return token.RevokedAt != nil && token.ReplacedBy != ""
+}
+
+func (s *AuthService) revokeReusedFamily(ctx context.Context, token *models.RefreshToken, client models.ClientInfo) error {
+	s.recordAttempt(ctx, models.AuditRefreshTokenReused, token.UserId, client, models.AuditOutcomeFailure,
+		map[string]interface{}{"sessionId": token.FamilyId})
+	if err := s.revokeSession(ctx, token.FamilyId); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func (s *AuthService) newRefreshToken(userId, familyId string) (string, *models.RefreshToken, error) {
+	token, err := s.security.GenerateRefreshToken(userId)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	now := time.Now()
+	return token, &models.RefreshToken{
+		Id:        uuid.New().String(),
+		UserId:    userId,
+		FamilyId:  familyId,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(s.security.RefreshTokenDuration()),
+		CreatedAt: now,
+	}, nil
+}
+
+func (s *AuthService) accessClaims(ctx context.Context, user *models.User, sessionId string) (security.Claims, error) {
+	claims := security.Claims{
+		UserId: user.Id,
+		Email: user.Email,
+		Role: string(user.Role),
+		EmailVerified: user.EmailVerified,
+		SessionId: sessionId,
+	}
+	if s.rbac != nil {
+		permissions, err := s.rbac.PermissionsFor(ctx, claims.Role)
+		if err != nil {
+			return claims, fmt.Errorf("failed to resolve permissions: %w", err)
+		}
+		claims.Permissions = permissions
+	}
+	return claims, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
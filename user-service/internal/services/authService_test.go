@@ -0,0 +1,27 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshTokenReused(t *testing.T) {
+	revokedAt := time.Now()
+	cases := []struct {
+		name   string
+		token  models.RefreshToken
+		reused bool
+	}{
+		{"current", models.RefreshToken{}, false},
+		{"already rotated", models.RefreshToken{RevokedAt: &revokedAt, ReplacedBy: "next-token"}, true},
+		{"logged out", models.RefreshToken{RevokedAt: &revokedAt}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.reused, refreshTokenReused(&tc.token))
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/notifications"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
+)
+
+var (
+	ErrInvalidEmailChangeToken = errors.New("email change link is invalid or has expired")
+	ErrEmailInUse              = errors.New("that email address is already in use")
+	ErrSameEmail               = errors.New("that is already your email address")
+	ErrIncorrectPassword       = errors.New("password is incorrect")
+)
+
+type EmailChangeConfig struct {
+	TokenTTL   time.Duration
+	ConfirmURL string
+}
+
+// EmailChangeService moves an account to a new address once the user proves they
+// own it, by following a link sent there
+type EmailChangeService struct {
+	userRepo     *postgres.UserRepository
+	tokenRepo    *postgres.OneTimeTokenRepository
+	signer       *security.TokenSigner
+	mailer       *notifications.Client
+	authService  *AuthService
+	auditService *AuditService
+	cfg          EmailChangeConfig
+}
+
+// mailer may be nil, in which case confirmation links are only logged
+func NewEmailChangeService(userRepo *postgres.UserRepository, tokenRepo *postgres.OneTimeTokenRepository,
+	signer *security.TokenSigner, mailer *notifications.Client, authService *AuthService, auditService *AuditService,
+	cfg EmailChangeConfig) *EmailChangeService {
+	return &EmailChangeService{
+		userRepo:     userRepo,
+		tokenRepo:    tokenRepo,
+		signer:       signer,
+		mailer:       mailer,
+		authService:  authService,
+		auditService: auditService,
+		cfg:          cfg,
+	}
+}
+
+// RequestChange sends a confirmation link to the new address and tells the old one
+// about it. Nothing changes until the link is followed, and a new request replaces
+// any earlier one. An address that already has an account gets a notice instead of a
+// link, and the request succeeds as usual so it doesn't reveal who is registered
+func (s *EmailChangeService) RequestChange(ctx context.Context, userId string, req *models.ChangeEmailRequest) error {
+	user, err := s.userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+	newEmail := strings.TrimSpace(req.NewEmail)
+	if strings.EqualFold(newEmail, user.Email) {
+		return ErrSameEmail
+	}
+
+	matches, err := s.authService.verifyPassword(ctx, userId, req.Password)
+	if err != nil {
+		return err
+	}
+	if !matches {
+		return ErrIncorrectPassword
+	}
+	subject := "Confirm your new email address"
+	var body string
+	if existing, _ := s.userRepo.GetUserByEmail(ctx, newEmail); existing != nil {
+		subject = "Your email address is already in use"
+		body = "Hi,\n\nSomeone asked to move another account to this email address, but it already belongs to an account. " +
+			"Nothing has changed. If this was you, sign in with this address or reset its password instead."
+	} else {
+		token, err := issueToken(ctx, s.tokenRepo, s.signer, &models.OneTimeToken{
+			UserId:   user.Id,
+			Purpose:  models.TokenPurposeEmailChange,
+			NewEmail: newEmail,
+		}, s.cfg.TokenTTL)
+		if err != nil {
+			return err
+		}
+		link := s.cfg.ConfirmURL + "?token=" + url.QueryEscape(token)
+		body = fmt.Sprintf("Hi %s,\n\nPlease confirm that you want to use this address for your account by opening the link below:\n\n%s\n\n"+
+			"The link expires in %s. You will be signed out everywhere once it is confirmed.", user.FirstName, link, s.cfg.TokenTTL)
+	}
+	if err := sendEmail(ctx, s.mailer, newEmail, subject, body, notifications.TypeEmailChange); err != nil {
+		return fmt.Errorf("failed to send confirmation email: %w", err)
+	}
+
+	notice := fmt.Sprintf("Hi %s,\n\nSomeone asked to change the email address on your account to %s. "+
+		"Nothing changes until the new address is confirmed. If this wasn't you, change your password and contact support.",
+		user.FirstName, newEmail)
+	if err := sendEmail(ctx, s.mailer, user.Email, "Your email address is being changed", notice, notifications.TypeEmailChange); err != nil {
+		//the confirmation went out, don't fail the request over the notice
+		log.Printf("failed to send email change notice to user %s: %v", user.Id, err)
+	}
+	return nil
+}
+
+// ConfirmChange consumes a confirmation token, switches the account to the new address
+// and signs the user out everywhere. It fails if someone else took the address meanwhile
+func (s *EmailChangeService) ConfirmChange(ctx context.Context, token, ipAddress string) error {
+	record, err := s.tokenRepo.ConsumeToken(ctx, models.TokenPurposeEmailChange, s.signer.Hash(token))
+	if err != nil || record.NewEmail == "" {
+		return ErrInvalidEmailChangeToken
+	}
+	user, err := s.userRepo.GetUserById(ctx, record.UserId)
+	if err != nil {
+		return ErrInvalidEmailChangeToken
+	}
+
+	if err := s.userRepo.ChangeEmail(ctx, user.Id, record.NewEmail); err != nil {
+		//someone registered the address after the link was sent. Only its owner can get
+		//this far, so saying so doesn't reveal anything to them
+		if errors.Is(err, postgres.ErrEmailTaken) {
+			return ErrEmailInUse
+		}
+		return err
+	}
+	if err := s.authService.RevokeAllSessions(ctx, user.Id); err != nil {
+		return err
+	}
+	s.auditService.Record(ctx, models.AuditEmailChanged, user.Id, "", ipAddress, map[string]interface{}{
+		"oldEmail": user.Email,
+		"newEmail": record.NewEmail,
+	})
+	return nil
+}
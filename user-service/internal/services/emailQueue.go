@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"log"
+)
+
+// EmailDispatcher runs email jobs in the background, so a request doesn't wait on the
+// mail server or reveal through its timing whether an email was sent
+type EmailDispatcher interface {
+	Dispatch(job func(ctx context.Context))
+}
+
+// EmailQueue is the EmailDispatcher the server uses. A fixed number of workers drain a
+// bounded queue, and a job that arrives while the queue is full is dropped and logged
+// rather than piling up goroutines during a flood of requests
+type EmailQueue struct {
+	jobs chan func(ctx context.Context)
+}
+
+func NewEmailQueue(workers, size int) *EmailQueue {
+	q := &EmailQueue{jobs: make(chan func(ctx context.Context), size)}
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+func (q *EmailQueue) Dispatch(job func(ctx context.Context)) {
+	select {
+	case q.jobs <- job:
+	default:
+		log.Printf("email queue is full, dropping an email")
+	}
+}
+
+func (q *EmailQueue) work() {
+	for job := range q.jobs {
+		job(context.Background())
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailQueueDropsJobsWhenFull(t *testing.T) {
+	q := NewEmailQueue(1, 1)
+	started, release := make(chan struct{}), make(chan struct{})
+	var ran atomic.Int32
+
+	q.Dispatch(func(ctx context.Context) {
+		close(started)
+		<-release
+		ran.Add(1)
+	})
+	<-started
+
+	done := make(chan struct{})
+	q.Dispatch(func(ctx context.Context) {
+		ran.Add(1)
+		close(done)
+	})
+	// the only worker is busy and the queue holds one job, so this one is dropped
+	q.Dispatch(func(ctx context.Context) { ran.Add(100) })
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("queued job never ran")
+	}
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, int32(2), ran.Load())
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
+)
+
+var ErrInvalidGuestProfile = errors.New("invalid guest profile")
+
+// GuestProfileService keeps guest profiles. Guests edit their own; front desk staff
+// edit them on a guest's behalf, which is recorded in the audit log
+type GuestProfileService struct {
+	guestRepo    *postgres.GuestProfileRepository
+	userRepo     *postgres.UserRepository
+	auditService *AuditService
+}
+
+func NewGuestProfileService(guestRepo *postgres.GuestProfileRepository, userRepo *postgres.UserRepository,
+	auditService *AuditService) *GuestProfileService {
+	return &GuestProfileService{
+		guestRepo:    guestRepo,
+		userRepo:     userRepo,
+		auditService: auditService,
+	}
+}
+
+// GetProfile returns the user's profile, an empty one if nothing was filled in yet
+func (s *GuestProfileService) GetProfile(ctx context.Context, userId string) (*models.GuestProfile, error) {
+	if _, err := s.userRepo.GetUserById(ctx, userId); err != nil {
+		return nil, err
+	}
+	profile, err := s.guestRepo.GetGuestProfile(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		profile = &models.GuestProfile{UserId: userId}
+	}
+	return profile, nil
+}
+
+// UpdateProfile replaces a profile's editable fields. actorId is empty when guests
+// edit their own profile
+func (s *GuestProfileService) UpdateProfile(ctx context.Context, actorId, userId string,
+	req *models.UpdateGuestProfileRequest, ipAddress string) (*models.GuestProfile, error) {
+	if err := validateGuestProfile(req); err != nil {
+		return nil, err
+	}
+	current, err := s.GetProfile(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &models.GuestProfile{
+		UserId:       userId,
+		Nationality:  strings.ToUpper(req.Nationality),
+		Address:      req.Address,
+		Preferences:  req.Preferences,
+		DietaryNotes: strings.TrimSpace(req.DietaryNotes),
+		Consents:     consentsFrom(current.Consents, req.Consents),
+		UpdatedBy:    actorId,
+	}
+	profile.Address.Country = strings.ToUpper(profile.Address.Country)
+	if doc := req.IdDocument; doc != nil {
+		number := strings.ReplaceAll(doc.Number, " ", "")
+		profile.IdDocument = &models.IdDocument{
+			Type:           doc.Type,
+			NumberLast4:    number[max(len(number)-4, 0):],
+			IssuingCountry: strings.ToUpper(doc.IssuingCountry),
+			ExpiresOn:      doc.ExpiresOn,
+		}
+	}
+
+	if err := s.guestRepo.SaveGuestProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(ctx, models.AuditGuestProfileUpdated, userId, actorId, ipAddress, map[string]interface{}{
+		"marketingEmail": profile.Consents.Email,
+		"marketingSms":   profile.Consents.SMS,
+	})
+	return s.GetProfile(ctx, userId)
+}
+
+// EnrollLoyalty makes the user a loyalty member. Enrolling again returns the existing membership
+func (s *GuestProfileService) EnrollLoyalty(ctx context.Context, actorId, userId, ipAddress string) (*models.LoyaltyMembership, error) {
+	profile, err := s.GetProfile(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if profile.Loyalty != nil {
+		return profile.Loyalty, nil
+	}
+
+	membership, err := s.guestRepo.EnrollLoyalty(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	s.auditService.Record(ctx, models.AuditLoyaltyEnrolled, userId, actorId, ipAddress, map[string]interface{}{
+		"number": membership.Number,
+	})
+	return membership, nil
+}
+
+// GetPreferences returns what the booking service needs to assign a room. Users
+// without a profile have no preferences
+func (s *GuestProfileService) GetPreferences(ctx context.Context, userId string) (*models.GuestPreferences, error) {
+	profile, err := s.guestRepo.GetGuestProfile(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	preferences := &models.GuestPreferences{UserId: userId}
+	if profile != nil {
+		preferences.Preferences = profile.Preferences
+		preferences.DietaryNotes = profile.DietaryNotes
+	}
+	return preferences, nil
+}
+
+// consentsFrom keeps the original consent time for consents that stay given
+func consentsFrom(current models.MarketingConsents, req models.MarketingConsentsRequest) models.MarketingConsents {
+	now := time.Now()
+	consents := models.MarketingConsents{Email: req.Email, SMS: req.SMS}
+	if req.Email {
+		consents.EmailConsentedAt = current.EmailConsentedAt
+		if consents.EmailConsentedAt == nil {
+			consents.EmailConsentedAt = &now
+		}
+	}
+	if req.SMS {
+		consents.SMSConsentedAt = current.SMSConsentedAt
+		if consents.SMSConsentedAt == nil {
+			consents.SMSConsentedAt = &now
+		}
+	}
+	return consents
+}
+
+func validateGuestProfile(req *models.UpdateGuestProfileRequest) error {
+	oneOf := func(field, value string, allowed ...string) error {
+		if value == "" {
+			return nil
+		}
+		for _, a := range allowed {
+			if value == a {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s must be one of %s", ErrInvalidGuestProfile, field, strings.Join(allowed, ", "))
+	}
+	country := func(field, value string) error {
+		if value == "" || (len(value) == 2 && strings.Trim(strings.ToUpper(value), "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == "") {
+			return nil
+		}
+		return fmt.Errorf("%w: %s must be a two letter country code", ErrInvalidGuestProfile, field)
+	}
+
+	checks := []error{
+		country("nationality", req.Nationality),
+		country("address.country", req.Address.Country),
+		oneOf("preferences.bed", req.Preferences.Bed, models.BedKing, models.BedQueen, models.BedTwin),
+		oneOf("preferences.floor", req.Preferences.Floor, models.FloorHigh, models.FloorLow),
+		oneOf("preferences.pillow", req.Preferences.Pillow,
+			models.PillowSoft, models.PillowFirm, models.PillowFeather, models.PillowHypoallergenic),
+	}
+	if doc := req.IdDocument; doc != nil {
+		checks = append(checks,
+			oneOf("idDocument.type", doc.Type, models.DocumentPassport, models.DocumentNationalId, models.DocumentDrivingLicence),
+			country("idDocument.issuingCountry", doc.IssuingCountry),
+		)
+		if doc.ExpiresOn != "" {
+			if _, err := time.Parse("2006-01-02", doc.ExpiresOn); err != nil {
+				checks = append(checks, fmt.Errorf("%w: idDocument.expiresOn must be a YYYY-MM-DD date", ErrInvalidGuestProfile))
+			}
+		}
+	}
+	for _, err := range checks {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
+)
+
+// LoginThrottledError is returned when a login is refused before the password is
+// even checked, because of earlier failures
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return "account is temporarily locked after too many failed logins"
+	}
+	return "too many failed logins, please try again later"
+}
+
+type LoginProtectionConfig struct {
+	FailureWindow   time.Duration
+	DelayAfter      int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockAfter       int
+	LockoutDuration time.Duration
+	IPMaxFailures   int
+}
+
+// LoginProtection slows down and stops password guessing. Failures are counted per
+// account in the database, with a growing delay between attempts and a temporary lock,
+// and per IP address in memory, which catches one client trying many accounts
+type LoginProtection struct {
+	userRepo *postgres.UserRepository
+	audit    *AuditService
+	ips      *security.AttemptTracker
+	cfg      LoginProtectionConfig
+}
+
+func NewLoginProtection(userRepo *postgres.UserRepository, audit *AuditService, cfg LoginProtectionConfig) *LoginProtection {
+	return &LoginProtection{
+		userRepo: userRepo,
+		audit:    audit,
+		ips:      security.NewAttemptTracker(cfg.FailureWindow),
+		cfg:      cfg,
+	}
+}
+
+// CheckIP refuses clients that failed too often recently, whichever accounts they tried
+func (p *LoginProtection) CheckIP(ip string) error {
+	count, clearsAt := p.ips.Count(ip)
+	if count >= p.cfg.IPMaxFailures {
+		return &LoginThrottledError{RetryAfter: time.Until(clearsAt)}
+	}
+	return nil
+}
+
+// CheckAccount refuses logins to a locked account, or ones that come before the
+// delay earned by the previous failures has passed
+func (p *LoginProtection) CheckAccount(ctx context.Context, userId string) error {
+	state, err := p.userRepo.GetLoginState(ctx, userId)
+	if err != nil {
+		return err
+	}
+	return p.throttle(state, time.Now())
+}
+
+// throttle decides whether an account in state may try to log in at now
+func (p *LoginProtection) throttle(state *models.LoginState, now time.Time) error {
+	if state.LockedUntil != nil && state.LockedUntil.After(now) {
+		return &LoginThrottledError{RetryAfter: state.LockedUntil.Sub(now), Locked: true}
+	}
+	if state.LastFailedAt == nil || now.Sub(*state.LastFailedAt) > p.cfg.FailureWindow {
+		return nil
+	}
+	if delay := p.delay(state.FailedAttempts); delay > 0 {
+		if allowedAt := state.LastFailedAt.Add(delay); allowedAt.After(now) {
+			return &LoginThrottledError{RetryAfter: allowedAt.Sub(now)}
+		}
+	}
+	return nil
+}
+
+// delay doubles for every failure past DelayAfter, up to MaxDelay
+func (p *LoginProtection) delay(failures int) time.Duration {
+	if failures < p.cfg.DelayAfter {
+		return 0
+	}
+	delay := p.cfg.BaseDelay
+	for i := p.cfg.DelayAfter; i < failures && delay < p.cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.cfg.MaxDelay)
+}
+
+// RecordFailure counts a failed login against the IP and, if the email belongs to
+// an account, against the account. userId is empty for unknown emails. reason says
+// which step failed, a wrong password or a wrong second factor code count alike
+func (p *LoginProtection) RecordFailure(ctx context.Context, userId, email, reason string, client models.ClientInfo) {
+	ip := client.IPAddress
+	if ipFailures := p.ips.Fail(ip); ipFailures == p.cfg.IPMaxFailures {
+		p.audit.RecordAttempt(ctx, models.AuditIPBlocked, "", client, models.AuditOutcomeSuccess, map[string]interface{}{
+			"failures": ipFailures,
+			"window":   p.cfg.FailureWindow.String(),
+		})
+	}
+
+	if userId == "" {
+		p.audit.RecordAttempt(ctx, models.AuditLoginFailed, "", client, models.AuditOutcomeFailure,
+			map[string]interface{}{"email": email, "reason": "unknown_email"})
+		return
+	}
+	state, err := p.userRepo.RecordFailedLogin(ctx, userId, p.cfg.FailureWindow, p.cfg.LockAfter, p.cfg.LockoutDuration)
+	if err != nil {
+		p.audit.RecordAttempt(ctx, models.AuditLoginFailed, userId, client, models.AuditOutcomeFailure,
+			map[string]interface{}{"reason": reason, "error": err.Error()})
+		return
+	}
+	p.audit.RecordAttempt(ctx, models.AuditLoginFailed, userId, client, models.AuditOutcomeFailure,
+		map[string]interface{}{"reason": reason, "failures": state.FailedAttempts})
+	if state.LockedUntil != nil && state.FailedAttempts >= p.cfg.LockAfter {
+		p.audit.RecordAttempt(ctx, models.AuditAccountLocked, userId, client, models.AuditOutcomeSuccess, map[string]interface{}{
+			"failures":    state.FailedAttempts,
+			"lockedUntil": state.LockedUntil.Format(time.RFC3339),
+		})
+	}
+}
+
+// RecordSuccess clears the account's failures once the user has fully logged in
+func (p *LoginProtection) RecordSuccess(ctx context.Context, userId string) error {
+	return p.userRepo.ResetFailedLogins(ctx, userId)
+}
+
+// Unlock lets an admin clear a lock before it runs out
+func (p *LoginProtection) Unlock(ctx context.Context, actorId, userId, ip string) error {
+	state, err := p.userRepo.GetLoginState(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("user not found")
+	}
+	if err := p.userRepo.ResetFailedLogins(ctx, userId); err != nil {
+		return err
+	}
+	details := map[string]interface{}{"failures": state.FailedAttempts}
+	if state.LockedUntil != nil {
+		details["lockedUntil"] = state.LockedUntil.Format(time.RFC3339)
+	}
+	p.audit.Record(ctx, models.AuditAccountUnlocked, userId, actorId, ip, details)
+	return nil
+}
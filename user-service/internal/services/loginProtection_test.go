@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var protectionConfig = LoginProtectionConfig{
+	FailureWindow:   15 * time.Minute,
+	DelayAfter:      3,
+	BaseDelay:       time.Second,
+	MaxDelay:        30 * time.Second,
+	LockAfter:       10,
+	LockoutDuration: 15 * time.Minute,
+	IPMaxFailures:   3,
+}
+
+func TestLoginDelay(t *testing.T) {
+	p := NewLoginProtection(nil, nil, protectionConfig)
+	cases := map[int]time.Duration{
+		0:  0,
+		2:  0,
+		3:  time.Second,
+		4:  2 * time.Second,
+		5:  4 * time.Second,
+		7:  16 * time.Second,
+		8:  30 * time.Second,
+		50: 30 * time.Second,
+	}
+	for failures, want := range cases {
+		assert.Equal(t, want, p.delay(failures), "%d failures", failures)
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	p := NewLoginProtection(nil, nil, protectionConfig)
+	now := time.Now()
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+
+	cases := []struct {
+		name       string
+		state      models.LoginState
+		retryAfter time.Duration
+		locked     bool
+	}{
+		{"no failures", models.LoginState{}, 0, false},
+		{"below the delay", models.LoginState{FailedAttempts: 2, LastFailedAt: ago(0)}, 0, false},
+		{"within the delay", models.LoginState{FailedAttempts: 5, LastFailedAt: ago(time.Second)}, 3 * time.Second, false},
+		{"delay passed", models.LoginState{FailedAttempts: 5, LastFailedAt: ago(5 * time.Second)}, 0, false},
+		{"failures outside the window", models.LoginState{FailedAttempts: 9, LastFailedAt: ago(time.Hour)}, 0, false},
+		{"locked", models.LoginState{FailedAttempts: 10, LastFailedAt: ago(0), LockedUntil: ago(-10 * time.Minute)}, 10 * time.Minute, true},
+		{"lock expired", models.LoginState{FailedAttempts: 10, LastFailedAt: ago(time.Hour), LockedUntil: ago(time.Minute)}, 0, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := p.throttle(&tc.state, now)
+			if tc.retryAfter == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			var throttled *LoginThrottledError
+			require.True(t, errors.As(err, &throttled))
+			assert.Equal(t, tc.retryAfter, throttled.RetryAfter)
+			assert.Equal(t, tc.locked, throttled.Locked)
+		})
+	}
+}
+
+func TestLoginCheckIP(t *testing.T) {
+	p := NewLoginProtection(nil, nil, protectionConfig)
+	for i := 0; i < protectionConfig.IPMaxFailures-1; i++ {
+		p.ips.Fail("203.0.113.7")
+	}
+	assert.NoError(t, p.CheckIP("203.0.113.7"))
+
+	p.ips.Fail("203.0.113.7")
+	var throttled *LoginThrottledError
+	require.True(t, errors.As(p.CheckIP("203.0.113.7"), &throttled))
+	assert.False(t, throttled.Locked, "an IP block doesn't lock anyone's account")
+	assert.InDelta(t, protectionConfig.FailureWindow, throttled.RetryAfter, float64(time.Second))
+
+	assert.NoError(t, p.CheckIP("203.0.113.8"), "other clients aren't affected")
+}
@@ -0,0 +1,404 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
+)
+
+var (
+	ErrNotLoyaltyMember   = errors.New("not a loyalty member, enroll first")
+	ErrInsufficientPoints = errors.New("not enough points")
+	ErrBelowMinRedemption = errors.New("too few points to redeem")
+	ErrRedemptionNotFound = errors.New("no redemption with this reference")
+	ErrVoucherUnavailable = errors.New("voucher is unknown, expired or already used")
+	ErrInvalidAdjustment  = errors.New("an adjustment needs a non-zero number of points")
+)
+
+// LoyaltyConfig holds the earn and redemption rules, see config.LoyaltyConfig
+type LoyaltyConfig struct {
+	PointsPerUnit           int
+	PointsPerNight          int
+	PointsPerRedemptionUnit int
+	MinRedemption           int
+	VoucherTTL              time.Duration
+}
+
+const (
+	defaultLedgerPageSize = 20
+	maxLedgerPageSize     = 100
+)
+
+// LoyaltyService runs the points program: members earn on completed stays and spend
+// points on payments or vouchers. Every change goes through the ledger
+type LoyaltyService struct {
+	loyaltyRepo  *postgres.LoyaltyRepository
+	guestRepo    *postgres.GuestProfileRepository
+	auditService *AuditService
+	config       LoyaltyConfig
+}
+
+func NewLoyaltyService(loyaltyRepo *postgres.LoyaltyRepository, guestRepo *postgres.GuestProfileRepository,
+	auditService *AuditService, config LoyaltyConfig) *LoyaltyService {
+	if config.PointsPerRedemptionUnit <= 0 {
+		config.PointsPerRedemptionUnit = 1
+	}
+	return &LoyaltyService{
+		loyaltyRepo:  loyaltyRepo,
+		guestRepo:    guestRepo,
+		auditService: auditService,
+		config:       config,
+	}
+}
+
+// GetAccount returns a member's balance, tier and how far they are from the next tier
+func (s *LoyaltyService) GetAccount(ctx context.Context, userId string) (*models.LoyaltyAccount, error) {
+	membership, err := s.membership(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	balance, lifetime, err := s.loyaltyRepo.GetBalance(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	tiers, err := s.loyaltyRepo.ListTiers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	account := &models.LoyaltyAccount{
+		UserId:         userId,
+		Number:         membership.Number,
+		Balance:        balance,
+		LifetimePoints: lifetime,
+	}
+	account.Tier, account.NextTier = tierFor(tiers, lifetime)
+	if account.NextTier != nil {
+		account.PointsToNextTier = account.NextTier.Threshold - lifetime
+	}
+	return account, nil
+}
+
+// History returns one page of a member's points history, newest first
+func (s *LoyaltyService) History(ctx context.Context, userId string, page, limit int) (*models.LedgerPage, error) {
+	if _, err := s.membership(ctx, userId); err != nil {
+		return nil, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultLedgerPageSize
+	}
+	limit = min(limit, maxLedgerPageSize)
+
+	entries, total, err := s.loyaltyRepo.ListEntries(ctx, userId, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	return &models.LedgerPage{Entries: entries, Total: total, Page: page, Limit: limit}, nil
+}
+
+// EarnForStay credits a completed stay. The booking id is the reference, so a stay
+// reported twice earns once and the first entry is returned
+func (s *LoyaltyService) EarnForStay(ctx context.Context, req *models.StayCompletedRequest) (*models.LedgerEntry, error) {
+	if _, err := s.membership(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	_, lifetime, err := s.loyaltyRepo.GetBalance(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	tiers, err := s.loyaltyRepo.ListTiers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tier, _ := tierFor(tiers, lifetime)
+
+	base := req.AmountSpent*float64(s.config.PointsPerUnit) + float64(req.Nights*s.config.PointsPerNight)
+	entry := &models.LedgerEntry{
+		Id:          uuid.New().String(),
+		UserId:      req.UserId,
+		Kind:        models.LedgerEarn,
+		Points:      int64(math.Floor(base * tier.EarnMultiplier)),
+		Reference:   req.BookingId,
+		Description: fmt.Sprintf("Stay of %d nights, %s tier", req.Nights, tier.Name),
+	}
+	if err := s.loyaltyRepo.PostEntry(ctx, entry); err != nil {
+		if errors.Is(err, postgres.ErrDuplicateLedgerEntry) {
+			return s.loyaltyRepo.GetEntryByReference(ctx, req.UserId, models.LedgerEarn, req.BookingId)
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// RedeemPoints spends points as a payment tender. The payment reference makes it
+// safe to retry; a retry returns the original redemption
+func (s *LoyaltyService) RedeemPoints(ctx context.Context, req *models.RedeemPointsRequest) (*models.RedemptionResponse, error) {
+	if _, err := s.membership(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	if req.Points < int64(s.config.MinRedemption) {
+		return nil, fmt.Errorf("%w, the minimum is %d", ErrBelowMinRedemption, s.config.MinRedemption)
+	}
+
+	entry := &models.LedgerEntry{
+		Id:          uuid.New().String(),
+		UserId:      req.UserId,
+		Kind:        models.LedgerRedeem,
+		Points:      -req.Points,
+		Reference:   req.Reference,
+		Description: "Redeemed towards payment " + req.Reference,
+	}
+	err := s.loyaltyRepo.PostEntry(ctx, entry)
+	switch {
+	case errors.Is(err, postgres.ErrDuplicateLedgerEntry):
+		entry, err = s.loyaltyRepo.GetEntryByReference(ctx, req.UserId, models.LedgerRedeem, req.Reference)
+		if err != nil {
+			return nil, err
+		}
+	case errors.Is(err, postgres.ErrInsufficientPoints):
+		return nil, ErrInsufficientPoints
+	case err != nil:
+		return nil, err
+	}
+	return &models.RedemptionResponse{
+		Points:  -entry.Points,
+		Amount:  s.pointsValue(-entry.Points),
+		Balance: entry.BalanceAfter,
+	}, nil
+}
+
+// ReverseRedemption gives back the points of a redemption whose payment failed or
+// was refunded. Reversing twice does nothing more
+func (s *LoyaltyService) ReverseRedemption(ctx context.Context, userId, reference string) (*models.LedgerEntry, error) {
+	redemption, err := s.loyaltyRepo.GetEntryByReference(ctx, userId, models.LedgerRedeem, reference)
+	if err != nil {
+		return nil, err
+	}
+	if redemption == nil {
+		return nil, ErrRedemptionNotFound
+	}
+
+	entry := &models.LedgerEntry{
+		Id:          uuid.New().String(),
+		UserId:      userId,
+		Kind:        models.LedgerReversal,
+		Points:      -redemption.Points,
+		Reference:   reference,
+		Description: "Reversed redemption for payment " + reference,
+	}
+	if err := s.loyaltyRepo.PostEntry(ctx, entry); err != nil {
+		if errors.Is(err, postgres.ErrDuplicateLedgerEntry) {
+			return s.loyaltyRepo.GetEntryByReference(ctx, userId, models.LedgerReversal, reference)
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// CreateVoucher turns points into a voucher code worth their redemption value
+func (s *LoyaltyService) CreateVoucher(ctx context.Context, userId string, points int64, ipAddress string) (*models.LoyaltyVoucher, error) {
+	if _, err := s.membership(ctx, userId); err != nil {
+		return nil, err
+	}
+	if points < int64(s.config.MinRedemption) {
+		return nil, fmt.Errorf("%w, the minimum is %d", ErrBelowMinRedemption, s.config.MinRedemption)
+	}
+	code, err := voucherCode()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	voucher := &models.LoyaltyVoucher{
+		Code:      code,
+		UserId:    userId,
+		Points:    points,
+		Value:     s.pointsValue(points),
+		ExpiresAt: now.Add(s.config.VoucherTTL),
+		CreatedAt: now,
+	}
+	entry := &models.LedgerEntry{
+		Id:          uuid.New().String(),
+		UserId:      userId,
+		Kind:        models.LedgerVoucher,
+		Points:      -points,
+		Reference:   code,
+		Description: fmt.Sprintf("Voucher worth %.2f", voucher.Value),
+	}
+	if err := s.loyaltyRepo.CreateVoucher(ctx, voucher, entry); err != nil {
+		if errors.Is(err, postgres.ErrInsufficientPoints) {
+			return nil, ErrInsufficientPoints
+		}
+		return nil, err
+	}
+	s.auditService.Record(ctx, models.AuditLoyaltyVoucherIssued, userId, "", ipAddress, map[string]interface{}{
+		"points": points,
+	})
+	return voucher, nil
+}
+
+func (s *LoyaltyService) ListVouchers(ctx context.Context, userId string) ([]models.LoyaltyVoucher, error) {
+	if _, err := s.membership(ctx, userId); err != nil {
+		return nil, err
+	}
+	return s.loyaltyRepo.ListVouchers(ctx, userId)
+}
+
+// RedeemVoucher uses a voucher as payment. Its points were spent when it was issued
+func (s *LoyaltyService) RedeemVoucher(ctx context.Context, code, reference string) (*models.LoyaltyVoucher, error) {
+	voucher, err := s.loyaltyRepo.RedeemVoucher(ctx, code, reference)
+	if errors.Is(err, postgres.ErrVoucherUnavailable) {
+		return nil, ErrVoucherUnavailable
+	}
+	return voucher, err
+}
+
+// AdjustPoints lets staff credit or debit points by hand, e.g. as a goodwill gesture.
+// Adjustments don't count towards tiers
+func (s *LoyaltyService) AdjustPoints(ctx context.Context, actorId, userId string, req *models.AdjustPointsRequest,
+	ipAddress string) (*models.LedgerEntry, error) {
+	if req.Points == 0 {
+		return nil, ErrInvalidAdjustment
+	}
+	if _, err := s.membership(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	entry := &models.LedgerEntry{
+		Id:          uuid.New().String(),
+		UserId:      userId,
+		Kind:        models.LedgerAdjustment,
+		Points:      req.Points,
+		Description: req.Reason,
+		CreatedBy:   actorId,
+	}
+	if err := s.loyaltyRepo.PostEntry(ctx, entry); err != nil {
+		if errors.Is(err, postgres.ErrInsufficientPoints) {
+			return nil, ErrInsufficientPoints
+		}
+		return nil, err
+	}
+	s.auditService.Record(ctx, models.AuditLoyaltyAdjusted, userId, actorId, ipAddress, map[string]interface{}{
+		"points": req.Points,
+		"reason": req.Reason,
+	})
+	return entry, nil
+}
+
+func (s *LoyaltyService) ListTiers(ctx context.Context) ([]models.LoyaltyTier, error) {
+	return s.loyaltyRepo.ListTiers(ctx)
+}
+
+func (s *LoyaltyService) UpdateTier(ctx context.Context, actorId, name string, req *models.UpdateLoyaltyTierRequest,
+	ipAddress string) (*models.LoyaltyTier, error) {
+	tier := &models.LoyaltyTier{
+		Name:           name,
+		Threshold:      req.Threshold,
+		EarnMultiplier: req.EarnMultiplier,
+		Benefits:       req.Benefits,
+	}
+	if tier.Benefits == nil {
+		tier.Benefits = []string{}
+	}
+	if err := s.loyaltyRepo.UpdateTier(ctx, tier); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(ctx, models.AuditLoyaltyTierUpdated, "", actorId, ipAddress, map[string]interface{}{
+		"tier":           name,
+		"threshold":      tier.Threshold,
+		"earnMultiplier": tier.EarnMultiplier,
+	})
+	return tier, nil
+}
+
+// Export returns a member's account and full points history for a data export, nil
+// for users who never enrolled
+func (s *LoyaltyService) Export(ctx context.Context, userId string) (*models.LoyaltyExport, error) {
+	account, err := s.GetAccount(ctx, userId)
+	if errors.Is(err, ErrNotLoyaltyMember) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries, _, err := s.loyaltyRepo.ListEntries(ctx, userId, math.MaxInt32, 0)
+	if err != nil {
+		return nil, err
+	}
+	vouchers, err := s.loyaltyRepo.ListVouchers(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	return &models.LoyaltyExport{Account: account, History: entries, Vouchers: vouchers}, nil
+}
+
+// Forfeit zeroes a deleted account's balance and drops its unused vouchers. The
+// ledger is kept, without personal details, for the points liability accounts
+func (s *LoyaltyService) Forfeit(ctx context.Context, userId string) error {
+	if err := s.loyaltyRepo.DeleteOpenVouchers(ctx, userId); err != nil {
+		return err
+	}
+	balance, _, err := s.loyaltyRepo.GetBalance(ctx, userId)
+	if err != nil || balance == 0 {
+		return err
+	}
+	return s.loyaltyRepo.PostEntry(ctx, &models.LedgerEntry{
+		Id:          uuid.New().String(),
+		UserId:      userId,
+		Kind:        models.LedgerAdjustment,
+		Points:      -balance,
+		Description: "Forfeited on account deletion",
+	})
+}
+
+func (s *LoyaltyService) membership(ctx context.Context, userId string) (*models.LoyaltyMembership, error) {
+	profile, err := s.guestRepo.GetGuestProfile(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil || profile.Loyalty == nil {
+		return nil, ErrNotLoyaltyMember
+	}
+	return profile.Loyalty, nil
+}
+
+// pointsValue is what points are worth at payment, rounded down to the cent
+func (s *LoyaltyService) pointsValue(points int64) float64 {
+	return math.Floor(float64(points)*100/float64(s.config.PointsPerRedemptionUnit)) / 100
+}
+
+// tierFor picks the highest tier the lifetime points reach and the one after it.
+// tiers must be sorted by threshold
+func tierFor(tiers []models.LoyaltyTier, lifetime int64) (models.LoyaltyTier, *models.LoyaltyTier) {
+	current := models.LoyaltyTier{Name: "member", EarnMultiplier: 1, Benefits: []string{}}
+	for i, tier := range tiers {
+		if tier.Threshold > lifetime {
+			return current, &tiers[i]
+		}
+		current = tier
+	}
+	return current, nil
+}
+
+// voucherCode returns a code like HS-7KQ2-M9XD, without look-alike characters
+func voucherCode() (string, error) {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate voucher code: %w", err)
+	}
+	for i := range buf {
+		buf[i] = alphabet[int(buf[i])%len(alphabet)]
+	}
+	return "HS-" + string(buf[:4]) + "-" + string(buf[4:]), nil
+}
@@ -0,0 +1,147 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/services"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Runs against Postgres like the handler tests, see TEST_DATABASE_URL there
+func newLoyaltyService(t *testing.T) (*services.LoyaltyService, string) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set, skipping integration test")
+	}
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, database.InitializeSchema(db))
+
+	ctx := context.Background()
+	userId := uuid.New().String()
+	require.NoError(t, postgres.NewUserRepository(db).CreateUser(ctx, &models.User{
+		Id:        userId,
+		Email:     "loyalty-" + userId[:8] + "@example.com",
+		FirstName: "Loyal",
+		LastName:  "Guest",
+		Role:      models.RoleCustomer,
+	}))
+	guestRepo := postgres.NewGuestProfileRepository(db)
+	_, err = guestRepo.EnrollLoyalty(ctx, userId)
+	require.NoError(t, err)
+
+	return services.NewLoyaltyService(postgres.NewLoyaltyRepository(db), guestRepo,
+		services.NewAuditService(postgres.NewAuditRepository(db)),
+		services.LoyaltyConfig{PointsPerUnit: 10, PointsPerRedemptionUnit: 100, MinRedemption: 100, VoucherTTL: time.Hour},
+	), userId
+}
+
+func TestEarnForStayIsIdempotent(t *testing.T) {
+	loyalty, userId := newLoyaltyService(t)
+	ctx := context.Background()
+	stay := &models.StayCompletedRequest{UserId: userId, BookingId: uuid.New().String(), AmountSpent: 120, Nights: 2}
+
+	first, err := loyalty.EarnForStay(ctx, stay)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1200), first.Points)
+
+	again, err := loyalty.EarnForStay(ctx, stay)
+	require.NoError(t, err)
+	assert.Equal(t, first.Id, again.Id)
+
+	account, err := loyalty.GetAccount(ctx, userId)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1200), account.Balance)
+	assert.Equal(t, int64(1200), account.LifetimePoints)
+}
+
+func TestConcurrentEarnAndRedeemKeepBalanceConsistent(t *testing.T) {
+	loyalty, userId := newLoyaltyService(t)
+	ctx := context.Background()
+	_, err := loyalty.EarnForStay(ctx, &models.StayCompletedRequest{UserId: userId, BookingId: "seed", AmountSpent: 100})
+	require.NoError(t, err)
+
+	const workers = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	redeemed := int64(0)
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			_, err := loyalty.EarnForStay(ctx, &models.StayCompletedRequest{
+				UserId: userId, BookingId: fmt.Sprintf("stay-%d", i), AmountSpent: 10,
+			})
+			assert.NoError(t, err)
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			_, err := loyalty.RedeemPoints(ctx, &models.RedeemPointsRequest{
+				UserId: userId, Points: 150, Reference: fmt.Sprintf("payment-%d", i),
+			})
+			if errors.Is(err, services.ErrInsufficientPoints) {
+				return
+			}
+			if assert.NoError(t, err) {
+				mu.Lock()
+				redeemed += 150
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	account, err := loyalty.GetAccount(ctx, userId)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000+workers*100)-redeemed, account.Balance)
+	assert.Equal(t, int64(1000+workers*100), account.LifetimePoints)
+
+	history, err := loyalty.History(ctx, userId, 1, 100)
+	require.NoError(t, err)
+	var sum int64
+	for _, entry := range history.Entries {
+		sum += entry.Points
+		assert.GreaterOrEqual(t, entry.BalanceAfter, int64(0))
+	}
+	assert.Equal(t, account.Balance, sum)
+	assert.Equal(t, account.Balance, history.Entries[0].BalanceAfter)
+}
+
+func TestReverseRedemptionRefundsOnce(t *testing.T) {
+	loyalty, userId := newLoyaltyService(t)
+	ctx := context.Background()
+	_, err := loyalty.EarnForStay(ctx, &models.StayCompletedRequest{UserId: userId, BookingId: "seed", AmountSpent: 50})
+	require.NoError(t, err)
+
+	redemption, err := loyalty.RedeemPoints(ctx, &models.RedeemPointsRequest{UserId: userId, Points: 300, Reference: "pay-1"})
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, redemption.Amount)
+	assert.Equal(t, int64(200), redemption.Balance)
+
+	_, err = loyalty.RedeemPoints(ctx, &models.RedeemPointsRequest{UserId: userId, Points: 300, Reference: "pay-2"})
+	assert.ErrorIs(t, err, services.ErrInsufficientPoints)
+
+	for i := 0; i < 2; i++ {
+		_, err = loyalty.ReverseRedemption(ctx, userId, "pay-1")
+		require.NoError(t, err)
+	}
+	account, err := loyalty.GetAccount(ctx, userId)
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), account.Balance)
+	assert.Equal(t, int64(500), account.LifetimePoints)
+}
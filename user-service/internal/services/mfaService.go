@@ -0,0 +1,374 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
+)
+
+var (
+	ErrInvalidMFAToken     = errors.New("mfa token is invalid or has expired, please log in again")
+	ErrInvalidMFACode      = errors.New("invalid authentication code")
+	ErrMFARequiredByPolicy = errors.New("two-factor authentication is required for your role")
+)
+
+type MFAConfig struct {
+	Issuer        string
+	RequiredRoles []string
+	TokenTTL      time.Duration
+	MaxAttempts   int
+	RecoveryCodes int
+}
+
+// MFAService manages TOTP enrollment and the second step of a two-step login
+type MFAService struct {
+	mfaRepo     *postgres.MFARepository
+	userRepo    *postgres.UserRepository
+	authService *AuthService
+	box         *security.SecretBox
+	signer      *security.TokenSigner
+	cfg         MFAConfig
+
+	mu       sync.Mutex
+	attempts map[string]*mfaAttempts
+}
+
+// mfaAttempts counts wrong codes sent with one mfa token
+type mfaAttempts struct {
+	failures  int
+	expiresAt time.Time
+}
+
+func NewMFAService(mfaRepo *postgres.MFARepository, userRepo *postgres.UserRepository, authService *AuthService,
+	box *security.SecretBox, signer *security.TokenSigner, cfg MFAConfig) *MFAService {
+	return &MFAService{
+		mfaRepo:     mfaRepo,
+		userRepo:    userRepo,
+		authService: authService,
+		box:         box,
+		signer:      signer,
+		cfg:         cfg,
+		attempts:    map[string]*mfaAttempts{},
+	}
+}
+
+// Required reports whether policy makes the user's role enable a second factor
+func (s *MFAService) Required(user *models.User) bool {
+	return slices.Contains(s.cfg.RequiredRoles, string(user.Role))
+}
+
+// loginChallenge returns the response that replaces tokens for users who must pass a
+// second factor, or nil if the password was enough
+func (s *MFAService) loginChallenge(ctx context.Context, user *models.User) (*models.LoginResponse, error) {
+	purpose := ""
+	if mfa, err := s.mfaRepo.GetMFA(ctx, user.Id); err == nil && mfa.Enabled {
+		purpose = models.MFAPurposeVerify
+	} else if s.Required(user) {
+		purpose = models.MFAPurposeEnroll
+	}
+	if purpose == "" {
+		return nil, nil
+	}
+
+	token, err := s.authService.security.GenerateMFAToken(user.Id, purpose, s.cfg.TokenTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate mfa token: %w", err)
+	}
+	user.PasswordHash = ""
+	return &models.LoginResponse{
+		User:                  *user,
+		MFARequired:           purpose == models.MFAPurposeVerify,
+		MFAEnrollmentRequired: purpose == models.MFAPurposeEnroll,
+		MFAToken:              token,
+	}, nil
+}
+
+func (s *MFAService) Status(ctx context.Context, userId string) (*models.MFAStatusResponse, error) {
+	user, err := s.userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	status := &models.MFAStatusResponse{Required: s.Required(user)}
+	if mfa, err := s.mfaRepo.GetMFA(ctx, userId); err == nil && mfa.Enabled {
+		status.Enabled = true
+		status.EnabledAt = mfa.EnabledAt
+		if status.RecoveryCodesRemaining, err = s.mfaRepo.CountRecoveryCodes(ctx, userId); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// BeginEnrollment creates a new secret for the user to add to their authenticator app.
+// It stays inactive until ConfirmEnrollment sees a code generated from it
+func (s *MFAService) BeginEnrollment(ctx context.Context, userId string) (*models.MFAEnrollResponse, error) {
+	user, err := s.userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.box.Seal(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.SavePendingEnrollment(ctx, userId, sealed); err != nil {
+		return nil, err
+	}
+	return &models.MFAEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: security.TOTPURI(s.cfg.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmEnrollment enables the pending secret once the user proves their app generates
+// its codes, and returns a fresh set of recovery codes
+func (s *MFAService) ConfirmEnrollment(ctx context.Context, userId, code string) ([]string, error) {
+	mfa, err := s.mfaRepo.GetMFA(ctx, userId)
+	if err != nil {
+		return nil, errors.New("no pending two-factor enrollment")
+	}
+	if mfa.Enabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	secret, err := s.box.Open(mfa.SecretEncrypted)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := security.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.EnableMFA(ctx, userId, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// BeginEnrollmentWithToken is BeginEnrollment for users whose login is waiting on a
+// required enrollment
+func (s *MFAService) BeginEnrollmentWithToken(ctx context.Context, mfaToken string) (*models.MFAEnrollResponse, error) {
+	claims, err := s.parseToken(ctx, mfaToken, models.MFAPurposeEnroll)
+	if err != nil {
+		return nil, err
+	}
+	return s.BeginEnrollment(ctx, claims.UserId)
+}
+
+// ConfirmEnrollmentWithToken confirms a required enrollment and finishes the login it
+// interrupted
+func (s *MFAService) ConfirmEnrollmentWithToken(ctx context.Context, mfaToken, code string, client models.ClientInfo) (*models.MFAConfirmResponse, error) {
+	claims, err := s.parseToken(ctx, mfaToken, models.MFAPurposeEnroll)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := s.ConfirmEnrollment(ctx, claims.UserId, code)
+	if errors.Is(err, ErrInvalidMFACode) {
+		s.failedAttempt(ctx, claims)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	login, err := s.finishLogin(ctx, claims, client)
+	if err != nil {
+		return nil, err
+	}
+	return &models.MFAConfirmResponse{RecoveryCodes: codes, Login: login}, nil
+}
+
+// VerifyLogin checks the second factor, a TOTP code or a recovery code, and issues
+// the tokens for the login
+func (s *MFAService) VerifyLogin(ctx context.Context, mfaToken, code string, client models.ClientInfo) (*models.LoginResponse, error) {
+	claims, err := s.parseToken(ctx, mfaToken, models.MFAPurposeVerify)
+	if err != nil {
+		return nil, err
+	}
+	//wrong codes count towards the account's lockout like wrong passwords, so logging
+	//in again for a fresh mfa token doesn't buy more guesses
+	if protection := s.authService.protection; protection != nil {
+		if err := protection.CheckAccount(ctx, claims.UserId); err != nil {
+			s.authService.recordAttempt(ctx, models.AuditLoginFailed, claims.UserId, client, models.AuditOutcomeFailure,
+				map[string]interface{}{"reason": "account_locked"})
+			return nil, err
+		}
+	}
+	if err := s.verifyCode(ctx, claims.UserId, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			s.failedAttempt(ctx, claims)
+			if protection := s.authService.protection; protection != nil {
+				protection.RecordFailure(ctx, claims.UserId, "", "invalid_mfa_code", client)
+			} else {
+				s.authService.recordAttempt(ctx, models.AuditLoginFailed, claims.UserId, client, models.AuditOutcomeFailure,
+					map[string]interface{}{"reason": "invalid_mfa_code"})
+			}
+		}
+		return nil, err
+	}
+	return s.finishLogin(ctx, claims, client)
+}
+
+// Disable turns off the second factor. It needs the password and a current code, and
+// is refused where policy requires a second factor
+func (s *MFAService) Disable(ctx context.Context, userId, password, code string) error {
+	user, err := s.userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if s.Required(user) {
+		return ErrMFARequiredByPolicy
+	}
+	matches, err := s.authService.verifyPassword(ctx, userId, password)
+	if err != nil {
+		return err
+	}
+	if !matches {
+		return errors.New("password is incorrect")
+	}
+	if err := s.verifyCode(ctx, userId, code); err != nil {
+		return err
+	}
+	return s.mfaRepo.DisableMFA(ctx, userId)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, after checking a current code
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userId, code string) ([]string, error) {
+	if err := s.verifyCode(ctx, userId, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.ReplaceRecoveryCodes(ctx, userId, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifyCode accepts a TOTP code that hasn't been used before, or an unused recovery code
+func (s *MFAService) verifyCode(ctx context.Context, userId, code string) error {
+	mfa, err := s.mfaRepo.GetMFA(ctx, userId)
+	if err != nil || !mfa.Enabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+	secret, err := s.box.Open(mfa.SecretEncrypted)
+	if err != nil {
+		return err
+	}
+
+	if step, ok := security.ValidateTOTP(secret, code, time.Now()); ok {
+		fresh, err := s.mfaRepo.UseStep(ctx, userId, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := s.mfaRepo.ConsumeRecoveryCode(ctx, userId, s.signer.Hash(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *MFAService) parseToken(ctx context.Context, mfaToken, purpose string) (*security.MFAClaims, error) {
+	claims, err := s.authService.security.VerifyMFAToken(mfaToken)
+	if err != nil || claims.Purpose != purpose {
+		return nil, ErrInvalidMFAToken
+	}
+	revoked, err := s.authService.revocations.IsRevoked(ctx, security.TokenKey(claims.ID))
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrInvalidMFAToken
+	}
+	return claims, nil
+}
+
+// finishLogin spends the mfa token so it can't start a second session
+func (s *MFAService) finishLogin(ctx context.Context, claims *security.MFAClaims, client models.ClientInfo) (*models.LoginResponse, error) {
+	if err := s.revokeToken(ctx, claims); err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetUserById(ctx, claims.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return s.authService.startSession(ctx, user, client)
+}
+
+// failedAttempt counts wrong codes against an mfa token and burns the token when
+// there have been too many, so codes can't be brute forced
+func (s *MFAService) failedAttempt(ctx context.Context, claims *security.MFAClaims) {
+	s.mu.Lock()
+	now := time.Now()
+	for jti, a := range s.attempts {
+		if now.After(a.expiresAt) {
+			delete(s.attempts, jti)
+		}
+	}
+	a, ok := s.attempts[claims.ID]
+	if !ok {
+		a = &mfaAttempts{expiresAt: claims.ExpiresAt.Time}
+		s.attempts[claims.ID] = a
+	}
+	a.failures++
+	exhausted := a.failures >= s.cfg.MaxAttempts
+	s.mu.Unlock()
+
+	if exhausted {
+		_ = s.revokeToken(ctx, claims)
+	}
+}
+
+func (s *MFAService) revokeToken(ctx context.Context, claims *security.MFAClaims) error {
+	s.mu.Lock()
+	delete(s.attempts, claims.ID)
+	s.mu.Unlock()
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.authService.revocations.Revoke(ctx, security.TokenKey(claims.ID), ttl)
+}
+
+func (s *MFAService) newRecoveryCodes() ([]string, []string, error) {
+	codes, err := security.GenerateRecoveryCodes(s.cfg.RecoveryCodes)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = s.signer.Hash(normalizeRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
@@ -0,0 +1,112 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/services"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/database"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newMFALogin builds the login path with a lock after lockAfter failures
+func newMFALogin(t *testing.T, lockAfter int) (*services.AuthService, *services.MFAService) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set, skipping integration test")
+	}
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, database.InitializeSchema(db))
+
+	signingKeyBox, err := security.NewSecretBox("test-signing-key")
+	require.NoError(t, err)
+	keyRing := security.NewKeyRing(postgres.NewSigningKeyRepository(db, signingKeyBox), 24*time.Hour, time.Hour)
+	require.NoError(t, keyRing.Load(context.Background()))
+	userRepo := postgres.NewUserRepository(db)
+	authService := services.NewAuthService(userRepo, postgres.NewRefreshTokenRepository(db),
+		postgres.NewSessionRepository(db), security.NewMemoryRevocationStore(),
+		security.NewJWTManager(keyRing, "test-refresh-key", 15*time.Minute, time.Hour),
+		security.NewPasswordHasher(security.BcryptScheme{Cost: bcrypt.MinCost}))
+
+	secretBox, err := security.NewSecretBox("test-mfa-key")
+	require.NoError(t, err)
+	mfaService := services.NewMFAService(postgres.NewMFARepository(db), userRepo, authService, secretBox,
+		security.NewTokenSigner("test-token-secret"),
+		services.MFAConfig{Issuer: "Test", TokenTTL: time.Minute, MaxAttempts: 5, RecoveryCodes: 2})
+	authService.SetMFAService(mfaService)
+
+	auditService := services.NewAuditService(postgres.NewAuditRepository(db))
+	authService.SetAuditService(auditService)
+	authService.SetLoginProtection(services.NewLoginProtection(userRepo, auditService, services.LoginProtectionConfig{
+		FailureWindow:   time.Minute,
+		DelayAfter:      100,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Second,
+		LockAfter:       lockAfter,
+		LockoutDuration: time.Minute,
+		IPMaxFailures:   1000,
+	}))
+	return authService, mfaService
+}
+
+func TestWrongMFACodesLockTheAccount(t *testing.T) {
+	authService, mfaService := newMFALogin(t, 3)
+	ctx := context.Background()
+	client := models.ClientInfo{IPAddress: "198.51.100.20", UserAgent: "mfa-test/1.0"}
+	email := "mfa-" + uuid.New().String()[:8] + "@example.com"
+
+	user, err := authService.Register(ctx, &models.CreateUserRequest{
+		Email: email, Password: "two-factor-pass", FirstName: "Tess", LastName: "Otp",
+	})
+	require.NoError(t, err)
+	enrollment, err := mfaService.BeginEnrollment(ctx, user.Id)
+	require.NoError(t, err)
+	code, err := security.TOTPCode(enrollment.Secret, security.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	_, err = mfaService.ConfirmEnrollment(ctx, user.Id, code)
+	require.NoError(t, err)
+
+	login := func() string {
+		t.Helper()
+		response, err := authService.Login(ctx, &models.LoginRequest{Email: email, Password: "two-factor-pass"}, client)
+		require.NoError(t, err)
+		require.True(t, response.MFARequired)
+		return response.MFAToken
+	}
+
+	first := login()
+	for i := 0; i < 2; i++ {
+		_, err := mfaService.VerifyLogin(ctx, first, "bad-code", client)
+		assert.ErrorIs(t, err, services.ErrInvalidMFACode)
+	}
+
+	// the correct password doesn't forgive the wrong codes, the third one locks
+	second := login()
+	_, err = mfaService.VerifyLogin(ctx, second, "bad-code", client)
+	assert.ErrorIs(t, err, services.ErrInvalidMFACode)
+
+	var throttled *services.LoginThrottledError
+	_, err = authService.Login(ctx, &models.LoginRequest{Email: email, Password: "two-factor-pass"}, client)
+	require.True(t, errors.As(err, &throttled), "login after wrong codes: %v", err)
+	assert.True(t, throttled.Locked)
+
+	// a token issued before the lock can't keep guessing either
+	third, err := security.TOTPCode(enrollment.Secret, security.TOTPStep(time.Now())+1)
+	require.NoError(t, err)
+	_, err = mfaService.VerifyLogin(ctx, second, third, client)
+	require.True(t, errors.As(err, &throttled), "verify after lock: %v", err)
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/oidc"
+)
+
+var (
+	ErrUnknownProvider      = errors.New("unknown identity provider")
+	ErrInvalidLoginState    = errors.New("login attempt is invalid or has expired, please start again")
+	ErrProviderLoginFailed  = errors.New("the identity provider login could not be verified")
+	ErrProviderEmailMissing = errors.New("the identity provider didn't share a verified email address")
+	ErrAccountNotLinkable   = errors.New("an account with this email exists but isn't verified, log in with your password and verify it first")
+	ErrLastLoginMethod      = errors.New("this is your only way to log in, set a password before unlinking it")
+)
+
+// OIDCService logs users in through external OpenID Connect providers. A provider
+// account is linked to a user by an identity; the first login links to the account
+// with the same verified email, or creates one
+type OIDCService struct {
+	providers    map[string]*oidc.Provider
+	identityRepo *postgres.IdentityRepository
+	userRepo     *postgres.UserRepository
+	authService  *AuthService
+	auditService *AuditService
+	stateTTL     time.Duration
+}
+
+func NewOIDCService(identityRepo *postgres.IdentityRepository, userRepo *postgres.UserRepository, authService *AuthService,
+	auditService *AuditService, stateTTL time.Duration, providers ...*oidc.Provider) *OIDCService {
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+	return &OIDCService{
+		providers:    byName,
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		authService:  authService,
+		auditService: auditService,
+		stateTTL:     stateTTL,
+	}
+}
+
+// Providers lists the configured provider names
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BeginLogin returns the provider URL to send the user to. The state, nonce and PKCE
+// verifier are kept server side until the user comes back
+func (s *OIDCService) BeginLogin(ctx context.Context, providerName string) (*models.OIDCAuthorizeResponse, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.identityRepo.SaveLoginState(ctx, &models.OIDCLoginState{
+		StateHash:    hashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(s.stateTTL),
+	}); err != nil {
+		return nil, err
+	}
+	return &models.OIDCAuthorizeResponse{AuthorizationURL: authURL, State: state}, nil
+}
+
+// CompleteLogin exchanges the code the provider returned, validates the ID token and
+// logs in the linked user, linking or creating one on the first login
+func (s *OIDCService) CompleteLogin(ctx context.Context, providerName, code, state string, client models.ClientInfo) (*models.LoginResponse, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	loginState, err := s.identityRepo.ConsumeLoginState(ctx, hashToken(state))
+	if err != nil || loginState.Provider != providerName {
+		return nil, ErrInvalidLoginState
+	}
+
+	idToken, err := provider.Exchange(ctx, code, loginState.CodeVerifier)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProviderLoginFailed, err)
+	}
+	claims, err := provider.VerifyIDToken(ctx, idToken, loginState.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProviderLoginFailed, err)
+	}
+
+	user, err := s.resolveUser(ctx, providerName, claims, client.IPAddress)
+	if err != nil {
+		return nil, err
+	}
+	return s.authService.completeLogin(ctx, user, client)
+}
+
+func (s *OIDCService) resolveUser(ctx context.Context, providerName string, claims *oidc.IDClaims, ipAddress string) (*models.User, error) {
+	identity, err := s.identityRepo.GetIdentity(ctx, providerName, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		if err := s.identityRepo.TouchIdentity(ctx, identity.Id, claims.Email); err != nil {
+			return nil, err
+		}
+		return s.userRepo.GetUserById(ctx, identity.UserId)
+	}
+
+	//only an address the provider vouches for can be matched to, or create, an account
+	email := strings.TrimSpace(claims.Email)
+	if email == "" || !bool(claims.EmailVerified) {
+		return nil, ErrProviderEmailMissing
+	}
+
+	user, _ := s.userRepo.GetUserByEmail(ctx, email)
+	if user != nil && !user.EmailVerified {
+		//whoever registered it never proved they own it, linking would hand them this login
+		return nil, ErrAccountNotLinkable
+	}
+	if user == nil {
+		if user, err = s.createUser(ctx, email, claims); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	if err := s.identityRepo.CreateIdentity(ctx, &models.Identity{
+		Id:          uuid.New().String(),
+		UserId:      user.Id,
+		Provider:    providerName,
+		Subject:     claims.Subject,
+		Email:       email,
+		CreatedAt:   now,
+		LastLoginAt: &now,
+	}); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(ctx, models.AuditIdentityLinked, user.Id, "", ipAddress, map[string]interface{}{
+		"provider": providerName,
+	})
+	return user, nil
+}
+
+// createUser signs up someone new from their provider profile. They have no password
+// until they set one with forgot password
+func (s *OIDCService) createUser(ctx context.Context, email string, claims *oidc.IDClaims) (*models.User, error) {
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName, lastName, _ = strings.Cut(claims.Name, " ")
+	}
+	user := &models.User{
+		Id:        uuid.New().String(),
+		Email:     email,
+		FirstName: firstName,
+		LastName:  lastName,
+		Role:      models.RoleCustomer,
+	}
+	if err := s.userRepo.CreateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	if err := s.userRepo.MarkEmailVerified(ctx, user.Id); err != nil {
+		return nil, err
+	}
+	user.EmailVerified = true
+	return user, nil
+}
+
+func (s *OIDCService) ListIdentities(ctx context.Context, userId string) ([]models.Identity, error) {
+	return s.identityRepo.ListIdentities(ctx, userId)
+}
+
+// Unlink removes a provider login, unless the user would be left with no way in
+func (s *OIDCService) Unlink(ctx context.Context, userId, identityId, ipAddress string) error {
+	identities, err := s.identityRepo.ListIdentities(ctx, userId)
+	if err != nil {
+		return err
+	}
+	passwordHash, err := s.userRepo.GetPasswordHash(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if passwordHash == "" && len(identities) <= 1 {
+		return ErrLastLoginMethod
+	}
+
+	if err := s.identityRepo.DeleteIdentity(ctx, userId, identityId); err != nil {
+		return err
+	}
+	s.auditService.Record(ctx, models.AuditIdentityUnlinked, userId, "", ipAddress, map[string]interface{}{
+		"identityId": identityId,
+	})
+	return nil
+}
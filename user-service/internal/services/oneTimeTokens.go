@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/notifications"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
+)
+
+// issueOneTimeToken replaces the user's outstanding tokens of a purpose with a new one
+// and returns the token to send them
+func issueOneTimeToken(ctx context.Context, tokenRepo *postgres.OneTimeTokenRepository, signer *security.TokenSigner,
+	userId string, purpose models.TokenPurpose, ttl time.Duration) (string, error) {
+	return issueToken(ctx, tokenRepo, signer, &models.OneTimeToken{UserId: userId, Purpose: purpose}, ttl)
+}
+
+// issueToken is issueOneTimeToken for records that carry more than the user and purpose
+func issueToken(ctx context.Context, tokenRepo *postgres.OneTimeTokenRepository, signer *security.TokenSigner,
+	record *models.OneTimeToken, ttl time.Duration) (string, error) {
+	if err := tokenRepo.InvalidateTokens(ctx, record.UserId, record.Purpose); err != nil {
+		return "", err
+	}
+
+	token, hash, err := signer.Generate()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	record.Id = uuid.New().String()
+	record.TokenHash = hash
+	record.ExpiresAt = now.Add(ttl)
+	record.CreatedAt = now
+	if err := tokenRepo.CreateToken(ctx, record); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// sendEmail goes through notification-service, or only logs the email when it isn't configured
+func sendEmail(ctx context.Context, mailer *notifications.Client, to, subject, body, notificationType string) error {
+	if mailer == nil {
+		log.Printf("notifications disabled, %s email to %s:\n%s", notificationType, to, body)
+		return nil
+	}
+	return mailer.SendEmail(ctx, to, subject, body, notificationType)
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/notifications"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
+)
+
+var ErrInvalidResetToken = errors.New("password reset link is invalid or has expired")
+
+type PasswordResetConfig struct {
+	TokenTTL       time.Duration
+	ResendInterval time.Duration
+	ResetURL       string
+}
+
+// PasswordResetService handles forgotten passwords with emailed single-use tokens
+type PasswordResetService struct {
+	userRepo    *postgres.UserRepository
+	tokenRepo   *postgres.OneTimeTokenRepository
+	signer      *security.TokenSigner
+	mailer      *notifications.Client
+	authService *AuthService
+	dispatcher  EmailDispatcher
+	cfg         PasswordResetConfig
+}
+
+// mailer may be nil, in which case reset links are only logged. Reset emails for
+// forgotten passwords are sent through dispatcher
+func NewPasswordResetService(userRepo *postgres.UserRepository, tokenRepo *postgres.OneTimeTokenRepository,
+	signer *security.TokenSigner, mailer *notifications.Client, authService *AuthService, dispatcher EmailDispatcher,
+	cfg PasswordResetConfig) *PasswordResetService {
+	return &PasswordResetService{
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		signer:      signer,
+		mailer:      mailer,
+		authService: authService,
+		dispatcher:  dispatcher,
+		cfg:         cfg,
+	}
+}
+
+// ForgotPassword emails a reset link to the account in the background. Nothing about
+// the account, whether it exists, was sent a link recently or the email failed, shows
+// in the answer or how long it takes, so it can't be used to discover accounts
+func (s *PasswordResetService) ForgotPassword(ctx context.Context, email string) {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return
+	}
+
+	s.dispatcher.Dispatch(func(ctx context.Context) {
+		if err := s.sendIfDue(ctx, user); err != nil {
+			log.Printf("failed to send password reset email to user %s: %v", user.Id, err)
+		}
+	})
+}
+
+// sendIfDue sends a reset link unless one went out within the resend interval
+func (s *PasswordResetService) sendIfDue(ctx context.Context, user *models.User) error {
+	lastSent, err := s.tokenRepo.LastIssuedAt(ctx, user.Id, models.TokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+	if time.Since(lastSent) < s.cfg.ResendInterval {
+		return nil
+	}
+	return s.SendResetLink(ctx, user)
+}
+
+// SendResetLink emails the user a new reset link, replacing any earlier one
+func (s *PasswordResetService) SendResetLink(ctx context.Context, user *models.User) error {
+	token, err := issueOneTimeToken(ctx, s.tokenRepo, s.signer, user.Id, models.TokenPurposePasswordReset, s.cfg.TokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.cfg.ResetURL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Hi %s,\n\nWe received a request to reset your password. Open the link below to choose a new one:\n\n%s\n\n"+
+		"The link expires in %s. If you didn't ask for this you can ignore this email.", user.FirstName, link, s.cfg.TokenTTL)
+	if err := sendEmail(ctx, s.mailer, user.Email, "Reset your password", body, notifications.TypePasswordReset); err != nil {
+		return fmt.Errorf("failed to send password reset email: %w", err)
+	}
+	return nil
+}
+
+// ResetPassword consumes a reset token, sets the new password and signs the user out
+// everywhere, since whoever had the old password may still hold a session
+func (s *PasswordResetService) ResetPassword(ctx context.Context, token, newPassword string, client models.ClientInfo) error {
+	record, err := s.tokenRepo.GetToken(ctx, models.TokenPurposePasswordReset, s.signer.Hash(token))
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	user, err := s.userRepo.GetUserById(ctx, record.UserId)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	//a password the policy refuses leaves the link usable for another try
+	if err := s.authService.checkNewPassword(ctx, user, newPassword); err != nil {
+		return err
+	}
+	if _, err := s.tokenRepo.ConsumeToken(ctx, models.TokenPurposePasswordReset, record.TokenHash); err != nil {
+		return ErrInvalidResetToken
+	}
+	if err := s.authService.setPassword(ctx, user.Id, newPassword); err != nil {
+		return err
+	}
+	s.authService.recordAttempt(ctx, models.AuditPasswordReset, user.Id, client, models.AuditOutcomeSuccess, nil)
+	if err := s.authService.RevokeAllSessions(ctx, user.Id); err != nil {
+		return err
+	}
+
+	//following the emailed link proves the user owns the address
+	if !user.EmailVerified {
+		if err := s.userRepo.MarkEmailVerified(ctx, user.Id); err != nil {
+			return err
+		}
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nYour password was just reset and all of your sessions were signed out. "+
+		"If this wasn't you, contact support immediately.", user.FirstName)
+	if err := sendEmail(ctx, s.mailer, user.Email, "Your password was changed", body, notifications.TypePasswordReset); err != nil {
+		//the reset itself succeeded, don't fail it over the notice
+		log.Printf("failed to send password changed notice to user %s: %v", user.Id, err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/internalapi"
+)
+
+// PrivacyClients are the services holding user data. A nil client means that service
+// isn't configured, its data is left out of exports and not erased
+type PrivacyClients struct {
+	Bookings      *internalapi.Client
+	Payments      *internalapi.Client
+	Notifications *internalapi.Client
+}
+
+// PrivacyService answers data export and erasure requests across every service
+type PrivacyService struct {
+	userRepo     *postgres.UserRepository
+	identityRepo *postgres.IdentityRepository
+	guestRepo    *postgres.GuestProfileRepository
+	loyalty      *LoyaltyService
+	authService  *AuthService
+	auditService *AuditService
+	clients      PrivacyClients
+}
+
+func NewPrivacyService(userRepo *postgres.UserRepository, identityRepo *postgres.IdentityRepository,
+	guestRepo *postgres.GuestProfileRepository, loyalty *LoyaltyService, authService *AuthService,
+	auditService *AuditService, clients PrivacyClients) *PrivacyService {
+	return &PrivacyService{
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		guestRepo:    guestRepo,
+		loyalty:      loyalty,
+		authService:  authService,
+		auditService: auditService,
+		clients:      clients,
+	}
+}
+
+// ExportUserData gathers the user's data from this and every other service. It fails
+// rather than hand out an archive with a configured service silently missing
+func (s *PrivacyService) ExportUserData(ctx context.Context, userId, ipAddress string) (*models.DataExport, error) {
+	user, err := s.userRepo.GetUserDetails(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.authService.ListSessions(ctx, userId, "")
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.auditService.ListAllEvents(ctx, models.AuditFilter{UserId: userId})
+	if err != nil {
+		return nil, err
+	}
+	identities, err := s.identityRepo.ListIdentities(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	guestProfile, err := s.guestRepo.GetGuestProfile(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	loyalty, err := s.loyalty.Export(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &models.DataExport{
+		ExportedAt:     time.Now().UTC(),
+		Profile:        user,
+		GuestProfile:   guestProfile,
+		Loyalty:        loyalty,
+		Sessions:       sessions,
+		Identities:     identities,
+		SecurityEvents: events,
+	}
+	byEmail := url.Values{"email": {user.Email}}
+	fetches := []struct {
+		client *internalapi.Client
+		path   string
+		query  url.Values
+		into   *json.RawMessage
+	}{
+		{s.clients.Bookings, "/users/" + url.PathEscape(userId) + "/data", nil, &export.Bookings},
+		{s.clients.Payments, "/customers/data", byEmail, &export.Payments},
+		{s.clients.Notifications, "/notifications", byEmail, &export.Notifications},
+	}
+	for _, f := range fetches {
+		if f.client == nil {
+			continue
+		}
+		data, err := f.client.Get(ctx, f.path, f.query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export data from %s: %w", f.client.Name(), err)
+		}
+		*f.into = data
+	}
+	export.Omitted = s.unconfigured()
+
+	s.auditService.Record(ctx, models.AuditDataExported, userId, "", ipAddress, nil)
+	return export, nil
+}
+
+// DeleteAccount erases the user's personal data everywhere. Other services go first,
+// while we still know the email they file data under, so a failure part way can be
+// retried. Bookings and payments are kept for accounting with the personal details
+// stripped; the audit log is kept as a security record
+func (s *PrivacyService) DeleteAccount(ctx context.Context, userId, password, ipAddress string) error {
+	user, err := s.userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+	matches, err := s.authService.verifyPassword(ctx, userId, password)
+	if err != nil {
+		return err
+	}
+	if !matches {
+		return ErrIncorrectPassword
+	}
+
+	placeholder := deletedEmail(userId)
+	byEmail := url.Values{"email": {user.Email}}
+	erasures := []struct {
+		client *internalapi.Client
+		path   string
+		query  url.Values
+	}{
+		{s.clients.Bookings, "/users/" + url.PathEscape(userId) + "/data", nil},
+		{s.clients.Payments, "/customers/data", url.Values{"email": {user.Email}, "placeholder": {placeholder}}},
+		{s.clients.Notifications, "/notifications", byEmail},
+	}
+	for _, e := range erasures {
+		if e.client == nil {
+			continue
+		}
+		if _, err := e.client.Delete(ctx, e.path, e.query); err != nil {
+			return fmt.Errorf("failed to erase data in %s: %w", e.client.Name(), err)
+		}
+	}
+
+	if err := s.authService.RevokeAllSessions(ctx, userId); err != nil {
+		return err
+	}
+	if err := s.loyalty.Forfeit(ctx, userId); err != nil {
+		return err
+	}
+	if err := s.userRepo.AnonymizeUser(ctx, userId, placeholder); err != nil {
+		return err
+	}
+	s.auditService.Record(ctx, models.AuditAccountDeleted, userId, "", ipAddress, map[string]interface{}{
+		"omitted": s.unconfigured(),
+	})
+	return nil
+}
+
+func (s *PrivacyService) unconfigured() []string {
+	var omitted []string
+	if s.clients.Bookings == nil {
+		omitted = append(omitted, "bookings")
+	}
+	if s.clients.Payments == nil {
+		omitted = append(omitted, "payments")
+	}
+	if s.clients.Notifications == nil {
+		omitted = append(omitted, "notifications")
+	}
+	return omitted
+}
+
+// deletedEmail is what a deleted account's email becomes here and on retained payments.
+// The .invalid domain can never receive mail
+func deletedEmail(userId string) string {
+	return "deleted-" + userId + "@deleted.invalid"
+}
@@ -0,0 +1,80 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/services"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRBACService runs against Postgres with the seeded roles. Only refusals are
+// tested, so no sessions are ever revoked
+func newRBACService(t *testing.T) (*services.RBACService, func(models.UserRole) string) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set, skipping integration test")
+	}
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, database.InitializeSchema(db))
+
+	userRepo := postgres.NewUserRepository(db)
+	createUser := func(role models.UserRole) string {
+		id := uuid.New().String()
+		require.NoError(t, userRepo.CreateUser(context.Background(), &models.User{
+			Id: id, Email: "rbac-" + id[:8] + "@example.com", FirstName: "Role", LastName: "Test", Role: role,
+		}))
+		return id
+	}
+	return services.NewRBACService(postgres.NewRoleRepository(db), userRepo, nil,
+		services.NewAuditService(postgres.NewAuditRepository(db)), time.Minute), createUser
+}
+
+func TestSetRolePermissionsRefusesEscalation(t *testing.T) {
+	rbac, createUser := newRBACService(t)
+	ctx := context.Background()
+	manager := createUser(models.RoleManager)
+
+	// managers don't hold payments:refund, nor the wildcard
+	err := rbac.SetRolePermissions(ctx, manager, auth.RoleFrontDesk, []string{auth.PermBookingsRead, auth.PermPaymentsRefund}, "")
+	assert.ErrorIs(t, err, services.ErrEscalation)
+	err = rbac.SetRolePermissions(ctx, manager, auth.RoleFrontDesk, []string{auth.PermAll}, "")
+	assert.ErrorIs(t, err, services.ErrEscalation)
+
+	// nor can they edit a role that already has a permission they lack
+	err = rbac.SetRolePermissions(ctx, manager, auth.RoleAccountant, []string{auth.PermBookingsRead}, "")
+	assert.ErrorIs(t, err, services.ErrOutranked)
+
+	permissions, err := rbac.PermissionsFor(ctx, auth.RoleFrontDesk)
+	require.NoError(t, err)
+	assert.NotContains(t, permissions, auth.PermPaymentsRefund)
+}
+
+func TestAssignRoleRefusesEscalation(t *testing.T) {
+	rbac, createUser := newRBACService(t)
+	ctx := context.Background()
+	manager := createUser(models.RoleManager)
+
+	guest := createUser(models.RoleCustomer)
+	_, err := rbac.AssignRole(ctx, manager, guest, auth.RoleAccountant, "")
+	assert.ErrorIs(t, err, services.ErrEscalation, "accountants can refund, managers can't")
+	_, err = rbac.AssignRole(ctx, manager, guest, auth.RoleAdmin, "")
+	assert.ErrorIs(t, err, services.ErrEscalation)
+
+	accountant := createUser(models.RoleAccountant)
+	_, err = rbac.AssignRole(ctx, manager, accountant, auth.RoleCustomer, "")
+	assert.ErrorIs(t, err, services.ErrOutranked)
+}
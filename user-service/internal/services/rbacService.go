@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
+)
+
+var ErrBuiltInRole = errors.New("the permissions of owner and admin can't be changed")
+
+// ErrEscalation is returned when an admin tries to hand out permissions they don't
+// hold themselves, directly or by assigning a role that has them
+var ErrEscalation = errors.New("you can't grant permissions you don't hold")
+
+// RBACService resolves role permissions for access tokens and lets admins manage them
+type RBACService struct {
+	roleRepo    *postgres.RoleRepository
+	userRepo    *postgres.UserRepository
+	authService *AuthService
+	audit       *AuditService
+	cacheTTL    time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedPermissions
+}
+
+type cachedPermissions struct {
+	permissions []string
+	loadedAt    time.Time
+}
+
+func NewRBACService(roleRepo *postgres.RoleRepository, userRepo *postgres.UserRepository, authService *AuthService,
+	audit *AuditService, cacheTTL time.Duration) *RBACService {
+	return &RBACService{
+		roleRepo:    roleRepo,
+		userRepo:    userRepo,
+		authService: authService,
+		audit:       audit,
+		cacheTTL:    cacheTTL,
+		cache:       map[string]cachedPermissions{},
+	}
+}
+
+// PermissionsFor returns a role's permissions, cached briefly since every token needs them
+func (s *RBACService) PermissionsFor(ctx context.Context, role string) ([]string, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[role]
+	s.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < s.cacheTTL {
+		return cached.permissions, nil
+	}
+
+	permissions, err := s.roleRepo.GetRolePermissions(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.cache[role] = cachedPermissions{permissions: permissions, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return permissions, nil
+}
+
+func (s *RBACService) ListRoles(ctx context.Context) ([]models.Role, error) {
+	return s.roleRepo.ListRoles(ctx)
+}
+
+func (s *RBACService) ListPermissions(ctx context.Context) ([]models.Permission, error) {
+	return s.roleRepo.ListPermissions(ctx)
+}
+
+// SetRolePermissions replaces a role's permissions. The actor must hold every permission
+// the role has now and every one it is given. Tokens already issued keep the old
+// permissions until they are refreshed
+func (s *RBACService) SetRolePermissions(ctx context.Context, actorId, role string, permissions []string, ip string) error {
+	if role == string(models.RoleOwner) || role == string(models.RoleAdmin) {
+		return ErrBuiltInRole
+	}
+	exists, err := s.roleRepo.RoleExists(ctx, role)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("role %s does not exist", role)
+	}
+
+	known, err := s.roleRepo.ListPermissions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, permission := range permissions {
+		if !slices.ContainsFunc(known, func(p models.Permission) bool { return p.Name == permission }) {
+			return fmt.Errorf("unknown permission: %s", permission)
+		}
+	}
+
+	previous, err := s.roleRepo.GetRolePermissions(ctx, role)
+	if err != nil {
+		return err
+	}
+	actorPermissions, err := s.actorPermissions(ctx, actorId)
+	if err != nil {
+		return err
+	}
+	if !holdsAll(actorPermissions, previous) {
+		return ErrOutranked
+	}
+	if !holdsAll(actorPermissions, permissions) {
+		return ErrEscalation
+	}
+	if err := s.roleRepo.SetRolePermissions(ctx, role, permissions); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.cache, role)
+	s.mu.Unlock()
+
+	s.audit.Record(ctx, models.AuditRoleUpdated, "", actorId, ip, map[string]interface{}{
+		"role":     role,
+		"previous": previous,
+		"current":  permissions,
+	})
+	return nil
+}
+
+// AssignRole changes a user's role and signs them out, so no token keeps the old
+// role's permissions. The actor must hold every permission of both the user's current
+// role and the new one
+func (s *RBACService) AssignRole(ctx context.Context, actorId, userId, role, ip string) (*models.User, error) {
+	if actorId == userId {
+		return nil, errors.New("you can't change your own role")
+	}
+	exists, err := s.roleRepo.RoleExists(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("role %s does not exist", role)
+	}
+
+	user, err := s.userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	previous := user.Role
+	if previous == models.UserRole(role) {
+		return user, nil
+	}
+
+	actorPermissions, err := s.actorPermissions(ctx, actorId)
+	if err != nil {
+		return nil, err
+	}
+	currentPermissions, err := s.PermissionsFor(ctx, string(previous))
+	if err != nil {
+		return nil, err
+	}
+	if !holdsAll(actorPermissions, currentPermissions) {
+		return nil, ErrOutranked
+	}
+	newPermissions, err := s.PermissionsFor(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	if !holdsAll(actorPermissions, newPermissions) {
+		return nil, ErrEscalation
+	}
+
+	if err := s.userRepo.UpdateRole(ctx, userId, models.UserRole(role)); err != nil {
+		return nil, err
+	}
+	if err := s.authService.RevokeAllSessions(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, models.AuditRoleChanged, userId, actorId, ip, map[string]interface{}{
+		"previous": string(previous),
+		"current":  role,
+	})
+	user.Role = models.UserRole(role)
+	return user, nil
+}
+
+// actorPermissions are the permissions of the admin's role as it is now, not as it
+// was when their token was issued
+func (s *RBACService) actorPermissions(ctx context.Context, actorId string) ([]string, error) {
+	actor, err := s.userRepo.GetUserById(ctx, actorId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load your account: %w", err)
+	}
+	return s.PermissionsFor(ctx, string(actor.Role))
+}
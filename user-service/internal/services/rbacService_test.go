@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltInRolesCantBeChanged(t *testing.T) {
+	s := NewRBACService(nil, nil, nil, nil, time.Minute)
+	for _, role := range []models.UserRole{models.RoleOwner, models.RoleAdmin} {
+		err := s.SetRolePermissions(context.Background(), "actor", string(role), []string{auth.PermBookingsRead}, "")
+		assert.ErrorIs(t, err, ErrBuiltInRole, string(role))
+	}
+}
+
+func TestNobodyAssignsTheirOwnRole(t *testing.T) {
+	s := NewRBACService(nil, nil, nil, nil, time.Minute)
+	_, err := s.AssignRole(context.Background(), "user-1", "user-1", string(models.RoleOwner), "")
+	assert.EqualError(t, err, "you can't change your own role")
+}
+
+func TestPermissionsForServesTheCache(t *testing.T) {
+	s := NewRBACService(nil, nil, nil, nil, time.Minute)
+	s.cache[string(models.RoleManager)] = cachedPermissions{permissions: managerPermissions, loadedAt: time.Now()}
+
+	permissions, err := s.PermissionsFor(context.Background(), string(models.RoleManager))
+	assert.NoError(t, err)
+	assert.Equal(t, managerPermissions, permissions)
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
+)
+
+// ListSessions returns the user's active sessions, flagging the one making the request
+func (s *AuthService) ListSessions(ctx context.Context, userId, currentSessionId string) ([]models.Session, error) {
+	sessions, err := s.sessionRepo.GetActiveSessions(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].Id == currentSessionId
+	}
+	return sessions, nil
+}
+
+// RevokeSession kills one of the user's sessions: its refresh tokens stop working
+// and its access tokens are rejected until they expire
+func (s *AuthService) RevokeSession(ctx context.Context, userId, sessionId string) error {
+	session, err := s.sessionRepo.GetSessionById(ctx, sessionId)
+	if err != nil || session.UserId != userId {
+		return errors.New("session not found")
+	}
+	return s.revokeSession(ctx, sessionId)
+}
+
+// RevokeAllSessions kills every session the user has
+func (s *AuthService) RevokeAllSessions(ctx context.Context, userId string) error {
+	sessions, err := s.sessionRepo.GetActiveSessions(ctx, userId)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if err := s.revokeSession(ctx, session.Id); err != nil {
+			return err
+		}
+	}
+	//also catch refresh tokens issued before sessions were tracked
+	return s.refreshTokenRepo.RevokeAllForUser(ctx, userId)
+}
+
+// RevokeAccessToken rejects a single access token until it expires
+func (s *AuthService) RevokeAccessToken(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	if tokenId == "" {
+		return nil
+	}
+	return s.revocations.Revoke(ctx, security.TokenKey(tokenId), time.Until(expiresAt))
+}
+
+func (s *AuthService) revokeSession(ctx context.Context, sessionId string) error {
+	if err := s.refreshTokenRepo.RevokeFamily(ctx, sessionId); err != nil {
+		return err
+	}
+	if err := s.sessionRepo.RevokeSession(ctx, sessionId); err != nil {
+		return err
+	}
+	//access tokens for the session live at most one access token lifetime
+	return s.revocations.Revoke(ctx, security.SessionKey(sessionId), s.security.AccessTokenDuration())
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
+)
+
+// ErrOutranked is returned when the account being managed has permissions its manager
+// lacks, so a manager can't lock out an owner or admin
+var ErrOutranked = errors.New("you can't manage an account whose role has permissions you don't")
+
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+)
+
+// UserAdminService lets staff find users and manage their accounts
+type UserAdminService struct {
+	userRepo      *postgres.UserRepository
+	authService   *AuthService
+	passwordReset *PasswordResetService
+	rbac          *RBACService
+	audit         *AuditService
+}
+
+func NewUserAdminService(userRepo *postgres.UserRepository, authService *AuthService,
+	passwordReset *PasswordResetService, rbac *RBACService, audit *AuditService) *UserAdminService {
+	return &UserAdminService{
+		userRepo:      userRepo,
+		authService:   authService,
+		passwordReset: passwordReset,
+		rbac:          rbac,
+		audit:         audit,
+	}
+}
+
+// ListUsers returns one page of users matching the search
+func (s *UserAdminService) ListUsers(ctx context.Context, query, role, status string, page, limit int) (*models.UserListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultUserPageSize
+	}
+	limit = min(limit, maxUserPageSize)
+
+	switch status {
+	case "", models.UserStatusActive, models.UserStatusSuspended, models.UserStatusLocked:
+	default:
+		return nil, fmt.Errorf("invalid status: %s", status)
+	}
+
+	users, total, err := s.userRepo.SearchUsers(ctx, &models.UserFilter{
+		Query:  query,
+		Role:   role,
+		Status: status,
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.UserListResponse{Users: users, Total: total, Page: page, Limit: limit}, nil
+}
+
+func (s *UserAdminService) GetUser(ctx context.Context, userId string) (*models.User, error) {
+	return s.userRepo.GetUserDetails(ctx, userId)
+}
+
+// SuspendUser blocks login and refresh for the account and ends its sessions
+func (s *UserAdminService) SuspendUser(ctx context.Context, actorId, userId, reason, ip string) (*models.User, error) {
+	if actorId == userId {
+		return nil, errors.New("you can't suspend your own account")
+	}
+	if _, err := s.checkCanManage(ctx, actorId, userId); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.SetSuspended(ctx, userId, true, reason); err != nil {
+		return nil, err
+	}
+	if err := s.authService.RevokeAllSessions(ctx, userId); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, models.AuditUserSuspended, userId, actorId, ip, map[string]interface{}{"reason": reason})
+	return s.userRepo.GetUserDetails(ctx, userId)
+}
+
+func (s *UserAdminService) ReactivateUser(ctx context.Context, actorId, userId, ip string) (*models.User, error) {
+	if _, err := s.checkCanManage(ctx, actorId, userId); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.SetSuspended(ctx, userId, false, ""); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, models.AuditUserReactivated, userId, actorId, ip, nil)
+	return s.userRepo.GetUserDetails(ctx, userId)
+}
+
+// ForcePasswordReset signs the user out, stops them logging in with their current
+// password and emails them a reset link
+func (s *UserAdminService) ForcePasswordReset(ctx context.Context, actorId, userId, ip string) error {
+	user, err := s.checkCanManage(ctx, actorId, userId)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.RequirePasswordReset(ctx, userId); err != nil {
+		return err
+	}
+	if err := s.authService.RevokeAllSessions(ctx, userId); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, models.AuditPasswordResetForced, userId, actorId, ip, nil)
+
+	if err := s.passwordReset.SendResetLink(ctx, user); err != nil {
+		return fmt.Errorf("password reset is required but the email could not be sent: %w", err)
+	}
+	return nil
+}
+
+// checkCanManage loads the target account and returns ErrOutranked unless the actor's
+// role holds every permission the target's role does
+func (s *UserAdminService) checkCanManage(ctx context.Context, actorId, userId string) (*models.User, error) {
+	actor, err := s.userRepo.GetUserById(ctx, actorId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load your account: %w", err)
+	}
+	target, err := s.userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	actorPermissions, err := s.rbac.PermissionsFor(ctx, string(actor.Role))
+	if err != nil {
+		return nil, err
+	}
+	targetPermissions, err := s.rbac.PermissionsFor(ctx, string(target.Role))
+	if err != nil {
+		return nil, err
+	}
+	if !holdsAll(actorPermissions, targetPermissions) {
+		return nil, ErrOutranked
+	}
+	return target, nil
+}
+
+// holdsAll reports whether granted covers every permission in wanted. Only the
+// wildcard covers the wildcard
+func holdsAll(granted, wanted []string) bool {
+	if slices.Contains(granted, auth.PermAll) {
+		return true
+	}
+	for _, permission := range wanted {
+		if !slices.Contains(granted, permission) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	managerPermissions = []string{
+		auth.PermBookingsRead, auth.PermBookingsWrite, auth.PermRoomsRead, auth.PermRoomsManage,
+		auth.PermReportsRead, auth.PermUsersRead, auth.PermUsersManage, auth.PermAuditRead,
+	}
+	frontDeskPermissions = []string{auth.PermBookingsRead, auth.PermBookingsWrite, auth.PermRoomsRead, auth.PermUsersRead}
+	ownerPermissions     = []string{auth.PermAll}
+	adminPermissions     = []string{auth.PermAll}
+)
+
+func TestHoldsAll(t *testing.T) {
+	cases := []struct {
+		name    string
+		actor   []string
+		target  []string
+		allowed bool
+	}{
+		{"manager on owner", managerPermissions, ownerPermissions, false},
+		{"manager on admin", managerPermissions, adminPermissions, false},
+		{"manager on front desk", managerPermissions, frontDeskPermissions, true},
+		{"manager on manager", managerPermissions, managerPermissions, true},
+		{"manager on customer", managerPermissions, nil, true},
+		{"front desk on manager", frontDeskPermissions, managerPermissions, false},
+		{"admin on owner", adminPermissions, ownerPermissions, true},
+		{"owner on manager", ownerPermissions, managerPermissions, true},
+		{"one permission short", managerPermissions[1:], managerPermissions, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.allowed, holdsAll(tc.actor, tc.target))
+		})
+	}
+}
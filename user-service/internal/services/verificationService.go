@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/models"
+	"github.com/ollatomiwa/hotelsystem/user-service/internal/repositories/postgres"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/notifications"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
+)
+
+var ErrInvalidVerificationToken = errors.New("verification link is invalid or has expired")
+
+type VerificationConfig struct {
+	TokenTTL       time.Duration
+	ResendInterval time.Duration
+	VerifyURL      string
+}
+
+// VerificationService issues email verification tokens and marks emails verified
+type VerificationService struct {
+	userRepo  *postgres.UserRepository
+	tokenRepo *postgres.OneTimeTokenRepository
+	signer    *security.TokenSigner
+	mailer    *notifications.Client
+	cfg       VerificationConfig
+}
+
+// mailer may be nil, in which case verification links are only logged
+func NewVerificationService(userRepo *postgres.UserRepository, tokenRepo *postgres.OneTimeTokenRepository,
+	signer *security.TokenSigner, mailer *notifications.Client, cfg VerificationConfig) *VerificationService {
+	return &VerificationService{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		signer:    signer,
+		mailer:    mailer,
+		cfg:       cfg,
+	}
+}
+
+// SendVerification replaces any outstanding verification token for the user with a new one
+// and emails it
+func (s *VerificationService) SendVerification(ctx context.Context, user *models.User) error {
+	if user.EmailVerified {
+		return nil
+	}
+	token, err := issueOneTimeToken(ctx, s.tokenRepo, s.signer, user.Id, models.TokenPurposeEmailVerification, s.cfg.TokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.cfg.VerifyURL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.",
+		user.FirstName, link, s.cfg.TokenTTL)
+	if err := sendEmail(ctx, s.mailer, user.Email, "Verify your email address", body, notifications.TypeEmailVerification); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+	return nil
+}
+
+// VerifyEmail consumes a verification token and marks the owner's email verified
+func (s *VerificationService) VerifyEmail(ctx context.Context, token string) error {
+	record, err := s.tokenRepo.ConsumeToken(ctx, models.TokenPurposeEmailVerification, s.signer.Hash(token))
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+	return s.userRepo.MarkEmailVerified(ctx, record.UserId)
+}
+
+// ResendVerification sends a fresh link to an unverified account. It reports success
+// whether or not the email exists so it can't be used to discover accounts
+func (s *VerificationService) ResendVerification(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil || user.EmailVerified {
+		return nil
+	}
+
+	lastSent, err := s.tokenRepo.LastIssuedAt(ctx, user.Id, models.TokenPurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+	if time.Since(lastSent) < s.cfg.ResendInterval {
+		return nil
+	}
+	return s.SendVerification(ctx, user)
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -13,7 +15,19 @@ type Config struct {
 	Server ServerConfig
 	Database DatabaseConfig
 	Security SecurityConfig
+	SessionCookies SessionCookiesConfig
+	PasswordPolicy PasswordPolicyConfig
 	Redis RedisConfig
+	Verification VerificationConfig
+	PasswordReset PasswordResetConfig
+	EmailChange EmailChangeConfig
+	MFA MFAConfig
+	LoginProtection LoginProtectionConfig
+	RBAC RBACConfig
+	Notifications NotificationsConfig
+	Privacy PrivacyConfig
+	OIDC OIDCConfig
+	Loyalty LoyaltyConfig
 }
 
 type ServerConfig struct {
@@ -22,6 +36,10 @@ type ServerConfig struct {
 	ReadTimeout time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout time.Duration
+	// TrustedProxies are the proxies (IPs or CIDRs) whose X-Forwarded-For is believed.
+	// Unset, the client IP is always the connection's address, so a forged header
+	// can't dodge rate limits or fake the IPs in the audit log
+	TrustedProxies []string
 }
 type DatabaseConfig struct {
 	Host string
@@ -33,24 +51,154 @@ type DatabaseConfig struct {
 }
 
 type SecurityConfig struct {
-	JWTSecretKey string
 	JWTRefreshKey string
+	KeyRotationInterval time.Duration
+	// SigningKeyEncryptionKey encrypts the access token signing keys at rest
+	SigningKeyEncryptionKey string
 	AccessTokenDuration time.Duration
 	RefreshToken time.Duration
+	// PasswordHashAlgorithm is argon2id or bcrypt. Hashes made by the other still
+	// verify, and are rehashed when their owner logs in
+	PasswordHashAlgorithm string
 	BCryptCost int
+	Argon2Memory int
+	Argon2Time int
+	Argon2Threads int
 	CORSAllowedOrigins []string
 	CSRFSecret string
 	RateLimitRequests int
 	RateLimitWindow time.Duration
 }
 
+// SessionCookiesConfig turns on cookie mode for browser clients, refresh tokens go
+// in an HttpOnly cookie and state-changing requests need a matching X-CSRF-Token.
+// SameSite is strict, lax or none
+type SessionCookiesConfig struct {
+	Enabled bool
+	RefreshCookieName string
+	CSRFCookieName string
+	Domain string
+	Path string
+	Secure bool
+	SameSite string
+}
+
+// PasswordPolicyConfig is what a new password must satisfy. History is how many earlier
+// passwords can't be reused. BreachedListFile is an optional sorted list of SHA-1
+// hashes of breached passwords, such as a Have I Been Pwned download
+type PasswordPolicyConfig struct {
+	MinLength int
+	MaxLength int
+	RequireUpper bool
+	RequireLower bool
+	RequireDigit bool
+	RequireSymbol bool
+	History int
+	BreachedListFile string
+}
+
 type RedisConfig struct {
+	Enabled bool
 	Host string
 	Port string
 	Password string
 	DB int
+	// PoolSize is how many connections token revocation checks may use at once
+	PoolSize int
+}
+
+type VerificationConfig struct {
+	TokenSecret string
+	TokenTTL time.Duration
+	ResendInterval time.Duration
+	VerifyURL string
+}
+
+type PasswordResetConfig struct {
+	TokenTTL time.Duration
+	ResendInterval time.Duration
+	ResetURL string
+}
+
+type EmailChangeConfig struct {
+	TokenTTL time.Duration
+	ConfirmURL string
+}
+
+type MFAConfig struct {
+	Issuer string
+	EncryptionKey string
+	RequiredRoles []string
+	TokenTTL time.Duration
+	MaxAttempts int
+	RecoveryCodes int
+}
+
+type LoginProtectionConfig struct {
+	FailureWindow time.Duration
+	DelayAfter int
+	BaseDelay time.Duration
+	MaxDelay time.Duration
+	LockAfter int
+	LockoutDuration time.Duration
+	IPMaxFailures int
+}
+
+type RBACConfig struct {
+	PermissionCacheTTL time.Duration
 }
 
+type NotificationsConfig struct {
+	Enabled bool
+	BaseURL string
+	ServiceKey string
+}
+
+// Where the other services holding user data live, for exports and account deletion.
+// Notifications use NotificationsConfig. A service left unset is listed as omitted
+// in exports and its data is not erased, so outside development both are required
+type PrivacyConfig struct {
+	BookingServiceURL string
+	PaymentServiceURL string
+}
+
+type OIDCConfig struct {
+	StateTTL time.Duration
+	Providers []OIDCProviderConfig
+}
+
+// OIDCProviderConfig is one login provider. OIDC_PROVIDERS names them, and each
+// is configured with OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL and _SCOPES
+type OIDCProviderConfig struct {
+	Name string
+	Issuer string
+	ClientID string
+	ClientSecret string
+	RedirectURL string
+	Scopes []string
+}
+
+// LoyaltyConfig holds the earn and redemption rules. Points earned on a stay are
+// PointsPerUnit for each currency unit spent plus PointsPerNight, times the tier's multiplier
+type LoyaltyConfig struct {
+	PointsPerUnit int
+	PointsPerNight int
+	// How many points make one currency unit when redeeming
+	PointsPerRedemptionUnit int
+	MinRedemption int
+	VoucherTTL time.Duration
+}
+
+// Secrets development runs with when they aren't set. Anyone can read them here, so
+// Validate refuses them everywhere else
+const (
+	defaultJWTRefreshKey = "refrsh-token"
+	defaultSigningKeyEncryptionKey = "signing-key-encryption-key"
+	defaultCSRFSecret = "scfr-key"
+	defaultVerificationTokenSecret = "verification-secret"
+	defaultMFAEncryptionKey = "mfa-encryption-key"
+)
+
 func Load() *Config {
 	return &Config{
 		Server: ServerConfig{
@@ -59,6 +207,7 @@ func Load() *Config {
 			ReadTimeout: getEnvDuration("READ_TIMEOUT", 15*time.Second),
 			WriteTimeout: getEnvDuration("WRITE_TIMEOUT", 15*time.Second),
 			IdleTimeout: getEnvDuration("IDLE_TIMEOUT", 60*time.Second),
+			TrustedProxies: getEnvSlice("TRUSTED_PROXIES", nil),
 		},
 		Database: DatabaseConfig{
 			Host: getEnv("DB_HOST","localhost"),
@@ -68,23 +217,172 @@ func Load() *Config {
 			DBName: getEnv("DB_NAME", "user_service"),
 			SSLMode: getEnv("DB_SSL_MODE", "disable"),
 		},Security: SecurityConfig{
-			JWTSecretKey: getEnv("JWT_SECRET_KEY", "256-bit-secret"),
-			JWTRefreshKey: getEnv("JWT_REFRESH_KEY", "refrsh-token"),
+			JWTRefreshKey: getEnv("JWT_REFRESH_KEY", defaultJWTRefreshKey),
+			KeyRotationInterval: getEnvDuration("JWT_KEY_ROTATION_INTERVAL", 30*24*time.Hour),
+			SigningKeyEncryptionKey: getEnv("SIGNING_KEY_ENCRYPTION_KEY", defaultSigningKeyEncryptionKey),
 			AccessTokenDuration: getEnvDuration("ACCESS_TOKEN_DURATION", 15*time.Minute),
 			RefreshToken: getEnvDuration("REFRESH_TOKEN", 7*24*time.Hour),
+			PasswordHashAlgorithm: getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
 			BCryptCost: getEnvInt("BCRYPT_COST", bcrypt.DefaultCost),
+			Argon2Memory: getEnvInt("ARGON2_MEMORY_KIB", 64*1024),
+			Argon2Time: getEnvInt("ARGON2_TIME", 3),
+			Argon2Threads: getEnvInt("ARGON2_THREADS", 2),
 			CORSAllowedOrigins: getEnvSlice("CORS_ALLOWED_ORIGINS", []string{"http://localhost:3000"}),
-			CSRFSecret: getEnv("CSRF_SECRET", "scfr-key"),
+			CSRFSecret: getEnv("CSRF_SECRET", defaultCSRFSecret),
 			RateLimitRequests: getEnvInt("RATE_LIMIT_REQUESTS", 100),
 			RateLimitWindow: getEnvDuration("RATE_LIMIT_WINDOW", 1*time.Minute),
 		},
+		SessionCookies: SessionCookiesConfig{
+			Enabled: getEnvBool("SESSION_COOKIES_ENABLED", false),
+			RefreshCookieName: getEnv("SESSION_REFRESH_COOKIE", "refresh_token"),
+			CSRFCookieName: getEnv("SESSION_CSRF_COOKIE", "csrf_token"),
+			Domain: getEnv("SESSION_COOKIE_DOMAIN", ""),
+			Path: getEnv("SESSION_COOKIE_PATH", "/api/v1/auth"),
+			Secure: getEnvBool("SESSION_COOKIE_SECURE", true),
+			SameSite: getEnv("SESSION_COOKIE_SAMESITE", "strict"),
+		},
+		PasswordPolicy: PasswordPolicyConfig{
+			MinLength: getEnvInt("PASSWORD_MIN_LENGTH", 8),
+			MaxLength: getEnvInt("PASSWORD_MAX_LENGTH", 128),
+			RequireUpper: getEnvBool("PASSWORD_REQUIRE_UPPER", false),
+			RequireLower: getEnvBool("PASSWORD_REQUIRE_LOWER", false),
+			RequireDigit: getEnvBool("PASSWORD_REQUIRE_DIGIT", false),
+			RequireSymbol: getEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+			History: getEnvInt("PASSWORD_HISTORY", 5),
+			BreachedListFile: getEnv("PASSWORD_BREACHED_LIST_FILE", ""),
+		},
 		Redis: RedisConfig{
+			Enabled: getEnvBool("REDIS_ENABLED", false),
 			Host: getEnv("REDIS_HOST", "localhost"),
 			Port: getEnv("REDIS_PORT", "6379"),
 			Password: getEnv("REDIS_PASSWORD", ""),
 			DB: getEnvInt("REDIS_DB", 0),
+			PoolSize: getEnvInt("REDIS_POOL_SIZE", 10),
+		},
+		Verification: VerificationConfig{
+			TokenSecret: getEnv("VERIFICATION_TOKEN_SECRET", defaultVerificationTokenSecret),
+			TokenTTL: getEnvDuration("VERIFICATION_TOKEN_TTL", 24*time.Hour),
+			ResendInterval: getEnvDuration("VERIFICATION_RESEND_INTERVAL", 1*time.Minute),
+			VerifyURL: getEnv("VERIFICATION_URL", "http://localhost:3000/verify-email"),
+		},
+		PasswordReset: PasswordResetConfig{
+			TokenTTL: getEnvDuration("PASSWORD_RESET_TOKEN_TTL", 1*time.Hour),
+			ResendInterval: getEnvDuration("PASSWORD_RESET_RESEND_INTERVAL", 1*time.Minute),
+			ResetURL: getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
 		},
+		EmailChange: EmailChangeConfig{
+			TokenTTL: getEnvDuration("EMAIL_CHANGE_TOKEN_TTL", 24*time.Hour),
+			ConfirmURL: getEnv("EMAIL_CHANGE_URL", "http://localhost:3000/confirm-email-change"),
+		},
+		MFA: MFAConfig{
+			Issuer: getEnv("MFA_ISSUER", "Hotel System"),
+			EncryptionKey: getEnv("MFA_ENCRYPTION_KEY", defaultMFAEncryptionKey),
+			RequiredRoles: getEnvSlice("MFA_REQUIRED_ROLES", []string{"admin", "owner", "manager", "accountant"}),
+			TokenTTL: getEnvDuration("MFA_TOKEN_TTL", 5*time.Minute),
+			MaxAttempts: getEnvInt("MFA_MAX_ATTEMPTS", 5),
+			RecoveryCodes: getEnvInt("MFA_RECOVERY_CODES", 10),
+		},
+		LoginProtection: LoginProtectionConfig{
+			FailureWindow: getEnvDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+			DelayAfter: getEnvInt("LOGIN_DELAY_AFTER", 3),
+			BaseDelay: getEnvDuration("LOGIN_BASE_DELAY", 1*time.Second),
+			MaxDelay: getEnvDuration("LOGIN_MAX_DELAY", 1*time.Minute),
+			LockAfter: getEnvInt("LOGIN_LOCK_AFTER", 10),
+			LockoutDuration: getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+			IPMaxFailures: getEnvInt("LOGIN_IP_MAX_FAILURES", 50),
+		},
+		RBAC: RBACConfig{
+			PermissionCacheTTL: getEnvDuration("RBAC_PERMISSION_CACHE_TTL", 1*time.Minute),
+		},
+		Notifications: NotificationsConfig{
+			Enabled: getEnvBool("NOTIFICATIONS_ENABLED", false),
+			BaseURL: getEnv("NOTIFICATION_SERVICE_URL", "http://localhost:8083"),
+			ServiceKey: getEnv("SERVICE_API_KEY", ""),
+		},
+		OIDC: OIDCConfig{
+			StateTTL: getEnvDuration("OIDC_STATE_TTL", 10*time.Minute),
+			Providers: loadOIDCProviders(),
+		},
+		Loyalty: LoyaltyConfig{
+			PointsPerUnit: getEnvInt("LOYALTY_POINTS_PER_UNIT", 10),
+			PointsPerNight: getEnvInt("LOYALTY_POINTS_PER_NIGHT", 0),
+			PointsPerRedemptionUnit: getEnvInt("LOYALTY_POINTS_PER_REDEMPTION_UNIT", 200),
+			MinRedemption: getEnvInt("LOYALTY_MIN_REDEMPTION", 500),
+			VoucherTTL: getEnvDuration("LOYALTY_VOUCHER_TTL", 365*24*time.Hour),
+		},
+		Privacy: PrivacyConfig{
+			BookingServiceURL: getEnv("BOOKING_SERVICE_URL", ""),
+			PaymentServiceURL: getEnv("PAYMENT_SERVICE_URL", ""),
+		},
+	}
+}
+
+// IsDevelopment reports whether we run somewhere the development defaults are acceptable
+func (c *Config) IsDevelopment() bool {
+	return c.Server.Env == "development" || c.Server.Env == "test"
+}
+
+// Validate refuses settings that are never safe, and outside development the ones
+// that are only safe there
+func (c *Config) Validate() error {
+	if slices.ContainsFunc(c.Security.CORSAllowedOrigins, func(o string) bool { return strings.TrimSpace(o) == "*" }) {
+		return fmt.Errorf("CORS_ALLOWED_ORIGINS can't be *, cross-origin requests carry credentials so list each origin")
+	}
+	if c.IsDevelopment() {
+		return nil
+	}
+	defaults := []struct {
+		name, value, fallback string
+	}{
+		{"JWT_REFRESH_KEY", c.Security.JWTRefreshKey, defaultJWTRefreshKey},
+		{"SIGNING_KEY_ENCRYPTION_KEY", c.Security.SigningKeyEncryptionKey, defaultSigningKeyEncryptionKey},
+		{"CSRF_SECRET", c.Security.CSRFSecret, defaultCSRFSecret},
+		{"VERIFICATION_TOKEN_SECRET", c.Verification.TokenSecret, defaultVerificationTokenSecret},
+		{"MFA_ENCRYPTION_KEY", c.MFA.EncryptionKey, defaultMFAEncryptionKey},
+	}
+	var weak []string
+	for _, secret := range defaults {
+		if secret.value == "" || secret.value == secret.fallback {
+			weak = append(weak, secret.name)
+		}
+	}
+	if len(weak) > 0 {
+		return fmt.Errorf("%s must be set to a secret value in %s, the defaults are only for development",
+			strings.Join(weak, ", "), c.Server.Env)
+	}
+
+	var missing []string
+	if c.Privacy.BookingServiceURL == "" {
+		missing = append(missing, "BOOKING_SERVICE_URL")
+	}
+	if c.Privacy.PaymentServiceURL == "" {
+		missing = append(missing, "PAYMENT_SERVICE_URL")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s must be set in %s, data exports and account deletion have to reach every service",
+			strings.Join(missing, ", "), c.Server.Env)
+	}
+	return nil
+}
+
+func loadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range getEnvSlice("OIDC_PROVIDERS", nil) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers = append(providers, OIDCProviderConfig{
+			Name: name,
+			Issuer: getEnv(prefix+"ISSUER", ""),
+			ClientID: getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL: getEnv(prefix+"REDIRECT_URL", "http://localhost:3000/auth/"+name+"/callback"),
+			Scopes: getEnvSlice(prefix+"SCOPES", []string{"openid", "email", "profile"}),
+		})
 	}
+	return providers
 }
 
 func getEnv(key, defaultValue string) string {
@@ -103,6 +401,15 @@ func getEnvInt(key string, defaultValue int) int {
 	return  defaultValue
 }
 
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != ""{
 		if duration, err := time.ParseDuration(value); err == nil {
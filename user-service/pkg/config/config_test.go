@@ -0,0 +1,64 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withSecrets sets every secret Validate requires outside development
+func withSecrets(cfg *Config) *Config {
+	cfg.Security.JWTRefreshKey = "refresh-from-vault"
+	cfg.Security.SigningKeyEncryptionKey = "signing-from-vault"
+	cfg.Security.CSRFSecret = "csrf-from-vault"
+	cfg.Verification.TokenSecret = "verification-from-vault"
+	cfg.MFA.EncryptionKey = "mfa-from-vault"
+	return cfg
+}
+
+func TestValidateRequiresEveryPrivacyServiceOutsideDevelopment(t *testing.T) {
+	cfg := &Config{Server: ServerConfig{Env: "development"}}
+	assert.NoError(t, cfg.Validate(), "development may leave services out")
+
+	cfg = withSecrets(cfg)
+	cfg.Server.Env = "production"
+	cfg.Privacy.BookingServiceURL = "http://booking:8080"
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "PAYMENT_SERVICE_URL")
+	assert.NotContains(t, err.Error(), "BOOKING_SERVICE_URL")
+
+	cfg.Privacy.PaymentServiceURL = "http://payment:8080"
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidateRefusesWildcardCORSOrigin(t *testing.T) {
+	for _, env := range []string{"development", "production"} {
+		cfg := &Config{
+			Server:   ServerConfig{Env: env},
+			Security: SecurityConfig{CORSAllowedOrigins: []string{"https://hotel.example", "*"}},
+			Privacy:  PrivacyConfig{BookingServiceURL: "http://booking:8080", PaymentServiceURL: "http://payment:8080"},
+		}
+		assert.ErrorContains(t, cfg.Validate(), "CORS_ALLOWED_ORIGINS", env)
+	}
+}
+
+func TestValidateRefusesDefaultSecretsOutsideDevelopment(t *testing.T) {
+	t.Setenv("MFA_ENCRYPTION_KEY", "mfa-from-vault")
+	cfg := Load()
+	cfg.Privacy = PrivacyConfig{BookingServiceURL: "http://booking:8080", PaymentServiceURL: "http://payment:8080"}
+	cfg.Security.CORSAllowedOrigins = nil
+
+	for _, env := range []string{"development", "test"} {
+		cfg.Server.Env = env
+		assert.NoError(t, cfg.Validate(), env)
+	}
+
+	cfg.Server.Env = "staging"
+	err := cfg.Validate()
+	for _, name := range []string{"JWT_REFRESH_KEY", "SIGNING_KEY_ENCRYPTION_KEY", "CSRF_SECRET", "VERIFICATION_TOKEN_SECRET"} {
+		assert.ErrorContains(t, err, name)
+	}
+	assert.NotContains(t, err.Error(), "MFA_ENCRYPTION_KEY", "a configured secret isn't reported")
+
+	assert.NoError(t, withSecrets(cfg).Validate())
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+type defaultTier struct {
+	name           string
+	threshold      int64
+	earnMultiplier float64
+	benefits       []string
+}
+
+// Tiers every installation starts with. Like roles, they are only written when
+// missing, so thresholds and benefits edited through the admin API survive restarts
+var defaultTiers = []defaultTier{
+	{"member", 0, 1, []string{"Members-only rates"}},
+	{"silver", 5000, 1.25, []string{"Members-only rates", "Late checkout on request"}},
+	{"gold", 15000, 1.5, []string{"Members-only rates", "Guaranteed late checkout", "Room upgrade when available"}},
+	{"platinum", 40000, 2, []string{"Members-only rates", "Guaranteed late checkout", "Guaranteed room upgrade",
+		"Welcome amenity"}},
+}
+
+func seedLoyaltyTiers(db *sql.DB) error {
+	query := `
+		INSERT INTO loyalty_tiers (name, threshold, earn_multiplier, benefits) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`
+	for _, tier := range defaultTiers {
+		if _, err := db.Exec(query, tier.name, tier.threshold, tier.earnMultiplier, pq.Array(tier.benefits)); err != nil {
+			return fmt.Errorf("failed to seed loyalty tier %s: %w", tier.name, err)
+		}
+	}
+	return nil
+}
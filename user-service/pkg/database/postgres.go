@@ -60,6 +60,262 @@ func InitializeSchema(db *sql.DB) error {
 		// Index for faster email lookups
 		`CREATE INDEX IF NOT EXISTS idx_users_email ON users(email)`,
 
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP`,
+
+		// Account state managed by admins
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT NOW()`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS suspension_reason TEXT`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP`,
+
+		// Failed login bookkeeping for brute-force protection
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_attempts INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS last_failed_login_at TIMESTAMP`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP`,
+
+		// Single-use tokens sent by email (verification, password reset, ...)
+		`CREATE TABLE IF NOT EXISTS one_time_tokens (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			purpose TEXT NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			used_at TIMESTAMP
+		)`,
+
+		`CREATE INDEX IF NOT EXISTS idx_one_time_tokens_user ON one_time_tokens(user_id, purpose)`,
+		`ALTER TABLE one_time_tokens ADD COLUMN IF NOT EXISTS new_email TEXT`,
+
+		// Refresh tokens, stored hashed so they can be rotated and revoked
+		`CREATE TABLE IF NOT EXISTS refresh_tokens (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			family_id TEXT NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			revoked_at TIMESTAMP,
+			replaced_by TEXT
+		)`,
+
+		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens(user_id)`,
+
+		// Login sessions, one per refresh token family
+		`CREATE TABLE IF NOT EXISTS sessions (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			user_agent TEXT,
+			ip_address TEXT,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			last_used_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			revoked_at TIMESTAMP
+		)`,
+
+		`CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id)`,
+
+		// Access token signing keys; retired keys stay published until their tokens expire.
+		// private_key is encrypted with SIGNING_KEY_ENCRYPTION_KEY
+		`CREATE TABLE IF NOT EXISTS signing_keys (
+			id TEXT PRIMARY KEY,
+			algorithm TEXT NOT NULL,
+			private_key TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			retired_at TIMESTAMP
+		)`,
+
+		// TOTP second factor; the secret is encrypted with MFA_ENCRYPTION_KEY
+		`CREATE TABLE IF NOT EXISTS user_mfa (
+			user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			secret_encrypted TEXT NOT NULL,
+			enabled BOOLEAN NOT NULL DEFAULT FALSE,
+			enabled_at TIMESTAMP,
+			last_used_step BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			code_hash TEXT NOT NULL,
+			used_at TIMESTAMP
+		)`,
+
+		`CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user ON mfa_recovery_codes(user_id)`,
+
+		// Security audit trail
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id TEXT PRIMARY KEY,
+			event TEXT NOT NULL,
+			user_id TEXT,
+			actor_id TEXT,
+			ip_address TEXT,
+			details JSONB NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_user ON audit_log(user_id, created_at)`,
+
+		// Events are chained by hash in sequence order. Events from before the chain have no sequence
+		`ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS seq BIGINT UNIQUE`,
+		`ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS outcome TEXT NOT NULL DEFAULT 'success'`,
+		`ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS user_agent TEXT`,
+		`ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS prev_hash TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS hash TEXT NOT NULL DEFAULT ''`,
+
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id, created_at)`,
+
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_event ON audit_log(event, created_at)`,
+
+		// The audit log is append-only, rows can't be changed or removed
+		`CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit_log is append-only';
+		END
+		$$ LANGUAGE plpgsql`,
+
+		`DROP TRIGGER IF EXISTS audit_log_no_change ON audit_log`,
+
+		`CREATE TRIGGER audit_log_no_change BEFORE UPDATE OR DELETE ON audit_log
+			FOR EACH ROW EXECUTE PROCEDURE audit_log_append_only()`,
+
+		`DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log`,
+
+		`CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+			FOR EACH STATEMENT EXECUTE PROCEDURE audit_log_append_only()`,
+
+		// Roles and their permissions; users.role names one of these roles
+		`CREATE TABLE IF NOT EXISTS roles (
+			name TEXT PRIMARY KEY,
+			description TEXT NOT NULL DEFAULT '',
+			built_in BOOLEAN NOT NULL DEFAULT FALSE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS permissions (
+			name TEXT PRIMARY KEY,
+			description TEXT NOT NULL DEFAULT ''
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS role_permissions (
+			role TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+			permission TEXT NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+			PRIMARY KEY (role, permission)
+		)`,
+
+		// Logins through external OpenID Connect providers, one row per provider account
+		`CREATE TABLE IF NOT EXISTS identities (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			provider TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			email TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			last_login_at TIMESTAMP,
+			UNIQUE (provider, subject)
+		)`,
+
+		`CREATE INDEX IF NOT EXISTS idx_identities_user ON identities(user_id)`,
+
+		// In-flight OIDC logins: the PKCE verifier and nonce wait here for the callback
+		`CREATE TABLE IF NOT EXISTS oidc_login_states (
+			state_hash TEXT PRIMARY KEY,
+			provider TEXT NOT NULL,
+			nonce TEXT NOT NULL,
+			code_verifier TEXT NOT NULL,
+			expires_at TIMESTAMP NOT NULL
+		)`,
+
+		// Guest profiles; only the last four characters of an ID document number are kept
+		`CREATE TABLE IF NOT EXISTS guest_profiles (
+			user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			nationality TEXT NOT NULL DEFAULT '',
+			address_line1 TEXT NOT NULL DEFAULT '',
+			address_line2 TEXT NOT NULL DEFAULT '',
+			city TEXT NOT NULL DEFAULT '',
+			region TEXT NOT NULL DEFAULT '',
+			postal_code TEXT NOT NULL DEFAULT '',
+			country TEXT NOT NULL DEFAULT '',
+			id_document_type TEXT,
+			id_document_last4 TEXT,
+			id_document_country TEXT,
+			id_document_expires_on DATE,
+			bed_preference TEXT NOT NULL DEFAULT '',
+			floor_preference TEXT NOT NULL DEFAULT '',
+			pillow_preference TEXT NOT NULL DEFAULT '',
+			dietary_notes TEXT NOT NULL DEFAULT '',
+			marketing_email_at TIMESTAMP,
+			marketing_sms_at TIMESTAMP,
+			loyalty_number TEXT UNIQUE,
+			loyalty_enrolled_at TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			updated_by TEXT
+		)`,
+
+		`CREATE SEQUENCE IF NOT EXISTS loyalty_number_seq START 1000001`,
+
+		// Loyalty tiers, reached by lifetime points earned
+		`CREATE TABLE IF NOT EXISTS loyalty_tiers (
+			name TEXT PRIMARY KEY,
+			threshold BIGINT NOT NULL UNIQUE,
+			earn_multiplier NUMERIC(4,2) NOT NULL DEFAULT 1,
+			benefits TEXT[] NOT NULL DEFAULT '{}'
+		)`,
+
+		// Points balances. The ledger is the record; balance and lifetime_points are
+		// kept alongside it in the same transaction, with the account row locked
+		`CREATE TABLE IF NOT EXISTS loyalty_accounts (
+			user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			balance BIGINT NOT NULL DEFAULT 0 CHECK (balance >= 0),
+			lifetime_points BIGINT NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS loyalty_ledger (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			kind TEXT NOT NULL,
+			points BIGINT NOT NULL,
+			balance_after BIGINT NOT NULL,
+			reference TEXT NOT NULL DEFAULT '',
+			description TEXT NOT NULL DEFAULT '',
+			created_by TEXT,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+
+		`CREATE INDEX IF NOT EXISTS idx_loyalty_ledger_user ON loyalty_ledger(user_id, created_at)`,
+		// a stay earns once and a payment redeems once, however often they are retried
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_loyalty_ledger_reference ON loyalty_ledger(user_id, kind, reference)
+			WHERE reference <> ''`,
+
+		`CREATE TABLE IF NOT EXISTS loyalty_vouchers (
+			code TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			points BIGINT NOT NULL,
+			value NUMERIC(10,2) NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			redeemed_at TIMESTAMP,
+			redeemed_reference TEXT,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+
+		`CREATE INDEX IF NOT EXISTS idx_loyalty_vouchers_user ON loyalty_vouchers(user_id)`,
+
+		// Earlier password hashes, so a password change can refuse a recent one
+		`CREATE TABLE IF NOT EXISTS password_history (
+			id BIGSERIAL PRIMARY KEY,
+			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			password_hash TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+
+		`CREATE INDEX IF NOT EXISTS idx_password_history_user ON password_history(user_id, created_at)`,
+
+		// Staff roles replaced the customer/admin check
+		`ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check`,
+
 		// Insert sample admin user (optional)
 		`INSERT INTO users (id, email, password_hash, first_name, last_name, role) 
 		VALUES (
@@ -79,6 +335,13 @@ func InitializeSchema(db *sql.DB) error {
 		}
 	}
 
+	if err := seedRoles(db); err != nil {
+		return err
+	}
+	if err := seedLoyaltyTiers(db); err != nil {
+		return err
+	}
+
 	log.Println(" Database schema initialized successfully")
 	return nil
 }
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
+)
+
+type defaultRole struct {
+	name        string
+	description string
+	permissions []string
+}
+
+// Roles every installation starts with. Their permissions are only written when the
+// role is first created, so changes made through the admin API survive restarts
+var defaultRoles = []defaultRole{
+	{auth.RoleCustomer, "Hotel guest", nil},
+	{auth.RoleFrontDesk, "Front desk staff", []string{
+		auth.PermBookingsRead, auth.PermBookingsWrite, auth.PermRoomsRead, auth.PermPaymentsRead, auth.PermUsersRead,
+		auth.PermGuestsRead, auth.PermGuestsManage,
+	}},
+	{auth.RoleHousekeeping, "Housekeeping staff", []string{
+		auth.PermRoomsRead, auth.PermHousekeepingUpdate,
+	}},
+	{auth.RoleManager, "Hotel manager", []string{
+		auth.PermBookingsRead, auth.PermBookingsWrite, auth.PermRoomsRead, auth.PermRoomsManage, auth.PermRatesManage,
+		auth.PermHousekeepingUpdate, auth.PermChannelsManage, auth.PermReportsRead, auth.PermReportsManage,
+		auth.PermPaymentsRead, auth.PermNotificationsSend, auth.PermUsersRead, auth.PermUsersManage,
+		auth.PermAuditRead, auth.PermGuestsRead, auth.PermGuestsManage,
+	}},
+	{auth.RoleAccountant, "Accounts and billing", []string{
+		auth.PermBookingsRead, auth.PermPaymentsRead, auth.PermPaymentsRefund, auth.PermReportsRead,
+	}},
+	{auth.RoleOwner, "Hotel owner", []string{auth.PermAll}},
+	{auth.RoleAdmin, "System administrator", []string{auth.PermAll}},
+}
+
+var permissionCatalogue = map[string]string{
+	auth.PermAll:                "Every permission",
+	auth.PermBookingsRead:       "View all bookings",
+	auth.PermBookingsWrite:      "Create and cancel bookings for guests",
+	auth.PermRoomsRead:          "View rooms",
+	auth.PermRoomsManage:        "Manage rooms",
+	auth.PermRatesManage:        "Manage rate plans",
+	auth.PermHousekeepingUpdate: "Update housekeeping status",
+	auth.PermChannelsManage:     "Manage calendar feeds and booking channels",
+	auth.PermReportsRead:        "View analytics and reports",
+	auth.PermReportsManage:      "Rebuild analytics snapshots",
+	auth.PermPaymentsRead:       "View payments and customers",
+	auth.PermPaymentsRefund:     "Refund payments",
+	auth.PermNotificationsSend:  "Send notifications",
+	auth.PermGuestsRead:         "View guest profiles and preferences",
+	auth.PermGuestsManage:       "Edit guest profiles and enroll guests in loyalty",
+	auth.PermUsersRead:          "View user accounts",
+	auth.PermUsersManage:        "Manage user accounts",
+	auth.PermRolesManage:        "Assign roles and edit role permissions",
+	auth.PermAuditRead:          "View the audit log",
+}
+
+func seedRoles(db *sql.DB) error {
+	for name, description := range permissionCatalogue {
+		_, err := db.Exec(`INSERT INTO permissions (name, description) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING`,
+			name, description)
+		if err != nil {
+			return fmt.Errorf("failed to seed permission %s: %w", name, err)
+		}
+	}
+
+	query := `
+		WITH created AS (
+			INSERT INTO roles (name, description, built_in) VALUES ($1, $2, TRUE)
+			ON CONFLICT (name) DO NOTHING
+			RETURNING name
+		)
+		INSERT INTO role_permissions (role, permission)
+		SELECT created.name, p FROM created, unnest($3::text[]) AS p
+	`
+	for _, role := range defaultRoles {
+		if _, err := db.Exec(query, role.name, role.description, pq.Array(role.permissions)); err != nil {
+			return fmt.Errorf("failed to seed role %s: %w", role.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package internalapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
+)
+
+// Client calls the /api/v1/internal routes of another service with our service key
+type Client struct {
+	name       string
+	baseURL    string
+	serviceKey string
+	httpClient *http.Client
+}
+
+func NewClient(name, baseURL, serviceKey string) *Client {
+	return &Client{
+		name:       name,
+		baseURL:    baseURL,
+		serviceKey: serviceKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name is the service the client talks to
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Get returns the JSON body of a GET to path, untouched
+func (c *Client) Get(ctx context.Context, path string, query url.Values) (json.RawMessage, error) {
+	return c.do(ctx, http.MethodGet, path, query)
+}
+
+// Delete returns the JSON body of a DELETE to path, untouched
+func (c *Client) Delete(ctx context.Context, path string, query url.Values) (json.RawMessage, error) {
+	return c.do(ctx, http.MethodDelete, path, query)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values) (json.RawMessage, error) {
+	endpoint := c.baseURL + "/api/v1/internal" + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	auth.SetServiceKey(req, c.serviceKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s unreachable: %w", c.name, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s response: %w", c.name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d: %s", c.name, resp.StatusCode, string(body))
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("%s returned invalid JSON", c.name)
+	}
+	return body, nil
+}
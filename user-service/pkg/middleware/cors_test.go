@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSOnlyAllowsListedOrigins(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORS([]string{"https://hotel.example", "*"}))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	cases := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://hotel.example", true},
+		{"https://evil.example", false},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", tc.origin)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if tc.allowed {
+			assert.Equal(t, tc.origin, w.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+		} else {
+			assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"), "* is not a wildcard")
+			assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+		}
+	}
+}
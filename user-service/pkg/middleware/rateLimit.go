@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// RateLimit allows each client IP at most requests per window
+func RateLimit(requests int, window time.Duration) gin.HandlerFunc {
+	var mu sync.Mutex
+	clients := map[string]*rateWindow{}
+	sweptAt := time.Now()
+
+	return func(c *gin.Context) {
+		now := time.Now()
+		ip := c.ClientIP()
+
+		mu.Lock()
+		if now.Sub(sweptAt) > window {
+			for key, w := range clients {
+				if now.Sub(w.start) > window {
+					delete(clients, key)
+				}
+			}
+			sweptAt = now
+		}
+		w, ok := clients[ip]
+		if !ok || now.Sub(w.start) > window {
+			w = &rateWindow{start: now}
+			clients[ip] = w
+		}
+		w.count++
+		count, resetAt := w.count, w.start.Add(window)
+		mu.Unlock()
+
+		if count > requests {
+			c.Header("Retry-After", strconv.Itoa(int(time.Until(resetAt).Seconds())+1))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many requests, please try again later"})
+			return
+		}
+		c.Next()
+	}
+}
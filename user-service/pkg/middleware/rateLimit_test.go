@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRateLimitedRouter(t *testing.T, trustedProxies []string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	require.NoError(t, router.SetTrustedProxies(trustedProxies))
+	router.Use(RateLimit(1, time.Minute))
+	router.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+	return router
+}
+
+func requestFrom(router *gin.Engine, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSpoofedForwardedForDoesNotResetTheLimit(t *testing.T) {
+	router := newRateLimitedRouter(t, nil)
+
+	first := requestFrom(router, "203.0.113.7:5000", "198.51.100.1")
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "203.0.113.7", first.Body.String(), "the header must not be believed")
+
+	second := requestFrom(router, "203.0.113.7:5001", "198.51.100.2")
+	assert.Equal(t, http.StatusTooManyRequests, second.Code)
+}
+
+func TestForwardedForFromATrustedProxyIsTheClient(t *testing.T) {
+	router := newRateLimitedRouter(t, []string{"10.0.0.0/8"})
+
+	first := requestFrom(router, "10.0.0.5:5000", "198.51.100.1")
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "198.51.100.1", first.Body.String())
+
+	// a different client behind the same proxy has its own limit
+	assert.Equal(t, http.StatusOK, requestFrom(router, "10.0.0.5:5001", "198.51.100.2").Code)
+	assert.Equal(t, http.StatusTooManyRequests, requestFrom(router, "10.0.0.5:5002", "198.51.100.1").Code)
+}
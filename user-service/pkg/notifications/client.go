@@ -0,0 +1,69 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
+)
+
+// Notification types understood by notification-service
+const (
+	TypeEmailVerification = "email_verification"
+	TypePasswordReset     = "password_reset"
+	TypeEmailChange       = "email_change"
+)
+
+// Client sends emails through notification-service
+type Client struct {
+	baseURL    string
+	serviceKey string
+	httpClient *http.Client
+}
+
+type sendEmailRequest struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	Type    string `json:"type"`
+}
+
+func NewClient(baseURL, serviceKey string) *Client {
+	return &Client{
+		baseURL:    baseURL,
+		serviceKey: serviceKey,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (c *Client) SendEmail(ctx context.Context, to, subject, body, notificationType string) error {
+	payload, err := json.Marshal(sendEmailRequest{To: to, Subject: subject, Body: body, Type: notificationType})
+	if err != nil {
+		return fmt.Errorf("failed to marshal email request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/notifications/email", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "User-Service/1.0")
+	auth.SetServiceKey(req, c.serviceKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send email request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("notification service returned %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
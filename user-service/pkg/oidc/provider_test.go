@@ -0,0 +1,139 @@
+package oidc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/oidc"
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newProvider(t *testing.T) (*oidctest.Server, *oidc.Provider) {
+	t.Helper()
+	server := oidctest.NewServer("hotel-client", "hotel-secret")
+	t.Cleanup(server.Close)
+	provider := oidc.NewProvider(oidc.Config{
+		Name:         "mock",
+		Issuer:       server.Issuer(),
+		ClientID:     server.ClientID,
+		ClientSecret: server.ClientSecret,
+		RedirectURL:  "http://localhost:3000/auth/callback",
+	})
+	return server, provider
+}
+
+// login runs the browser half of the flow and returns the authorization code
+func login(t *testing.T, server *oidctest.Server, provider *oidc.Provider, nonce, verifier string) string {
+	t.Helper()
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-1", nonce, oidc.CodeChallenge(verifier))
+	require.NoError(t, err)
+	code, state, err := server.Authorize(authURL)
+	require.NoError(t, err)
+	require.Equal(t, "state-1", state)
+	return code
+}
+
+func TestAuthorizationCodeFlowWithPKCE(t *testing.T) {
+	server, provider := newProvider(t)
+	server.SetUser(oidctest.User{Subject: "abc-123", Email: "ada@example.com", EmailVerified: true, GivenName: "Ada", FamilyName: "Lovelace"})
+	ctx := context.Background()
+
+	verifier, err := oidc.RandomString()
+	require.NoError(t, err)
+	code := login(t, server, provider, "nonce-1", verifier)
+
+	idToken, err := provider.Exchange(ctx, code, verifier)
+	require.NoError(t, err)
+	claims, err := provider.VerifyIDToken(ctx, idToken, "nonce-1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "abc-123", claims.Subject)
+	assert.Equal(t, "ada@example.com", claims.Email)
+	assert.True(t, bool(claims.EmailVerified))
+	assert.Equal(t, "Ada", claims.GivenName)
+	assert.Equal(t, "Lovelace", claims.FamilyName)
+
+	//codes are single use
+	_, err = provider.Exchange(ctx, code, verifier)
+	assert.Error(t, err)
+}
+
+func TestExchangeRejectsWrongCodeVerifier(t *testing.T) {
+	server, provider := newProvider(t)
+	verifier, _ := oidc.RandomString()
+	code := login(t, server, provider, "nonce-1", verifier)
+
+	other, _ := oidc.RandomString()
+	_, err := provider.Exchange(context.Background(), code, other)
+	assert.ErrorContains(t, err, "invalid_grant")
+}
+
+func TestVerifyIDTokenRejectsBadTokens(t *testing.T) {
+	server, provider := newProvider(t)
+	ctx := context.Background()
+	user := oidctest.User{Subject: "abc-123", Email: "ada@example.com", EmailVerified: true}
+
+	with := func(change func(jwt.MapClaims)) string {
+		claims := server.IDTokenClaims(user, "nonce-1")
+		change(claims)
+		return server.SignIDToken(claims)
+	}
+
+	cases := map[string]string{
+		"wrong nonce":    with(func(c jwt.MapClaims) { c["nonce"] = "other" }),
+		"wrong audience": with(func(c jwt.MapClaims) { c["aud"] = "someone-else" }),
+		"wrong issuer":   with(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }),
+		"expired":        with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }),
+		"no subject":     with(func(c jwt.MapClaims) { delete(c, "sub") }),
+		"other party": with(func(c jwt.MapClaims) {
+			c["aud"] = []string{server.ClientID, "someone-else"}
+			c["azp"] = "someone-else"
+		}),
+	}
+	for name, token := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := provider.VerifyIDToken(ctx, token, "nonce-1")
+			assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+		})
+	}
+
+	t.Run("unsigned", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodNone, server.IDTokenClaims(user, "nonce-1"))
+		unsigned, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+		_, err = provider.VerifyIDToken(ctx, unsigned, "nonce-1")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+}
+
+func TestUnknownKeyRefetchIsThrottled(t *testing.T) {
+	server, provider := newProvider(t)
+	ctx := context.Background()
+	user := oidctest.User{Subject: "abc-123"}
+
+	_, err := provider.VerifyIDToken(ctx, server.SignIDToken(server.IDTokenClaims(user, "n")), "n")
+	require.NoError(t, err)
+
+	//keys were fetched moments ago, so a token from an unseen key is refused rather
+	//than letting a burst of bad kids hammer the provider
+	server.RotateKey()
+	_, err = provider.VerifyIDToken(ctx, server.SignIDToken(server.IDTokenClaims(user, "n")), "n")
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
+
+func TestAuthCodeURLFailsWithoutDiscovery(t *testing.T) {
+	server := oidctest.NewServer("hotel-client", "hotel-secret")
+	defer server.Close()
+	provider := oidc.NewProvider(oidc.Config{
+		Name:     "mock",
+		Issuer:   server.Issuer() + "/no-such-tenant",
+		ClientID: server.ClientID,
+	})
+
+	_, err := provider.AuthCodeURL(context.Background(), "s", "n", "c")
+	assert.Error(t, err)
+}
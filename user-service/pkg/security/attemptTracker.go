@@ -0,0 +1,78 @@
+package security
+
+import (
+	"sync"
+	"time"
+)
+
+// AttemptTracker counts failures per key (an IP address, say) over a sliding window.
+// It lives in memory, so counts are per instance and reset on restart
+type AttemptTracker struct {
+	window time.Duration
+
+	mu       sync.Mutex
+	failures map[string][]time.Time
+	sweptAt  time.Time
+}
+
+func NewAttemptTracker(window time.Duration) *AttemptTracker {
+	return &AttemptTracker{
+		window:   window,
+		failures: map[string][]time.Time{},
+		sweptAt:  time.Now(),
+	}
+}
+
+// Fail records a failure for key and returns how many it has within the window
+func (t *AttemptTracker) Fail(key string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.sweep(now)
+	recent := append(t.recent(key, now), now)
+	t.failures[key] = recent
+	return len(recent)
+}
+
+// Count returns how many failures key has within the window, and when the oldest
+// of them drops out of it
+func (t *AttemptTracker) Count(key string) (int, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	recent := t.recent(key, time.Now())
+	if len(recent) == 0 {
+		return 0, time.Time{}
+	}
+	return len(recent), recent[0].Add(t.window)
+}
+
+func (t *AttemptTracker) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, key)
+}
+
+func (t *AttemptTracker) recent(key string, now time.Time) []time.Time {
+	times := t.failures[key]
+	cutoff := now.Add(-t.window)
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+// sweep drops keys with no recent failures, at most once per window
+func (t *AttemptTracker) sweep(now time.Time) {
+	if now.Sub(t.sweptAt) < t.window {
+		return
+	}
+	for key := range t.failures {
+		if len(t.recent(key, now)) == 0 {
+			delete(t.failures, key)
+		}
+	}
+	t.sweptAt = now
+}
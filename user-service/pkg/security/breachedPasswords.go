@@ -0,0 +1,107 @@
+package security
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const sha1HexLength = 40
+
+// BreachedPasswords looks passwords up in a local list of breached password hashes,
+// so no password ever leaves the service. The file has one uppercase hex SHA-1 per
+// line, sorted, optionally followed by ":count" as in the Have I Been Pwned downloads.
+// Lookups binary search the file on disk, so it can be far larger than memory
+type BreachedPasswords struct {
+	file *os.File
+	size int64
+}
+
+func OpenBreachedPasswords(path string) (*BreachedPasswords, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+	return &BreachedPasswords{file: file, size: info.Size()}, nil
+}
+
+func (b *BreachedPasswords) Close() error {
+	return b.file.Close()
+}
+
+// Contains reports whether the password's hash is on the list
+func (b *BreachedPasswords) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	target := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	//if the hash is listed, its line starts somewhere in [lo, hi)
+	lo, hi := int64(0), b.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, err := b.lineStart(mid)
+		if err != nil {
+			return false, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+		hash, err := b.hashAt(start)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case hash == target:
+			return true, nil
+		case hash < target:
+			lo = start + 1
+		default:
+			hi = start
+		}
+	}
+	return false, nil
+}
+
+// lineStart returns where the first line starting at or after offset begins, or
+// the file size if there is none
+func (b *BreachedPasswords) lineStart(offset int64) (int64, error) {
+	if offset == 0 {
+		return 0, nil
+	}
+	buf := make([]byte, 128)
+	for pos := offset - 1; pos < b.size; pos += int64(len(buf)) {
+		n, err := b.file.ReadAt(buf, pos)
+		if err != nil && err != io.EOF {
+			return 0, fmt.Errorf("failed to read breached password list: %w", err)
+		}
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return pos + int64(i) + 1, nil
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return b.size, nil
+}
+
+func (b *BreachedPasswords) hashAt(start int64) (string, error) {
+	buf := make([]byte, sha1HexLength)
+	n, err := b.file.ReadAt(buf, start)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read breached password list: %w", err)
+	}
+	line := buf[:n]
+	if i := bytes.IndexAny(line, ":\r\n"); i >= 0 {
+		line = line[:i]
+	}
+	return strings.ToUpper(string(line)), nil
+}
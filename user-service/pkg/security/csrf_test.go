@@ -0,0 +1,27 @@
+package security_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSRFTokens(t *testing.T) {
+	tokens := security.NewCSRFTokens("csrf-secret")
+	token, err := tokens.Generate()
+	require.NoError(t, err)
+	assert.True(t, tokens.Valid(token))
+
+	other, err := tokens.Generate()
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+
+	value, _, _ := strings.Cut(token, ".")
+	assert.False(t, tokens.Valid(value), "unsigned")
+	assert.False(t, tokens.Valid(value+".forged"), "bad signature")
+	assert.False(t, tokens.Valid(""))
+	assert.False(t, security.NewCSRFTokens("other-secret").Valid(token), "signed with another secret")
+}
@@ -1,52 +1,67 @@
 package security
 
 import (
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"time"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
 )
 
+// JWTManager signs access tokens with the key ring's current Ed25519 key, so other
+// services can verify them from the JWKS. Refresh tokens are only ever read back by
+// user-service and stay HMAC signed
 type JWTManager struct {
-	secretKey string
+	keys *KeyRing
 	refreshKey string
 	accessTokenDuration time.Duration
 	refreshTokenDuration time.Duration
 }
 
-type Claims struct  {
+//access token claims are shared with every service through the auth package
+type Claims = auth.Claims
+
+type RefreshClaims struct {
 	UserId string `json:"userId"`
-	Role string `json:"role"`
 	jwt.RegisteredClaims 
 }
 
-type RefreshClaims struct {
+// MFAClaims is carried by the short-lived token handed out between the password and
+// the second factor. Purpose says whether the user must verify or enroll
+type MFAClaims struct {
 	UserId string `json:"userId"`
-	jwt.RegisteredClaims 
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
 }
 
-func NewJWTManager(secretKey, refreshKey string, accessDuration, refreshDuration time.Duration) *JWTManager{
+func NewJWTManager(keys *KeyRing, refreshKey string, accessDuration, refreshDuration time.Duration) *JWTManager{
 	return &JWTManager{
-		secretKey: secretKey,
+		keys: keys,
 		refreshKey: refreshKey,
 		accessTokenDuration: accessDuration,
 		refreshTokenDuration: refreshDuration,
 	}
 }
 
-func (m *JWTManager) GenerateAccessToken(userId, role string) (string, error) {
-	claims := &Claims{
-		UserId : userId,
-		Role : role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.accessTokenDuration)),
-			IssuedAt : jwt.NewNumericDate(time.Now()),
-			Issuer : "user-service",
-		},
+//signs the given user claims, filling in expiry, issuer and a fresh jti
+func (m *JWTManager) GenerateAccessToken(subject Claims) (string, error) {
+	claims := &subject
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.accessTokenDuration)),
+		IssuedAt : jwt.NewNumericDate(time.Now()),
+		Issuer : "user-service",
+		ID: uuid.New().String(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(m.secretKey))
+	key, ok := m.keys.Current()
+	if !ok {
+		return "", errors.New("no active signing key")
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = key.Id
+	return token.SignedString(key.PrivateKey)
 }
 
 func (m *JWTManager) GenerateRefreshToken(userId string) (string, error) {
@@ -56,28 +71,58 @@ func (m *JWTManager) GenerateRefreshToken(userId string) (string, error) {
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.refreshTokenDuration)),
 			IssuedAt: jwt.NewNumericDate(time.Now()),
 			Issuer: "user-service",
+			//unique id so two tokens issued in the same second never collide
+			ID: uuid.New().String(),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(m.refreshKey))
 }
 
-func (m *JWTManager) VerifyAccessToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token)(interface{}, error){
+//mfa tokens use their own key so they can never pass as refresh tokens
+func (m *JWTManager) mfaKey() []byte {
+	sum := sha256.Sum256([]byte("mfa:" + m.refreshKey))
+	return sum[:]
+}
+
+func (m *JWTManager) GenerateMFAToken(userId, purpose string, duration time.Duration) (string, error) {
+	claims := &MFAClaims{
+		UserId: userId,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+			Issuer: "user-service",
+			ID: uuid.New().String(),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(m.mfaKey())
+}
+
+func (m *JWTManager) VerifyMFAToken(tokenString string) (*MFAClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &MFAClaims{}, func(token *jwt.Token)(interface{}, error){
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(m.secretKey), nil 
+		return m.mfaKey(), nil
 	})
-
 	if err != nil {
-		return nil, err 
+		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil 
+	if claims, ok := token.Claims.(*MFAClaims); ok && token.Valid {
+		return claims, nil
 	}
-	return nil, errors.New("invalid token")
+	return nil, errors.New("invalid mfa token")
+}
+
+func (m *JWTManager) AccessTokenDuration() time.Duration {
+	return m.accessTokenDuration
+}
+
+func (m *JWTManager) RefreshTokenDuration() time.Duration {
+	return m.refreshTokenDuration
 }
 
 func (m *JWTManager) VerifyRefreshToken(tokenString string) (*RefreshClaims, error) {
@@ -97,22 +142,3 @@ func (m *JWTManager) VerifyRefreshToken(tokenString string) (*RefreshClaims, err
 	}
 	return nil, errors.New("invalid refresh token")
 }
-
-func (m *JWTManager) RefreshTokens(refreshToken string) (string, string, error) {
-	claims, err := m.VerifyRefreshToken(refreshToken)
-	if err != nil {
-		return "", "", fmt.Errorf("invalid refresh token: %w", err)
-	}
-
-	newAccessToken, err := m.GenerateAccessToken(claims.UserId, "")
-	if err != nil {
-		return "", "", fmt.Errorf("failed to generate access token: %w",err)
-	}
-	newRefreshToken, err := m.GenerateRefreshToken(claims.UserId)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
-	}
-
-	return newAccessToken, newRefreshToken, nil 
-}
-
@@ -0,0 +1,218 @@
+package security
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SigningKey is an Ed25519 key used to sign access tokens. The key id is put in the
+// token's kid header so verifiers can pick the matching public key from the JWKS
+type SigningKey struct {
+	Id         string
+	PrivateKey ed25519.PrivateKey
+	CreatedAt  time.Time
+	RetiredAt  *time.Time
+}
+
+func (k *SigningKey) PublicKey() ed25519.PublicKey {
+	return k.PrivateKey.Public().(ed25519.PublicKey)
+}
+
+// KeyStore persists signing keys so restarts and other instances share them
+type KeyStore interface {
+	LoadKeys(ctx context.Context) ([]SigningKey, error)
+	// RotateKey saves key as the only current key and retires every other, as one step
+	// that instances take in turn. If a current key created after since already exists,
+	// another instance got there first and nothing changes
+	RotateKey(ctx context.Context, key *SigningKey, since time.Time) (bool, error)
+}
+
+// JWK is the public half of a signing key as published in the JWKS
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeyRing holds the current signing key and the retired keys whose tokens may still be in use.
+// A new key is generated every rotateEvery; retired keys stay verifiable for retainFor
+type KeyRing struct {
+	store       KeyStore
+	rotateEvery time.Duration
+	retainFor   time.Duration
+
+	mu   sync.RWMutex
+	keys []SigningKey // newest first
+}
+
+func NewKeyRing(store KeyStore, rotateEvery, retainFor time.Duration) *KeyRing {
+	return &KeyRing{
+		store:       store,
+		rotateEvery: rotateEvery,
+		retainFor:   retainFor,
+	}
+}
+
+// Load reads the stored keys and creates the first one if none is active
+func (k *KeyRing) Load(ctx context.Context) error {
+	if err := k.reload(ctx); err != nil {
+		return err
+	}
+	if _, ok := k.Current(); !ok {
+		//any instance starting alongside this one may create it instead
+		_, err := k.rotate(ctx, time.Time{})
+		return err
+	}
+	return nil
+}
+
+func (k *KeyRing) reload(ctx context.Context) error {
+	keys, err := k.store.LoadKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+	k.mu.Lock()
+	k.keys = keys
+	k.mu.Unlock()
+	return nil
+}
+
+// Rotate makes a fresh key current and retires every other
+func (k *KeyRing) Rotate(ctx context.Context) error {
+	_, err := k.rotate(ctx, time.Now())
+	return err
+}
+
+// rotate makes a fresh key current unless a current key created after since exists
+func (k *KeyRing) rotate(ctx context.Context, since time.Time) (bool, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return false, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	key := &SigningKey{Id: uuid.New().String(), PrivateKey: private, CreatedAt: time.Now()}
+	rotated, err := k.store.RotateKey(ctx, key, since)
+	if err != nil {
+		return false, err
+	}
+	return rotated, k.reload(ctx)
+}
+
+// Current returns the key new tokens are signed with
+func (k *KeyRing) Current() (SigningKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.keys {
+		if key.RetiredAt == nil {
+			return key, true
+		}
+	}
+	return SigningKey{}, false
+}
+
+// PublicKey returns the verification key for a kid, if it is still trusted
+func (k *KeyRing) PublicKey(kid string) (ed25519.PublicKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.keys {
+		if key.Id == kid && k.trusted(key) {
+			return key.PublicKey(), true
+		}
+	}
+	return nil, false
+}
+
+func (k *KeyRing) trusted(key SigningKey) bool {
+	return key.RetiredAt == nil || time.Since(*key.RetiredAt) < k.retainFor
+}
+
+// JWKS lists the public keys of every trusted key
+func (k *KeyRing) JWKS() JWKSet {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range k.keys {
+		if !k.trusted(key) {
+			continue
+		}
+		set.Keys = append(set.Keys, JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key.PublicKey()),
+			Kid: key.Id,
+			Alg: "EdDSA",
+			Use: "sig",
+		})
+	}
+	return set
+}
+
+// RunRotation rotates the current key once it is older than rotateEvery and otherwise
+// reloads, so keys rotated by another instance are picked up
+func (k *KeyRing) RunRotation(ctx context.Context, checkEvery time.Duration) {
+	ticker := time.NewTicker(checkEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.reload(ctx); err != nil {
+				log.Printf("signing key reload failed: %v", err)
+				continue
+			}
+			current, ok := k.Current()
+			if ok && time.Since(current.CreatedAt) < k.rotateEvery {
+				continue
+			}
+			//instances that find the key due at the same time rotate it once between them
+			rotated, err := k.rotate(ctx, time.Now().Add(-k.rotateEvery))
+			if err != nil {
+				log.Printf("signing key rotation failed: %v", err)
+			} else if rotated {
+				log.Printf("rotated access token signing key")
+			}
+		}
+	}
+}
+
+func EncodePrivateKey(key ed25519.PrivateKey) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+func DecodePrivateKey(encoded string) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return nil, errors.New("invalid private key encoding")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("signing key is not ed25519")
+	}
+	return key, nil
+}
@@ -0,0 +1,110 @@
+package security_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryKeyStore follows the contract of the postgres store: rotations are serialised
+// and skipped when a current key newer than since already exists
+type memoryKeyStore struct {
+	mu   sync.Mutex
+	keys []security.SigningKey
+}
+
+func (s *memoryKeyStore) LoadKeys(ctx context.Context) ([]security.SigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]security.SigningKey, len(s.keys))
+	for i := range s.keys {
+		keys[len(s.keys)-1-i] = s.keys[i]
+	}
+	return keys, nil
+}
+
+func (s *memoryKeyStore) RotateKey(ctx context.Context, key *security.SigningKey, since time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.keys {
+		if existing.RetiredAt == nil && existing.CreatedAt.After(since) {
+			return false, nil
+		}
+	}
+	now := time.Now()
+	for i := range s.keys {
+		if s.keys[i].RetiredAt == nil {
+			s.keys[i].RetiredAt = &now
+		}
+	}
+	s.keys = append(s.keys, *key)
+	return true, nil
+}
+
+func (s *memoryKeyStore) unretired() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, key := range s.keys {
+		if key.RetiredAt == nil {
+			count++
+		}
+	}
+	return count
+}
+
+func TestKeyRingRotation(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryKeyStore{}
+	ring := security.NewKeyRing(store, time.Hour, time.Hour)
+	require.NoError(t, ring.Load(ctx))
+	first, ok := ring.Current()
+	require.True(t, ok)
+
+	require.NoError(t, ring.Rotate(ctx))
+	second, ok := ring.Current()
+	require.True(t, ok)
+	assert.NotEqual(t, first.Id, second.Id)
+	assert.Equal(t, 1, store.unretired())
+
+	// the retired key still verifies tokens it signed until retainFor passes
+	_, ok = ring.PublicKey(first.Id)
+	assert.True(t, ok)
+	assert.Len(t, ring.JWKS().Keys, 2)
+
+	expired := security.NewKeyRing(store, time.Hour, 0)
+	require.NoError(t, expired.Load(ctx))
+	_, ok = expired.PublicKey(first.Id)
+	assert.False(t, ok)
+	assert.Len(t, expired.JWKS().Keys, 1)
+}
+
+func TestKeyRingsStartingTogetherShareOneKey(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryKeyStore{}
+	rings := make([]*security.KeyRing, 5)
+	var wg sync.WaitGroup
+	for i := range rings {
+		rings[i] = security.NewKeyRing(store, time.Hour, time.Hour)
+		wg.Add(1)
+		go func(ring *security.KeyRing) {
+			defer wg.Done()
+			assert.NoError(t, ring.Load(ctx))
+		}(rings[i])
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, store.unretired())
+	want, _ := rings[0].Current()
+	for _, ring := range rings {
+		require.NoError(t, ring.Load(ctx))
+		got, ok := ring.Current()
+		require.True(t, ok)
+		assert.Equal(t, want.Id, got.Id)
+	}
+}
@@ -0,0 +1,36 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// TokenSigner issues random single-use tokens and hashes them with a server
+// secret, so a leaked token table cannot be used to forge or replay links
+type TokenSigner struct {
+	secret []byte
+}
+
+func NewTokenSigner(secret string) *TokenSigner {
+	return &TokenSigner{secret: []byte(secret)}
+}
+
+// Generate returns a new token for the user and the hash to store
+func (s *TokenSigner) Generate() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, s.Hash(token), nil
+}
+
+func (s *TokenSigner) Hash(token string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,154 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordScheme is one way of hashing passwords. The stored hash names the scheme
+// and carries its parameters, so hashes made under old settings still verify
+type PasswordScheme interface {
+	// Owns reports whether the hash was made by this scheme
+	Owns(hash string) bool
+	Hash(password string) (string, error)
+	Verify(hash, password string) bool
+	// Current reports whether the hash was made with the scheme's current parameters
+	Current(hash string) bool
+}
+
+// PasswordHasher hashes new passwords with the preferred scheme and verifies hashes
+// made by it or any of the legacy ones
+type PasswordHasher struct {
+	preferred PasswordScheme
+	schemes   []PasswordScheme
+}
+
+func NewPasswordHasher(preferred PasswordScheme, legacy ...PasswordScheme) *PasswordHasher {
+	return &PasswordHasher{
+		preferred: preferred,
+		schemes:   append([]PasswordScheme{preferred}, legacy...),
+	}
+}
+
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+// Verify reports whether the password matches the hash. An empty or unrecognised
+// hash matches nothing
+func (h *PasswordHasher) Verify(hash, password string) bool {
+	for _, scheme := range h.schemes {
+		if scheme.Owns(hash) {
+			return scheme.Verify(hash, password)
+		}
+	}
+	return false
+}
+
+// NeedsRehash reports whether a hash that just verified should be replaced, because
+// it was made by a legacy scheme or with old parameters
+func (h *PasswordHasher) NeedsRehash(hash string) bool {
+	return !h.preferred.Owns(hash) || !h.preferred.Current(hash)
+}
+
+// Argon2idScheme hashes with argon2id into the PHC string format,
+// $argon2id$v=19$m=<KiB>,t=<passes>,p=<threads>$<salt>$<key>
+type Argon2idScheme struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+const argon2idPrefix = "$argon2id$"
+
+func (s Argon2idScheme) Owns(hash string) bool {
+	return strings.HasPrefix(hash, argon2idPrefix)
+}
+
+func (s Argon2idScheme) Hash(password string) (string, error) {
+	salt := make([]byte, s.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, s.Time, s.Memory, s.Threads, s.KeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version, s.Memory, s.Time, s.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (s Argon2idScheme) Verify(hash, password string) bool {
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false
+	}
+	computed := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(computed, key) == 1
+}
+
+func (s Argon2idScheme) Current(hash string) bool {
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false
+	}
+	return params.Memory == s.Memory && params.Time == s.Time && params.Threads == s.Threads &&
+		uint32(len(salt)) == s.SaltLen && uint32(len(key)) == s.KeyLen
+}
+
+func parseArgon2id(hash string) (Argon2idScheme, []byte, []byte, error) {
+	var params Argon2idScheme
+	//"", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, fmt.Errorf("not an argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("invalid argon2 key")
+	}
+	return params, salt, key, nil
+}
+
+// BcryptScheme is the scheme passwords were stored with before argon2id. Its hashes
+// start $2a$, $2b$ or $2y$ and carry their cost
+type BcryptScheme struct {
+	Cost int
+}
+
+func (s BcryptScheme) Owns(hash string) bool {
+	return strings.HasPrefix(hash, "$2")
+}
+
+func (s BcryptScheme) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.Cost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+func (s BcryptScheme) Verify(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+func (s BcryptScheme) Current(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err == nil && cost == s.Cost
+}
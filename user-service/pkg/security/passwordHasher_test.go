@@ -0,0 +1,61 @@
+package security_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testArgon2 = security.Argon2idScheme{Memory: 1024, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32}
+
+func TestPasswordHasherArgon2id(t *testing.T) {
+	hasher := security.NewPasswordHasher(testArgon2)
+
+	hash, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"), hash)
+
+	assert.True(t, hasher.Verify(hash, "correct horse"))
+	assert.False(t, hasher.Verify(hash, "correct horse!"))
+	assert.False(t, hasher.NeedsRehash(hash))
+
+	again, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, again, "each hash gets its own salt")
+}
+
+func TestPasswordHasherMigratesLegacyHashes(t *testing.T) {
+	bcryptScheme := security.BcryptScheme{Cost: bcrypt.MinCost}
+	legacy, err := bcryptScheme.Hash("correct horse")
+	require.NoError(t, err)
+	oldArgon2, err := security.NewPasswordHasher(testArgon2).Hash("correct horse")
+	require.NoError(t, err)
+
+	stronger := testArgon2
+	stronger.Time = 2
+	hasher := security.NewPasswordHasher(stronger, bcryptScheme)
+
+	// legacy bcrypt and argon2id made with older parameters both verify but need rehashing
+	assert.True(t, hasher.Verify(legacy, "correct horse"))
+	assert.False(t, hasher.Verify(legacy, "wrong horse"))
+	assert.True(t, hasher.NeedsRehash(legacy))
+	assert.True(t, hasher.Verify(oldArgon2, "correct horse"))
+	assert.True(t, hasher.NeedsRehash(oldArgon2))
+
+	rehashed, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	assert.True(t, hasher.Verify(rehashed, "correct horse"))
+	assert.False(t, hasher.NeedsRehash(rehashed))
+}
+
+func TestPasswordHasherRejectsUnknownHashes(t *testing.T) {
+	hasher := security.NewPasswordHasher(testArgon2, security.BcryptScheme{Cost: bcrypt.MinCost})
+
+	for _, hash := range []string{"", "plaintext", "$argon2id$v=19$m=1024,t=1,p=1$bad", "$2a$10$ExampleHashedPasswordForAdmin123"} {
+		assert.False(t, hasher.Verify(hash, "plaintext"), hash)
+	}
+}
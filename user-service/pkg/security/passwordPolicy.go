@@ -0,0 +1,113 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrWeakPassword = errors.New("password does not meet the password policy")
+
+type PasswordPolicyConfig struct {
+	MinLength int
+	// MaxLength is in bytes, bcrypt can't hash more than 72
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// PasswordPolicy decides whether a new password is acceptable. It knows nothing
+// about the user's earlier passwords, the caller checks those
+type PasswordPolicy struct {
+	cfg      PasswordPolicyConfig
+	breached *BreachedPasswords
+}
+
+// breached may be nil, in which case passwords aren't checked against a breach list
+func NewPasswordPolicy(cfg PasswordPolicyConfig, breached *BreachedPasswords) *PasswordPolicy {
+	return &PasswordPolicy{
+		cfg:      cfg,
+		breached: breached,
+	}
+}
+
+// Check returns ErrWeakPassword, listing every rule the password breaks. personal
+// holds things the password must not contain, like the user's email and name
+func (p *PasswordPolicy) Check(password string, personal ...string) error {
+	var problems []string
+	if utf8.RuneCountInString(password) < p.cfg.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", p.cfg.MinLength))
+	}
+	if p.cfg.MaxLength > 0 && len(password) > p.cfg.MaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes", p.cfg.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+	if p.cfg.RequireUpper && !upper {
+		problems = append(problems, "must contain an uppercase letter")
+	}
+	if p.cfg.RequireLower && !lower {
+		problems = append(problems, "must contain a lowercase letter")
+	}
+	if p.cfg.RequireDigit && !digit {
+		problems = append(problems, "must contain a digit")
+	}
+	if p.cfg.RequireSymbol && !symbol {
+		problems = append(problems, "must contain a symbol")
+	}
+
+	if containsPersonal(password, personal) {
+		problems = append(problems, "must not contain your email or name")
+	}
+
+	if p.breached != nil {
+		breached, err := p.breached.Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			problems = append(problems, "has appeared in a data breach, choose another")
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrWeakPassword, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// containsPersonal reports whether the password contains any of the personal
+// values, or the local part of an email among them. Very short values are ignored
+// so a name like "Al" doesn't rule out half the dictionary
+func containsPersonal(password string, personal []string) bool {
+	lowered := strings.ToLower(password)
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		candidates := []string{value}
+		if at := strings.LastIndex(value, "@"); at > 0 {
+			candidates = append(candidates, value[:at])
+		}
+		for _, candidate := range candidates {
+			if utf8.RuneCountInString(candidate) >= 3 && strings.Contains(lowered, candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
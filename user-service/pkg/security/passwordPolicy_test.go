@@ -0,0 +1,94 @@
+package security_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// writeBreachedList writes a sorted list in the Have I Been Pwned format
+func writeBreachedList(t *testing.T, passwords []string) string {
+	t.Helper()
+	lines := make([]string, 0, len(passwords))
+	for i, password := range passwords {
+		lines = append(lines, fmt.Sprintf("%s:%d", sha1Hex(password), i+1))
+	}
+	sort.Strings(lines)
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o600))
+	return path
+}
+
+func TestBreachedPasswordsFindsEveryListedPassword(t *testing.T) {
+	var listed []string
+	for i := 0; i < 500; i++ {
+		listed = append(listed, fmt.Sprintf("password%d", i))
+	}
+	breached, err := security.OpenBreachedPasswords(writeBreachedList(t, listed))
+	require.NoError(t, err)
+	defer breached.Close()
+
+	for _, password := range listed {
+		found, err := breached.Contains(password)
+		require.NoError(t, err)
+		assert.True(t, found, password)
+	}
+	for _, password := range []string{"", "password500", "correct horse battery staple"} {
+		found, err := breached.Contains(password)
+		require.NoError(t, err)
+		assert.False(t, found, password)
+	}
+}
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	breached, err := security.OpenBreachedPasswords(writeBreachedList(t, []string{"Tr0ub4dor&3x"}))
+	require.NoError(t, err)
+	defer breached.Close()
+
+	policy := security.NewPasswordPolicy(security.PasswordPolicyConfig{
+		MinLength:     10,
+		MaxLength:     72,
+		RequireUpper:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}, breached)
+
+	tests := []struct {
+		password string
+		problem  string
+	}{
+		{"Sh0rt!", "at least 10 characters"},
+		{strings.Repeat("Aa1!", 19), "at most 72 bytes"},
+		{"no-upper-case-1", "uppercase letter"},
+		{"No-Digits-Here", "digit"},
+		{"NoSymbols12345", "symbol"},
+		{"Grace-Hopper-1906", "email or name"},
+		{"ghopper-Navy-1906", "email or name"},
+		{"Tr0ub4dor&3x", "data breach"},
+		{"Correct-Horse-9", ""},
+	}
+	for _, tt := range tests {
+		err := policy.Check(tt.password, "ghopper@example.com", "Grace", "Hopper")
+		if tt.problem == "" {
+			assert.NoError(t, err, tt.password)
+			continue
+		}
+		if assert.ErrorIs(t, err, security.ErrWeakPassword, tt.password) {
+			assert.Contains(t, err.Error(), tt.problem, tt.password)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package security
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedisRevocationStore keeps revocations in Redis so every instance sees them.
+// It speaks just enough RESP for AUTH, SELECT, PING, SET and EXISTS over a small pool
+// of connections, so one slow reply doesn't hold up every other token check. Each call
+// has a deadline, the context's or redisTimeout, covering the wait for a connection too
+type RedisRevocationStore struct {
+	addr     string
+	password string
+	db       int
+
+	// slots bounds the connections in use, idle holds the ones free for reuse
+	slots chan struct{}
+	idle  chan *redisConn
+}
+
+const redisTimeout = 2 * time.Second
+
+type redisConn struct {
+	net.Conn
+	rd *bufio.Reader
+}
+
+func NewRedisRevocationStore(addr, password string, db, poolSize int) *RedisRevocationStore {
+	if poolSize < 1 {
+		poolSize = 1
+	}
+	return &RedisRevocationStore{
+		addr:     addr,
+		password: password,
+		db:       db,
+		slots:    make(chan struct{}, poolSize),
+		idle:     make(chan *redisConn, poolSize),
+	}
+}
+
+func (s *RedisRevocationStore) Ping(ctx context.Context) error {
+	_, err := s.do(ctx, "PING")
+	return err
+}
+
+func (s *RedisRevocationStore) Revoke(ctx context.Context, key string, ttl time.Duration) error {
+	ms := ttl.Milliseconds()
+	if ms <= 0 {
+		ms = 1
+	}
+	_, err := s.do(ctx, "SET", key, "1", "PX", strconv.FormatInt(ms, 10))
+	return err
+}
+
+func (s *RedisRevocationStore) IsRevoked(ctx context.Context, key string) (bool, error) {
+	reply, err := s.do(ctx, "EXISTS", key)
+	if err != nil {
+		return false, err
+	}
+	return reply == "1", nil
+}
+
+// Close closes the idle connections
+func (s *RedisRevocationStore) Close() {
+	for {
+		select {
+		case conn := <-s.idle:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+func (s *RedisRevocationStore) do(ctx context.Context, args ...string) (string, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, redisTimeout)
+		defer cancel()
+	}
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		return "", fmt.Errorf("no redis connection free: %w", ctx.Err())
+	}
+
+	conn, reused, err := s.get(ctx)
+	if err != nil {
+		return "", err
+	}
+	reply, err := conn.command(ctx, args...)
+	if err == nil || isRedisError(err) {
+		s.put(conn)
+		return reply, err
+	}
+	//connection is in an unknown state, drop it
+	conn.Close()
+	if !reused || errors.Is(err, os.ErrDeadlineExceeded) || ctx.Err() != nil {
+		return "", err
+	}
+
+	//redis restarted or closed the idle connection, the commands are idempotent so retry once
+	conn, err = s.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	reply, err = conn.command(ctx, args...)
+	if err == nil || isRedisError(err) {
+		s.put(conn)
+	} else {
+		conn.Close()
+	}
+	return reply, err
+}
+
+// get reuses an idle connection when there is one and dials otherwise
+func (s *RedisRevocationStore) get(ctx context.Context) (*redisConn, bool, error) {
+	select {
+	case conn := <-s.idle:
+		return conn, true, nil
+	default:
+	}
+	conn, err := s.dial(ctx)
+	return conn, false, err
+}
+
+func (s *RedisRevocationStore) put(conn *redisConn) {
+	select {
+	case s.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+func (s *RedisRevocationStore) dial(ctx context.Context) (*redisConn, error) {
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	conn := &redisConn{Conn: netConn, rd: bufio.NewReader(netConn)}
+
+	if s.password != "" {
+		if _, err := conn.command(ctx, "AUTH", s.password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis auth failed: %w", err)
+		}
+	}
+	if s.db != 0 {
+		if _, err := conn.command(ctx, "SELECT", strconv.Itoa(s.db)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis select failed: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// isRedisError reports an error reply, after which the connection is still usable
+func isRedisError(err error) bool {
+	var redisErr redisError
+	return errors.As(err, &redisErr)
+}
+
+func (c *redisConn) command(ctx context.Context, args ...string) (string, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisTimeout)
+	}
+	c.SetDeadline(deadline)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := c.Write([]byte(b.String())); err != nil {
+		return "", err
+	}
+
+	line, err := c.rd.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return "", errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", err
+		}
+		if n < 0 {
+			return "", nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.rd, buf); err != nil {
+			return "", err
+		}
+		return string(buf[:n]), nil
+	default:
+		return "", fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
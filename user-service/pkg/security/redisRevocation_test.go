@@ -0,0 +1,272 @@
+package security_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/user-service/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis answers the commands RedisRevocationStore sends. hang makes it stop
+// replying, delay makes it slow and dropConnections simulates a restart
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu    sync.Mutex
+	keys  map[string]bool
+	conns []net.Conn
+	dials int
+	hang  bool
+	delay time.Duration
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	f := &fakeRedis{listener: listener, password: password, keys: map[string]bool{}}
+	t.Cleanup(func() {
+		listener.Close()
+		f.dropConnections()
+	})
+	go f.serve()
+	return f
+}
+
+func (f *fakeRedis) addr() string { return f.listener.Addr().String() }
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.conns = append(f.conns, conn)
+		f.dials++
+		f.mu.Unlock()
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	rd := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		args, err := readCommand(rd)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		hang, delay := f.hang, f.delay
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if args[1] == f.password {
+				authed = true
+				reply = "+OK"
+			} else {
+				reply = "-WRONGPASS invalid password"
+			}
+		case "PING":
+			reply = "+PONG"
+		case "SELECT":
+			reply = "+OK"
+		case "SET":
+			f.keys[args[1]] = true
+			reply = "+OK"
+		case "EXISTS":
+			reply = ":0"
+			if f.keys[args[1]] {
+				reply = ":1"
+			}
+		default:
+			reply = "-ERR unknown command"
+		}
+		if !authed && strings.ToUpper(args[0]) != "AUTH" {
+			reply = "-NOAUTH Authentication required"
+		}
+		f.mu.Unlock()
+		if hang {
+			continue
+		}
+		time.Sleep(delay)
+		fmt.Fprintf(conn, "%s\r\n", reply)
+	}
+}
+
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := rd.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := rd.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func (f *fakeRedis) dropConnections() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, conn := range f.conns {
+		conn.Close()
+	}
+	f.conns = nil
+}
+
+func (f *fakeRedis) setHang(hang bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hang = hang
+}
+
+func (f *fakeRedis) setDelay(delay time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delay = delay
+}
+
+func (f *fakeRedis) dialCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.dials
+}
+
+func TestRedisRevocationStore(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+	store := security.NewRedisRevocationStore(server.addr(), "secret", 1, 4)
+	ctx := context.Background()
+
+	require.NoError(t, store.Ping(ctx))
+	require.NoError(t, store.Revoke(ctx, security.SessionKey("session-1"), time.Minute))
+	revoked, err := store.IsRevoked(ctx, security.SessionKey("session-1"))
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = store.IsRevoked(ctx, security.SessionKey("session-2"))
+	require.NoError(t, err)
+	assert.False(t, revoked)
+	assert.Equal(t, 1, server.dialCount(), "one caller at a time reuses the same connection")
+}
+
+func TestRedisRevocationStoreWrongPassword(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+	store := security.NewRedisRevocationStore(server.addr(), "guessed", 0, 4)
+
+	err := store.Ping(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "WRONGPASS")
+}
+
+func TestRedisRevocationStoreReconnects(t *testing.T) {
+	server := newFakeRedis(t, "")
+	store := security.NewRedisRevocationStore(server.addr(), "", 0, 4)
+	ctx := context.Background()
+	require.NoError(t, store.Revoke(ctx, "token:1", time.Minute))
+
+	// a restart closes the connection under the store, the next call redials transparently
+	server.dropConnections()
+	revoked, err := store.IsRevoked(ctx, "token:1")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	assert.Equal(t, 2, server.dialCount())
+}
+
+func TestRedisRevocationStoreUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	store := security.NewRedisRevocationStore(addr, "", 0, 4)
+	_, err = store.IsRevoked(context.Background(), "token:1")
+	assert.ErrorContains(t, err, "failed to connect to redis")
+}
+
+func TestRedisRevocationStoreTimeout(t *testing.T) {
+	server := newFakeRedis(t, "")
+	store := security.NewRedisRevocationStore(server.addr(), "", 0, 4)
+	require.NoError(t, store.Ping(context.Background()))
+
+	server.setHang(true)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	_, err := store.IsRevoked(ctx, "token:1")
+	require.Error(t, err)
+	assert.Less(t, time.Since(started), time.Second, "a timeout isn't retried")
+
+	// the late reply would be read as the answer to the next command, so the
+	// connection must be replaced rather than reused
+	server.setHang(false)
+	revoked, err := store.IsRevoked(context.Background(), "token:1")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+	assert.Equal(t, 2, server.dialCount())
+}
+
+func TestRedisRevocationStoreChecksInParallel(t *testing.T) {
+	server := newFakeRedis(t, "")
+	store := security.NewRedisRevocationStore(server.addr(), "", 0, 4)
+	server.setDelay(200 * time.Millisecond)
+
+	// one connection would answer these one after another, taking 1.6s
+	var wg sync.WaitGroup
+	started := time.Now()
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.IsRevoked(context.Background(), "token:1")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Less(t, time.Since(started), 1200*time.Millisecond)
+	assert.Equal(t, 4, server.dialCount(), "connections are reused and capped at the pool size")
+}
+
+func TestRedisRevocationStoreWaitForConnectionHasADeadline(t *testing.T) {
+	server := newFakeRedis(t, "")
+	store := security.NewRedisRevocationStore(server.addr(), "", 0, 1)
+	require.NoError(t, store.Ping(context.Background()))
+	server.setHang(true)
+
+	// the second check waits for the only connection, and gives up with the first
+	var wg sync.WaitGroup
+	started := time.Now()
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			_, err := store.IsRevoked(ctx, "token:1")
+			assert.Error(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Less(t, time.Since(started), time.Second)
+}
@@ -0,0 +1,71 @@
+package security
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ollatomiwa/hotelsystem/shared/auth"
+)
+
+// RevocationStore remembers revoked access tokens (by jti) and sessions (by sid)
+// until the tokens they cover would have expired anyway
+type RevocationStore interface {
+	Revoke(ctx context.Context, key string, ttl time.Duration) error
+	IsRevoked(ctx context.Context, key string) (bool, error)
+}
+
+func TokenKey(jti string) string {
+	return "revoked:jti:" + jti
+}
+
+func SessionKey(sid string) string {
+	return "revoked:sid:" + sid
+}
+
+// RevocationCheck lets the shared auth middleware reject revoked tokens and sessions
+func RevocationCheck(store RevocationStore) auth.RevocationCheck {
+	return func(ctx context.Context, claims *auth.Claims) (bool, error) {
+		if claims.ID != "" {
+			revoked, err := store.IsRevoked(ctx, TokenKey(claims.ID))
+			if err != nil || revoked {
+				return revoked, err
+			}
+		}
+		if claims.SessionId != "" {
+			return store.IsRevoked(ctx, SessionKey(claims.SessionId))
+		}
+		return false, nil
+	}
+}
+
+// MemoryRevocationStore is the fallback when Redis is not configured.
+// Revocations are lost on restart and are not shared between instances
+type MemoryRevocationStore struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+}
+
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{entries: map[string]time.Time{}}
+}
+
+func (s *MemoryRevocationStore) Revoke(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, expiresAt := range s.entries {
+		if now.After(expiresAt) {
+			delete(s.entries, k)
+		}
+	}
+	s.entries[key] = now.Add(ttl)
+	return nil
+}
+
+func (s *MemoryRevocationStore) IsRevoked(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiresAt, ok := s.entries[key]
+	return ok && time.Now().Before(expiresAt), nil
+}